@@ -62,12 +62,12 @@ const HomePage: React.FC = () => {
   };
   const getAgents = async () => {
     const res = await agents();
-    setAgentsList(res?.agents || []);
+    setAgentsList(res?.items || []);
   };
 
   const getLatestBlocks = async () => {
     const res = await latestBlocks();
-    setLatestBlocksData(res?.blocks?.[0] || null)
+    setLatestBlocksData(res?.items?.[0] || null)
   };
 
   useEffect(() => {
@@ -295,8 +295,8 @@ const HomePage: React.FC = () => {
 
             setListTotal(res?.total|| 0)
             return {
-              data: res?.proposals || [],
-              success: !!res?.proposals,
+              data: res?.items || [],
+              success: !!res?.items,
               total: res?.total|| 0,
             };
           }}
@@ -17,11 +17,14 @@ declare namespace AGENTAPI {
     new_height?: number, //提案发起时区块高度
     settle_height?: number, //提案决议时区块高度，为0表示尚未投票决议
     status?: number, //状态码
+    status_name?: string, // proposed | ignored | discussing | passed | rejected | expired
     create_timestamp?: number, //提案发起unix时间戳
     expire_timestamp?: number, //提案超时unix时间戳
     title?: string,
+    summary?: string,
     image_url?:string,
-    link?:string
+    link?:string,
+    expedited?: boolean, //紧急提案，截止时间更短
   }
 
   interface ProposalInfo {
@@ -33,20 +36,42 @@ declare namespace AGENTAPI {
     decisionReject: number, // 决议投反对票的个数
     draftVotes?: DraftVote[],
     decisionVotes?: DraftVote[],
+    draftTally?: VoteTally, // 草案按投票权重统计
+    decisionTally?: VoteTally, // 决议按投票权重统计
+  }
+  // 按投票高度的质押计算的投票权重统计
+  interface VoteTally {
+    height: number,
+    pass_power: number,
+    reject_power: number,
+    total_power: number,
+    pass_percent: number,
+    reject_percent: number,
+    quorum_percent: number, // 已投票权重占总权重的百分比
+    threshold: number, // 通过所需的权重占比
+    passed: boolean, // 通过权重超过 threshold，已结算的提案以链上状态为准
   }
   interface DraftVote {
     pass: boolean, //通过or拒绝
     voter_index: number, //投票人id
     voter_address: string, //投票人地址
     height: number,
-    voteCode: number //投票码
+    voteCode: string //投票码
+    reason?: string
+    power?: number //投票权重
   }
 
-  interface ProposalsRes {
-    proposals?: ProposalInfo[],
+  // 列表分页
+  interface Page<T> {
+    items?: T[],
     total?: number,
+    page?: number,
+    page_size?: number,
+    next_cursor?: string,
   }
 
+  type ProposalsRes = Page<ProposalInfo>
+
   // 提案详情
   interface ProposalDetailRes {
     proposal?: Proposal,
@@ -61,7 +86,8 @@ declare namespace AGENTAPI {
     discussions: DiscussionInfo[],
     decisionVotes: DraftVote[],
     decisionPass: number,
-    decisionReject: number
+    decisionReject: number,
+    decisionTally?: VoteTally,
   }
 
   interface DiscussionInfo {
@@ -71,7 +97,9 @@ declare namespace AGENTAPI {
     speaker_address: string, //发言人地址
     speaker_name: string, //发言人名称
     data: string, //发言内容
-    height: string //发言高度
+    height: string, //发言高度
+    tx_hash?: string,
+    block_time?: string,
   }
 
 
@@ -81,9 +109,7 @@ declare namespace AGENTAPI {
   }
 
   // 获取agent列表
-  interface AgentsRes {
-    agents?: AgentInfo[]
-  }
+  type AgentsRes = Page<AgentInfo>
   interface AgentInfo {
     id?:number,
     address?: string, //地址
@@ -102,9 +128,7 @@ declare namespace AGENTAPI {
   }
 
   // 获取最新块区
-  interface LatestBlocksRes {
-    blocks?: BlockInfo[],
-  }
+  type LatestBlocksRes = Page<BlockInfo>
 
   interface BlockInfo {
     height?: number,
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	ActivationScheduled = "scheduled"
+	ActivationNotified  = "notified"
+	ActivationActivated = "activated"
+	ActivationCancelled = "cancelled"
+)
+
+// ActivationNoticeDefault is the number of blocks before its activation
+// height a scheduled proposal is announced when no notice is configured.
+const ActivationNoticeDefault = 20
+
+// ProposalActivation schedules a proposal carrying an activation height. It
+// is announced to the agent and the notifiers activation_notice_blocks before
+// the height, activated at the height when the proposal passed by then and
+// cancelled otherwise.
+type ProposalActivation struct {
+	Id               uint64    `gorm:"primary_key" json:"id"`
+	Proposal         uint64    `gorm:"unique_index" json:"proposal"`
+	Title            string    `json:"title"`
+	ActivationHeight uint64    `gorm:"index" json:"activation_height"`
+	Status           string    `gorm:"index" json:"status"`
+	ScheduledHeight  uint64    `json:"scheduled_height"`
+	NotifiedHeight   uint64    `json:"notified_height"`
+	ClosedHeight     uint64    `json:"closed_height"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// scheduleActivation indexes the activation height of a new proposal in tx.
+// An activation height the chain already reached is ignored.
+func (c *ChainIndexer) scheduleActivation(tx *gorm.DB, proposal Proposal, height uint64) error {
+	if proposal.ActivationHeight == 0 {
+		return nil
+	}
+	if proposal.ActivationHeight <= height {
+		c.logger.Info("ignore past activation height", "proposal", proposal.Id, "activationHeight", proposal.ActivationHeight, "height", height)
+		return nil
+	}
+	var activation ProposalActivation
+	err := tx.Where("proposal = ?", proposal.Id).First(&activation).Error
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return tx.Create(&ProposalActivation{
+		Proposal:         proposal.Id,
+		Title:            proposal.Title,
+		ActivationHeight: proposal.ActivationHeight,
+		Status:           ActivationScheduled,
+		ScheduledHeight:  height,
+	}).Error
+}
+
+// cancelActivation cancels the pending activation of a proposal settled
+// without passing.
+func (c *ChainIndexer) cancelActivation(tx *gorm.DB, proposal uint64, height uint64) error {
+	return tx.Model(&ProposalActivation{}).
+		Where("proposal = ? AND status IN (?)", proposal, []string{ActivationScheduled, ActivationNotified}).
+		Updates(map[string]interface{}{"status": ActivationCancelled, "closed_height": height}).Error
+}
+
+// advanceActivations runs once height is indexed. It announces the
+// activations entering their notice window and closes the ones reached.
+func (c *ChainIndexer) advanceActivations(height uint64, blockTime time.Time) error {
+	var due []ProposalActivation
+	err := c.db.Where("status IN (?) AND activation_height <= ?", []string{ActivationScheduled, ActivationNotified}, height+c.activateNotice).
+		Order("activation_height asc, id asc").Find(&due).Error
+	if err != nil {
+		return err
+	}
+	for _, activation := range due {
+		var proposal Proposal
+		if err := c.db.First(&proposal, activation.Proposal).Error; err != nil {
+			return err
+		}
+		status := activation.Status
+		switch {
+		case proposal.Status == ProposalStatusIgnored || (proposal.Status.Settled() && proposal.Status != ProposalStatusPassed):
+			status = ActivationCancelled
+		case activation.ActivationHeight <= height && proposal.Status == ProposalStatusPassed:
+			status = ActivationActivated
+		case activation.ActivationHeight <= height:
+			// reached before the proposal passed
+			status = ActivationCancelled
+		case activation.Status == ActivationScheduled:
+			status = ActivationNotified
+		}
+		if status == activation.Status {
+			continue
+		}
+		activation.Status = status
+		if status == ActivationNotified {
+			activation.NotifiedHeight = height
+		} else {
+			activation.ClosedHeight = height
+		}
+		err := c.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&activation).Error; err != nil {
+				return err
+			}
+			switch status {
+			case ActivationActivated:
+				return c.closeSpend(tx, activation.Proposal, SpendExecuted, height, blockTime)
+			case ActivationCancelled:
+				return c.closeSpend(tx, activation.Proposal, SpendRejected, height, blockTime)
+			}
+			return c.enqueueAgentCall(tx, "activation:"+strconv.FormatUint(activation.Proposal, 10), AgentOutbox{
+				Method:   outboxNotifyActivation,
+				Proposal: activation.Proposal,
+				Address:  proposal.ProposerAddress,
+				Height:   activation.ActivationHeight,
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("advance activation of proposal %d: %w", activation.Proposal, err)
+		}
+		c.logger.Info("proposal activation", "proposal", activation.Proposal, "status", status, "activationHeight", activation.ActivationHeight, "height", height)
+		if status == ActivationNotified {
+			c.wakeOutbox()
+			c.notifier.notify(Notification{
+				Event:            NotifyProposalActivating,
+				Kind:             voteKindProposal,
+				ProposalId:       proposal.Id,
+				Title:            proposal.Title,
+				Proposer:         proposal.ProposerName,
+				Status:           proposal.Status.String(),
+				Expedited:        proposal.Expedited,
+				Height:           height,
+				ActivationHeight: activation.ActivationHeight,
+				BlockTime:        blockTime,
+			})
+		}
+	}
+	return nil
+}
+
+// getUpcomingActivations returns the pending activations, the nearest first.
+func (c *ChainIndexer) getUpcomingActivations(page int, pageSize int) (Page[ProposalActivation], error) {
+	db := c.db.Model(&ProposalActivation{}).Where("status IN (?)", []string{ActivationScheduled, ActivationNotified})
+	return paginate[ProposalActivation](db, "activation_height asc, id asc", page, pageSize)
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// DefaultRetryStatuses are the statuses of an agent call retried when none
+// are configured.
+var DefaultRetryStatuses = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// RetryPolicy retries the agent calls failing to connect or answered with
+// one of Statuses, up to MaxAttempts attempts in all, waiting an exponential
+// backoff with jitter between Backoff and MaxBackoff. A MaxAttempts of 1 or
+// less sends every call once.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Statuses    []int
+}
+
+func (p RetryPolicy) retryStatus(status int) bool {
+	return slices.Contains(p.Statuses, status)
+}
+
+// do sends the request built by newReq until it gets an answer that is not
+// retried or runs out of attempts, the last response or error is returned.
+// The calls carry their idempotency key, so the agent sees a retried call
+// as the same one.
+func (p RetryPolicy) do(ctx context.Context, method string, client *http.Client, newReq func() (*http.Request, error)) (*http.Response, error) {
+	var b *backoff
+	for attempt := 1; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, err
+		}
+		res, err := client.Do(req)
+		retry := attempt < p.MaxAttempts && ctx.Err() == nil
+		switch {
+		case err != nil && !retry:
+			return nil, err
+		case err == nil && (!retry || !p.retryStatus(res.StatusCode)):
+			return res, nil
+		case err == nil:
+			// read the body so the connection is reused
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+			return nil, err
+		}
+		if b == nil {
+			b = newBackoff(p.Backoff, p.MaxBackoff)
+		}
+		timer := time.NewTimer(b.Next())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if err == nil {
+				err = ctx.Err()
+			}
+			return nil, err
+		case <-timer.C:
+		}
+		agentCallRetries.WithLabelValues(method).Inc()
+	}
+}
+
+var _ Client = &RetryClient{}
+
+// RetryClient calls the wrapped agent again when it is unavailable or timed
+// out, following its policy. It retries the calls of any backend, the eliza
+// backend retries its http requests itself.
+type RetryClient struct {
+	interceptedClient
+	policy RetryPolicy
+}
+
+func NewRetryClient(inner Client, p RetryPolicy) *RetryClient {
+	r := &RetryClient{policy: p}
+	r.interceptedClient = interceptedClient{Client: inner, intercept: r.retry}
+	return r
+}
+
+func (r *RetryClient) retry(ctx context.Context, method string, send func() error) error {
+	var b *backoff
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || attempt >= r.policy.MaxAttempts || ctx.Err() != nil ||
+			!(errors.Is(err, ErrAgentUnavailable) || errors.Is(err, ErrAgentDecisionTimeout)) {
+			return err
+		}
+		if b == nil {
+			b = newBackoff(r.policy.Backoff, r.policy.MaxBackoff)
+		}
+		timer := time.NewTimer(b.Next())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		agentCallRetries.WithLabelValues(method).Inc()
+	}
+}
@@ -0,0 +1,252 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/calehh/hac-app/tx"
+	hac_types "github.com/calehh/hac-app/types"
+	"github.com/jinzhu/gorm"
+)
+
+// anchorMaxLeaves caps the transcripts anchored by one tx, a backlog is
+// anchored over the following ticks.
+const anchorMaxLeaves = 4096
+
+var ErrDecisionNotAnchored = errors.New("decision not anchored yet")
+
+// DecisionAnchor is the merkle root of the transcripts FromId to ToId, the
+// local agent's decision log, anchored on-chain by the tx TxHash at Height.
+type DecisionAnchor struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Root      string    `gorm:"unique_index" json:"root"`
+	FromId    uint64    `gorm:"index" json:"from_id"`
+	ToId      uint64    `gorm:"index" json:"to_id"`
+	Leaves    int       `json:"leaves"`
+	TxHash    string    `json:"tx_hash"`
+	Height    uint64    `json:"height"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProofStep is a sibling hash on the path from a leaf to the root, Left when
+// the sibling is hashed on the left.
+type ProofStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// DecisionProof proves the transcript Transcript hashing to Leaf is part of
+// the anchor Anchor. Before is whether the anchor was included at or before
+// the height asked for, OnChain whether the chain confirmed the tx carries
+// the root.
+type DecisionProof struct {
+	Transcript uint64         `json:"transcript"`
+	Kind       string         `json:"kind"`
+	RefId      uint64         `json:"ref_id"`
+	Vote       string         `json:"vote"`
+	DecidedAt  time.Time      `json:"decided_at"`
+	Leaf       string         `json:"leaf"`
+	Proof      []ProofStep    `json:"proof"`
+	Anchor     DecisionAnchor `json:"anchor"`
+	Height     uint64         `json:"height"`
+	Before     bool           `json:"before"`
+	OnChain    bool           `json:"on_chain"`
+}
+
+// decisionLeaf hashes the fields of a transcript that make the decision.
+// CreatedAt is taken in seconds since databases keep different precisions.
+func decisionLeaf(t Transcript) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	var num [8]byte
+	for _, v := range []uint64{t.Id, t.RefId, uint64(t.CreatedAt.Unix()), uint64(t.StatusCode)} {
+		binary.BigEndian.PutUint64(num[:], v)
+		h.Write(num[:])
+	}
+	for _, s := range []string{t.Kind, t.Voter, t.Method, t.Prompt, t.Response, t.Vote, t.Error} {
+		binary.BigEndian.PutUint64(num[:], uint64(len(s)))
+		h.Write(num[:])
+		h.Write([]byte(s))
+	}
+	return h.Sum(nil)
+}
+
+func merkleNode(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleRoot returns the root over leaves and the proof of the leaf at index.
+// A node without a sibling is carried up unchanged.
+func merkleRoot(leaves [][]byte, index int) ([]byte, []ProofStep) {
+	var proof []ProofStep
+	level := leaves
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			switch index {
+			case i:
+				proof = append(proof, ProofStep{Hash: hex.EncodeToString(level[i+1])})
+			case i + 1:
+				proof = append(proof, ProofStep{Hash: hex.EncodeToString(level[i]), Left: true})
+			}
+			next = append(next, merkleNode(level[i], level[i+1]))
+		}
+		index /= 2
+		level = next
+	}
+	return level[0], proof
+}
+
+func (c *ChainIndexer) runAnchor(ctx context.Context) {
+	ticker := time.NewTicker(c.anchorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if c.follower() {
+			continue
+		}
+		if err := c.anchorDecisions(ctx); err != nil {
+			c.logger.Error("anchor decisions fail", "err", err)
+		}
+	}
+}
+
+// anchorDecisions submits the root of the transcripts recorded since the last
+// anchor and stores the anchor once included.
+func (c *ChainIndexer) anchorDecisions(ctx context.Context) error {
+	var last DecisionAnchor
+	if err := c.db.Order("to_id desc").First(&last).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	var transcripts []Transcript
+	if err := c.db.Where("id > ?", last.ToId).Order("id asc").Limit(anchorMaxLeaves).Find(&transcripts).Error; err != nil {
+		return err
+	}
+	if len(transcripts) == 0 {
+		return nil
+	}
+	leaves := make([][]byte, len(transcripts))
+	for i, t := range transcripts {
+		leaves[i] = decisionLeaf(t)
+	}
+	root, _ := merkleRoot(leaves, 0)
+
+	// drafts from the proposal webhook use the same account
+	c.webhook.mtx.Lock()
+	defer c.webhook.mtx.Unlock()
+	submitter, err := NewTxSubmitter(ctx, c.cli, c.pv)
+	if err != nil {
+		return err
+	}
+	res, err := submitter.SubmitCommit(ctx, tx.HACTxTypeAnchor, &tx.AnchorTx{Root: root, Leaves: uint64(len(leaves))})
+	if err != nil {
+		return err
+	}
+	anchor := DecisionAnchor{
+		Root:   hex.EncodeToString(root),
+		FromId: transcripts[0].Id,
+		ToId:   transcripts[len(transcripts)-1].Id,
+		Leaves: len(leaves),
+		TxHash: res.Hash.String(),
+		Height: uint64(res.Height),
+	}
+	if err := c.db.Create(&anchor).Error; err != nil {
+		return err
+	}
+	decisionAnchors.Inc()
+	c.logger.Info("decisions anchored", "root", anchor.Root, "leaves", anchor.Leaves, "height", anchor.Height)
+	return nil
+}
+
+// proveDecision proves the transcript id was anchored, and whether before
+// height.
+func (c *ChainIndexer) proveDecision(ctx context.Context, id uint64, height uint64) (DecisionProof, error) {
+	var anchor DecisionAnchor
+	err := c.db.Where("from_id <= ? AND to_id >= ?", id, id).First(&anchor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DecisionProof{}, ErrDecisionNotAnchored
+	}
+	if err != nil {
+		return DecisionProof{}, err
+	}
+	var transcripts []Transcript
+	if err := c.db.Where("id >= ? AND id <= ?", anchor.FromId, anchor.ToId).Order("id asc").Find(&transcripts).Error; err != nil {
+		return DecisionProof{}, err
+	}
+	index := -1
+	leaves := make([][]byte, len(transcripts))
+	for i, t := range transcripts {
+		leaves[i] = decisionLeaf(t)
+		if t.Id == id {
+			index = i
+		}
+	}
+	if index < 0 {
+		return DecisionProof{}, ErrDecisionNotAnchored
+	}
+	root, proof := merkleRoot(leaves, index)
+	if hex.EncodeToString(root) != anchor.Root {
+		return DecisionProof{}, fmt.Errorf("transcripts %d to %d no longer hash to anchor %s", anchor.FromId, anchor.ToId, anchor.Root)
+	}
+	t := transcripts[index]
+	response := DecisionProof{
+		Transcript: t.Id,
+		Kind:       t.Kind,
+		RefId:      t.RefId,
+		Vote:       t.Vote,
+		DecidedAt:  t.CreatedAt,
+		Leaf:       hex.EncodeToString(leaves[index]),
+		Proof:      proof,
+		Anchor:     anchor,
+		Height:     height,
+		Before:     anchor.Height <= height,
+	}
+	response.OnChain, err = c.anchorOnChain(ctx, anchor, root)
+	if err != nil {
+		c.logger.Error("query anchor tx fail", "tx", anchor.TxHash, "err", err)
+	}
+	return response, nil
+}
+
+// anchorOnChain checks the chain included the anchor tx at its height with
+// root, submitted by this node.
+func (c *ChainIndexer) anchorOnChain(ctx context.Context, anchor DecisionAnchor, root []byte) (bool, error) {
+	hash, err := hex.DecodeString(anchor.TxHash)
+	if err != nil {
+		return false, err
+	}
+	res, err := c.cli.Tx(ctx, hash, false)
+	if err != nil {
+		return false, err
+	}
+	if uint64(res.Height) != anchor.Height {
+		return false, nil
+	}
+	for _, ev := range res.TxResult.Events {
+		if ev.Type != hac_types.EventAnchorType {
+			continue
+		}
+		if a := hac_types.DecodeEventAnchor(ev); a != nil && a.Address == c.localAddress && bytes.Equal(a.Root, root) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
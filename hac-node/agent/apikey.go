@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	ScopeReadGovernance = "read-governance"
+	ScopeReadVotes      = "read-votes"
+	ScopeAdmin          = "admin"
+)
+
+var apiScopes = map[string]bool{ScopeReadGovernance: true, ScopeReadVotes: true, ScopeAdmin: true}
+
+var (
+	ErrInvalidApiKey = errors.New("invalid name or scopes, scopes are read-governance, read-votes and admin")
+	errForbidden     = errors.New("api key lacks the required scope")
+	errRateLimited   = errors.New("api key rate limit exceeded")
+	errQuotaExceeded = errors.New("api key daily quota exceeded")
+)
+
+// apiKeyAuth checks the keys of api requests. Auth is off without an admin
+// key, every endpoint is then open as before keys existed. With one, admin
+// endpoints need the admin key or a key with the admin scope, and public
+// endpoints need a key only when requireKey is set. Every stored key has its
+// own rate and quota bucket; the buckets live in memory, so quotas restart
+// with the node.
+type apiKeyAuth struct {
+	adminKey   string
+	requireKey bool
+	mtx        sync.Mutex
+	buckets    map[uint64]*apiKeyBucket
+}
+
+// apiKeyBucket is a token bucket refilled at RateLimit per minute, plus the
+// number of requests of the current UTC day.
+type apiKeyBucket struct {
+	tokens float64
+	last   time.Time
+	day    string
+	used   int
+}
+
+// CreatedApiKey is returned once when a key is issued, the key itself is not
+// stored.
+type CreatedApiKey struct {
+	ApiKey
+	Key string `json:"key"`
+}
+
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseScopes validates a scope list and returns it sorted and deduplicated.
+func parseScopes(scopes []string) (string, error) {
+	set := make(map[string]bool)
+	for _, s := range scopes {
+		s = strings.TrimSpace(s)
+		if !apiScopes[s] {
+			return "", ErrInvalidApiKey
+		}
+		set[s] = true
+	}
+	if len(set) == 0 {
+		return "", ErrInvalidApiKey
+	}
+	list := make([]string, 0, len(set))
+	for s := range set {
+		list = append(list, s)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ","), nil
+}
+
+func (k ApiKey) hasScope(scope string) bool {
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *ChainIndexer) addApiKey(name string, scopes []string, rateLimit int, dailyQuota int) (CreatedApiKey, error) {
+	name = strings.TrimSpace(name)
+	list, err := parseScopes(scopes)
+	if err != nil || name == "" || rateLimit < 0 || dailyQuota < 0 {
+		return CreatedApiKey{}, ErrInvalidApiKey
+	}
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return CreatedApiKey{}, err
+	}
+	key := "hac_" + hex.EncodeToString(raw)
+	created := CreatedApiKey{
+		ApiKey: ApiKey{
+			Name:       name,
+			Prefix:     key[:12],
+			KeyHash:    hashApiKey(key),
+			Scopes:     list,
+			RateLimit:  rateLimit,
+			DailyQuota: dailyQuota,
+		},
+		Key: key,
+	}
+	return created, c.db.Create(&created.ApiKey).Error
+}
+
+func (c *ChainIndexer) removeApiKey(id uint64) (bool, error) {
+	res := c.db.Where("id = ?", id).Delete(&ApiKey{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	c.apiKeys.mtx.Lock()
+	delete(c.apiKeys.buckets, id)
+	c.apiKeys.mtx.Unlock()
+	return res.RowsAffected > 0, nil
+}
+
+func (c *ChainIndexer) getApiKeys(page int, pageSize int) (Page[ApiKey], error) {
+	return paginate[ApiKey](c.db.Model(&ApiKey{}), "id asc", page, pageSize)
+}
+
+// authorizeApiKey checks that the key of a request, sent as a bearer token or
+// in X-Api-Key, grants scope, and takes one request from its bucket. Without
+// an admin key only the public scopes are served, the admin endpoints are
+// closed.
+func (c *ChainIndexer) authorizeApiKey(header http.Header, scope string) error {
+	auth := &c.apiKeys
+	if auth.adminKey == "" {
+		if scope == ScopeAdmin {
+			return errUnauthorized
+		}
+		return nil
+	}
+	key := header.Get("X-Api-Key")
+	if token, ok := strings.CutPrefix(header.Get("Authorization"), "Bearer "); ok {
+		key = token
+	}
+	if key == "" {
+		if scope != ScopeAdmin && !auth.requireKey {
+			return nil
+		}
+		return errUnauthorized
+	}
+	if subtle.ConstantTimeCompare([]byte(key), []byte(auth.adminKey)) == 1 {
+		return nil
+	}
+	var apiKey ApiKey
+	if err := c.db.Where("key_hash = ?", hashApiKey(key)).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errUnauthorized
+		}
+		return err
+	}
+	if !apiKey.hasScope(scope) {
+		return errForbidden
+	}
+	return auth.take(apiKey, time.Now().UTC())
+}
+
+func (a *apiKeyAuth) take(key ApiKey, now time.Time) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.buckets == nil {
+		a.buckets = make(map[uint64]*apiKeyBucket)
+	}
+	b, ok := a.buckets[key.Id]
+	if !ok {
+		b = &apiKeyBucket{tokens: float64(key.RateLimit), last: now}
+		a.buckets[key.Id] = b
+	}
+	if day := now.Format("2006-01-02"); b.day != day {
+		b.day, b.used = day, 0
+	}
+	if key.DailyQuota > 0 && b.used >= key.DailyQuota {
+		return errQuotaExceeded
+	}
+	if key.RateLimit > 0 {
+		rate := float64(key.RateLimit)
+		b.tokens += now.Sub(b.last).Minutes() * rate
+		if b.tokens > rate {
+			b.tokens = rate
+		}
+		b.last = now
+		if b.tokens < 1 {
+			return errRateLimited
+		}
+		b.tokens--
+	}
+	b.used++
+	return nil
+}
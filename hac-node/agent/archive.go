@@ -0,0 +1,247 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ArchiveKindProposal = "proposal"
+	ArchiveKindThread   = "thread"
+)
+
+// ArchiveInterval is the time between archival passes.
+var ArchiveInterval = 30 * time.Second
+
+// archiveBatch bounds the proposals archived per kind in one pass.
+const archiveBatch = 20
+
+// ArchivedProposal is the document pinned when a proposal is created.
+type ArchivedProposal struct {
+	ChainId         string    `json:"chain_id"`
+	Id              uint64    `json:"id"`
+	ProposerIndex   uint64    `json:"proposer_index"`
+	ProposerAddress string    `json:"proposer_address"`
+	Title           string    `json:"title"`
+	Summary         string    `json:"summary"`
+	Data            string    `json:"data"`
+	Link            string    `json:"link"`
+	ImageUrl        string    `json:"image_url"`
+	Height          uint64    `json:"height"`
+	BlockTime       time.Time `json:"block_time"`
+}
+
+type ArchivedDiscussion struct {
+	SpeakerIndex   uint64    `json:"speaker_index"`
+	SpeakerAddress string    `json:"speaker_address"`
+	Data           string    `json:"data"`
+	Height         uint64    `json:"height"`
+	TxHash         string    `json:"tx_hash"`
+	BlockTime      time.Time `json:"block_time"`
+}
+
+// ArchivedThread is the snapshot of the discussion thread pinned when a
+// proposal settles. It links the proposal document by its cid.
+type ArchivedThread struct {
+	ChainId      string               `json:"chain_id"`
+	Proposal     uint64               `json:"proposal"`
+	ProposalCid  string               `json:"proposal_cid"`
+	Status       string               `json:"status"`
+	SettleHeight uint64               `json:"settle_height"`
+	Discussions  []ArchivedDiscussion `json:"discussions"`
+}
+
+// ipfsClient adds documents through the /api/v0/add endpoint of an ipfs
+// node. Pinning services exposing the same api are supported with a bearer
+// token.
+type ipfsClient struct {
+	cli   *http.Client
+	url   string
+	token string
+}
+
+func newIpfsClient(apiUrl string, token string) *ipfsClient {
+	return &ipfsClient{
+		cli:   &http.Client{Timeout: time.Minute},
+		url:   strings.TrimRight(apiUrl, "/"),
+		token: token,
+	}
+}
+
+// add pins data and returns its cid.
+func (i *ipfsClient) add(ctx context.Context, name string, data []byte) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.url+"/api/v0/add?pin=true&cid-version=1", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if i.token != "" {
+		req.Header.Set("Authorization", "Bearer "+i.token)
+	}
+	res, err := i.cli.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return "", urlErr.Err
+		}
+		return "", err
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(resBody)))
+	}
+	var added struct {
+		Hash string `json:"Hash"`
+	}
+	if err := json.Unmarshal(resBody, &added); err != nil {
+		return "", err
+	}
+	if added.Hash == "" {
+		return "", errors.New("no cid in response")
+	}
+	return added.Hash, nil
+}
+
+// runArchiver pins the content of new proposals and the discussion threads
+// of settled ones until ctx is done. Proposals are picked up by what is
+// missing in ipfs_archives, so failed pins are retried on the next pass and
+// proposals indexed before archiving was enabled are archived too.
+func (c *ChainIndexer) runArchiver(ctx context.Context) {
+	ticker := time.NewTicker(ArchiveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.archivePending(ctx); err != nil {
+				c.logger.Error("ipfs archive fail", "err", err)
+			}
+		}
+	}
+}
+
+func (c *ChainIndexer) archivePending(ctx context.Context) error {
+	var proposals []Proposal
+	err := c.db.Where("id NOT IN (SELECT proposal FROM ipfs_archives WHERE kind = ?)", ArchiveKindProposal).
+		Order("id asc").Limit(archiveBatch).Find(&proposals).Error
+	if err != nil {
+		return err
+	}
+	for _, p := range proposals {
+		if err := c.archiveProposal(ctx, p); err != nil {
+			return fmt.Errorf("proposal %d: %w", p.Id, err)
+		}
+	}
+
+	proposals = nil
+	err = c.db.Where("status IN (?) AND id IN (SELECT proposal FROM ipfs_archives WHERE kind = ?) AND id NOT IN (SELECT proposal FROM ipfs_archives WHERE kind = ?)",
+		[]ProposalStatus{ProposalStatusPassed, ProposalStatusRejected, ProposalStatusExpired}, ArchiveKindProposal, ArchiveKindThread).
+		Order("id asc").Limit(archiveBatch).Find(&proposals).Error
+	if err != nil {
+		return err
+	}
+	for _, p := range proposals {
+		if err := c.archiveThread(ctx, p); err != nil {
+			return fmt.Errorf("thread %d: %w", p.Id, err)
+		}
+	}
+	return nil
+}
+
+func (c *ChainIndexer) archiveProposal(ctx context.Context, p Proposal) error {
+	doc := ArchivedProposal{
+		ChainId:         c.ChainId,
+		Id:              p.Id,
+		ProposerIndex:   p.ProposerIndex,
+		ProposerAddress: p.ProposerAddress,
+		Title:           p.Title,
+		Summary:         p.Summary,
+		Data:            p.Data,
+		Link:            p.Link,
+		ImageUrl:        p.ImageUrl,
+		Height:          p.NewHeight,
+		BlockTime:       p.BlockTime.UTC(),
+	}
+	return c.pinArchive(ctx, ArchiveKindProposal, p.Id, p.NewHeight, doc)
+}
+
+func (c *ChainIndexer) archiveThread(ctx context.Context, p Proposal) error {
+	var archived IpfsArchive
+	if err := c.db.Where("proposal = ? AND kind = ?", p.Id, ArchiveKindProposal).First(&archived).Error; err != nil {
+		return err
+	}
+	var discussions []Discussion
+	if err := c.db.Where("proposal = ?", p.Id).Order("height asc, id asc").Find(&discussions).Error; err != nil {
+		return err
+	}
+	doc := ArchivedThread{
+		ChainId:      c.ChainId,
+		Proposal:     p.Id,
+		ProposalCid:  archived.Cid,
+		Status:       p.Status.String(),
+		SettleHeight: p.SettleHeight,
+		Discussions:  make([]ArchivedDiscussion, 0, len(discussions)),
+	}
+	for _, d := range discussions {
+		doc.Discussions = append(doc.Discussions, ArchivedDiscussion{
+			SpeakerIndex:   d.SpeakerIndex,
+			SpeakerAddress: d.SpeakerAddress,
+			Data:           d.Data,
+			Height:         d.Height,
+			TxHash:         d.TxHash,
+			BlockTime:      d.BlockTime.UTC(),
+		})
+	}
+	return c.pinArchive(ctx, ArchiveKindThread, p.Id, p.SettleHeight, doc)
+}
+
+func (c *ChainIndexer) pinArchive(ctx context.Context, kind string, proposal uint64, height uint64, doc interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	cid, err := c.ipfs.add(ctx, fmt.Sprintf("%s-%d.json", kind, proposal), data)
+	if err != nil {
+		return err
+	}
+	c.logger.Info("archived to ipfs", "kind", kind, "proposal", proposal, "cid", cid)
+	return c.db.Create(&IpfsArchive{
+		Proposal: proposal,
+		Kind:     kind,
+		Cid:      cid,
+		Height:   height,
+		Size:     uint64(len(data)),
+	}).Error
+}
+
+func (c *ChainIndexer) getIpfsArchives(proposal uint64) ([]IpfsArchive, error) {
+	var archives []IpfsArchive
+	err := c.db.Where("proposal = ?", proposal).Order("id asc").Find(&archives).Error
+	return archives, err
+}
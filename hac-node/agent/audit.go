@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/calehh/hac-app/state"
+)
+
+// auditor samples indexed rows on a schedule and compares them with the
+// chain state served over ABCI queries.
+type auditor struct {
+	interval time.Duration
+	sample   int
+	repair   bool
+}
+
+// runAudit audits until ctx is done. Rows are only compared when the indexer
+// has caught up with the height the chain answered at, otherwise differences
+// would just be events not indexed yet.
+func (c *ChainIndexer) runAudit(ctx context.Context) {
+	ticker := time.NewTicker(c.audit.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			found, err := c.auditOnce(ctx)
+			if err != nil {
+				c.logger.Error("audit fail", "err", err)
+				continue
+			}
+			c.logger.Info("audit done", "divergences", found)
+		}
+	}
+}
+
+func (c *ChainIndexer) auditOnce(ctx context.Context) (int, error) {
+	found := 0
+	var proposals []Proposal
+	if err := c.db.Order("RANDOM()").Limit(c.audit.sample).Find(&proposals).Error; err != nil {
+		return found, err
+	}
+	for i := range proposals {
+		n, err := c.auditProposal(ctx, &proposals[i])
+		if err != nil {
+			return found, err
+		}
+		found += n
+	}
+	var validators []ValidatorAgent
+	if err := c.db.Order("RANDOM()").Limit(c.audit.sample).Find(&validators).Error; err != nil {
+		return found, err
+	}
+	for i := range validators {
+		n, err := c.auditAccount(ctx, &validators[i])
+		if err != nil {
+			return found, err
+		}
+		found += n
+	}
+	return found, nil
+}
+
+func (c *ChainIndexer) auditProposal(ctx context.Context, proposal *Proposal) (int, error) {
+	p, height, err := c.queryProposal(ctx, proposal.Id)
+	if err != nil {
+		return 0, err
+	}
+	if !c.auditable(height) {
+		return 0, nil
+	}
+	var divergences []AuditDivergence
+	if p == nil {
+		divergences = append(divergences, newDivergence("proposal", proposal.Id, "exists", "true", "false", height))
+		return c.reportDivergences(divergences, nil)
+	}
+	// expired proposals are rejected ones from the chain's point of view
+	status := proposal.Status
+	if status == ProposalStatusExpired {
+		status = ProposalStatusRejected
+	}
+	if status != ProposalStatus(p.Status) {
+		divergences = append(divergences, newDivergence("proposal", proposal.Id, "status", status.String(), ProposalStatus(p.Status).String(), height))
+	}
+	if proposal.ProposerAddress != p.ProposerAddress {
+		divergences = append(divergences, newDivergence("proposal", proposal.Id, "proposer_address", proposal.ProposerAddress, p.ProposerAddress, height))
+	}
+	if proposal.Data != string(p.Data) {
+		divergences = append(divergences, newDivergence("proposal", proposal.Id, "data", proposal.Data, string(p.Data), height))
+	}
+	return c.reportDivergences(divergences, func() error {
+		proposal.Status = ProposalStatus(p.Status)
+		proposal.ProposerAddress = p.ProposerAddress
+		proposal.Data = string(p.Data)
+		return c.db.Save(proposal).Error
+	})
+}
+
+func (c *ChainIndexer) auditAccount(ctx context.Context, val *ValidatorAgent) (int, error) {
+	res, err := c.cli.ABCIQuery(ctx, "/accounts/", indexBytes(val.Id))
+	if err != nil {
+		return 0, err
+	}
+	height := res.Response.Height
+	if res.Response.Code != 0 {
+		if !c.auditable(height) {
+			return 0, nil
+		}
+		return c.reportDivergences([]AuditDivergence{newDivergence("account", val.Id, "exists", "true", "false", height)}, nil)
+	}
+	if !c.auditable(height) {
+		return 0, nil
+	}
+	var acc state.Account
+	if err := acc.UnmarshalJSON(res.Response.Value); err != nil {
+		return 0, err
+	}
+	var divergences []AuditDivergence
+	if val.Address != acc.Address() {
+		divergences = append(divergences, newDivergence("account", val.Id, "address", val.Address, acc.Address(), height))
+	}
+	if val.Stake != acc.Stake {
+		divergences = append(divergences, newDivergence("account", val.Id, "stake", strconv.FormatUint(val.Stake, 10), strconv.FormatUint(acc.Stake, 10), height))
+	}
+	if val.AgentUrl != acc.AgentUrl {
+		divergences = append(divergences, newDivergence("account", val.Id, "agent_url", val.AgentUrl, acc.AgentUrl, height))
+	}
+	if val.Name != acc.Name {
+		divergences = append(divergences, newDivergence("account", val.Id, "name", val.Name, acc.Name, height))
+	}
+	return c.reportDivergences(divergences, func() error {
+		val.Address = acc.Address()
+		val.Stake = acc.Stake
+		val.AgentUrl = acc.AgentUrl
+		val.Name = acc.Name
+		return c.db.Save(val).Error
+	})
+}
+
+func (c *ChainIndexer) auditable(height int64) bool {
+	return height > 0 && height < c.Height
+}
+
+func newDivergence(kind string, ref uint64, field string, indexed string, chain string, height int64) AuditDivergence {
+	return AuditDivergence{
+		Kind:    kind,
+		RefId:   ref,
+		Field:   field,
+		Indexed: indexed,
+		Chain:   chain,
+		Height:  uint64(height),
+	}
+}
+
+// reportDivergences logs and stores divergences found for one row and, when
+// repair is enabled, overwrites the row with the chain state.
+func (c *ChainIndexer) reportDivergences(divergences []AuditDivergence, repair func() error) (int, error) {
+	if len(divergences) == 0 {
+		return 0, nil
+	}
+	repaired := false
+	if c.audit.repair && repair != nil {
+		if err := repair(); err != nil {
+			return 0, fmt.Errorf("repair %s %d: %w", divergences[0].Kind, divergences[0].RefId, err)
+		}
+		repaired = true
+	}
+	for _, d := range divergences {
+		d.Repaired = repaired
+		auditDivergences.WithLabelValues(d.Kind).Inc()
+		c.logger.Error("audit divergence", "kind", d.Kind, "id", d.RefId, "field", d.Field, "indexed", d.Indexed, "chain", d.Chain, "repaired", d.Repaired)
+		if err := c.db.Save(&d).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(divergences), nil
+}
+
+func (c *ChainIndexer) getAuditDivergences(page int, pageSize int) (Page[AuditDivergence], error) {
+	return paginate[AuditDivergence](c.db.Model(&AuditDivergence{}), "id desc", page, pageSize)
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AgentAuth authenticates the requests to an agent behind an auth gateway.
+// The token is Token, else the TokenEnv environment variable, else the
+// content of TokenFile, read again when the file changes so a rotated token
+// is picked up. It is sent as a bearer token in the Authorization header, or
+// as is in Header, an api key header like X-API-Key.
+type AgentAuth struct {
+	Header    string
+	Token     string
+	TokenEnv  string
+	TokenFile string
+
+	mtx      sync.Mutex
+	fileMod  time.Time
+	fileData string
+}
+
+// Enabled tells whether a token source is configured.
+func (a *AgentAuth) Enabled() bool {
+	return a != nil && (a.Token != "" || a.TokenEnv != "" || a.TokenFile != "")
+}
+
+func (a *AgentAuth) token() (string, error) {
+	if a.Token != "" {
+		return a.Token, nil
+	}
+	if a.TokenEnv != "" {
+		if token := strings.TrimSpace(os.Getenv(a.TokenEnv)); token != "" {
+			return token, nil
+		}
+		if a.TokenFile == "" {
+			return "", fmt.Errorf("agent token env %s is empty", a.TokenEnv)
+		}
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	info, err := os.Stat(a.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("agent token file: %w", err)
+	}
+	if !info.ModTime().Equal(a.fileMod) {
+		data, err := os.ReadFile(a.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("agent token file: %w", err)
+		}
+		a.fileData = strings.TrimSpace(string(data))
+		a.fileMod = info.ModTime()
+	}
+	if a.fileData == "" {
+		return "", errors.New("agent token file is empty")
+	}
+	return a.fileData, nil
+}
+
+// apply sets the auth header of req, a nil or disabled AgentAuth sets none.
+func (a *AgentAuth) apply(req *http.Request) error {
+	if !a.Enabled() {
+		return nil
+	}
+	token, err := a.token()
+	if err != nil {
+		return err
+	}
+	if a.Header == "" || strings.EqualFold(a.Header, "Authorization") {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+	req.Header.Set(a.Header, token)
+	return nil
+}
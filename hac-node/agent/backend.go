@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cometbft/cometbft/crypto"
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// Names of the built-in agent backends.
+const (
+	BackendEliza  = "eliza"
+	BackendMock   = "mock"
+	BackendNoop   = "noop"
+	BackendOpenAI = "openai"
+	BackendOllama = "ollama"
+	BackendGRPC   = "grpc"
+)
+
+// BackendConfig configures an agent backend. Url is the service address,
+// HTTP the client calling it, http.DefaultClient when nil, Agent the id or
+// name of the agent on an eliza service, Retry the retries of its calls and
+// Auth the token sent to an eliza service behind an auth gateway and
+// VotePubKey the key the votes of an eliza agent must be signed with.
+// The other fields are used by the backends calling a model directly. A
+// Seed of 0 leaves the sampling unseeded.
+type BackendConfig struct {
+	Url         string
+	HTTP        *http.Client
+	Agent       string
+	Retry       RetryPolicy
+	Auth        *AgentAuth
+	VotePubKey  crypto.PubKey
+	Model       string
+	ApiKey      string
+	Temperature float64
+	Seed        int64
+	Prompts     map[string]string
+}
+
+// BackendFactory creates the client of an agent backend.
+type BackendFactory func(cfg BackendConfig, logger cmtlog.Logger) (Client, error)
+
+var agentBackends = struct {
+	sync.RWMutex
+	factories map[string]BackendFactory
+	active    Client
+}{factories: make(map[string]BackendFactory)}
+
+func init() {
+	Register(BackendEliza, func(cfg BackendConfig, logger cmtlog.Logger) (Client, error) {
+		cli, err := NewElizaAgentClient(cfg.Url, cfg.Agent, cfg.HTTP, logger)
+		if err != nil {
+			return nil, err
+		}
+		cli.SetRetryPolicy(cfg.Retry)
+		cli.SetAuth(cfg.Auth)
+		cli.SetVotePubKey(cfg.VotePubKey)
+		if err := cli.SetTexts(cfg.Prompts); err != nil {
+			return nil, err
+		}
+		return cli, nil
+	})
+	Register(BackendOpenAI, func(cfg BackendConfig, logger cmtlog.Logger) (Client, error) {
+		return NewOpenAIClient(cfg, logger)
+	})
+	Register(BackendOllama, func(cfg BackendConfig, logger cmtlog.Logger) (Client, error) {
+		return NewOllamaClient(cfg, logger)
+	})
+	Register(BackendGRPC, func(cfg BackendConfig, logger cmtlog.Logger) (Client, error) {
+		cli, err := NewGRPCAgentClient(cfg.Url, logger)
+		if err != nil {
+			return nil, err
+		}
+		if err := cli.SetTexts(cfg.Prompts); err != nil {
+			return nil, err
+		}
+		return cli, nil
+	})
+	Register(BackendMock, func(BackendConfig, cmtlog.Logger) (Client, error) { return NewMockClient(), nil })
+	Register(BackendNoop, func(BackendConfig, cmtlog.Logger) (Client, error) { return NewNoopClient(), nil })
+}
+
+// Register adds or replaces the agent backend selected by name in the
+// agent_backend config.
+func Register(name string, factory BackendFactory) {
+	agentBackends.Lock()
+	defer agentBackends.Unlock()
+	agentBackends.factories[name] = factory
+}
+
+// Backends returns the names of the registered backends in order.
+func Backends() []string {
+	agentBackends.RLock()
+	defer agentBackends.RUnlock()
+	names := make([]string, 0, len(agentBackends.factories))
+	for name := range agentBackends.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewBackend creates the client of the backend registered as name.
+func NewBackend(name string, cfg BackendConfig, logger cmtlog.Logger) (Client, error) {
+	agentBackends.RLock()
+	factory, ok := agentBackends.factories[name]
+	agentBackends.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown agent backend %q, expected one of %s", name, strings.Join(Backends(), ", "))
+	}
+	return factory(cfg, logger)
+}
+
+// SetActiveClient sets the client the indexer and the consensus hooks call,
+// the backend client with the wrappers configured on top.
+func SetActiveClient(cli Client) {
+	agentBackends.Lock()
+	defer agentBackends.Unlock()
+	agentBackends.active = cli
+}
+
+// ActiveClient returns the client set by SetActiveClient, a NoopClient
+// before any is set.
+func ActiveClient() Client {
+	agentBackends.RLock()
+	defer agentBackends.RUnlock()
+	if agentBackends.active == nil {
+		return NewNoopClient()
+	}
+	return agentBackends.active
+}
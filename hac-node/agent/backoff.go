@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"math/rand"
+	"time"
+)
+
+// backoff computes exponentially growing delays with jitter. The zero value
+// is not usable, use newBackoff.
+type backoff struct {
+	base    time.Duration
+	max     time.Duration
+	attempt int
+}
+
+func newBackoff(base time.Duration, max time.Duration) *backoff {
+	return &backoff{base: base, max: max}
+}
+
+// Next returns the delay before the next attempt: a random duration between
+// half and all of base*2^attempt, capped at max.
+func (b *backoff) Next() time.Duration {
+	d := b.max
+	if b.attempt < 32 {
+		if exp := b.base << uint(b.attempt); exp > 0 && exp < b.max {
+			d = exp
+		}
+	}
+	b.attempt++
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (b *backoff) Attempt() int {
+	return b.attempt
+}
+
+func (b *backoff) Reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"bufio"
+	"compress/gzip"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SnapshotIndexDB writes a consistent copy of the sqlite indexer db at dsn
+// to dst, gzip compressed. The node may keep running, VACUUM INTO reads a
+// single transaction. Postgres dbs are backed up with pg_dump instead.
+func SnapshotIndexDB(dsn string, dst string) (err error) {
+	if IsPostgresDSN(dsn) {
+		return errors.New("postgres indexer dbs are backed up with pg_dump")
+	}
+	if _, err := os.Stat(dsn); err != nil {
+		return err
+	}
+	db, err := openIndexDB(dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	raw := dst + ".db"
+	os.Remove(raw)
+	if err := db.Exec("VACUUM INTO ?", raw).Error; err != nil {
+		return err
+	}
+	defer os.Remove(raw)
+
+	in, err := os.Open(raw)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ExportParquet writes every table of the indexer db at dsn to dir, one
+// <table>.parquet file each, for analytics outside the node. Columns are
+// nullable, integers are INT64, times INT64 timestamps in microseconds, and
+// the columns of other types UTF8 strings. It returns the files written.
+func ExportParquet(dsn string, dir string) ([]string, error) {
+	db, err := openIndexDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(copyTables))
+	for _, t := range copyTables {
+		table := db.NewScope(t.model).TableName()
+		if !db.HasTable(table) {
+			continue
+		}
+		file := filepath.Join(dir, table+".parquet")
+		if err := exportTableParquet(db, t, file); err != nil {
+			return files, fmt.Errorf("export %s: %w", table, err)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// parquetField is a column of a table exported to parquet, scanned into
+// value and appended to column.
+type parquetField struct {
+	column *parquetColumn
+	value  interface{}
+}
+
+func newParquetField(name string, typ reflect.Type) parquetField {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch {
+	case typ == reflect.TypeOf(time.Time{}):
+		return parquetField{&parquetColumn{name: name, typ: parquetInt64, converted: parquetTimestampMicros}, &sql.NullTime{}}
+	case typ.Kind() == reflect.Bool:
+		return parquetField{&parquetColumn{name: name, typ: parquetBoolean, converted: -1}, &sql.NullBool{}}
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Uint64:
+		return parquetField{&parquetColumn{name: name, typ: parquetInt64, converted: -1}, &sql.NullInt64{}}
+	case typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64:
+		return parquetField{&parquetColumn{name: name, typ: parquetDouble, converted: -1}, &sql.NullFloat64{}}
+	}
+	return parquetField{&parquetColumn{name: name, typ: parquetByteArray, converted: parquetUTF8}, &sql.NullString{}}
+}
+
+func (f parquetField) append() {
+	switch v := f.value.(type) {
+	case *sql.NullTime:
+		if v.Valid {
+			f.column.appendInt64(v.Time.UnixMicro())
+			return
+		}
+	case *sql.NullBool:
+		if v.Valid {
+			f.column.appendBool(v.Bool)
+			return
+		}
+	case *sql.NullInt64:
+		if v.Valid {
+			f.column.appendInt64(v.Int64)
+			return
+		}
+	case *sql.NullFloat64:
+		if v.Valid {
+			f.column.appendDouble(v.Float64)
+			return
+		}
+	case *sql.NullString:
+		if v.Valid {
+			f.column.appendBytes([]byte(v.String))
+			return
+		}
+	}
+	f.column.appendNull()
+}
+
+func exportTableParquet(db *gorm.DB, t copyTable, file string) (err error) {
+	scope := db.NewScope(t.model)
+	var fields []parquetField
+	var names []string
+	for _, sf := range scope.GetModelStruct().StructFields {
+		if sf.IsIgnored || !sf.IsNormal {
+			continue
+		}
+		fields = append(fields, newParquetField(sf.DBName, sf.Struct.Type))
+		names = append(names, scope.Quote(sf.DBName))
+	}
+	rows, err := db.Table(scope.TableName()).Select(names).Order(scope.Quote(t.key)).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(file)
+		}
+	}()
+	bw := bufio.NewWriter(out)
+	columns := make([]*parquetColumn, len(fields))
+	dest := make([]interface{}, len(fields))
+	for i, f := range fields {
+		columns[i] = f.column
+		dest[i] = f.value
+	}
+	pw, err := newParquetWriter(bw, columns)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		for _, f := range fields {
+			f.append()
+		}
+		if err := pw.endRow(); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := pw.Close(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
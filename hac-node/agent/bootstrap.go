@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/calehh/hac-app/state"
+	hac_types "github.com/calehh/hac-app/types"
+	"github.com/jinzhu/gorm"
+)
+
+// importChainState fills a fresh database with the validators, grants and
+// proposals known to the chain, so an indexer configured with a start height
+// does not need to replay the blocks below it. The chain only serves its
+// latest state, so rows reflect the state at query time rather than exactly
+// the start height.
+func (c *ChainIndexer) importChainState(ctx context.Context) error {
+	c.logger.Info("import chain state", "start", c.Height)
+	accounts, err := c.importAccounts(ctx)
+	if err != nil {
+		return fmt.Errorf("import accounts: %w", err)
+	}
+	proposals, err := c.importProposals(ctx)
+	if err != nil {
+		return fmt.Errorf("import proposals: %w", err)
+	}
+	// record the cursor so a restart does not import again
+	if err := c.db.Save(Height{Id: 1, Height: uint64(c.Height - 1)}).Error; err != nil {
+		return err
+	}
+	c.importState = false
+	c.logger.Info("chain state imported", "accounts", accounts, "proposals", proposals)
+	return nil
+}
+
+func (c *ChainIndexer) importAccounts(ctx context.Context) (int, error) {
+	res, err := c.cli.ABCIQuery(ctx, "/validators/", nil)
+	if err != nil {
+		return 0, err
+	}
+	if res.Response.Code != 0 {
+		return 0, fmt.Errorf("query validators code %d", res.Response.Code)
+	}
+	var validators []*state.Account
+	if err := json.Unmarshal(res.Response.Value, &validators); err != nil {
+		return 0, err
+	}
+	active := make(map[uint64]bool)
+	for _, v := range validators {
+		active[v.Index] = true
+	}
+	height := uint64(res.Response.Height)
+
+	cnt := 0
+	for index := uint64(state.StartAccountIdx); ; index++ {
+		acc, err := c.queryAccount(ctx, index, "")
+		if err != nil || acc == nil {
+			// accounts are numbered without holes, the first miss is the end
+			break
+		}
+		grant := Grant{
+			Id:      acc.Index,
+			Address: acc.Address(),
+			Height:  height,
+			Stake:   acc.Stake,
+			Grant:   active[acc.Index],
+		}
+		if err := c.db.Save(&grant).Error; err != nil {
+			return cnt, err
+		}
+		var val ValidatorAgent
+		if err := c.db.First(&val, acc.Index).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return cnt, err
+		}
+		val.Id = acc.Index
+		val.Address = acc.Address()
+		val.Stake = acc.Stake
+		val.AgentUrl = acc.AgentUrl
+		val.Name = acc.Name
+		val.Active = active[acc.Index]
+		if err := c.db.Save(&val).Error; err != nil {
+			return cnt, err
+		}
+		if err := recordStake(c.db, val.Id, val.Address, height, val.Stake); err != nil {
+			return cnt, err
+		}
+		cnt++
+	}
+	return cnt, nil
+}
+
+func (c *ChainIndexer) importProposals(ctx context.Context) (int, error) {
+	cnt := 0
+	for index := uint64(1); ; index++ {
+		p, _, err := c.queryProposal(ctx, index)
+		if err != nil {
+			return cnt, err
+		}
+		if p == nil {
+			break
+		}
+		if err := c.importProposal(ctx, p); err != nil {
+			return cnt, err
+		}
+		cnt++
+	}
+	return cnt, nil
+}
+
+func (c *ChainIndexer) importProposal(ctx context.Context, p *hac_types.Proposal) error {
+	var blockTime time.Time
+	height := int64(p.Height)
+	if header, err := c.cli.Header(ctx, &height); err == nil && header.Header != nil {
+		blockTime = header.Header.Time
+	}
+	title, summary := parseProposalPayload(p.Data, p.Title)
+	payloadType, payloadData, err := validatePayload(p.Data)
+	payloadError := ""
+	if err != nil {
+		payloadError = err.Error()
+	}
+	proposal := Proposal{
+		Id:              p.Index,
+		ProposerIndex:   p.Proposer,
+		ProposerAddress: p.ProposerAddress,
+		Data:            string(p.Data),
+		NewHeight:       p.Height,
+		Status:          ProposalStatus(p.Status),
+		Title:           title,
+		Summary:         summary,
+		PayloadType:     payloadType,
+		PayloadData:     payloadData,
+		PayloadError:    payloadError,
+		Link:            p.Link,
+		ImageUrl:        p.ImageUrl,
+		CreateTimestamp: blockTime.Unix(),
+		ExpireTimestamp: blockTime.Add(time.Hour * 24 * 365).Unix(),
+		BlockTime:       blockTime,
+	}
+	validator, err := c.getValidatorByAddress(p.ProposerAddress)
+	if err == nil && validator != nil {
+		proposal.ProposerName = validator.Name
+	}
+	if proposal.ProposerName == "" {
+		proposal.ProposerName = "Enigma"
+	}
+	return c.db.Save(&proposal).Error
+}
+
+// queryProposal returns the chain state of proposal index and the height it
+// was read at, or a nil proposal if the chain does not know it.
+func (c *ChainIndexer) queryProposal(ctx context.Context, index uint64) (*hac_types.Proposal, int64, error) {
+	res, err := c.cli.ABCIQuery(ctx, "/proposals/", indexBytes(index))
+	if err != nil {
+		return nil, 0, err
+	}
+	if res.Response.Code != 0 {
+		return nil, res.Response.Height, nil
+	}
+	var p hac_types.Proposal
+	if err := json.Unmarshal(res.Response.Value, &p); err != nil {
+		return nil, 0, err
+	}
+	return &p, res.Response.Height, nil
+}
+
+func indexBytes(index uint64) []byte {
+	var dat []byte
+	for ; index > 0; index >>= 8 {
+		dat = append([]byte{byte(index)}, dat...)
+	}
+	return dat
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// ErrAgentCircuitOpen is a call not sent because the circuit breaker of the
+// agent is open. It is classed as ErrAgentUnavailable.
+var ErrAgentCircuitOpen = errors.New("agent circuit open")
+
+// BreakerState is the state of a circuit breaker, exported as the value of
+// the agent_breaker_state gauge.
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerHalfOpen:
+		return "half-open"
+	case BreakerOpen:
+		return "open"
+	}
+	return "closed"
+}
+
+// BreakerConfig configures a BreakerClient. Failures consecutive unavailable
+// or timed out calls open the breaker, Cooldown later a single call is let
+// through to probe the agent, and Fallback is the vote cast while it is
+// open.
+type BreakerConfig struct {
+	Failures int
+	Cooldown time.Duration
+	Fallback TimeoutDecision
+}
+
+var _ Client = &BreakerClient{}
+
+// BreakerClient short-circuits the calls to the wrapped agent while it is
+// consistently failing. A voting decision is cast with the fallback and the
+// other calls fail with ErrAgentCircuitOpen until a probe succeeds.
+type BreakerClient struct {
+	Client
+	backend string
+	cfg     BreakerConfig
+	logger  cmtlog.Logger
+
+	mtx      sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewBreakerClient(inner Client, backend string, cfg BreakerConfig, logger cmtlog.Logger) *BreakerClient {
+	agentBreakerState.WithLabelValues(backend).Set(float64(BreakerClosed))
+	return &BreakerClient{
+		Client:  inner,
+		backend: backend,
+		cfg:     cfg,
+		logger:  logger.With("module", "agent-breaker"),
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *BreakerClient) State() BreakerState {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cfg.Cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow tells whether a call may be sent. Once the cool-down is over the
+// breaker half-opens and lets a single probe through, probe is then true.
+func (b *BreakerClient) allow() (ok bool, probe bool) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	switch b.state {
+	case BreakerClosed:
+		return true, false
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cfg.Cooldown {
+			return false, false
+		}
+		b.setState(BreakerHalfOpen)
+	}
+	if b.probing {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// done records the outcome of a call. Only an unavailable or timed out agent
+// counts as a failure, an agent rejecting a request is still answering. A
+// call the caller gave up on tells nothing.
+func (b *BreakerClient) done(ctx context.Context, probe bool, err error) {
+	failed := errors.Is(err, ErrAgentUnavailable) || errors.Is(err, ErrAgentDecisionTimeout)
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	if !failed {
+		if b.state != BreakerClosed {
+			b.logger.Info("agent circuit closed", "backend", b.backend)
+			b.setState(BreakerClosed)
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.cfg.Failures) {
+		b.logger.Error("agent circuit open", "backend", b.backend, "failures", b.failures, "cooldown", b.cfg.Cooldown, "err", err)
+		b.openedAt = time.Now()
+		b.setState(BreakerOpen)
+	}
+}
+
+func (b *BreakerClient) setState(s BreakerState) {
+	b.state = s
+	agentBreakerState.WithLabelValues(b.backend).Set(float64(s))
+}
+
+// call sends a call to the wrapped agent unless the breaker is open.
+func (b *BreakerClient) call(ctx context.Context, method string, send func() error) error {
+	ok, probe := b.allow()
+	if !ok {
+		agentBreakerShortCircuits.WithLabelValues(b.backend, method).Inc()
+		return &AgentError{Class: ErrAgentUnavailable, Method: method, Err: ErrAgentCircuitOpen}
+	}
+	err := send()
+	b.done(ctx, probe, err)
+	return err
+}
+
+// vote asks the wrapped agent for a voting decision, or casts the fallback
+// when the breaker is open.
+func (b *BreakerClient) vote(ctx context.Context, method string, kind string, id uint64, ask func() (Decision, error)) (Decision, error) {
+	var decision Decision
+	err := b.call(ctx, method, func() error {
+		var err error
+		decision, err = ask()
+		return err
+	})
+	if !errors.Is(err, ErrAgentCircuitOpen) {
+		return decision, err
+	}
+	b.logger.Info("agent circuit open, vote fallback", "method", method, "id", id, "decision", b.cfg.Fallback)
+	reason := fmt.Sprintf("agent circuit open, fallback %s", b.cfg.Fallback)
+	return b.cfg.Fallback.fallback(FallbackBreaker, reason), nil
+}
+
+// IfProcessProposal ignores the proposal when the fallback abstains.
+func (b *BreakerClient) IfProcessProposal(ctx context.Context, proposer uint64, data []byte) (bool, error) {
+	decision, err := b.vote(ctx, "IfProcessProposal", voteKindProposal, 0, func() (Decision, error) {
+		pass, err := b.Client.IfProcessProposal(ctx, proposer, data)
+		return decisionOf(pass, ""), err
+	})
+	return decision.Pass(), err
+}
+
+func (b *BreakerClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	return b.vote(ctx, "IfAcceptProposal", voteKindProposal, proposal, func() (Decision, error) {
+		return b.Client.IfAcceptProposal(ctx, proposal, voter)
+	})
+}
+
+func (b *BreakerClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	return b.vote(ctx, "IfGrantNewMember", voteKindGrant, validator, func() (Decision, error) {
+		return b.Client.IfGrantNewMember(ctx, validator, proposer, amount, statement)
+	})
+}
+
+func (b *BreakerClient) CommentPropoal(ctx context.Context, proposal uint64, speaker string) (string, error) {
+	var comment string
+	err := b.call(ctx, "CommentPropoal", func() error {
+		var err error
+		comment, err = b.Client.CommentPropoal(ctx, proposal, speaker)
+		return err
+	})
+	return comment, err
+}
+
+func (b *BreakerClient) AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error {
+	return b.call(ctx, "AddProposal", func() error {
+		return b.Client.AddProposal(ctx, proposal, proposer, text)
+	})
+}
+
+func (b *BreakerClient) AddDiscussion(ctx context.Context, proposal uint64, speaker string, text string) error {
+	return b.call(ctx, "AddDiscussion", func() error {
+		return b.Client.AddDiscussion(ctx, proposal, speaker, text)
+	})
+}
+
+func (b *BreakerClient) AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error {
+	return b.call(ctx, "AddDiscussions", func() error {
+		return b.Client.AddDiscussions(ctx, msgs)
+	})
+}
+
+func (b *BreakerClient) NotifyActivation(ctx context.Context, proposal uint64, activationHeight uint64) error {
+	return b.call(ctx, "NotifyActivation", func() error {
+		return b.Client.NotifyActivation(ctx, proposal, activationHeight)
+	})
+}
+
+func (b *BreakerClient) NotifyMisbehavior(ctx context.Context, validator string, kind string, height uint64) error {
+	return b.call(ctx, "NotifyMisbehavior", func() error {
+		return b.Client.NotifyMisbehavior(ctx, validator, kind, height)
+	})
+}
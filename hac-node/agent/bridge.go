@@ -0,0 +1,282 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	app_config "github.com/calehh/hac-app/config"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	eth_crypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	BridgeStatusPending   = "pending"
+	BridgeStatusConfirmed = "confirmed"
+	BridgeStatusReverted  = "reverted"
+)
+
+// BridgeInterval is the time between bridge passes.
+var BridgeInterval = 15 * time.Second
+
+// BridgeMaxAttempts bounds the txs sent for a proposal whose mirror txs keep
+// reverting.
+const BridgeMaxAttempts = 3
+
+// bridgeABI is the method of the mirror contract called for every settled
+// proposal. result is the proposal status, the powers are the tally of the
+// Snapshot export.
+const bridgeABI = `[{"type":"function","name":"recordOutcome","stateMutability":"nonpayable","inputs":[
+	{"name":"proposalId","type":"uint64"},
+	{"name":"proposalHash","type":"bytes32"},
+	{"name":"result","type":"uint8"},
+	{"name":"forPower","type":"uint256"},
+	{"name":"againstPower","type":"uint256"},
+	{"name":"abstainPower","type":"uint256"}],"outputs":[]}]`
+
+// evmBridge mirrors settled proposals to a contract with legacy EIP-155
+// transactions, built and signed here so only the json-rpc api of the node
+// is needed.
+type evmBridge struct {
+	rpc      *evmRpc
+	contract common.Address
+	key      *ecdsa.PrivateKey
+	from     common.Address
+	chainId  *big.Int
+	abi      abi.ABI
+}
+
+func newEvmBridge(cfg *app_config.HACAppConfig) (*evmBridge, error) {
+	if cfg.BridgeRpcUrl == "" {
+		return nil, nil
+	}
+	if !common.IsHexAddress(cfg.BridgeContract) {
+		return nil, fmt.Errorf("invalid bridge_contract %q", cfg.BridgeContract)
+	}
+	key, err := eth_crypto.LoadECDSA(cfg.BridgeKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load bridge_key_file: %w", err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(bridgeABI))
+	if err != nil {
+		return nil, err
+	}
+	return &evmBridge{
+		rpc:      &evmRpc{url: cfg.BridgeRpcUrl, cli: &http.Client{Timeout: 30 * time.Second}},
+		contract: common.HexToAddress(cfg.BridgeContract),
+		key:      key,
+		from:     eth_crypto.PubkeyToAddress(key.PublicKey),
+		abi:      parsed,
+	}, nil
+}
+
+func (c *ChainIndexer) runBridge(ctx context.Context) {
+	ticker := time.NewTicker(BridgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !c.leader.isLeader() {
+				continue
+			}
+			if err := c.bridgePass(ctx); err != nil {
+				c.logger.Error("bridge fail", "err", err)
+			}
+		}
+	}
+}
+
+// bridgePass updates pending mirror txs from their receipts, then submits
+// settled proposals not mirrored yet, one at a time so nonces stay in order.
+func (c *ChainIndexer) bridgePass(ctx context.Context) error {
+	if c.bridge.chainId == nil {
+		var id hexutil.Big
+		if err := c.bridge.rpc.call(ctx, &id, "eth_chainId"); err != nil {
+			return err
+		}
+		c.bridge.chainId = id.ToInt()
+	}
+
+	var pending []BridgeMirror
+	if err := c.db.Where("status = ?", BridgeStatusPending).Order("id asc").Find(&pending).Error; err != nil {
+		return err
+	}
+	for _, m := range pending {
+		if err := c.updateBridgeMirror(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	var proposals []Proposal
+	err := c.db.Where("status IN (?) AND id NOT IN (SELECT proposal FROM bridge_mirrors WHERE status <> ?) "+
+		"AND id NOT IN (SELECT proposal FROM bridge_mirrors GROUP BY proposal HAVING COUNT(*) >= ?)",
+		[]ProposalStatus{ProposalStatusPassed, ProposalStatusRejected, ProposalStatusExpired}, BridgeStatusReverted, BridgeMaxAttempts).
+		Order("id asc").Limit(20).Find(&proposals).Error
+	if err != nil {
+		return err
+	}
+	for _, p := range proposals {
+		if err := c.mirrorProposal(ctx, p); err != nil {
+			return fmt.Errorf("mirror proposal %d: %w", p.Id, err)
+		}
+	}
+	return nil
+}
+
+func (c *ChainIndexer) mirrorProposal(ctx context.Context, p Proposal) error {
+	pkg, err := c.snapshotExport(p.Id, "")
+	if err != nil {
+		return err
+	}
+	hash := eth_crypto.Keccak256Hash([]byte(p.Data))
+	data, err := c.bridge.abi.Pack("recordOutcome", p.Id, hash, uint8(p.Status),
+		scoreInt(pkg.Proposal.Scores[0]), scoreInt(pkg.Proposal.Scores[1]), scoreInt(pkg.Proposal.Scores[2]))
+	if err != nil {
+		return err
+	}
+	txHash, nonce, err := c.bridge.send(ctx, data)
+	if err != nil {
+		return err
+	}
+	c.logger.Info("mirror proposal to evm", "proposal", p.Id, "tx", txHash.Hex(), "nonce", nonce)
+	return c.db.Create(&BridgeMirror{
+		Proposal:     p.Id,
+		ProposalHash: hash.Hex(),
+		Result:       p.Status.String(),
+		TxHash:       txHash.Hex(),
+		Nonce:        nonce,
+		Status:       BridgeStatusPending,
+	}).Error
+}
+
+func (c *ChainIndexer) updateBridgeMirror(ctx context.Context, m BridgeMirror) error {
+	var receipt *struct {
+		Status      hexutil.Uint64 `json:"status"`
+		BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	}
+	if err := c.bridge.rpc.call(ctx, &receipt, "eth_getTransactionReceipt", m.TxHash); err != nil {
+		return err
+	}
+	if receipt == nil {
+		return nil
+	}
+	m.BlockNumber = uint64(receipt.BlockNumber)
+	m.Status = BridgeStatusConfirmed
+	if receipt.Status != 1 {
+		// reverted mirrors are sent again, up to BridgeMaxAttempts txs
+		m.Status = BridgeStatusReverted
+	}
+	c.logger.Info("evm mirror tx mined", "proposal", m.Proposal, "tx", m.TxHash, "status", m.Status)
+	return c.db.Save(&m).Error
+}
+
+func scoreInt(score float64) *big.Int {
+	n, _ := big.NewFloat(score).Int(nil)
+	return n
+}
+
+// send signs and broadcasts a call of the mirror contract.
+func (b *evmBridge) send(ctx context.Context, data []byte) (common.Hash, uint64, error) {
+	var nonce hexutil.Uint64
+	if err := b.rpc.call(ctx, &nonce, "eth_getTransactionCount", b.from, "pending"); err != nil {
+		return common.Hash{}, 0, err
+	}
+	var gasPrice hexutil.Big
+	if err := b.rpc.call(ctx, &gasPrice, "eth_gasPrice"); err != nil {
+		return common.Hash{}, 0, err
+	}
+	var gas hexutil.Uint64
+	call := map[string]interface{}{"from": b.from, "to": b.contract, "data": hexutil.Bytes(data)}
+	if err := b.rpc.call(ctx, &gas, "eth_estimateGas", call); err != nil {
+		return common.Hash{}, 0, err
+	}
+	// headroom for state changes between estimation and inclusion
+	gas = gas * 6 / 5
+
+	fields := []interface{}{uint64(nonce), gasPrice.ToInt(), uint64(gas), b.contract, big.NewInt(0), data}
+	sighash, err := rlpHash(append(fields, b.chainId, uint(0), uint(0)))
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	sig, err := eth_crypto.Sign(sighash.Bytes(), b.key)
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	v := new(big.Int).Add(new(big.Int).Mul(b.chainId, big.NewInt(2)), big.NewInt(int64(sig[64])+35))
+	raw, err := rlp.EncodeToBytes(append(fields, v, new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])))
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	var txHash common.Hash
+	if err := b.rpc.call(ctx, &txHash, "eth_sendRawTransaction", hexutil.Bytes(raw)); err != nil {
+		return common.Hash{}, 0, err
+	}
+	return txHash, uint64(nonce), nil
+}
+
+func rlpHash(v interface{}) (common.Hash, error) {
+	data, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return eth_crypto.Keccak256Hash(data), nil
+}
+
+func (c *ChainIndexer) getBridgeMirrors(page int, pageSize int) (Page[BridgeMirror], error) {
+	return paginate[BridgeMirror](c.db.Model(&BridgeMirror{}), "id desc", page, pageSize)
+}
+
+// evmRpc is a minimal ethereum json-rpc client.
+type evmRpc struct {
+	url string
+	cli *http.Client
+}
+
+func (r *evmRpc) call(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := r.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return fmt.Errorf("%s: %s", method, res.Status)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s: %s (%d)", method, resp.Error.Message, resp.Error.Code)
+	}
+	if len(resp.Result) == 0 {
+		return errors.New(method + ": empty result")
+	}
+	return json.Unmarshal(resp.Result, result)
+}
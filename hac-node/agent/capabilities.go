@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// AgentCapabilities is what an agent reports it implements at startup: its
+// version and the names of the Client methods it serves.
+type AgentCapabilities struct {
+	Version string   `json:"version"`
+	Methods []string `json:"methods"`
+}
+
+// Supports tells whether the agent serves method, every method when it did
+// not list them.
+func (c *AgentCapabilities) Supports(method string) bool {
+	if c == nil || len(c.Methods) == 0 {
+		return true
+	}
+	for _, m := range c.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// CapabilityProber is an agent backend that reports its capabilities.
+type CapabilityProber interface {
+	Capabilities(ctx context.Context) (*AgentCapabilities, error)
+}
+
+var _ CapabilityProber = &ElizaClient{}
+
+// Capabilities asks the service for its capabilities. A service without the
+// endpoint predates the negotiation, it returns nil and no error.
+func (e *ElizaClient) Capabilities(ctx context.Context) (*AgentCapabilities, error) {
+	res, err := e.get(ctx, fmt.Sprintf("%s/capabilities", e.Url))
+	if err != nil {
+		return nil, agentCallError("Capabilities", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, agentCallError("Capabilities", err)
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if res.StatusCode/100 != 2 {
+		return nil, agentStatusError("Capabilities", res.StatusCode, body)
+	}
+	var caps AgentCapabilities
+	if err := json.Unmarshal(body, &caps); err != nil {
+		return nil, agentResponseError("Capabilities", res.StatusCode, body, err)
+	}
+	return &caps, nil
+}
+
+var _ Client = &CapabilityClient{}
+
+// CapabilityClient skips the calls the agent does not support, so an older
+// agent is not sent requests it fails on while blocks are processed. The
+// skipped calls succeed with empty results, a comment is not posted and the
+// agent is not told about the event. Votes are always sent, a node cannot
+// vote without its agent. A batch of discussions the agent cannot take at
+// once is sent one by one.
+type CapabilityClient struct {
+	interceptedClient
+	caps   *AgentCapabilities
+	logger cmtlog.Logger
+}
+
+func NewCapabilityClient(inner Client, caps *AgentCapabilities, logger cmtlog.Logger) *CapabilityClient {
+	c := &CapabilityClient{caps: caps, logger: logger.With("module", "agent-capabilities")}
+	c.interceptedClient = interceptedClient{Client: inner, intercept: c.skip}
+	return c
+}
+
+// Capabilities returns what the agent reported, nil when it did not.
+func (c *CapabilityClient) Capabilities() *AgentCapabilities {
+	return c.caps
+}
+
+func (c *CapabilityClient) skip(ctx context.Context, method string, send func() error) error {
+	switch method {
+	case "IfProcessProposal", "IfAcceptProposal", "IfGrantNewMember":
+		return send()
+	}
+	if c.caps.Supports(method) {
+		return send()
+	}
+	c.logger.Debug("skip call unsupported by the agent", "method", method)
+	agentUnsupportedCalls.WithLabelValues(method).Inc()
+	return nil
+}
+
+func (c *CapabilityClient) AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error {
+	if c.caps.Supports("AddDiscussions") {
+		return c.Client.AddDiscussions(ctx, msgs)
+	}
+	for _, msg := range msgs {
+		if err := c.AddDiscussion(ctx, msg.Proposal, msg.Speaker, msg.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CapabilityClient) Unwrap() Client { return c.Client }
+
+// WithCapabilities skips the calls caps does not support, see
+// NewCapabilityClient.
+func WithCapabilities(caps *AgentCapabilities, logger cmtlog.Logger) Middleware {
+	return func(next Client) Client {
+		return NewCapabilityClient(next, caps, logger)
+	}
+}
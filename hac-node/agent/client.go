@@ -6,26 +6,42 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/cometbft/cometbft/crypto"
 	cmtlog "github.com/cometbft/cometbft/libs/log"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
 )
 
-var ElizaCli Client
-
 var DiscussionRate = 0
 
 var DiscussionTrigger = 0
 
+// PeerDiscussionLimit is the number of peer discussions included when asking
+// the agent to vote on a proposal.
+var PeerDiscussionLimit = 5
+
+// DiscussionHistoryLimit is the number of the last discussions of a proposal
+// sent in order with a vote request, 0 sends the ranked peer discussions
+// instead.
+var DiscussionHistoryLimit = 20
+
 type Client interface {
 	IfProcessProposal(ctx context.Context, proposer uint64, data []byte) (bool, error)
-	IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (bool, error)
-	IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (bool, error)
+	IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error)
+	IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error)
 	CommentPropoal(ctx context.Context, proposal uint64, speaker string) (string, error)
 	AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error
 	AddDiscussion(ctx context.Context, proposal uint64, speaker string, text string) error
+	AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error
+	NotifyActivation(ctx context.Context, proposal uint64, activationHeight uint64) error
+	NotifyMisbehavior(ctx context.Context, validator string, kind string, height uint64) error
 	GetSelfIntro(ctx context.Context) (string, error)
 	GetHeadPhoto(ctx context.Context) (string, error)
 }
@@ -33,14 +49,192 @@ type Client interface {
 var _ Client = &MockClient{}
 var _ Client = &ElizaClient{}
 
+// DiscussionSource provides the discussions other validators posted on a
+// proposal, ranked and deduplicated, and the whole debate in order.
+type DiscussionSource interface {
+	PeerDiscussions(proposal uint64, exclude string, limit int) ([]Discussion, error)
+	DiscussionHistory(proposal uint64, limit int) ([]Discussion, error)
+}
+
+// ExpeditedSource tells which proposals are expedited.
+type ExpeditedSource interface {
+	IsExpedited(proposal uint64) bool
+}
+
+// UptimeSource provides the uptime and recent absences of a validator.
+type UptimeSource interface {
+	UptimeContext(address string, absences int) (UptimeReport, error)
+}
+
+// TreasurySource provides the spend a proposal asks for and the treasury
+// budget, nil for a proposal spending nothing.
+type TreasurySource interface {
+	TreasuryContext(proposal uint64) (*TreasurySpend, TreasuryBudget, error)
+}
+
+// VoteContextClient is a backend adding the indexed context of a vote to its
+// prompts and keeping the transcripts of its decisions.
+type VoteContextClient interface {
+	Client
+	SetDiscussionSource(src DiscussionSource)
+	SetExpeditedSource(src ExpeditedSource)
+	SetTreasurySource(src TreasurySource)
+	SetUptimeSource(src UptimeSource)
+	SetProposalSource(src ProposalSource)
+	SetDossierSource(src DossierSource)
+	SetCharacterSource(src CharacterSource)
+	SetTranscripts(r *TranscriptRecorder)
+}
+
+var _ VoteContextClient = &ElizaClient{}
+var _ VoteContextClient = &GRPCAgentClient{}
+
+// voteContext adds the indexed context of a vote to the prompt of the agent
+// backends.
+type voteContext struct {
+	logger      cmtlog.Logger
+	discussions DiscussionSource
+	expedited   ExpeditedSource
+	treasury    TreasurySource
+	uptime      UptimeSource
+	proposals   ProposalSource
+	dossiers    DossierSource
+	characters  CharacterSource
+	texts       map[string]*template.Template
+}
+
+func (v *voteContext) SetDiscussionSource(src DiscussionSource) {
+	v.discussions = src
+}
+
+// SetExpeditedSource puts the decision votes on expedited proposals ahead
+// of the other agent calls.
+func (v *voteContext) SetExpeditedSource(src ExpeditedSource) {
+	v.expedited = src
+}
+
+// SetTreasurySource gives the agent the treasury budget when it votes on a
+// spend.
+func (v *voteContext) SetTreasurySource(src TreasurySource) {
+	v.treasury = src
+}
+
+// SetUptimeSource gives the agent the absences of the proposer when it votes
+// on a grant.
+func (v *voteContext) SetUptimeSource(src UptimeSource) {
+	v.uptime = src
+}
+
+// SetProposalSource gives the agent the indexed proposal it decides on.
+func (v *voteContext) SetProposalSource(src ProposalSource) {
+	v.proposals = src
+}
+
+// SetDossierSource gives the agent the track record of the proposer when it
+// votes on a grant.
+func (v *voteContext) SetDossierSource(src DossierSource) {
+	v.dossiers = src
+}
+
+func (v *voteContext) isExpedited(proposal uint64) bool {
+	return v.expedited != nil && v.expedited.IsExpedited(proposal)
+}
+
 type ElizaClient struct {
-	Url     string
-	AgentId string
-	logger  cmtlog.Logger
+	voteContext
+	Url         string
+	AgentId     string
+	HTTP        *http.Client
+	retry       RetryPolicy
+	auth        *AgentAuth
+	votePubKey  crypto.PubKey
+	selection   string
+	agentMtx    sync.RWMutex
+	logger      cmtlog.Logger
+	queue       *AgentQueue
+	transcripts *TranscriptRecorder
+	drafts      CommentDraftStore
+}
+
+// SetQueue limits the requests in flight to the agent, method by method
+// priority.
+func (e *ElizaClient) SetQueue(q *AgentQueue) {
+	e.queue = q
+}
+
+// SetRetryPolicy retries the calls failing transiently with p.
+func (e *ElizaClient) SetRetryPolicy(p RetryPolicy) {
+	e.retry = p
+}
+
+// SetAuth authenticates the requests to the agent with a.
+func (e *ElizaClient) SetAuth(a *AgentAuth) {
+	e.auth = a
+}
+
+// SetVotePubKey only accepts the votes signed by the private key of pk.
+func (e *ElizaClient) SetVotePubKey(pk crypto.PubKey) {
+	e.votePubKey = pk
+}
+
+// SetCommentDraftStore saves the comments while they are streamed.
+func (e *ElizaClient) SetCommentDraftStore(store CommentDraftStore) {
+	e.drafts = store
+}
+
+// SetTranscripts keeps the transcripts of the voting decisions with r.
+func (e *ElizaClient) SetTranscripts(r *TranscriptRecorder) {
+	e.transcripts = r
+}
+
+// post sends a json request for method to the agent with the idempotency key
+// of ctx, or one derived from the request, once the queue has a slot. A
+// transient failure is sent again following the retry policy.
+func (e *ElizaClient) post(ctx context.Context, method string, url string, body []byte) (*http.Response, error) {
+	return e.postAccept(ctx, method, url, body, "")
+}
+
+// postAccept is post asking for a response of the accept media types.
+func (e *ElizaClient) postAccept(ctx context.Context, method string, url string, body []byte, accept string) (*http.Response, error) {
+	if e.queue != nil {
+		release, err := e.queue.acquire(ctx, method)
+		if err != nil {
+			return nil, agentCallError(method, err)
+		}
+		defer release()
+	}
+	key := idempotencyKey(ctx, url, body)
+	res, err := e.retry.do(ctx, method, httpClientOrDefault(e.HTTP), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		req.Header.Set(IdempotencyKeyHeader, key)
+		return req, e.auth.apply(req)
+	})
+	if err != nil {
+		return nil, agentCallError(method, err)
+	}
+	return res, nil
+}
+
+// get sends a GET request to the agent.
+func (e *ElizaClient) get(ctx context.Context, url string) (*http.Response, error) {
+	return e.retry.do(ctx, "get", httpClientOrDefault(e.HTTP), func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		return req, e.auth.apply(req)
+	})
 }
 
 func (c *ElizaClient) GetHeadPhoto(ctx context.Context) (string, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/%s/headphoto", c.Url, c.AgentId))
+	resp, err := c.get(ctx, fmt.Sprintf("%s/%s/headphoto", c.Url, c.agent()))
 	if err != nil {
 		return "", err
 	}
@@ -53,12 +247,12 @@ func (c *ElizaClient) GetHeadPhoto(ctx context.Context) (string, error) {
 }
 
 func (c *ElizaClient) GetSelfIntro(ctx context.Context) (string, error) {
-	agentUrl, err := url.JoinPath(c.Url, c.AgentId, "/selfintro")
+	agentUrl, err := url.JoinPath(c.Url, c.agent(), "/selfintro")
 	if err != nil {
 		c.logger.Error("join url fail", "err", err)
 		return "", err
 	}
-	res, err := http.Get(agentUrl)
+	res, err := c.get(ctx, agentUrl)
 	if err != nil {
 		c.logger.Error("get agent url fail", "err", err)
 		return "", err
@@ -81,26 +275,110 @@ func (c *ElizaClient) GetSelfIntro(ctx context.Context) (string, error) {
 	return selfIntro.Character, nil
 }
 
+// NewElizaClient talks to the first agent of the service at url.
 func NewElizaClient(url string, logger cmtlog.Logger) (*ElizaClient, error) {
+	return NewElizaAgentClient(url, "", nil, logger)
+}
+
+// NewElizaAgentClient talks to the agent of the service at url selected by
+// its id or name, the first agent when selection is empty, with httpClient,
+// http.DefaultClient when nil.
+func NewElizaAgentClient(url string, selection string, httpClient *http.Client, logger cmtlog.Logger) (*ElizaClient, error) {
 	l := logger.With("module", "eliza")
 	client := &ElizaClient{
-		Url:    url,
-		logger: l,
+		voteContext: voteContext{logger: l},
+		Url:         url,
+		HTTP:        httpClient,
+		selection:   selection,
+		logger:      l,
 	}
-	ids, err := client.GetAgentIds(context.Background())
+	agents, err := client.GetAgents(context.Background())
 	if err != nil {
 		return nil, err
 	}
-	if len(ids) == 0 {
-		return nil, errors.New("no agent id")
+	client.AgentId, err = SelectElizaAgent(agents, selection)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
 	}
-	client.AgentId = ids[0]
 	return client, nil
 }
 
-func (e *ElizaClient) GetAgentIds(ctx context.Context) ([]string, error) {
+// ElizaAgent is an agent loaded by an Eliza service.
+type ElizaAgent struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// SelectElizaAgent returns the id of the agent whose id or name is
+// selection, the first agent when selection is empty. The error of a
+// missing agent lists the available ones.
+func SelectElizaAgent(agents []ElizaAgent, selection string) (string, error) {
+	if len(agents) == 0 {
+		return "", errors.New("no agent id")
+	}
+	if selection == "" {
+		return agents[0].Id, nil
+	}
+	for _, ag := range agents {
+		if ag.Id == selection || strings.EqualFold(ag.Name, selection) {
+			return ag.Id, nil
+		}
+	}
+	available := make([]string, 0, len(agents))
+	for _, ag := range agents {
+		available = append(available, fmt.Sprintf("%s (%s)", ag.Name, ag.Id))
+	}
+	return "", fmt.Errorf("agent %q not found, available: %s", selection, strings.Join(available, ", "))
+}
+
+func (e *ElizaClient) agent() string {
+	e.agentMtx.RLock()
+	defer e.agentMtx.RUnlock()
+	return e.AgentId
+}
+
+// RefreshAgent lists the agents of the service every interval until ctx is
+// done, and follows the selected agent when its id changes, as it does when
+// the service restarts. Without a selection the current agent is kept while
+// it is loaded.
+func (e *ElizaClient) RefreshAgent(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		agents, err := e.GetAgents(ctx)
+		if err != nil {
+			e.logger.Error("refresh agents fail", "err", err)
+			continue
+		}
+		selection := e.selection
+		if selection == "" {
+			selection = e.agent()
+			if _, err := SelectElizaAgent(agents, selection); err != nil {
+				selection = ""
+			}
+		}
+		id, err := SelectElizaAgent(agents, selection)
+		if err != nil {
+			e.logger.Error("refresh agents fail", "err", err)
+			continue
+		}
+		e.agentMtx.Lock()
+		if id != e.AgentId {
+			e.logger.Info("agent changed", "from", e.AgentId, "to", id)
+			e.AgentId = id
+		}
+		e.agentMtx.Unlock()
+	}
+}
+
+func (e *ElizaClient) GetAgents(ctx context.Context) ([]ElizaAgent, error) {
 	url := fmt.Sprintf("%s/agents", e.Url)
-	res, err := http.Get(url)
+	res, err := e.get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -110,76 +388,137 @@ func (e *ElizaClient) GetAgentIds(ctx context.Context) ([]string, error) {
 		return nil, err
 	}
 	var agents struct {
-		Agents []struct {
-			Id   string `json:"id"`
-			Name string `json:"name"`
-		} `json:"agents"`
+		Agents []ElizaAgent `json:"agents"`
 	}
 	err = json.Unmarshal(bodyBytes, &agents)
 	if err != nil {
 		return nil, err
 	}
-	ids := make([]string, 0, len(agents.Agents))
-	for _, ag := range agents.Agents {
+	return agents.Agents, nil
+}
+
+func (e *ElizaClient) GetAgentIds(ctx context.Context) ([]string, error) {
+	agents, err := e.GetAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(agents))
+	for _, ag := range agents {
 		ids = append(ids, ag.Id)
 	}
 	return ids, nil
 }
 
+// VoteGrantReq asks the agent to vote on a grant. Dossier is the track
+// record of the proposer, for agents reading it apart from the text.
 type VoteGrantReq struct {
-	GrantId          uint64 `json:"grantId"`
-	ValidatorAddress string `json:"validatorAddress"`
-	Text             string `json:"text"`
+	GrantId          uint64           `json:"grantId"`
+	ValidatorAddress string           `json:"validatorAddress"`
+	Text             string           `json:"text"`
+	Dossier          *ProposerDossier `json:"dossier,omitempty"`
 }
 
-func (e *ElizaClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (bool, error) {
+func (e *ElizaClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
 	e.logger.Info("IfGrantNewMember", "validator", validator, "proposer", proposer, "amount", amount, "statement", statement)
-	url := fmt.Sprintf("%s/%s/votegrant", e.Url, e.AgentId)
+	url := fmt.Sprintf("%s/%s/votegrant", e.Url, e.agent())
+	text := e.grantRequest(validator, proposer, amount, statement)
 	req := VoteGrantReq{
 		GrantId:          validator,
 		ValidatorAddress: proposer,
-		Text:             statement,
+		Text:             e.requestText("IfGrantNewMember", text),
+		Dossier:          text.dossier,
 	}
 	data, _ := json.Marshal(req)
-	res, err := http.Post(url, "application/json", bytes.NewBuffer([]byte(data)))
+	vote, err := e.askVote(ctx, "IfGrantNewMember", url, data, voteKindGrant, validator, "")
 	if err != nil {
-		return false, err
+		return Decision{}, err
 	}
-	defer res.Body.Close()
-	bodyBytes, err := io.ReadAll(res.Body)
-	if err != nil {
-		e.logger.Error("read response body fail", "err", err)
-		return false, err
+	e.logger.Info("vote grant", "validator", validator, "proposer", proposer, "vote", vote.Vote, "confidence", vote.Confidence, "reason", vote.Reason)
+	return vote, nil
+}
+
+// uptimeText describes the uptime and recent absences of the proposer of a
+// grant and the least available validators, "" without an uptime source.
+func (v *voteContext) uptimeText(proposer string) string {
+	if v.uptime == nil {
+		return ""
 	}
-	var vote VoteResponse
-	err = json.Unmarshal(bodyBytes, &vote)
+	report, err := v.uptime.UptimeContext(proposer, uptimeGrantAbsences)
 	if err != nil {
-		e.logger.Error("unmarshal response body fail", "err", err)
-		return false, err
+		v.logger.Error("get uptime fail", "proposer", proposer, "err", err)
+		return ""
 	}
-	e.logger.Info("vote grant", "validator", validator, "proposer", proposer, "vote", vote.Vote, "reason", vote.Reason)
-	if vote.Vote == "yes" {
-		return true, nil
+	var b strings.Builder
+	u := report.Uptime
+	fmt.Fprintf(&b, "\n\nThe proposer signed %d and missed %d of its last %d blocks, an uptime of %.1f%%.", u.WindowSigned, u.WindowMissed, u.WindowSigned+u.WindowMissed, u.Uptime)
+	if len(report.Absences) > 0 {
+		heights := make([]string, len(report.Absences))
+		for i, a := range report.Absences {
+			heights[i] = strconv.FormatUint(a.Height, 10)
+		}
+		fmt.Fprintf(&b, "\nRecent absences at heights %s.", strings.Join(heights, ", "))
 	}
-	return false, nil
+	if len(report.Lowest) > 0 {
+		b.WriteString("\nLeast available validators:")
+		for _, v := range report.Lowest {
+			name := v.Name
+			if name == "" {
+				name = v.Address
+			}
+			fmt.Fprintf(&b, "\n- %s: %.1f%%, last missed height %d", name, v.Uptime, v.LastMissed)
+		}
+	}
+	return b.String()
 }
 
 func (e *ElizaClient) CommentPropoal(ctx context.Context, proposal uint64, speaker string) (string, error) {
+	comment, err := e.StreamComment(ctx, proposal, speaker, nil)
+	if err != nil {
+		return "", err
+	}
+	return comment, nil
+}
+
+// StreamComment asks the agent for a comment as server-sent events, and
+// reads an agent answering at once or in chunks as well. The comment is saved
+// as a draft while it arrives, so what the agent sent before a disconnect is
+// kept, and returned with the error.
+func (e *ElizaClient) StreamComment(ctx context.Context, proposal uint64, speaker string, onChunk func(chunk string)) (string, error) {
 	e.logger.Info("CommentPropoal", "proposal", proposal, "speaker", speaker)
-	url := fmt.Sprintf("%s/%s/newdiscussion", e.Url, e.AgentId)
-	body := fmt.Sprintf(`{"proposalId":"%d","validatorAddress":"%s","text":"comment"}`, proposal, speaker)
-	res, err := http.Post(url, "application/json", bytes.NewBuffer([]byte(body)))
+	url := fmt.Sprintf("%s/%s/newdiscussion", e.Url, e.agent())
+	body, _ := json.Marshal(VoteProposalReq{
+		ProposalId:       fmt.Sprintf("%d", proposal),
+		ValidatorAddress: speaker,
+		Text:             e.commentRequestText(ctx, proposal, speaker),
+	})
+	res, err := e.postAccept(ctx, "CommentPropoal", url, body, "text/event-stream, text/plain;q=0.9, */*;q=0.8")
 	if err != nil {
 		return "", err
 	}
 	defer res.Body.Close()
-	bodyBytes, err := io.ReadAll(res.Body)
+	if res.StatusCode/100 != 2 {
+		bodyBytes, _ := io.ReadAll(res.Body)
+		return "", agentStatusError("CommentPropoal", res.StatusCode, bodyBytes)
+	}
+	draft := newCommentDraft(e.drafts, proposal, speaker)
+	onData := func(data string) {
+		draft.write(data)
+		if onChunk != nil {
+			onChunk(data)
+		}
+	}
+	if strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream") {
+		err = readSSE(res.Body, onData)
+	} else {
+		err = readChunks(res.Body, onData)
+	}
+	comment := draft.finish(err)
 	if err != nil {
-		e.logger.Error("read response body fail", "err", err)
-		return "", err
+		e.logger.Error("read comment stream fail", "proposal", proposal, "received", len(comment), "err", err)
+		return comment, agentCallError("CommentPropoal", err)
 	}
-	e.logger.Info("comment proposal", "proposal", proposal, "speaker", speaker, "comment", string(bodyBytes))
-	return string(bodyBytes), nil
+	e.logger.Info("comment proposal", "proposal", proposal, "speaker", speaker, "comment", comment)
+	return comment, nil
 }
 
 type AddDiscussionReq struct {
@@ -190,22 +529,67 @@ type AddDiscussionReq struct {
 
 func (e *ElizaClient) AddDiscussion(ctx context.Context, proposal uint64, speaker string, text string) error {
 	e.logger.Info("AddDiscussion", "proposal", proposal, "speaker", speaker, "text", text)
-	url := fmt.Sprintf("%s/%s/discussion", e.Url, e.AgentId)
+	url := fmt.Sprintf("%s/%s/discussion", e.Url, e.agent())
 	req := AddDiscussionReq{
 		ProposalId:       proposal,
 		ValidatorAddress: speaker,
 		Text:             text,
 	}
 	data, _ := json.Marshal(req)
-	res, err := http.Post(url, "application/json", bytes.NewBuffer([]byte(data)))
+	res, err := e.post(ctx, "AddDiscussion", url, data)
 	if err != nil {
 		return err
 	}
 	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(res.Body)
+		return agentStatusError("AddDiscussion", res.StatusCode, body)
+	}
 	e.logger.Info("add discussion", "proposal", proposal, "speaker", speaker, "text", text)
 	return nil
 }
 
+// DiscussionMsg is a discussion sent to the agent in a batch.
+type DiscussionMsg struct {
+	Proposal uint64
+	Speaker  string
+	Text     string
+}
+
+type AddDiscussionsReq struct {
+	Discussions []AddDiscussionReq `json:"discussions"`
+}
+
+// AddDiscussions sends msgs to the agent in one request, the batch is
+// accepted or refused as a whole.
+func (e *ElizaClient) AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	e.logger.Info("AddDiscussions", "count", len(msgs))
+	url := fmt.Sprintf("%s/%s/discussions", e.Url, e.agent())
+	req := AddDiscussionsReq{Discussions: make([]AddDiscussionReq, 0, len(msgs))}
+	for _, msg := range msgs {
+		req.Discussions = append(req.Discussions, AddDiscussionReq{
+			ProposalId:       msg.Proposal,
+			ValidatorAddress: msg.Speaker,
+			Text:             msg.Text,
+		})
+	}
+	data, _ := json.Marshal(req)
+	res, err := e.post(ctx, "AddDiscussions", url, data)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(res.Body)
+		return agentStatusError("AddDiscussions", res.StatusCode, body)
+	}
+	e.logger.Info("add discussions", "count", len(msgs))
+	return nil
+}
+
 type AddProposalReq struct {
 	ProposalId       uint64 `json:"proposalId"`
 	ValidatorAddress string `json:"validatorAddress"`
@@ -214,14 +598,14 @@ type AddProposalReq struct {
 
 func (e *ElizaClient) AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error {
 	e.logger.Info("AddProposal", "proposal", proposal, "proposer", proposer, "text", text)
-	url := fmt.Sprintf("%s/%s/proposal", e.Url, e.AgentId)
+	url := fmt.Sprintf("%s/%s/proposal", e.Url, e.agent())
 	req := AddProposalReq{
 		ProposalId:       proposal,
 		ValidatorAddress: proposer,
 		Text:             text,
 	}
 	data, _ := json.Marshal(req)
-	res, err := http.Post(url, "application/json", bytes.NewBuffer([]byte(data)))
+	res, err := e.post(ctx, "AddProposal", url, data)
 	if err != nil {
 		return err
 	}
@@ -231,40 +615,329 @@ func (e *ElizaClient) AddProposal(ctx context.Context, proposal uint64, proposer
 	if err == nil {
 		resp = string(data)
 	}
+	if err := agentStatusError("AddProposal", res.StatusCode, data); err != nil {
+		return err
+	}
 	e.logger.Info("add proposal", "proposal", proposal, "proposer", proposer, "text", text, "resp", resp)
 	return nil
 }
 
-type VoteResponse struct {
-	Vote   string `json:"vote"`
-	Reason string `json:"reason"`
+type NotifyActivationReq struct {
+	ProposalId       uint64 `json:"proposalId"`
+	ActivationHeight uint64 `json:"activationHeight"`
 }
 
-func (e *ElizaClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (bool, error) {
-	e.logger.Info("IfAcceptProposal", "proposal", proposal, "voter", voter)
-	url := fmt.Sprintf("%s/%s/voteproposal", e.Url, e.AgentId)
-	body := fmt.Sprintf(`{"proposalId":"%d","validatorAddress":"%s","text":"analyze proposal"}`, proposal, voter)
-	res, err := http.Post(url, "application/json", bytes.NewBuffer([]byte(body)))
+// NotifyActivation tells the agent a proposal takes effect at
+// activationHeight, so it can prepare before the chain gets there.
+func (e *ElizaClient) NotifyActivation(ctx context.Context, proposal uint64, activationHeight uint64) error {
+	e.logger.Info("NotifyActivation", "proposal", proposal, "activationHeight", activationHeight)
+	url := fmt.Sprintf("%s/%s/activation", e.Url, e.agent())
+	data, _ := json.Marshal(NotifyActivationReq{ProposalId: proposal, ActivationHeight: activationHeight})
+	res, err := e.post(ctx, "NotifyActivation", url, data)
 	if err != nil {
-		return false, err
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(res.Body)
+		return agentStatusError("NotifyActivation", res.StatusCode, body)
+	}
+	return nil
+}
+
+type NotifyMisbehaviorReq struct {
+	ValidatorAddress string `json:"validatorAddress"`
+	Kind             string `json:"kind"`
+	Height           uint64 `json:"height"`
+}
+
+// NotifyMisbehavior alerts the agent that a member was caught misbehaving
+// at height, so it can weigh it in the votes that follow.
+func (e *ElizaClient) NotifyMisbehavior(ctx context.Context, validator string, kind string, height uint64) error {
+	e.logger.Info("NotifyMisbehavior", "validator", validator, "kind", kind, "height", height)
+	url := fmt.Sprintf("%s/%s/misbehavior", e.Url, e.agent())
+	data, _ := json.Marshal(NotifyMisbehaviorReq{ValidatorAddress: validator, Kind: kind, Height: height})
+	res, err := e.post(ctx, "NotifyMisbehavior", url, data)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(res.Body)
+		return agentStatusError("NotifyMisbehavior", res.StatusCode, body)
+	}
+	return nil
+}
+
+type DraftAnnouncementReq struct {
+	ProposalId uint64 `json:"proposalId"`
+	Title      string `json:"title"`
+	Status     string `json:"status"`
+}
+
+// DraftAnnouncement asks the agent for a short public announcement of a
+// settled proposal.
+func (e *ElizaClient) DraftAnnouncement(ctx context.Context, proposal uint64, title string, status string) (string, error) {
+	e.logger.Info("DraftAnnouncement", "proposal", proposal, "status", status)
+	url := fmt.Sprintf("%s/%s/announcement", e.Url, e.agent())
+	data, _ := json.Marshal(DraftAnnouncementReq{
+		ProposalId: proposal,
+		Title:      title,
+		Status:     status,
+	})
+	res, err := e.post(ctx, "DraftAnnouncement", url, data)
+	if err != nil {
+		return "", err
 	}
 	defer res.Body.Close()
 	bodyBytes, err := io.ReadAll(res.Body)
 	if err != nil {
-		e.logger.Error("read response body fail", "err", err)
-		return false, err
+		return "", agentCallError("DraftAnnouncement", err)
+	}
+	if err := agentStatusError("DraftAnnouncement", res.StatusCode, bodyBytes); err != nil {
+		return "", err
 	}
-	var vote VoteResponse
-	err = json.Unmarshal(bodyBytes, &vote)
+	var announcement struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(bodyBytes, &announcement); err != nil {
+		return "", agentResponseError("DraftAnnouncement", res.StatusCode, bodyBytes, err)
+	}
+	return announcement.Text, nil
+}
+
+// RefineProposal asks the agent to rewrite a proposal draft into a clear
+// title, summary and body.
+func (e *ElizaClient) RefineProposal(ctx context.Context, draft ProposalEnvelope) (ProposalEnvelope, error) {
+	e.logger.Info("RefineProposal", "title", draft.Title)
+	url := fmt.Sprintf("%s/%s/refineproposal", e.Url, e.agent())
+	data, _ := json.Marshal(draft)
+	res, err := e.post(ctx, "RefineProposal", url, data)
+	if err != nil {
+		return ProposalEnvelope{}, err
+	}
+	defer res.Body.Close()
+	bodyBytes, err := io.ReadAll(res.Body)
 	if err != nil {
-		e.logger.Error("unmarshal response body fail", "err", err)
-		return false, err
+		return ProposalEnvelope{}, agentCallError("RefineProposal", err)
 	}
-	e.logger.Info("vote proposal", "proposal", proposal, "voter", voter, "vote", vote.Vote, "reason", vote.Reason)
-	if vote.Vote == "yes" {
-		return true, nil
+	if err := agentStatusError("RefineProposal", res.StatusCode, bodyBytes); err != nil {
+		return ProposalEnvelope{}, err
 	}
-	return false, nil
+	var refined ProposalEnvelope
+	if err := json.Unmarshal(bodyBytes, &refined); err != nil {
+		return ProposalEnvelope{}, agentResponseError("RefineProposal", res.StatusCode, bodyBytes, err)
+	}
+	return refined, nil
+}
+
+// VoteResponse is the answer of the agent to a vote request, yes, no or
+// abstain. A confidence left out is full confidence. Signature is the hex
+// signature of the vote by an agent signing its votes, see voteSignBytes.
+type VoteResponse struct {
+	Vote       string   `json:"vote"`
+	Confidence *float64 `json:"confidence,omitempty"`
+	Reason     string   `json:"reason"`
+	Signature  string   `json:"signature,omitempty"`
+}
+
+// askVote posts a vote request and decodes the answer. The exchange is kept
+// as the transcript of the vote of voter on the proposal or grant id.
+func (e *ElizaClient) askVote(ctx context.Context, method string, url string, data []byte, kind string, id uint64, voter string) (Decision, error) {
+	transcript := Transcript{Kind: kind, RefId: id, Voter: voter, Method: method, Prompt: string(data)}
+	started := time.Now()
+	decision, err := func() (Decision, error) {
+		var vote VoteResponse
+		res, err := e.post(ctx, method, url, data)
+		if err != nil {
+			return Decision{}, err
+		}
+		defer res.Body.Close()
+		transcript.StatusCode = res.StatusCode
+		bodyBytes, err := io.ReadAll(res.Body)
+		if err != nil {
+			e.logger.Error("read response body fail", "err", err)
+			return Decision{}, agentCallError(method, err)
+		}
+		transcript.Response = string(bodyBytes)
+		if err := agentStatusError(method, res.StatusCode, bodyBytes); err != nil {
+			return Decision{}, err
+		}
+		err = json.Unmarshal(bodyBytes, &vote)
+		if err != nil {
+			e.logger.Error("unmarshal response body fail", "err", err)
+			return Decision{}, agentResponseError(method, res.StatusCode, bodyBytes, err)
+		}
+		if err := verifyVote(e.votePubKey, idempotencyKey(ctx, url, data), vote); err != nil {
+			e.logger.Error("verify vote signature fail", "method", method, "err", err)
+			return Decision{}, agentResponseError(method, res.StatusCode, bodyBytes, err)
+		}
+		decision, err := parseDecision(vote.Vote, vote.Confidence, vote.Reason)
+		if err != nil {
+			return decision, agentResponseError(method, res.StatusCode, bodyBytes, err)
+		}
+		return decision, nil
+	}()
+	transcript.DurationMs = time.Since(started).Milliseconds()
+	transcript.Vote = string(decision.Vote)
+	if err != nil {
+		transcript.Error = err.Error()
+	}
+	if err := e.transcripts.record(transcript); err != nil {
+		e.logger.Error("save transcript fail", "err", err)
+	}
+	return decision, err
+}
+
+// treasuryText describes the spend a proposal asks for against the
+// remaining treasury and the recent spends, "" for other proposals.
+func (v *voteContext) treasuryText(proposal uint64) string {
+	if v.treasury == nil {
+		return ""
+	}
+	spend, budget, err := v.treasury.TreasuryContext(proposal)
+	if err != nil {
+		v.logger.Error("get treasury budget fail", "proposal", proposal, "err", err)
+		return ""
+	}
+	if spend == nil {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\nThe proposal spends %v from the treasury to %s.", spend.Amount, spend.Recipient)
+	fmt.Fprintf(&b, "\nTreasury: %v remaining of %v, %v asked by the proposals not settled yet, this one included.", budget.Balance, budget.Funds, budget.Pending)
+	if len(budget.Recent) > 0 {
+		b.WriteString("\nRecent spends:")
+		for _, s := range budget.Recent {
+			fmt.Fprintf(&b, "\n- proposal #%d: %v to %s at height %d", s.Proposal, s.Amount, s.Recipient, s.ClosedHeight)
+		}
+	}
+	return b.String()
+}
+
+// VoteProposalReq asks the agent to vote on a proposal. Proposal and
+// Discussions carry the indexed proposal and its debate so far, for agents
+// reading them apart from the text.
+type VoteProposalReq struct {
+	ProposalId       string                `json:"proposalId"`
+	ValidatorAddress string                `json:"validatorAddress"`
+	Text             string                `json:"text"`
+	Proposal         *VoteProposalContext  `json:"proposal,omitempty"`
+	Discussions      []VoteDiscussionEntry `json:"discussions,omitempty"`
+}
+
+// VoteProposalContext is the indexed text of the proposal voted on.
+type VoteProposalContext struct {
+	Title    string `json:"title"`
+	Summary  string `json:"summary"`
+	Body     string `json:"body"`
+	Proposer string `json:"proposer"`
+}
+
+// VoteDiscussionEntry is a discussion posted on the proposal voted on.
+type VoteDiscussionEntry struct {
+	Speaker        string `json:"speaker"`
+	SpeakerAddress string `json:"speakerAddress"`
+	Text           string `json:"text"`
+	Height         uint64 `json:"height"`
+}
+
+// voteProposalText builds the vote prompt, appending the treasury budget of
+// a spend and the debate so far, the last discussions in order or the top
+// ranked ones of the other validators, so the agent sees the whole debate.
+// An expedited proposal needs the same share as any other, see
+// PayloadTypeExpedited.
+func (v *voteContext) voteProposalText(proposal uint64, voter string, expedited bool) string {
+	text := "analyze proposal"
+	if expedited {
+		text = fmt.Sprintf("analyze expedited proposal, it needs %.0f%% of the voting power to pass", TallyPassShare*100)
+	}
+	text += v.treasuryText(proposal)
+	title := "Discussion so far:"
+	discussions := v.discussionHistory(proposal)
+	if len(discussions) == 0 {
+		title = "Discussions from other validators:"
+		discussions = v.peerDiscussions(proposal, voter)
+	}
+	if len(discussions) == 0 {
+		return text
+	}
+	var b strings.Builder
+	b.WriteString(text)
+	b.WriteString("\n\n" + title)
+	for _, d := range discussions {
+		fmt.Fprintf(&b, "\n- %s: %s", d.Speaker, d.Text)
+	}
+	return b.String()
+}
+
+// peerDiscussions are the top ranked discussions of the validators other
+// than voter on proposal.
+func (v *voteContext) peerDiscussions(proposal uint64, voter string) []agentTextDiscussion {
+	if v.discussions == nil || PeerDiscussionLimit <= 0 {
+		return nil
+	}
+	discussions, err := v.discussions.PeerDiscussions(proposal, voter, PeerDiscussionLimit)
+	if err != nil {
+		v.logger.Error("get peer discussions fail", "proposal", proposal, "err", err)
+		return nil
+	}
+	return textDiscussions(discussions)
+}
+
+// discussionHistory is the last DiscussionHistoryLimit discussions on
+// proposal, from the oldest.
+func (v *voteContext) discussionHistory(proposal uint64) []agentTextDiscussion {
+	if v.discussions == nil || DiscussionHistoryLimit <= 0 {
+		return nil
+	}
+	discussions, err := v.discussions.DiscussionHistory(proposal, DiscussionHistoryLimit)
+	if err != nil {
+		v.logger.Error("get discussion history fail", "proposal", proposal, "err", err)
+		return nil
+	}
+	return textDiscussions(discussions)
+}
+
+func textDiscussions(discussions []Discussion) []agentTextDiscussion {
+	texts := make([]agentTextDiscussion, 0, len(discussions))
+	for _, d := range discussions {
+		speaker := d.SpeakerName
+		if speaker == "" {
+			speaker = d.SpeakerAddress
+		}
+		texts = append(texts, agentTextDiscussion{Speaker: speaker, Address: d.SpeakerAddress, Text: d.Data, Height: d.Height})
+	}
+	return texts
+}
+
+func (e *ElizaClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	e.logger.Info("IfAcceptProposal", "proposal", proposal, "voter", voter)
+	expedited := e.isExpedited(proposal)
+	if expedited {
+		ctx = WithExpedited(ctx)
+	}
+	url := fmt.Sprintf("%s/%s/voteproposal", e.Url, e.agent())
+	text := e.voteRequest(proposal, voter, expedited)
+	req := VoteProposalReq{
+		ProposalId:       fmt.Sprintf("%d", proposal),
+		ValidatorAddress: voter,
+		Text:             e.requestText("IfAcceptProposal", text),
+		Discussions:      make([]VoteDiscussionEntry, 0, len(text.History)),
+	}
+	if text.Title != "" || text.Body != "" {
+		req.Proposal = &VoteProposalContext{Title: text.Title, Summary: text.Summary, Body: text.Body, Proposer: text.Proposer}
+	}
+	for _, d := range text.History {
+		req.Discussions = append(req.Discussions, VoteDiscussionEntry{Speaker: d.Speaker, SpeakerAddress: d.Address, Text: d.Text, Height: d.Height})
+	}
+	data, _ := json.Marshal(req)
+	vote, err := e.askVote(ctx, "IfAcceptProposal", url, data, voteKindProposal, proposal, voter)
+	if err != nil {
+		return Decision{}, err
+	}
+	e.logger.Info("vote proposal", "proposal", proposal, "voter", voter, "vote", vote.Vote, "confidence", vote.Confidence, "reason", vote.Reason)
+	return vote, nil
 }
 
 func (e *ElizaClient) IfProcessProposal(ctx context.Context, proposer uint64, data []byte) (bool, error) {
@@ -286,6 +959,18 @@ func (m *MockClient) AddDiscussion(ctx context.Context, proposal uint64, speaker
 	return nil
 }
 
+func (m *MockClient) AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error {
+	return nil
+}
+
+func (m *MockClient) NotifyActivation(ctx context.Context, proposal uint64, activationHeight uint64) error {
+	return nil
+}
+
+func (m *MockClient) NotifyMisbehavior(ctx context.Context, validator string, kind string, height uint64) error {
+	return nil
+}
+
 func (m *MockClient) AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error {
 	return nil
 }
@@ -298,14 +983,72 @@ func NewMockClient() *MockClient {
 	return &MockClient{}
 }
 
-func (m *MockClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (bool, error) {
-	return true, nil
+func (m *MockClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	return decisionOf(true, "mock"), nil
 }
 
-func (m *MockClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (bool, error) {
-	return true, nil
+func (m *MockClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	return decisionOf(true, "mock"), nil
 }
 
 func (m *MockClient) IfProcessProposal(ctx context.Context, proposer uint64, data []byte) (bool, error) {
 	return true, nil
 }
+
+// ErrIndexOnly is returned by NoopClient for decisions that need an agent.
+var ErrIndexOnly = errors.New("agent disabled in index-only mode")
+
+var _ Client = &NoopClient{}
+
+// NoopClient is used in index-only mode, where no agent is deployed. Indexed
+// data is not forwarded and voting decisions fail instead of being made up.
+type NoopClient struct {
+}
+
+func NewNoopClient() *NoopClient {
+	return &NoopClient{}
+}
+
+func (n *NoopClient) IfProcessProposal(ctx context.Context, proposer uint64, data []byte) (bool, error) {
+	return false, ErrIndexOnly
+}
+
+func (n *NoopClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	return Decision{}, ErrIndexOnly
+}
+
+func (n *NoopClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	return Decision{}, ErrIndexOnly
+}
+
+func (n *NoopClient) CommentPropoal(ctx context.Context, proposal uint64, speaker string) (string, error) {
+	return "", ErrIndexOnly
+}
+
+func (n *NoopClient) AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error {
+	return nil
+}
+
+func (n *NoopClient) AddDiscussion(ctx context.Context, proposal uint64, speaker string, text string) error {
+	return nil
+}
+
+func (n *NoopClient) AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error {
+	return nil
+}
+
+func (n *NoopClient) NotifyActivation(ctx context.Context, proposal uint64, activationHeight uint64) error {
+	return nil
+}
+
+func (n *NoopClient) NotifyMisbehavior(ctx context.Context, validator string, kind string, height uint64) error {
+	return nil
+}
+
+func (n *NoopClient) GetSelfIntro(ctx context.Context) (string, error) {
+	return "", nil
+}
+
+func (n *NoopClient) GetHeadPhoto(ctx context.Context) (string, error) {
+	return "", nil
+}
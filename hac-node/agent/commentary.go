@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CommentMinGapDefault is the number of blocks between two comments of the
+// agent on a proposal when no gap is configured.
+const CommentMinGapDefault = 5
+
+// CommentMaxFollowUpsDefault is the number of follow-up comments of the
+// agent on a proposal when no limit is configured.
+const CommentMaxFollowUpsDefault = 5
+
+// commentSchedule re-engages the agent on the proposals under discussion
+// after the comment posted when they were indexed: every interval blocks, or
+// once other validators discussed since its last comment when onDiscussion
+// is set, never twice within minGap blocks and at most maxFollowUps times a
+// proposal. The comments are queued in the outbox like the first one.
+type commentSchedule struct {
+	interval     uint64
+	onDiscussion bool
+	minGap       uint64
+	maxFollowUps int
+}
+
+func (s commentSchedule) enabled() bool {
+	return s.interval > 0 || s.onDiscussion
+}
+
+// scheduleComments queues the follow-up comments due at height. The entries
+// are keyed by proposal and height, a replayed height queues nothing new.
+func (c *ChainIndexer) scheduleComments(height uint64) error {
+	if !c.commentary.enabled() || c.debate.rounds > 0 || c.indexOnly {
+		return nil
+	}
+	proposals, err := c.getProposalsByStatus(ProposalStatusDiscussing, 0, 100)
+	if err != nil {
+		return err
+	}
+	queued := false
+	for _, p := range proposals.Items {
+		trigger, err := c.commentDue(p, height)
+		if err != nil {
+			return fmt.Errorf("schedule comment on proposal %d: %w", p.Id, err)
+		}
+		if trigger == "" {
+			continue
+		}
+		err = c.db.Transaction(func(tx *gorm.DB) error {
+			return c.enqueueAgentCall(tx, fmt.Sprintf("comment:%d:%d", p.Id, height), AgentOutbox{
+				Method:    outboxCommentProposal,
+				Proposal:  p.Id,
+				Address:   c.localAddress,
+				Height:    height,
+				Expedited: p.Expedited,
+			})
+		})
+		if err != nil {
+			return fmt.Errorf("schedule comment on proposal %d: %w", p.Id, err)
+		}
+		queued = true
+		c.logger.Info("follow-up comment scheduled", "proposal", p.Id, "trigger", trigger, "height", height)
+	}
+	if queued {
+		c.wakeOutbox()
+	}
+	return nil
+}
+
+// commentDue returns what makes a follow-up comment on p due at height,
+// interval or discussion, "" when none is.
+func (c *ChainIndexer) commentDue(p Proposal, height uint64) (string, error) {
+	var comments []AgentOutbox
+	err := c.db.Where("method = ? AND proposal = ?", outboxCommentProposal, p.Id).Order("height desc").Find(&comments).Error
+	if err != nil {
+		return "", err
+	}
+	last := p.NewHeight
+	if len(comments) > 0 {
+		last = comments[0].Height
+		// the first comment is the one queued when the proposal was indexed
+		if len(comments)-1 >= c.commentary.maxFollowUps {
+			return "", nil
+		}
+	}
+	if height <= last || height-last < c.commentary.minGap {
+		return "", nil
+	}
+	if c.commentary.interval > 0 && height-last >= c.commentary.interval {
+		return "interval", nil
+	}
+	if !c.commentary.onDiscussion {
+		return "", nil
+	}
+	var discussed int
+	err = c.db.Model(&Discussion{}).Where("proposal = ? AND height > ? AND speaker_address <> ?", p.Id, last, c.localAddress).Count(&discussed).Error
+	if err != nil {
+		return "", err
+	}
+	if discussed > 0 {
+		return "discussion", nil
+	}
+	return "", nil
+}
@@ -1,4 +1,18 @@
 package agent
 
+import "time"
+
 const MANIFESTO = `GO TO MARS!`
 const DEFAULT_PROPOSAL_EXPIRE_DUR = 1000
+
+// FetchBlockAttempts bounds how often the indexer retries fetching a block
+// before reporting the height as failed.
+const FetchBlockAttempts = 5
+
+// FailedEventAttempts bounds how often the reprocessor retries a failed event
+// before it waits for an operator to requeue it.
+const FailedEventAttempts = 10
+
+// FailedEventInterval is how often the indexing loop retries the failed
+// events, between two heights.
+const FailedEventInterval = 30 * time.Second
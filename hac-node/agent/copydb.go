@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/jinzhu/gorm"
+)
+
+// CopyProgress is stored in the target db after every batch, so an
+// interrupted copy resumes after the last copied key.
+type CopyProgress struct {
+	Name    string `gorm:"primary_key" json:"name"`
+	LastKey uint64 `json:"last_key"`
+	Copied  uint64 `json:"copied"`
+	Done    bool   `json:"done"`
+}
+
+// CopyReport is passed to the progress callback of CopyIndexDB.
+type CopyReport struct {
+	Table  string
+	Copied uint64
+	Total  uint64
+	Done   bool
+}
+
+type copyTable struct {
+	model interface{}
+	key   string
+}
+
+// copyTables lists the tables copied by CopyIndexDB, parents first.
+// schema_versions is not copied, the target is migrated by its own run of
+// migrate.
+var copyTables = []copyTable{
+	{&Height{}, "id"},
+	{&IndexedBlock{}, "height"},
+	{&ValidatorAgent{}, "id"},
+	{&Grant{}, "id"},
+	{&GrantVote{}, "id"},
+	{&Proposal{}, "id"},
+	{&ProposalStatusChange{}, "id"},
+	{&ProposalVote{}, "id"},
+	{&Discussion{}, "id"},
+	{&BlockDiscrepancy{}, "id"},
+	{&FailedEvent{}, "id"},
+	{&AuditDivergence{}, "id"},
+	{&DryRunDecision{}, "id"},
+	{&SocialPost{}, "id"},
+	{&IpfsArchive{}, "id"},
+	{&BridgeMirror{}, "id"},
+	{&DigestSubscriber{}, "id"},
+	{&ApiKey{}, "id"},
+	{&AgentOutbox{}, "id"},
+	{&ProposalScore{}, "id"},
+	{&StakeChange{}, "id"},
+	{&Transcript{}, "id"},
+	{&DecisionAnchor{}, "id"},
+	{&ProposalAnomaly{}, "id"},
+	{&ProposalActivation{}, "id"},
+	{&TreasurySpend{}, "id"},
+	{&BlockSignature{}, "id"},
+	{&ValidatorUptime{}, "id"},
+	{&ValidatorMisbehavior{}, "id"},
+	{&VoteFallback{}, "id"},
+	{&AgentQuestion{}, "id"},
+	{&EnsembleVote{}, "id"},
+	{&CachedDecision{}, "id"},
+	{&AgentHealthCheck{}, "id"},
+	{&CommentDraft{}, "id"},
+	{&AgentDecision{}, "id"},
+	{&DebateRound{}, "id"},
+	{&ProposalSummary{}, "proposal"},
+}
+
+// CopyIndexDB copies the indexer db at src into dst, usually a sqlite file
+// into postgres, in batches of batch rows ordered by primary key. The schema
+// of dst is created and migrated first. Rows are written unchanged, hooks and
+// timestamps are skipped, and copying again overwrites instead of duplicating.
+func CopyIndexDB(ctx context.Context, src string, dst string, batch int, progress func(CopyReport)) error {
+	if batch <= 0 {
+		batch = 500
+	}
+	from, err := openIndexDB(src)
+	if err != nil {
+		return fmt.Errorf("open source: %w", err)
+	}
+	defer from.Close()
+	to, err := openIndexDB(dst)
+	if err != nil {
+		return fmt.Errorf("open target: %w", err)
+	}
+	defer to.Close()
+
+	if err := to.AutoMigrate(indexerModels...).Error; err != nil {
+		return err
+	}
+	if err := migrate(to); err != nil {
+		return err
+	}
+	if err := to.AutoMigrate(&CopyProgress{}).Error; err != nil {
+		return err
+	}
+	for _, t := range copyTables {
+		if err := copyTableRows(ctx, from, to, t, batch, progress); err != nil {
+			return fmt.Errorf("copy %s: %w", from.NewScope(t.model).TableName(), err)
+		}
+	}
+	return nil
+}
+
+func copyTableRows(ctx context.Context, from *gorm.DB, to *gorm.DB, t copyTable, batch int, progress func(CopyReport)) error {
+	table := from.NewScope(t.model).TableName()
+	state := CopyProgress{Name: table}
+	if err := to.Where("name = ?", table).First(&state).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	var total uint64
+	if err := from.Model(t.model).Count(&total).Error; err != nil {
+		return err
+	}
+	report := func() {
+		if progress != nil {
+			progress(CopyReport{Table: table, Copied: state.Copied, Total: total, Done: state.Done})
+		}
+	}
+	if state.Done {
+		report()
+		return nil
+	}
+
+	elem := reflect.TypeOf(t.model).Elem()
+	raw := to.Set("gorm:update_column", true).Set("gorm:save_associations", false)
+	first := state.Copied == 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rows := reflect.New(reflect.SliceOf(elem))
+		query := from.Order(t.key + " asc").Limit(batch)
+		if !first {
+			query = query.Where(t.key+" > ?", state.LastKey)
+		}
+		if err := query.Find(rows.Interface()).Error; err != nil {
+			return err
+		}
+		n := rows.Elem().Len()
+		if n == 0 {
+			break
+		}
+		err := raw.Transaction(func(tx *gorm.DB) error {
+			for i := 0; i < n; i++ {
+				if err := tx.Save(rows.Elem().Index(i).Addr().Interface()).Error; err != nil {
+					return err
+				}
+			}
+			last := rows.Elem().Index(n - 1).Addr().Interface()
+			key, ok := tx.NewScope(last).FieldByName(t.key)
+			if !ok {
+				return fmt.Errorf("no field %s", t.key)
+			}
+			state.LastKey = key.Field.Uint()
+			state.Copied += uint64(n)
+			return tx.Save(&state).Error
+		})
+		if err != nil {
+			return err
+		}
+		first = false
+		report()
+		if n < batch {
+			break
+		}
+	}
+
+	if to.Dialect().GetName() == "postgres" && t.key == "id" {
+		if err := advanceSequence(to, table); err != nil {
+			return err
+		}
+	}
+	state.Done = true
+	if err := to.Save(&state).Error; err != nil {
+		return err
+	}
+	report()
+	return nil
+}
+
+// advanceSequence moves the id sequence of a postgres table past the ids
+// inserted explicitly.
+func advanceSequence(db *gorm.DB, table string) error {
+	return db.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), MAX(id)) FROM %s HAVING MAX(id) IS NOT NULL", table, table)).Error
+}
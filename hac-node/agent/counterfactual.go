@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"time"
+)
+
+// CounterfactualProposal compares the recorded outcome of a settled proposal
+// with the decision of an alternate agent replayed over the same history.
+// The alternate outcome assumes every validator runs the alternate agent, so
+// the proposal passes exactly when the agent votes yes.
+type CounterfactualProposal struct {
+	Proposal       Proposal `json:"proposal"`
+	Discussions    int      `json:"discussions"`
+	DecisionPass   int      `json:"decision_pass"`
+	DecisionReject int      `json:"decision_reject"`
+	// Recorded is the vote of the compared voter, empty when it did not vote.
+	Recorded  string `json:"recorded"`
+	AgentPass bool   `json:"agent_pass"`
+	Outcome   string `json:"outcome"`
+	Changed   bool   `json:"changed"`
+	Error     string `json:"error,omitempty"`
+}
+
+type CounterfactualReport struct {
+	Agent       string    `json:"agent"`
+	Voter       string    `json:"voter"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Compared    int       `json:"compared"`
+	Changed     int       `json:"changed"`
+	Failed      int       `json:"failed"`
+	Passed      int       `json:"passed"`
+	WouldPass   int       `json:"would_pass"`
+	// Agreement is the share of the compared proposals keeping their outcome.
+	Agreement float64                  `json:"agreement"`
+	Proposals []CounterfactualProposal `json:"proposals"`
+}
+
+// CounterfactualBaseline returns the recorded side of the comparison for a
+// settled proposal: its decision tally and the decision vote of voter.
+func (r *IndexReader) CounterfactualBaseline(p Proposal, voter string) (CounterfactualProposal, error) {
+	item := CounterfactualProposal{Proposal: p}
+	votes, err := r.c.getProposalVotesByProposal(p.Id, 0, 1000)
+	if err != nil {
+		return item, err
+	}
+	_, decision := ProposalVotesToVoteInfo(votes.Items)
+	for _, v := range decision {
+		if v.Pass {
+			item.DecisionPass++
+		} else {
+			item.DecisionReject++
+		}
+		if v.VoterAddress != voter {
+			continue
+		}
+		item.Recorded = "no"
+		if v.Pass {
+			item.Recorded = "yes"
+		}
+	}
+	return item, nil
+}
+
+// Add records the alternate agent decision on item and counts it.
+func (r *CounterfactualReport) Add(item CounterfactualProposal, pass bool, err error) {
+	if err != nil {
+		item.Error = err.Error()
+		r.Failed++
+		r.Proposals = append(r.Proposals, item)
+		return
+	}
+	item.AgentPass = pass
+	item.Outcome = ProposalStatusRejected.String()
+	if pass {
+		item.Outcome = ProposalStatusPassed.String()
+		r.WouldPass++
+	}
+	passed := item.Proposal.Status == ProposalStatusPassed
+	if passed {
+		r.Passed++
+	}
+	item.Changed = passed != pass
+	if item.Changed {
+		r.Changed++
+	}
+	r.Compared++
+	r.Agreement = float64(r.Compared-r.Changed) / float64(r.Compared)
+	r.Proposals = append(r.Proposals, item)
+}
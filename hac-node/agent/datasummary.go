@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// ProposalSummaryThreshold is the size in bytes of the proposal data above
+// which the agent summarizes it when the proposal is indexed, 0 never does.
+var ProposalSummaryThreshold = 16384
+
+// ProposalChunkSize is the size in bytes of the chunks of the proposal data
+// summarized one by one.
+var ProposalChunkSize = 8192
+
+// SummarizeTextReq asks the agent to summarize Content, part Part of Parts
+// of the data of proposal ProposalId. Part 0 asks to merge the summaries of
+// the parts.
+type SummarizeTextReq struct {
+	ProposalId uint64 `json:"proposalId"`
+	Part       int    `json:"part"`
+	Parts      int    `json:"parts"`
+	Text       string `json:"text"`
+	Content    string `json:"content"`
+}
+
+type SummarizeTextResponse struct {
+	Summary string `json:"summary"`
+}
+
+// SummarizeText asks the agent to summarize a part of a proposal. A service
+// without the endpoint returns "" and no error.
+func (e *ElizaClient) SummarizeText(ctx context.Context, req SummarizeTextReq) (string, error) {
+	e.logger.Info("SummarizeText", "proposal", req.ProposalId, "part", req.Part, "parts", req.Parts, "bytes", len(req.Content))
+	url := fmt.Sprintf("%s/%s/summarizetext", e.Url, e.agent())
+	data, _ := json.Marshal(req)
+	res, err := e.post(ctx, "SummarizeText", url, data)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	bodyBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", agentCallError("SummarizeText", err)
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err := agentStatusError("SummarizeText", res.StatusCode, bodyBytes); err != nil {
+		return "", err
+	}
+	var answer SummarizeTextResponse
+	if err := json.Unmarshal(bodyBytes, &answer); err != nil {
+		return "", agentResponseError("SummarizeText", res.StatusCode, bodyBytes, err)
+	}
+	return strings.TrimSpace(answer.Summary), nil
+}
+
+// needsDataSummary tells whether the data of p is too large to be sent as
+// is with the requests to the agent.
+func needsDataSummary(p Proposal) bool {
+	return ProposalSummaryThreshold > 0 && len(p.Data) > ProposalSummaryThreshold
+}
+
+// agentBody is the proposal text sent to the agent, the summary of its data
+// once there is one.
+func (p Proposal) agentBody() string {
+	if p.DataSummary != "" {
+		return p.DataSummary
+	}
+	return p.Data
+}
+
+// summarizeProposalData has the summarizer summarize the data of the
+// proposal of entry chunk by chunk, merges the summaries of the chunks and
+// stores the result with the proposal. The agent declining any part leaves
+// the proposal without summary.
+func (c *ChainIndexer) summarizeProposalData(ctx context.Context, entry AgentOutbox) error {
+	if c.summarizer == nil {
+		return nil
+	}
+	var proposal Proposal
+	if err := c.db.First(&proposal, entry.Proposal).Error; err != nil {
+		return err
+	}
+	if proposal.DataSummary != "" || !needsDataSummary(proposal) {
+		return nil
+	}
+	chunks := chunkText(proposal.Data, ProposalChunkSize)
+	summaries := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		summary, err := c.summarizer.SummarizeText(ctx, SummarizeTextReq{
+			ProposalId: proposal.Id,
+			Part:       i + 1,
+			Parts:      len(chunks),
+			Text:       fmt.Sprintf("summarize part %d of %d of proposal #%d %s", i+1, len(chunks), proposal.Id, proposal.Title),
+			Content:    chunk,
+		})
+		if err != nil {
+			return err
+		}
+		if summary == "" {
+			c.logger.Info("agent declined to summarize proposal", "proposal", proposal.Id, "part", i+1)
+			return nil
+		}
+		summaries = append(summaries, summary)
+	}
+	summary := summaries[0]
+	if len(summaries) > 1 {
+		var err error
+		summary, err = c.summarizer.SummarizeText(ctx, SummarizeTextReq{
+			ProposalId: proposal.Id,
+			Parts:      len(chunks),
+			Text:       fmt.Sprintf("merge the summaries of the %d parts of proposal #%d %s", len(chunks), proposal.Id, proposal.Title),
+			Content:    strings.Join(summaries, "\n\n"),
+		})
+		if err != nil {
+			return err
+		}
+		if summary == "" {
+			summary = strings.Join(summaries, "\n\n")
+		}
+	}
+	c.logger.Info("proposal data summarized", "proposal", proposal.Id, "bytes", len(proposal.Data), "parts", len(chunks), "summary", len(summary))
+	return c.db.Model(&Proposal{}).Where("id = ?", proposal.Id).Update("data_summary", summary).Error
+}
+
+// chunkText cuts s into chunks of at most size bytes, at a paragraph break
+// in the second half of a chunk when there is one, and never inside a rune.
+func chunkText(s string, size int) []string {
+	if size <= 0 {
+		return []string{s}
+	}
+	var chunks []string
+	for len(s) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if i := strings.LastIndex(s[:cut], "\n\n"); i >= size/2 {
+			cut = i + 2
+		}
+		if cut == 0 {
+			cut = size
+		}
+		chunks = append(chunks, s[:cut])
+		s = s[cut:]
+	}
+	if len(s) > 0 || len(chunks) == 0 {
+		chunks = append(chunks, s)
+	}
+	return chunks
+}
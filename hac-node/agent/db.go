@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// indexerModels are the tables created by AutoMigrate.
+var indexerModels = []interface{}{
+	&Grant{}, &Discussion{}, &Proposal{}, &Height{}, &GrantVote{}, &ProposalVote{}, &ValidatorAgent{},
+	&ProposalStatusChange{}, &IndexedBlock{}, &BlockDiscrepancy{}, &FailedEvent{}, &AuditDivergence{}, &DryRunDecision{}, &SocialPost{},
+	&IpfsArchive{}, &BridgeMirror{}, &DigestSubscriber{}, &ApiKey{}, &AgentOutbox{}, &ProposalScore{}, &StakeChange{}, &Transcript{}, &DecisionAnchor{},
+	&ProposalAnomaly{}, &ProposalActivation{}, &TreasurySpend{},
+	&BlockSignature{}, &ValidatorUptime{}, &ValidatorMisbehavior{}, &VoteFallback{}, &AgentQuestion{}, &EnsembleVote{}, &CachedDecision{}, &AgentHealthCheck{}, &CommentDraft{}, &AgentDecision{}, &DebateRound{}, &ProposalSummary{},
+}
+
+// openIndexDB opens the indexer db. dsn is either a postgres connection url
+// or the path of a sqlite file.
+func openIndexDB(dsn string) (*gorm.DB, error) {
+	if IsPostgresDSN(dsn) {
+		return gorm.Open("postgres", dsn)
+	}
+	return gorm.Open("sqlite3", dsn)
+}
+
+func IsPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
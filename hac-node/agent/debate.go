@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/calehh/hac-app/tx"
+	"github.com/jinzhu/gorm"
+)
+
+// DebateRoundBlocksDefault is the number of blocks a debate round lasts when
+// no length is configured.
+const DebateRoundBlocksDefault = 10
+
+// DebateRound is the rebuttal the local agent posted on a proposal in a
+// round of its debate, by the discussion tx TxHash submitted at Height. A
+// round whose comment could not be submitted keeps its Error and is not
+// retried.
+type DebateRound struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Proposal  uint64    `gorm:"unique_index:idx_debate_round_proposal_round" json:"proposal"`
+	Round     int       `gorm:"unique_index:idx_debate_round_proposal_round" json:"round"`
+	Comment   string    `json:"comment"`
+	TxHash    string    `json:"tx_hash"`
+	Height    uint64    `json:"height"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// debate runs the proposals under discussion as debates of rounds rounds of
+// roundBlocks blocks. In every round the local agent reads the debate so far
+// and posts its rebuttal on-chain, the proposal is settled once the rounds
+// are over. No rounds keeps the random single comments.
+type debate struct {
+	rounds      int
+	roundBlocks uint64
+}
+
+type debateRoundCtx struct{}
+
+type debateRoundInfo struct {
+	round  int
+	rounds int
+}
+
+// WithDebateRound tells the agent the comment asked with ctx is its rebuttal
+// in round of rounds of the debate of a proposal.
+func WithDebateRound(ctx context.Context, round int, rounds int) context.Context {
+	return context.WithValue(ctx, debateRoundCtx{}, debateRoundInfo{round: round, rounds: rounds})
+}
+
+// debateRound returns the round of the debate a comment is asked for, 0
+// outside of a debate.
+func debateRound(ctx context.Context) (round int, rounds int) {
+	info, _ := ctx.Value(debateRoundCtx{}).(debateRoundInfo)
+	return info.round, info.rounds
+}
+
+// over tells whether the rounds of the debate of p are over at height.
+func (d debate) over(p Proposal, height uint64) bool {
+	return height >= p.NewHeight+uint64(d.rounds)*d.roundBlocks
+}
+
+// due returns the round of the debate of p open at height, 0 before it
+// starts or once it is over.
+func (d debate) due(p Proposal, height uint64) int {
+	if height < p.NewHeight || d.over(p, height) {
+		return 0
+	}
+	return int((height-p.NewHeight)/d.roundBlocks) + 1
+}
+
+// runDebates posts the rebuttals of the local agent for the rounds opened
+// since it last spoke.
+func (c *ChainIndexer) runDebates(ctx context.Context) {
+	proposals, err := c.getProposalsByStatus(ProposalStatusDiscussing, 0, 100)
+	if err != nil {
+		c.logger.Error("get proposals fail", "err", err)
+		return
+	}
+	for _, p := range proposals.Items {
+		round := c.debate.due(p, uint64(c.Height))
+		if round == 0 {
+			continue
+		}
+		var last DebateRound
+		err := c.db.Where("proposal = ?", p.Id).Order("round desc").First(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.logger.Error("get debate round fail", "proposal", p.Id, "err", err)
+			continue
+		}
+		if last.Round >= round {
+			continue
+		}
+		if err := c.debateRound(ctx, p, round); err != nil {
+			c.logger.Error("debate round fail", "proposal", p.Id, "round", round, "err", err)
+		}
+	}
+}
+
+// debateRound asks the local agent for its rebuttal in round of the debate
+// of p and submits it as a discussion. An agent failing is asked again on
+// the next block.
+func (c *ChainIndexer) debateRound(ctx context.Context, p Proposal, round int) error {
+	actx := WithDebateRound(ctx, round, c.debate.rounds)
+	if p.Expedited {
+		actx = WithExpedited(actx)
+	}
+	comment, err := ActiveClient().CommentPropoal(actx, p.Id, c.localAddress)
+	if err != nil {
+		return err
+	}
+	row := DebateRound{Proposal: p.Id, Round: round, Comment: comment}
+	if err := c.submitDiscussion(ctx, p.Id, comment, &row); err != nil {
+		row.Error = err.Error()
+		c.logger.Error("submit debate round fail", "proposal", p.Id, "round", round, "err", err)
+	}
+	if err := c.db.Create(&row).Error; err != nil {
+		return err
+	}
+	c.logger.Info("debate round", "proposal", p.Id, "round", round, "rounds", c.debate.rounds, "tx", row.TxHash)
+	return nil
+}
+
+func (c *ChainIndexer) submitDiscussion(ctx context.Context, proposal uint64, comment string, row *DebateRound) error {
+	// drafts from the proposal webhook use the same account
+	c.webhook.mtx.Lock()
+	defer c.webhook.mtx.Unlock()
+	submitter, err := NewTxSubmitter(ctx, c.cli, c.pv)
+	if err != nil {
+		return err
+	}
+	res, err := submitter.Submit(ctx, tx.HACTxTypeDiscussion, &tx.DiscussionTx{Proposal: proposal, Data: []byte(comment)})
+	if err != nil {
+		return err
+	}
+	row.TxHash = res.Hash.String()
+	row.Height = uint64(c.Height)
+	return nil
+}
+
+// getDebateRounds lists the rounds the local agent posted on proposal, of
+// every proposal for 0.
+func (c *ChainIndexer) getDebateRounds(proposal uint64, page int, pageSize int) (Page[DebateRound], error) {
+	db := c.db.Model(&DebateRound{})
+	if proposal != 0 {
+		db = db.Where("proposal = ?", proposal)
+	}
+	return paginate[DebateRound](db, "id desc", page, pageSize)
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VoteChoice is the answer of an agent to a voting decision.
+type VoteChoice string
+
+const (
+	VoteYes     VoteChoice = "yes"
+	VoteNo      VoteChoice = "no"
+	VoteAbstain VoteChoice = "abstain"
+)
+
+// Fallbacks casting a decision for an agent that did not make it.
+const (
+	FallbackTimeout = "timeout"
+	FallbackBreaker = "breaker"
+)
+
+// Decision is the vote of the agent on a proposal or a grant. Confidence
+// goes from 0 to 1, an agent not reporting it is fully confident. Fallback
+// names the fallback that cast the decision, empty for the agent's choice.
+// Cached is set on a decision answered again from the cache of CacheClient.
+type Decision struct {
+	Vote       VoteChoice `json:"vote"`
+	Confidence float64    `json:"confidence"`
+	Reason     string     `json:"reason"`
+	Fallback   string     `json:"fallback,omitempty"`
+	Cached     bool       `json:"cached,omitempty"`
+}
+
+// Pass tells whether the decision accepts.
+func (d Decision) Pass() bool {
+	return d.Vote == VoteYes
+}
+
+// decisionOf is the fully confident yes or no of pass.
+func decisionOf(pass bool, reason string) Decision {
+	d := Decision{Vote: VoteNo, Confidence: 1, Reason: reason}
+	if pass {
+		d.Vote = VoteYes
+	}
+	return d
+}
+
+// fallback is the decision cast by d for the agent by the fallback named
+// source.
+func (d TimeoutDecision) fallback(source string, reason string) Decision {
+	return Decision{Vote: VoteChoice(d), Confidence: 1, Reason: reason, Fallback: source}
+}
+
+// parseDecision reads the vote and the confidence an agent answered, a nil
+// confidence being full confidence.
+func parseDecision(vote string, confidence *float64, reason string) (Decision, error) {
+	d := Decision{Vote: VoteChoice(strings.ToLower(strings.TrimSpace(vote))), Confidence: 1, Reason: reason}
+	switch d.Vote {
+	case VoteYes, VoteNo, VoteAbstain:
+	default:
+		return d, fmt.Errorf("unknown vote %q", vote)
+	}
+	if confidence != nil {
+		if *confidence < 0 || *confidence > 1 {
+			return d, fmt.Errorf("confidence %v out of [0, 1]", *confidence)
+		}
+		d.Confidence = *confidence
+	}
+	return d, nil
+}
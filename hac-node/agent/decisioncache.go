@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+	"github.com/jinzhu/gorm"
+)
+
+// CachedDecision is a voting decision of the agent answered again without
+// asking it until ExpiresAt. A zero ExpiresAt never expires. The Voter of a
+// grant is the fingerprint of the request, see grantFingerprint, so another
+// grant proposed for the same account index is asked again.
+type CachedDecision struct {
+	Id         uint64    `gorm:"primary_key" json:"id"`
+	Kind       string    `gorm:"unique_index:idx_cached_decision_key" json:"kind"`
+	RefId      uint64    `gorm:"unique_index:idx_cached_decision_key" json:"ref_id"`
+	Voter      string    `gorm:"unique_index:idx_cached_decision_key" json:"voter"`
+	Vote       string    `json:"vote"`
+	Confidence float64   `json:"confidence"`
+	Reason     string    `json:"reason"`
+	Fallback   string    `json:"fallback"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DecisionCacheStore persists the cached voting decisions.
+type DecisionCacheStore interface {
+	GetCachedDecision(kind string, id uint64, voter string) (*CachedDecision, error)
+	SaveCachedDecision(d *CachedDecision) error
+}
+
+var _ Client = &CacheClient{}
+
+// CacheClient asks the wrapped agent once for the decision on a proposal by
+// a voter, or on a grant, and answers the later rounds of consensus asking
+// again from the store. Failed decisions are not cached.
+type CacheClient struct {
+	Client
+	ttl    time.Duration
+	logger cmtlog.Logger
+	store  DecisionCacheStore
+}
+
+// NewCacheClient caches the decisions of inner for ttl, 0 keeps them until
+// they are invalidated.
+func NewCacheClient(inner Client, ttl time.Duration, logger cmtlog.Logger) *CacheClient {
+	return &CacheClient{
+		Client: inner,
+		ttl:    ttl,
+		logger: logger.With("module", "decision-cache"),
+	}
+}
+
+func (c *CacheClient) SetDecisionCacheStore(store DecisionCacheStore) {
+	c.store = store
+}
+
+func (c *CacheClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	return c.decide(voteKindProposal, proposal, voter, func() (Decision, error) {
+		return c.Client.IfAcceptProposal(ctx, proposal, voter)
+	})
+}
+
+func (c *CacheClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	return c.decide(voteKindGrant, validator, grantFingerprint(ctx, proposer, amount, statement), func() (Decision, error) {
+		return c.Client.IfGrantNewMember(ctx, validator, proposer, amount, statement)
+	})
+}
+
+type grantApplicantCtx struct{}
+
+// WithGrantApplicant tells the cache the grant decision asked with ctx is on
+// the membership of the account of pubkey.
+func WithGrantApplicant(ctx context.Context, pubkey []byte) context.Context {
+	return context.WithValue(ctx, grantApplicantCtx{}, pubkey)
+}
+
+// grantFingerprint identifies a grant request by the applicant, the proposer,
+// the amount and the statement. The account index of the applicant only
+// advances on commit, a grant proposed again in a later round of the same
+// height gets the same one.
+func grantFingerprint(ctx context.Context, proposer string, amount uint64, statement string) string {
+	applicant, _ := ctx.Value(grantApplicantCtx{}).([]byte)
+	h := sha256.New()
+	h.Write([]byte(hex.EncodeToString(applicant) + "\n" + proposer + "\n"))
+	h.Write(binary.BigEndian.AppendUint64(nil, amount))
+	h.Write([]byte(statement))
+	return "grant:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// decide answers the cached decision, the decisions cached before they had a
+// vote are asked again.
+func (c *CacheClient) decide(kind string, id uint64, voter string, ask func() (Decision, error)) (Decision, error) {
+	if c.store == nil {
+		return ask()
+	}
+	cached, err := c.store.GetCachedDecision(kind, id, voter)
+	if err != nil {
+		c.logger.Error("get cached decision fail", "kind", kind, "id", id, "err", err)
+	} else if cached != nil && cached.Vote != "" && (cached.ExpiresAt.IsZero() || time.Now().Before(cached.ExpiresAt)) {
+		agentDecisionCacheHits.WithLabelValues(kind).Inc()
+		return Decision{Vote: VoteChoice(cached.Vote), Confidence: cached.Confidence, Reason: cached.Reason, Fallback: cached.Fallback, Cached: true}, nil
+	}
+	decision, err := ask()
+	if err != nil {
+		return decision, err
+	}
+	d := CachedDecision{
+		Kind:       kind,
+		RefId:      id,
+		Voter:      voter,
+		Vote:       string(decision.Vote),
+		Confidence: decision.Confidence,
+		Reason:     decision.Reason,
+		Fallback:   decision.Fallback,
+	}
+	if c.ttl > 0 {
+		d.ExpiresAt = time.Now().Add(c.ttl).UTC()
+	}
+	if err := c.store.SaveCachedDecision(&d); err != nil {
+		c.logger.Error("save cached decision fail", "kind", kind, "id", id, "err", err)
+	}
+	return decision, nil
+}
+
+// GetCachedDecision returns the cached decision of voter on kind id, nil
+// when there is none.
+func (c *ChainIndexer) GetCachedDecision(kind string, id uint64, voter string) (*CachedDecision, error) {
+	var d CachedDecision
+	err := c.db.Where("kind = ? AND ref_id = ? AND voter = ?", kind, id, voter).First(&d).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// SaveCachedDecision stores d, replacing the decision cached for its key.
+func (c *ChainIndexer) SaveCachedDecision(d *CachedDecision) error {
+	return c.db.Where("kind = ? AND ref_id = ? AND voter = ?", d.Kind, d.RefId, d.Voter).Assign(map[string]interface{}{
+		"vote":       d.Vote,
+		"confidence": d.Confidence,
+		"reason":     d.Reason,
+		"fallback":   d.Fallback,
+		"expires_at": d.ExpiresAt,
+	}).FirstOrCreate(d).Error
+}
+
+// invalidateCachedDecisions removes the decisions cached on kind id, only
+// those of voter when it is set, so the agent is asked again.
+func (c *ChainIndexer) invalidateCachedDecisions(kind string, id uint64, voter string) (int64, error) {
+	db := c.db.Where("kind = ? AND ref_id = ?", kind, id)
+	if voter != "" {
+		db = db.Where("voter = ?", voter)
+	}
+	res := db.Delete(&CachedDecision{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// AgentDecision records a voting decision of the agent with its reason, so
+// operators can audit why their agent voted the way it did. A failed decision
+// has its error and no vote, one cast by a fallback names it in Fallback.
+// A Cached decision was answered again from the decision cache, the original
+// one is recorded when it was made.
+type AgentDecision struct {
+	Id         uint64    `gorm:"primary_key" json:"id"`
+	Kind       string    `gorm:"index:idx_agent_decision_ref" json:"kind"`
+	RefId      uint64    `gorm:"index:idx_agent_decision_ref" json:"ref_id"`
+	Voter      string    `json:"voter"`
+	Decision   string    `json:"decision"`
+	Confidence float64   `json:"confidence"`
+	Reason     string    `json:"reason"`
+	Fallback   string    `json:"fallback"`
+	Model      string    `json:"model"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Error      string    `json:"error"`
+	Cached     bool      `json:"cached"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AgentDecisionStore persists the voting decisions of the agent.
+type AgentDecisionStore interface {
+	SaveAgentDecision(d *AgentDecision) error
+}
+
+var _ Client = &DecisionLogClient{}
+
+// DecisionLogClient records every voting decision of the wrapped agent, the
+// fallbacks cast for it included, with the time it took.
+type DecisionLogClient struct {
+	Client
+	model  string
+	logger cmtlog.Logger
+	store  AgentDecisionStore
+}
+
+// NewDecisionLogClient records the decisions of inner, made by model.
+func NewDecisionLogClient(inner Client, model string, logger cmtlog.Logger) *DecisionLogClient {
+	return &DecisionLogClient{
+		Client: inner,
+		model:  model,
+		logger: logger.With("module", "decision-log"),
+	}
+}
+
+func (d *DecisionLogClient) SetAgentDecisionStore(store AgentDecisionStore) {
+	d.store = store
+}
+
+func (d *DecisionLogClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	return d.record(voteKindProposal, proposal, voter, func() (Decision, error) {
+		return d.Client.IfAcceptProposal(ctx, proposal, voter)
+	})
+}
+
+func (d *DecisionLogClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	return d.record(voteKindGrant, validator, "", func() (Decision, error) {
+		return d.Client.IfGrantNewMember(ctx, validator, proposer, amount, statement)
+	})
+}
+
+func (d *DecisionLogClient) record(kind string, id uint64, voter string, ask func() (Decision, error)) (Decision, error) {
+	started := time.Now()
+	decision, err := ask()
+	if d.store == nil {
+		return decision, err
+	}
+	row := AgentDecision{
+		Kind:       kind,
+		RefId:      id,
+		Voter:      voter,
+		Decision:   string(decision.Vote),
+		Confidence: decision.Confidence,
+		Reason:     decision.Reason,
+		Fallback:   decision.Fallback,
+		Model:      d.model,
+		LatencyMs:  time.Since(started).Milliseconds(),
+		Cached:     decision.Cached,
+	}
+	if err != nil {
+		row.Error = err.Error()
+	}
+	if err := d.store.SaveAgentDecision(&row); err != nil {
+		d.logger.Error("save agent decision fail", "kind", kind, "id", id, "err", err)
+	}
+	return decision, err
+}
+
+func (d *DecisionLogClient) Unwrap() Client { return d.Client }
+
+func (c *ChainIndexer) SaveAgentDecision(d *AgentDecision) error {
+	return c.db.Create(d).Error
+}
+
+// getAgentDecisions lists the decisions on kind refId by voter, every one
+// for an empty filter. fallback keeps the decisions cast by that fallback,
+// "agent" the ones the agent made.
+func (c *ChainIndexer) getAgentDecisions(kind string, refId uint64, voter string, fallback string, page int, pageSize int) (Page[AgentDecision], error) {
+	db := c.db.Model(&AgentDecision{})
+	switch fallback {
+	case "":
+	case "agent":
+		db = db.Where("fallback = ?", "")
+	default:
+		db = db.Where("fallback = ?", fallback)
+	}
+	if kind != "" {
+		db = db.Where("kind = ?", kind)
+	}
+	if refId != 0 {
+		db = db.Where("ref_id = ?", refId)
+	}
+	if voter != "" {
+		db = db.Where("voter = ?", voter)
+	}
+	return paginate[AgentDecision](db, "id desc", page, pageSize)
+}
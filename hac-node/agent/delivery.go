@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// IdempotencyKeyHeader carries the idempotency key of every agent request.
+// The agent must process a key once and answer repeats with the first
+// result.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey sets the idempotency key of the agent calls made with
+// ctx. Calls without one get a key derived from their request.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+func idempotencyKey(ctx context.Context, url string, body []byte) string {
+	if key, ok := ctx.Value(idempotencyKeyCtx{}).(string); ok && key != "" {
+		return key
+	}
+	sum := sha256.New()
+	sum.Write([]byte(url))
+	sum.Write([]byte{0})
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// eventKey is the hash of an event at its height and tx, the same every time
+// the block is indexed.
+func eventKey(event abci.Event, height int64, txHash string) string {
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%s\x00%d\x00%s", event.Type, height, txHash)
+	for _, attr := range event.Attributes {
+		sum.Write([]byte{0})
+		sum.Write([]byte(attr.Key))
+		sum.Write([]byte{'='})
+		sum.Write([]byte(attr.Value))
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
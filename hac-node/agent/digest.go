@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	app_config "github.com/calehh/hac-app/config"
+)
+
+const (
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// DigestCheckInterval is the time between checks for subscribers due a
+// digest.
+var DigestCheckInterval = 10 * time.Minute
+
+var digestPeriods = map[string]time.Duration{
+	DigestDaily:  24 * time.Hour,
+	DigestWeekly: 7 * 24 * time.Hour,
+}
+
+var ErrInvalidSubscriber = errors.New("invalid email or frequency, frequency is daily or weekly")
+
+var defaultDigestTemplate = `Governance digest {{.Since.Format "2006-01-02 15:04"}} - {{.Until.Format "2006-01-02 15:04"}} UTC
+
+New proposals ({{len .NewProposals}})
+{{range .NewProposals}}  #{{.Id}} {{.Title}} by {{.ProposerName}}
+{{else}}  none
+{{end}}
+Settled proposals ({{len .Settled}})
+{{range .Settled}}  #{{.Proposal.Id}} {{.Proposal.Title}}: {{.Status}}
+{{else}}  none
+{{end}}
+Our validator's votes ({{len .Votes}})
+{{range .Votes}}  {{.Kind}} #{{.Id}}: {{.Vote}}{{if .Reason}} - {{.Reason}}{{end}}
+{{else}}  none
+{{end}}`
+
+type DigestSettlement struct {
+	Proposal Proposal
+	Status   ProposalStatus
+}
+
+type DigestVote struct {
+	Kind   string
+	Id     uint64
+	Vote   VoteCode
+	Reason string
+}
+
+// Digest is the data passed to the digest template.
+type Digest struct {
+	Since        time.Time
+	Until        time.Time
+	NewProposals []Proposal
+	Settled      []DigestSettlement
+	Votes        []DigestVote
+}
+
+// digestMailer sends governance digests to the subscribers stored in the db.
+type digestMailer struct {
+	addr     string
+	auth     smtp.Auth
+	from     string
+	template *template.Template
+}
+
+func newDigestMailer(cfg *app_config.HACAppConfig) (*digestMailer, error) {
+	if cfg.SmtpAddr == "" {
+		return nil, nil
+	}
+	host, _, err := net.SplitHostPort(cfg.SmtpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp_addr: %w", err)
+	}
+	if _, err := mail.ParseAddress(cfg.SmtpFrom); err != nil {
+		return nil, fmt.Errorf("invalid smtp_from: %w", err)
+	}
+	text := defaultDigestTemplate
+	if cfg.DigestTemplate != "" {
+		text = cfg.DigestTemplate
+	}
+	tmpl, err := template.New("digest").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("digest_template: %w", err)
+	}
+	m := &digestMailer{addr: cfg.SmtpAddr, from: cfg.SmtpFrom, template: tmpl}
+	if cfg.SmtpUsername != "" {
+		m.auth = smtp.PlainAuth("", cfg.SmtpUsername, cfg.SmtpPassword, host)
+	}
+	return m, nil
+}
+
+func (c *ChainIndexer) runDigests(ctx context.Context) {
+	ticker := time.NewTicker(DigestCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.sendDueDigests(time.Now().UTC()); err != nil {
+				c.logger.Error("send digests fail", "err", err)
+			}
+		}
+	}
+}
+
+// sendDueDigests sends one digest per frequency to the subscribers whose
+// last digest is a period old. A subscriber is only marked sent when the
+// mail was accepted, so failed deliveries are retried on the next check.
+func (c *ChainIndexer) sendDueDigests(now time.Time) error {
+	var subscribers []DigestSubscriber
+	if err := c.db.Order("id asc").Find(&subscribers).Error; err != nil {
+		return err
+	}
+	for _, sub := range subscribers {
+		period := digestPeriods[sub.Frequency]
+		if period == 0 || now.Sub(sub.LastSentAt) < period {
+			continue
+		}
+		since := now.Add(-period)
+		if sub.LastSentAt.After(since) {
+			since = sub.LastSentAt
+		}
+		body, err := c.renderDigest(since, now)
+		if err != nil {
+			return err
+		}
+		subject := fmt.Sprintf("HAC governance %s digest %s", sub.Frequency, now.Format(time.DateOnly))
+		if err := c.digest.send(sub.Email, subject, body); err != nil {
+			c.logger.Error("send digest fail", "email", sub.Email, "err", err)
+			continue
+		}
+		if err := c.db.Model(&sub).Update("last_sent_at", now).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ChainIndexer) renderDigest(since time.Time, until time.Time) (string, error) {
+	d := Digest{Since: since, Until: until}
+	if err := c.db.Where("block_time >= ? AND block_time < ?", since, until).Order("id asc").Find(&d.NewProposals).Error; err != nil {
+		return "", err
+	}
+	var changes []ProposalStatusChange
+	err := c.db.Where("block_time >= ? AND block_time < ? AND to_status IN (?)", since, until,
+		[]ProposalStatus{ProposalStatusPassed, ProposalStatusRejected, ProposalStatusExpired}).Order("id asc").Find(&changes).Error
+	if err != nil {
+		return "", err
+	}
+	for _, change := range changes {
+		p, err := c.getProposalById(change.Proposal)
+		if err != nil {
+			return "", err
+		}
+		d.Settled = append(d.Settled, DigestSettlement{Proposal: p, Status: change.ToStatus})
+	}
+	if c.localAddress != "" {
+		var proposalVotes []ProposalVote
+		if err := c.db.Where("voter_address = ? AND block_time >= ? AND block_time < ?", c.localAddress, since, until).Order("id asc").Find(&proposalVotes).Error; err != nil {
+			return "", err
+		}
+		for _, v := range proposalVotes {
+			d.Votes = append(d.Votes, DigestVote{Kind: voteKindProposal, Id: v.Proposal, Vote: v.Vote, Reason: v.Reason})
+		}
+		var grantVotes []GrantVote
+		if err := c.db.Where("voter_address = ? AND block_time >= ? AND block_time < ?", c.localAddress, since, until).Order("id asc").Find(&grantVotes).Error; err != nil {
+			return "", err
+		}
+		for _, v := range grantVotes {
+			d.Votes = append(d.Votes, DigestVote{Kind: voteKindGrant, Id: v.AccountIndex, Vote: v.Vote, Reason: v.Reason})
+		}
+	}
+	var buf bytes.Buffer
+	if err := c.digest.template.Execute(&buf, d); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (m *digestMailer) send(to string, subject string, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.addr, m.auth, from.Address, []string{to}, []byte(msg.String()))
+}
+
+// addDigestSubscriber subscribes email or changes its frequency.
+func (c *ChainIndexer) addDigestSubscriber(email string, frequency string) (DigestSubscriber, error) {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || digestPeriods[frequency] == 0 {
+		return DigestSubscriber{}, ErrInvalidSubscriber
+	}
+	sub := DigestSubscriber{Email: addr.Address}
+	if err := c.db.Where(DigestSubscriber{Email: addr.Address}).FirstOrInit(&sub).Error; err != nil {
+		return sub, err
+	}
+	if sub.Id == 0 {
+		// the first digest covers the first full period
+		sub.LastSentAt = time.Now().UTC()
+	}
+	sub.Frequency = frequency
+	return sub, c.db.Save(&sub).Error
+}
+
+func (c *ChainIndexer) removeDigestSubscriber(email string) (bool, error) {
+	res := c.db.Where("email = ?", strings.TrimSpace(email)).Delete(&DigestSubscriber{})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (c *ChainIndexer) getDigestSubscribers(page int, pageSize int) (Page[DigestSubscriber], error) {
+	return paginate[DigestSubscriber](c.db.Model(&DigestSubscriber{}), "id asc", page, pageSize)
+}
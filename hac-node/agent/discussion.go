@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"strings"
+)
+
+var _ DiscussionSource = &ChainIndexer{}
+
+// PeerDiscussions returns up to limit discussions posted on proposal by
+// validators other than exclude. Duplicated texts are dropped and the newest
+// discussion of every speaker is ranked before a speaker's older ones, so the
+// agent hears as many distinct voices as possible.
+func (c *ChainIndexer) PeerDiscussions(proposal uint64, exclude string, limit int) ([]Discussion, error) {
+	var discussions []Discussion
+	err := c.db.Where("proposal = ? AND speaker_address <> ?", proposal, exclude).Order("height desc, id desc").Find(&discussions).Error
+	if err != nil {
+		return nil, err
+	}
+	return rankDiscussions(discussions, limit), nil
+}
+
+// rankDiscussions expects discussions ordered from newest to oldest.
+func rankDiscussions(discussions []Discussion, limit int) []Discussion {
+	if limit <= 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	unique := make([]Discussion, 0, len(discussions))
+	for _, d := range discussions {
+		key := strings.Join(strings.Fields(strings.ToLower(d.Data)), " ")
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, d)
+	}
+
+	ranked := make([]Discussion, 0, limit)
+	speakers := make(map[string]bool)
+	rest := make([]Discussion, 0, len(unique))
+	for _, d := range unique {
+		if len(ranked) >= limit {
+			return ranked
+		}
+		if speakers[d.SpeakerAddress] {
+			rest = append(rest, d)
+			continue
+		}
+		speakers[d.SpeakerAddress] = true
+		ranked = append(ranked, d)
+	}
+	for _, d := range rest {
+		if len(ranked) >= limit {
+			break
+		}
+		ranked = append(ranked, d)
+	}
+	return ranked
+}
+
+// DiscussionHistory returns the last limit discussions posted on proposal by
+// any validator, the voter included, from the oldest to the newest.
+func (c *ChainIndexer) DiscussionHistory(proposal uint64, limit int) ([]Discussion, error) {
+	var discussions []Discussion
+	err := c.db.Where("proposal = ?", proposal).Order("height desc, id desc").Limit(limit).Find(&discussions).Error
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(discussions)-1; i < j; i, j = i+1, j-1 {
+		discussions[i], discussions[j] = discussions[j], discussions[i]
+	}
+	return discussions, nil
+}
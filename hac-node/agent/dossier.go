@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dossierProposals is the number of latest proposals of the proposer listed
+// in a grant vote prompt.
+const dossierProposals = 5
+
+// dossierGrants is the number of latest grant decisions on the proposer
+// listed in a grant vote prompt.
+const dossierGrants = 5
+
+var _ DossierSource = &ChainIndexer{}
+
+// DossierSource provides the track record of a validator.
+type DossierSource interface {
+	ProposerDossier(address string) (ProposerDossier, error)
+}
+
+// ProposerDossier is the track record of a validator asking to join the
+// validator set: the proposals it made and how they settled, the votes it
+// cast and the grants decided on it before. Proposals and Grants are the
+// latest, the counts cover its whole history.
+type ProposerDossier struct {
+	Address           string     `json:"address"`
+	ProposalsMade     int        `json:"proposals_made"`
+	ProposalsPassed   int        `json:"proposals_passed"`
+	ProposalsRejected int        `json:"proposals_rejected"`
+	Proposals         []Proposal `json:"proposals"`
+	VotesAccept       int        `json:"votes_accept"`
+	VotesReject       int        `json:"votes_reject"`
+	GrantVotesGrant   int        `json:"grant_votes_grant"`
+	GrantVotesReject  int        `json:"grant_votes_reject"`
+	Grants            []Grant    `json:"grants"`
+}
+
+// ProposerDossier returns the track record of address, an empty one for a
+// validator the chain never saw. The agent client adds it to grant votes.
+func (c *ChainIndexer) ProposerDossier(address string) (ProposerDossier, error) {
+	d := ProposerDossier{Address: address}
+	proposals := c.db.Model(&Proposal{}).Where("proposer_address = ?", address)
+	if err := proposals.Count(&d.ProposalsMade).Error; err != nil {
+		return ProposerDossier{}, err
+	}
+	if err := proposals.Where("status = ?", ProposalStatusPassed).Count(&d.ProposalsPassed).Error; err != nil {
+		return ProposerDossier{}, err
+	}
+	if err := proposals.Where("status = ?", ProposalStatusRejected).Count(&d.ProposalsRejected).Error; err != nil {
+		return ProposerDossier{}, err
+	}
+	latest, err := c.getProposalsByProposerAddr(address, 0, dossierProposals)
+	if err != nil {
+		return ProposerDossier{}, err
+	}
+	d.Proposals = latest.Items
+	votes := c.db.Model(&ProposalVote{}).Where("voter_address = ?", address)
+	if err := votes.Where("vote = ?", VoteCodeAcceptProposal).Count(&d.VotesAccept).Error; err != nil {
+		return ProposerDossier{}, err
+	}
+	if err := votes.Where("vote = ?", VoteCodeRejectProposal).Count(&d.VotesReject).Error; err != nil {
+		return ProposerDossier{}, err
+	}
+	grantVotes := c.db.Model(&GrantVote{}).Where("voter_address = ?", address)
+	if err := grantVotes.Where("vote = ?", VoteCodeGrantNewMember).Count(&d.GrantVotesGrant).Error; err != nil {
+		return ProposerDossier{}, err
+	}
+	if err := grantVotes.Where("vote = ?", VoteCodeRejectNewMember).Count(&d.GrantVotesReject).Error; err != nil {
+		return ProposerDossier{}, err
+	}
+	grants, err := paginate[Grant](c.db.Model(&Grant{}).Where("address = ?", address), "height desc, id desc", 0, dossierGrants)
+	if err != nil {
+		return ProposerDossier{}, err
+	}
+	d.Grants = grants.Items
+	return d, nil
+}
+
+// dossier is the track record of the proposer of a grant, nil without a
+// dossier source.
+func (v *voteContext) dossier(proposer string) *ProposerDossier {
+	if v.dossiers == nil {
+		return nil
+	}
+	d, err := v.dossiers.ProposerDossier(proposer)
+	if err != nil {
+		v.logger.Error("get proposer dossier fail", "proposer", proposer, "err", err)
+		return nil
+	}
+	return &d
+}
+
+// dossierText describes the track record of the proposer of a grant, ""
+// without a dossier.
+func dossierText(d *ProposerDossier) string {
+	if d == nil {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\nThe proposer made %d proposals, %d passed and %d rejected.", d.ProposalsMade, d.ProposalsPassed, d.ProposalsRejected)
+	if len(d.Proposals) > 0 {
+		b.WriteString("\nLatest proposals:")
+		for _, p := range d.Proposals {
+			title := p.Title
+			if title == "" {
+				title = "untitled"
+			}
+			fmt.Fprintf(&b, "\n- #%d %s: %s", p.Id, title, p.Status)
+		}
+	}
+	fmt.Fprintf(&b, "\nIt voted to accept %d and to reject %d proposals, to grant %d and to refuse %d members.", d.VotesAccept, d.VotesReject, d.GrantVotesGrant, d.GrantVotesReject)
+	if len(d.Grants) > 0 {
+		b.WriteString("\nPast grant decisions on it:")
+		for _, g := range d.Grants {
+			decision := "refused"
+			if g.Grant {
+				decision = "granted"
+			}
+			fmt.Fprintf(&b, "\n- %s with a stake of %d at height %d", decision, g.Stake, g.Height)
+		}
+	}
+	return b.String()
+}
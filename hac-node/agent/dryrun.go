@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// DryRunPolicy is the static decision a DryRunClient votes with.
+type DryRunPolicy string
+
+const (
+	DryRunPolicyAccept DryRunPolicy = "accept"
+	DryRunPolicyReject DryRunPolicy = "reject"
+)
+
+func ParseDryRunPolicy(s string) (DryRunPolicy, error) {
+	switch p := DryRunPolicy(s); p {
+	case DryRunPolicyAccept, DryRunPolicyReject:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown dry run policy %q, expected accept or reject", s)
+}
+
+// DecisionStore persists the decisions taken by the agent in dry-run mode.
+type DecisionStore interface {
+	SaveDryRunDecision(d *DryRunDecision) error
+}
+
+var _ Client = &DryRunClient{}
+
+// DryRunClient asks the wrapped agent for every voting decision, logs and
+// stores it, and votes with a static policy instead. Operators use it to
+// evaluate a new agent before trusting its decisions.
+type DryRunClient struct {
+	Client
+	policy DryRunPolicy
+	logger cmtlog.Logger
+	store  DecisionStore
+}
+
+func NewDryRunClient(inner Client, policy DryRunPolicy, logger cmtlog.Logger) *DryRunClient {
+	return &DryRunClient{
+		Client: inner,
+		policy: policy,
+		logger: logger.With("module", "dry-run"),
+	}
+}
+
+func (d *DryRunClient) SetDecisionStore(store DecisionStore) {
+	d.store = store
+}
+
+func (d *DryRunClient) IfProcessProposal(ctx context.Context, proposer uint64, data []byte) (bool, error) {
+	pass, err := d.Client.IfProcessProposal(ctx, proposer, data)
+	return d.decide(voteKindProposal, 0, fmt.Sprintf("process proposal of %d", proposer), decisionOf(pass, ""), err).Pass(), nil
+}
+
+func (d *DryRunClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	decision, err := d.Client.IfAcceptProposal(ctx, proposal, voter)
+	return d.decide(voteKindProposal, proposal, "accept proposal", decision, err), nil
+}
+
+func (d *DryRunClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	decision, err := d.Client.IfGrantNewMember(ctx, validator, proposer, amount, statement)
+	return d.decide(voteKindGrant, validator, "grant new member", decision, err), nil
+}
+
+// decide records the agent decision and returns the policy decision. The
+// agent's reason is replaced so vote rows do not show it for a vote it did
+// not cast.
+func (d *DryRunClient) decide(kind string, id uint64, question string, agent Decision, agentErr error) Decision {
+	pass := d.policy == DryRunPolicyAccept
+	decision := DryRunDecision{
+		Kind:       kind,
+		RefId:      id,
+		Question:   question,
+		AgentPass:  agent.Pass(),
+		AgentVote:  string(agent.Vote),
+		PolicyPass: pass,
+		Reason:     agent.Reason,
+		CreatedAt:  time.Now(),
+	}
+	if agentErr != nil {
+		decision.AgentError = agentErr.Error()
+	}
+	reason := fmt.Sprintf("dry run, %s policy", d.policy)
+	d.logger.Info("dry run decision", "question", question, "id", id, "agent", agent.Vote, "agentErr", agentErr, "policy", pass)
+	if d.store != nil {
+		if err := d.store.SaveDryRunDecision(&decision); err != nil {
+			d.logger.Error("save dry run decision fail", "err", err)
+		}
+	}
+	return decisionOf(pass, reason)
+}
@@ -0,0 +1,306 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// EnsembleMode is how the votes of the ensemble members are aggregated.
+type EnsembleMode string
+
+const (
+	EnsembleMajority EnsembleMode = "majority"
+	EnsembleWeighted EnsembleMode = "weighted"
+)
+
+func ParseEnsembleMode(s string) (EnsembleMode, error) {
+	switch m := EnsembleMode(s); m {
+	case EnsembleMajority, EnsembleWeighted:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown ensemble mode %q, expected majority or weighted", s)
+}
+
+// EnsembleMember is one agent of an ensemble, a backend with its config and
+// its weight in weighted mode.
+type EnsembleMember struct {
+	Name    string
+	Backend string
+	Weight  float64
+	Config  BackendConfig
+}
+
+// EnsembleMemberVote is the answer of one member in an EnsembleVote.
+type EnsembleMemberVote struct {
+	Name       string  `json:"name"`
+	Backend    string  `json:"backend"`
+	Weight     float64 `json:"weight"`
+	Vote       string  `json:"vote,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+	Reason     string  `json:"reason,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// EnsembleVote records a voting decision of the ensemble and the answer of
+// every member.
+type EnsembleVote struct {
+	Id        uint64               `gorm:"primary_key" json:"id"`
+	Kind      string               `gorm:"index:idx_ensemble_vote_ref" json:"kind"`
+	RefId     uint64               `gorm:"index:idx_ensemble_vote_ref" json:"ref_id"`
+	Voter     string               `json:"voter"`
+	Mode      string               `json:"mode"`
+	Yes       float64              `json:"yes"`
+	Total     float64              `json:"total"`
+	Pass      bool                 `json:"pass"`
+	Members   string               `json:"-"`
+	Results   []EnsembleMemberVote `gorm:"-" json:"members"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+// EnsembleStore persists the ensemble votes.
+type EnsembleStore interface {
+	SaveEnsembleVote(v *EnsembleVote) error
+}
+
+type ensembleMember struct {
+	name    string
+	backend string
+	weight  float64
+	client  Client
+}
+
+var _ Client = &EnsembleClient{}
+
+// EnsembleClient asks every member agent for the voting decisions at once
+// and aggregates their votes, by majority or by weight, to reduce the bias
+// of a single model. A member failing is left out of the vote, the vote
+// fails only when every member does. Proposals, discussions and
+// notifications go to every member, the other calls to the first one.
+type EnsembleClient struct {
+	Client
+	members []ensembleMember
+	mode    EnsembleMode
+	logger  cmtlog.Logger
+	store   EnsembleStore
+}
+
+func NewEnsembleClient(members []EnsembleMember, mode EnsembleMode, logger cmtlog.Logger) (*EnsembleClient, error) {
+	if len(members) == 0 {
+		return nil, errors.New("ensemble without members")
+	}
+	e := &EnsembleClient{
+		mode:   mode,
+		logger: logger.With("module", "ensemble"),
+	}
+	names := make(map[string]bool)
+	for i, m := range members {
+		name := m.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", m.Backend, i)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate ensemble member %q", name)
+		}
+		names[name] = true
+		weight := m.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("negative weight for ensemble member %q", name)
+		}
+		client, err := NewBackend(m.Backend, m.Config, logger)
+		if err != nil {
+			return nil, fmt.Errorf("ensemble member %q: %w", name, err)
+		}
+		e.members = append(e.members, ensembleMember{name: name, backend: m.Backend, weight: weight, client: client})
+	}
+	e.Client = e.members[0].client
+	return e, nil
+}
+
+func (e *EnsembleClient) SetEnsembleStore(store EnsembleStore) {
+	e.store = store
+}
+
+// Backends returns the clients of the members, to give them their sources.
+func (e *EnsembleClient) Backends() []Client {
+	clients := make([]Client, len(e.members))
+	for i, m := range e.members {
+		clients[i] = m.client
+	}
+	return clients
+}
+
+func (e *EnsembleClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	return e.vote(ctx, voteKindProposal, proposal, voter, func(ctx context.Context, c Client) (Decision, error) {
+		return c.IfAcceptProposal(ctx, proposal, voter)
+	})
+}
+
+func (e *EnsembleClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	return e.vote(ctx, voteKindGrant, validator, "", func(ctx context.Context, c Client) (Decision, error) {
+		return c.IfGrantNewMember(ctx, validator, proposer, amount, statement)
+	})
+}
+
+// vote asks every member at once, the reason of the vote lists the reasons
+// of the members. The members abstaining are left out of the count, the
+// ensemble abstains when they all do. The confidence of the ensemble is the
+// share of the count on the winning side.
+func (e *EnsembleClient) vote(ctx context.Context, kind string, id uint64, voter string, ask func(ctx context.Context, c Client) (Decision, error)) (Decision, error) {
+	results := make([]EnsembleMemberVote, len(e.members))
+	errs := make([]error, len(e.members))
+	var wg sync.WaitGroup
+	for i, m := range e.members {
+		wg.Add(1)
+		go func(i int, m ensembleMember) {
+			defer wg.Done()
+			decision, err := ask(ctx, m.client)
+			results[i] = EnsembleMemberVote{Name: m.name, Backend: m.backend, Weight: m.weight, Reason: decision.Reason}
+			if err != nil {
+				errs[i] = err
+				results[i].Error = err.Error()
+				e.logger.Error("ensemble member vote fail", "member", m.name, "kind", kind, "id", id, "err", err)
+				return
+			}
+			results[i].Vote = string(decision.Vote)
+			results[i].Confidence = decision.Confidence
+		}(i, m)
+	}
+	wg.Wait()
+	var yes, total float64
+	abstained := false
+	summary := make([]string, 0, len(results))
+	for _, r := range results {
+		if r.Error != "" {
+			summary = append(summary, fmt.Sprintf("%s failed", r.Name))
+			continue
+		}
+		if r.Vote == string(VoteAbstain) {
+			abstained = true
+			summary = append(summary, fmt.Sprintf("%s abstains", r.Name))
+			continue
+		}
+		weight := 1.0
+		if e.mode == EnsembleWeighted {
+			weight = r.Weight
+		}
+		total += weight
+		if r.Vote == string(VoteYes) {
+			yes += weight
+		}
+		if r.Reason != "" {
+			summary = append(summary, fmt.Sprintf("%s %s: %s", r.Name, r.Vote, r.Reason))
+		} else {
+			summary = append(summary, fmt.Sprintf("%s %s", r.Name, r.Vote))
+		}
+	}
+	if total == 0 && !abstained {
+		return Decision{}, fmt.Errorf("every ensemble member failed: %w", errors.Join(errs...))
+	}
+	reason := fmt.Sprintf("ensemble %s %g of %g: %s", e.mode, yes, total, strings.Join(summary, "; "))
+	var decision Decision
+	if total == 0 {
+		decision = Decision{Vote: VoteAbstain, Confidence: 1, Reason: reason}
+	} else {
+		// a tie rejects
+		decision = decisionOf(yes*2 > total, reason)
+		decision.Confidence = yes / total
+		if !decision.Pass() {
+			decision.Confidence = 1 - decision.Confidence
+		}
+	}
+	pass := decision.Pass()
+	e.logger.Info("ensemble vote", "kind", kind, "id", id, "yes", yes, "total", total, "pass", pass)
+	if e.store != nil {
+		data, _ := json.Marshal(results)
+		v := EnsembleVote{
+			Kind:      kind,
+			RefId:     id,
+			Voter:     voter,
+			Mode:      string(e.mode),
+			Yes:       yes,
+			Total:     total,
+			Pass:      pass,
+			Members:   string(data),
+			CreatedAt: time.Now(),
+		}
+		if err := e.store.SaveEnsembleVote(&v); err != nil {
+			e.logger.Error("save ensemble vote fail", "err", err)
+		}
+	}
+	return decision, nil
+}
+
+// each calls every member, the errors are joined.
+func (e *EnsembleClient) each(call func(c Client) error) error {
+	var errs []error
+	for _, m := range e.members {
+		if err := call(m.client); err != nil {
+			errs = append(errs, fmt.Errorf("ensemble member %q: %w", m.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *EnsembleClient) AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error {
+	return e.each(func(c Client) error {
+		return c.AddProposal(ctx, proposal, proposer, text)
+	})
+}
+
+func (e *EnsembleClient) AddDiscussion(ctx context.Context, proposal uint64, speaker string, text string) error {
+	return e.each(func(c Client) error {
+		return c.AddDiscussion(ctx, proposal, speaker, text)
+	})
+}
+
+func (e *EnsembleClient) AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error {
+	return e.each(func(c Client) error {
+		return c.AddDiscussions(ctx, msgs)
+	})
+}
+
+func (e *EnsembleClient) NotifyActivation(ctx context.Context, proposal uint64, activationHeight uint64) error {
+	return e.each(func(c Client) error {
+		return c.NotifyActivation(ctx, proposal, activationHeight)
+	})
+}
+
+func (e *EnsembleClient) NotifyMisbehavior(ctx context.Context, validator string, kind string, height uint64) error {
+	return e.each(func(c Client) error {
+		return c.NotifyMisbehavior(ctx, validator, kind, height)
+	})
+}
+
+func (c *ChainIndexer) SaveEnsembleVote(v *EnsembleVote) error {
+	return c.db.Create(v).Error
+}
+
+func (c *ChainIndexer) getEnsembleVotes(kind string, id uint64, page int, pageSize int) (Page[EnsembleVote], error) {
+	db := c.db.Model(&EnsembleVote{})
+	if kind != "" {
+		db = db.Where("kind = ?", kind)
+	}
+	if id != 0 {
+		db = db.Where("ref_id = ?", id)
+	}
+	votes, err := paginate[EnsembleVote](db, "id desc", page, pageSize)
+	if err != nil {
+		return votes, err
+	}
+	for i := range votes.Items {
+		if err := json.Unmarshal([]byte(votes.Items[i].Members), &votes.Items[i].Results); err != nil {
+			return votes, err
+		}
+	}
+	return votes, nil
+}
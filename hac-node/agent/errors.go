@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+)
+
+// Classes of the failures of an agent call. An AgentError is one of them,
+// callers pick a fallback with errors.Is.
+var (
+	// ErrAgentUnavailable is an agent not reachable or failing on its side,
+	// a later call may succeed.
+	ErrAgentUnavailable = errors.New("agent unavailable")
+	// ErrAgentDecisionTimeout is an agent not answering in time, the caller
+	// giving up or the agent reporting it timed out itself.
+	ErrAgentDecisionTimeout = errors.New("agent decision timeout")
+	// ErrMalformedAgentResponse is an answer the node cannot read.
+	ErrMalformedAgentResponse = errors.New("malformed agent response")
+	// ErrAgentRejected is a request refused by the agent, sending it again
+	// gets the same answer.
+	ErrAgentRejected = errors.New("agent rejected the request")
+)
+
+// agentErrorBodyLimit is the length of the response body kept in an
+// AgentError.
+const agentErrorBodyLimit = 512
+
+// AgentError is a failed agent call with its class, the status and a
+// snapshot of the body of the response when there is one.
+type AgentError struct {
+	Class  error
+	Method string
+	Status int
+	Body   string
+	Err    error
+}
+
+func (e *AgentError) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.Method, e.Class)
+	if e.Status != 0 {
+		msg += fmt.Sprintf(": %d %s", e.Status, http.StatusText(e.Status))
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Unwrap matches both the class and the cause, so a timeout is still a
+// context.DeadlineExceeded.
+func (e *AgentError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Class}
+	}
+	return []error{e.Class, e.Err}
+}
+
+func bodySnapshot(body []byte) string {
+	if len(body) <= agentErrorBodyLimit {
+		return string(body)
+	}
+	cut := agentErrorBodyLimit
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return string(body[:cut]) + "..."
+}
+
+// agentCallError classifies the failure of a call getting no response.
+func agentCallError(method string, err error) error {
+	var agentErr *AgentError
+	if errors.As(err, &agentErr) {
+		return err
+	}
+	class := ErrAgentUnavailable
+	if errors.Is(err, context.DeadlineExceeded) {
+		class = ErrAgentDecisionTimeout
+	}
+	return &AgentError{Class: class, Method: method, Err: err}
+}
+
+// agentStatusError classifies a response by its status, nil for a success.
+func agentStatusError(method string, status int, body []byte) error {
+	if status/100 == 2 {
+		return nil
+	}
+	class := ErrAgentRejected
+	switch {
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		class = ErrAgentDecisionTimeout
+	case status == http.StatusTooManyRequests || status/100 == 5:
+		class = ErrAgentUnavailable
+	}
+	return &AgentError{Class: class, Method: method, Status: status, Body: bodySnapshot(body)}
+}
+
+// agentResponseError is a successful response the node cannot read.
+func agentResponseError(method string, status int, body []byte, err error) error {
+	return &AgentError{Class: ErrMalformedAgentResponse, Method: method, Status: status, Body: bodySnapshot(body), Err: err}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	// EventStatsWindow is the number of heights aggregated in one stats window.
+	EventStatsWindow = 100
+	// eventStatsWindows is the number of recent windows kept in memory.
+	eventStatsWindows = 10
+)
+
+const (
+	eventResultHandled = "handled"
+	eventResultSkipped = "skipped"
+	eventResultFailed  = "failed"
+)
+
+type EventTypeStats struct {
+	Handled uint64 `json:"handled"`
+	Skipped uint64 `json:"skipped"`
+	Failed  uint64 `json:"failed"`
+}
+
+type EventStatsWindowInfo struct {
+	FromHeight uint64                     `json:"from_height"`
+	ToHeight   uint64                     `json:"to_height"`
+	Types      map[string]*EventTypeStats `json:"types"`
+}
+
+// eventStats counts event handling results per type for the most recent
+// height windows.
+type eventStats struct {
+	mtx     sync.Mutex
+	windows map[uint64]map[string]*EventTypeStats
+}
+
+func newEventStats() *eventStats {
+	return &eventStats{windows: make(map[uint64]map[string]*EventTypeStats)}
+}
+
+func (s *eventStats) record(height int64, typ string, result string) {
+	eventsProcessed.WithLabelValues(typ, result).Inc()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	window := uint64(height) / EventStatsWindow
+	types, ok := s.windows[window]
+	if !ok {
+		types = make(map[string]*EventTypeStats)
+		s.windows[window] = types
+		s.prune()
+	}
+	st, ok := types[typ]
+	if !ok {
+		st = &EventTypeStats{}
+		types[typ] = st
+	}
+	switch result {
+	case eventResultHandled:
+		st.Handled++
+	case eventResultSkipped:
+		st.Skipped++
+	case eventResultFailed:
+		st.Failed++
+	}
+}
+
+// prune drops the oldest windows beyond eventStatsWindows.
+func (s *eventStats) prune() {
+	for len(s.windows) > eventStatsWindows {
+		oldest := uint64(0)
+		first := true
+		for w := range s.windows {
+			if first || w < oldest {
+				oldest = w
+				first = false
+			}
+		}
+		delete(s.windows, oldest)
+	}
+}
+
+// snapshot returns a copy of the kept windows, newest first.
+func (s *eventStats) snapshot() []EventStatsWindowInfo {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	infos := make([]EventStatsWindowInfo, 0, len(s.windows))
+	for w, types := range s.windows {
+		info := EventStatsWindowInfo{
+			FromHeight: w * EventStatsWindow,
+			ToHeight:   (w+1)*EventStatsWindow - 1,
+			Types:      make(map[string]*EventTypeStats, len(types)),
+		}
+		for typ, st := range types {
+			cp := *st
+			info.Types[typ] = &cp
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].FromHeight > infos[j].FromHeight
+	})
+	return infos
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	MisbehaviorDuplicateVote     = "duplicate_vote"
+	MisbehaviorLightClientAttack = "light_client_attack"
+)
+
+// ValidatorMisbehavior is a validator named by evidence included in a block:
+// a double sign, or a light client attack naming every byzantine validator.
+// Height is the height of the infraction, IncludedHeight the height of the
+// block carrying the evidence. Member is whether the validator was an active
+// member when the evidence was indexed.
+type ValidatorMisbehavior struct {
+	Id             uint64    `gorm:"primary_key" json:"id"`
+	EvidenceHash   string    `gorm:"unique_index:idx_misbehavior_evidence_address" json:"evidence_hash"`
+	Address        string    `gorm:"unique_index:idx_misbehavior_evidence_address;index" json:"address"`
+	Name           string    `json:"name"`
+	Kind           string    `gorm:"index" json:"kind"`
+	Height         uint64    `json:"height"`
+	IncludedHeight uint64    `gorm:"index" json:"included_height"`
+	Power          int64     `json:"power"`
+	Member         bool      `json:"member"`
+	Detail         string    `json:"detail"`
+	EvidenceTime   time.Time `json:"evidence_time"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// misbehaviors returns a record for every validator ev names.
+func misbehaviors(ev cmttypes.Evidence) []ValidatorMisbehavior {
+	hash := fmt.Sprintf("%X", ev.Hash())
+	height := uint64(ev.Height())
+	switch e := ev.(type) {
+	case *cmttypes.DuplicateVoteEvidence:
+		return []ValidatorMisbehavior{{
+			EvidenceHash: hash,
+			Address:      e.VoteA.ValidatorAddress.String(),
+			Kind:         MisbehaviorDuplicateVote,
+			Height:       height,
+			Power:        e.ValidatorPower,
+			Detail:       fmt.Sprintf("signed %s and %s in round %d", e.VoteA.BlockID.Hash, e.VoteB.BlockID.Hash, e.VoteA.Round),
+			EvidenceTime: ev.Time(),
+		}}
+	case *cmttypes.LightClientAttackEvidence:
+		records := make([]ValidatorMisbehavior, 0, len(e.ByzantineValidators))
+		for _, v := range e.ByzantineValidators {
+			records = append(records, ValidatorMisbehavior{
+				EvidenceHash: hash,
+				Address:      v.Address.String(),
+				Kind:         MisbehaviorLightClientAttack,
+				Height:       height,
+				Power:        v.VotingPower,
+				Detail:       fmt.Sprintf("conflicting block from common height %d", e.CommonHeight),
+				EvidenceTime: ev.Time(),
+			})
+		}
+		return records
+	}
+	return nil
+}
+
+// indexEvidence stores the misbehaviors proven by the evidence of block. A
+// current member misbehaving is reported to the agent and the notifiers once,
+// a replayed height finds its records stored.
+func (c *ChainIndexer) indexEvidence(block *cmttypes.Block) error {
+	height := uint64(block.Height)
+	for _, ev := range block.Evidence.Evidence {
+		for _, record := range misbehaviors(ev) {
+			var existing ValidatorMisbehavior
+			err := c.db.Where("evidence_hash = ? AND address = ?", record.EvidenceHash, record.Address).First(&existing).Error
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			record.IncludedHeight = height
+			if agent, err := c.getValidatorByAddress(record.Address); err == nil && agent != nil {
+				record.Name = agent.Name
+				record.Member = agent.Active
+			}
+			err = c.db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Create(&record).Error; err != nil {
+					return err
+				}
+				if !record.Member {
+					return nil
+				}
+				return c.enqueueAgentCall(tx, "misbehavior:"+record.EvidenceHash+":"+record.Address, AgentOutbox{
+					Method:  outboxMisbehavior,
+					Address: record.Address,
+					Text:    record.Kind,
+					Height:  record.Height,
+				})
+			})
+			if err != nil {
+				return fmt.Errorf("index evidence %s: %w", record.EvidenceHash, err)
+			}
+			validatorMisbehaviors.WithLabelValues(record.Kind).Inc()
+			c.logger.Error("validator misbehavior", "validator", record.Address, "kind", record.Kind, "height", record.Height, "included", height, "member", record.Member)
+			if !record.Member {
+				continue
+			}
+			c.wakeOutbox()
+			name := record.Name
+			if name == "" {
+				name = record.Address
+			}
+			c.notifier.notify(Notification{
+				Event:       NotifyMisbehavior,
+				Validator:   name,
+				Misbehavior: fmt.Sprintf("%s at height %d", record.Kind, record.Height),
+				Height:      height,
+				BlockTime:   block.Time,
+			})
+		}
+	}
+	return nil
+}
+
+func (c *ChainIndexer) getMisbehaviors(address string, page int, pageSize int) (Page[ValidatorMisbehavior], error) {
+	db := c.db.Model(&ValidatorMisbehavior{})
+	if address != "" {
+		db = db.Where("address = ?", address)
+	}
+	return paginate[ValidatorMisbehavior](db, "included_height desc, id desc", page, pageSize)
+}
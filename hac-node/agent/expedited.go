@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PayloadTypeExpedited is the payload type of an emergency proposal. It
+// expires after the expedited window instead of a year, is settled after
+// fewer discussions and its agent calls go ahead of the regular ones. The
+// chain settles it like any proposal, with TallyPassShare of the voting
+// power: a higher pass share for expedited proposals is not implemented, the
+// outcome is the vote code committed by the consensus quorum, which the app
+// cannot raise per proposal.
+const PayloadTypeExpedited = "expedited"
+
+// ExpeditedWindowDefault is how long an expedited proposal stays open when
+// no window is configured.
+const ExpeditedWindowDefault = 72 * time.Hour
+
+// The proposer settles a proposal once it has this many discussions.
+const (
+	regularDiscussions   = 15
+	expeditedDiscussions = 5
+)
+
+func init() {
+	RegisterPayloadSchema(PayloadSchema{
+		Type:     PayloadTypeExpedited,
+		Required: []string{"body", "reason"},
+		Properties: map[string]string{
+			"title":             "string",
+			"summary":           "string",
+			"body":              "string",
+			"reason":            "string",
+			"activation_height": "number",
+		},
+	})
+}
+
+// IsExpeditedPayload tells whether the proposal data selects the expedited
+// payload type.
+func IsExpeditedPayload(data []byte) bool {
+	var payload struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(data, &payload) != nil {
+		return false
+	}
+	return payload.Type == PayloadTypeExpedited
+}
+
+// handleExpeditedProposal flags a new expedited proposal and shortens its
+// expiry to the expedited window.
+func (c *ChainIndexer) handleExpeditedProposal(tx *gorm.DB, proposal *Proposal, height uint64, blockTime time.Time) error {
+	proposal.Expedited = true
+	proposal.ExpireTimestamp = blockTime.Add(c.expediteWindow).Unix()
+	c.logger.Info("expedited proposal", "proposal", proposal.Id, "expire", proposal.ExpireTimestamp, "height", height)
+	return nil
+}
+
+// IsExpedited tells whether the proposal was indexed as expedited, the
+// agent client asks it to prioritize the decision votes on it.
+func (c *ChainIndexer) IsExpedited(proposalId uint64) bool {
+	var proposal Proposal
+	err := c.db.Select("id, expedited").First(&proposal, proposalId).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.logger.Error("get proposal fail", "proposal", proposalId, "err", err)
+	}
+	return proposal.Expedited
+}
+
+func settleDiscussions(proposal Proposal) uint64 {
+	if proposal.Expedited {
+		return expeditedDiscussions
+	}
+	return regularDiscussions
+}
@@ -0,0 +1,282 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// failoverEndpoint is an agent url with its client, nil until the agent
+// answered once.
+type failoverEndpoint struct {
+	url    string
+	client Client
+}
+
+var _ Client = &FailoverClient{}
+
+// FailoverClient sends the calls to the first of its agent urls that is up.
+// A call failing with an unavailable or timed out agent is sent to the next
+// url, which stays active, and Run fails back to the primary url once it
+// answers again.
+type FailoverClient struct {
+	backend   string
+	cfg       BackendConfig
+	interval  time.Duration
+	logger    cmtlog.Logger
+	setup     func(c Client)
+	mtx       sync.Mutex
+	endpoints []*failoverEndpoint
+	active    int
+}
+
+// NewFailoverClient makes clients of backend with cfg for every url, the
+// first being the primary. An agent down at start is made on first use, it
+// fails when none of them answers.
+func NewFailoverClient(backend string, cfg BackendConfig, urls []string, interval time.Duration, logger cmtlog.Logger) (*FailoverClient, error) {
+	f := &FailoverClient{
+		backend:  backend,
+		cfg:      cfg,
+		interval: interval,
+		logger:   logger.With("module", "agent-failover"),
+		active:   -1,
+	}
+	var errs []error
+	for i, url := range urls {
+		f.endpoints = append(f.endpoints, &failoverEndpoint{url: url})
+		if _, err := f.endpoint(i); err != nil {
+			f.logger.Error("agent endpoint unavailable", "url", url, "err", err)
+			errs = append(errs, err)
+		} else if f.active < 0 {
+			f.active = i
+		}
+	}
+	if f.active < 0 {
+		return nil, fmt.Errorf("no agent endpoint available: %w", errors.Join(errs...))
+	}
+	f.setActive(f.active)
+	return f, nil
+}
+
+// SetClientSetup sets a function run on every client made, including the
+// ones made once their agent is up.
+func (f *FailoverClient) SetClientSetup(setup func(c Client)) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.setup = setup
+	for _, e := range f.endpoints {
+		if e.client != nil {
+			setup(e.client)
+		}
+	}
+}
+
+// Backends returns the clients made so far, the primary first.
+func (f *FailoverClient) Backends() []Client {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	var clients []Client
+	for _, e := range f.endpoints {
+		if e.client != nil {
+			clients = append(clients, e.client)
+		}
+	}
+	return clients
+}
+
+// ActiveUrl returns the url the calls are sent to.
+func (f *FailoverClient) ActiveUrl() string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.endpoints[f.active].url
+}
+
+// endpoint returns the client of endpoint i, made on first use.
+func (f *FailoverClient) endpoint(i int) (Client, error) {
+	f.mtx.Lock()
+	e := f.endpoints[i]
+	c := e.client
+	f.mtx.Unlock()
+	if c != nil {
+		return c, nil
+	}
+	cfg := f.cfg
+	cfg.Url = e.url
+	c, err := NewBackend(f.backend, cfg, f.logger)
+	if err != nil {
+		return nil, err
+	}
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if e.client != nil {
+		return e.client, nil
+	}
+	if f.setup != nil {
+		f.setup(c)
+	}
+	e.client = c
+	return c, nil
+}
+
+func (f *FailoverClient) setActive(i int) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if i != f.active {
+		f.logger.Info("agent endpoint switched", "from", f.endpoints[f.active].url, "to", f.endpoints[i].url)
+	}
+	f.active = i
+	for j, e := range f.endpoints {
+		v := 0.0
+		if j == i {
+			v = 1
+		}
+		agentEndpointActive.WithLabelValues(e.url).Set(v)
+	}
+}
+
+// call sends the call to the active endpoint and on to the next ones while
+// their agent is unavailable.
+func (f *FailoverClient) call(ctx context.Context, method string, send func(c Client) error) error {
+	f.mtx.Lock()
+	start := f.active
+	f.mtx.Unlock()
+	var err error
+	for n := 0; n < len(f.endpoints); n++ {
+		i := (start + n) % len(f.endpoints)
+		c, cerr := f.endpoint(i)
+		if cerr != nil {
+			err = agentCallError(method, cerr)
+			continue
+		}
+		err = send(c)
+		if err == nil || ctx.Err() != nil || !(errors.Is(err, ErrAgentUnavailable) || errors.Is(err, ErrAgentDecisionTimeout)) {
+			if i != start {
+				f.setActive(i)
+			}
+			return err
+		}
+		f.logger.Error("agent endpoint failed", "url", f.endpoints[i].url, "method", method, "err", err)
+	}
+	return err
+}
+
+// Run checks every interval whether the primary endpoint answers a ping
+// again and fails back to it, until ctx is done.
+func (f *FailoverClient) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		f.mtx.Lock()
+		active := f.active
+		f.mtx.Unlock()
+		if active == 0 {
+			continue
+		}
+		c, err := f.endpoint(0)
+		if err != nil {
+			continue
+		}
+		if p, ok := c.(Pinger); ok {
+			pingCtx, cancel := context.WithTimeout(ctx, f.interval)
+			err = p.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				continue
+			}
+		}
+		f.setActive(0)
+	}
+}
+
+func (f *FailoverClient) IfProcessProposal(ctx context.Context, proposer uint64, data []byte) (bool, error) {
+	var pass bool
+	err := f.call(ctx, "IfProcessProposal", func(c Client) (err error) {
+		pass, err = c.IfProcessProposal(ctx, proposer, data)
+		return err
+	})
+	return pass, err
+}
+
+func (f *FailoverClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	var decision Decision
+	err := f.call(ctx, "IfAcceptProposal", func(c Client) (err error) {
+		decision, err = c.IfAcceptProposal(ctx, proposal, voter)
+		return err
+	})
+	return decision, err
+}
+
+func (f *FailoverClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	var decision Decision
+	err := f.call(ctx, "IfGrantNewMember", func(c Client) (err error) {
+		decision, err = c.IfGrantNewMember(ctx, validator, proposer, amount, statement)
+		return err
+	})
+	return decision, err
+}
+
+func (f *FailoverClient) CommentPropoal(ctx context.Context, proposal uint64, speaker string) (string, error) {
+	var comment string
+	err := f.call(ctx, "CommentPropoal", func(c Client) (err error) {
+		comment, err = c.CommentPropoal(ctx, proposal, speaker)
+		return err
+	})
+	return comment, err
+}
+
+func (f *FailoverClient) AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error {
+	return f.call(ctx, "AddProposal", func(c Client) error {
+		return c.AddProposal(ctx, proposal, proposer, text)
+	})
+}
+
+func (f *FailoverClient) AddDiscussion(ctx context.Context, proposal uint64, speaker string, text string) error {
+	return f.call(ctx, "AddDiscussion", func(c Client) error {
+		return c.AddDiscussion(ctx, proposal, speaker, text)
+	})
+}
+
+func (f *FailoverClient) AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error {
+	return f.call(ctx, "AddDiscussions", func(c Client) error {
+		return c.AddDiscussions(ctx, msgs)
+	})
+}
+
+func (f *FailoverClient) NotifyActivation(ctx context.Context, proposal uint64, activationHeight uint64) error {
+	return f.call(ctx, "NotifyActivation", func(c Client) error {
+		return c.NotifyActivation(ctx, proposal, activationHeight)
+	})
+}
+
+func (f *FailoverClient) NotifyMisbehavior(ctx context.Context, validator string, kind string, height uint64) error {
+	return f.call(ctx, "NotifyMisbehavior", func(c Client) error {
+		return c.NotifyMisbehavior(ctx, validator, kind, height)
+	})
+}
+
+func (f *FailoverClient) GetSelfIntro(ctx context.Context) (string, error) {
+	var intro string
+	err := f.call(ctx, "GetSelfIntro", func(c Client) (err error) {
+		intro, err = c.GetSelfIntro(ctx)
+		return err
+	})
+	return intro, err
+}
+
+func (f *FailoverClient) GetHeadPhoto(ctx context.Context) (string, error) {
+	var photo string
+	err := f.call(ctx, "GetHeadPhoto", func(c Client) (err error) {
+		photo, err = c.GetHeadPhoto(ctx)
+		return err
+	})
+	return photo, err
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// eventFeedBuffer is the number of events a subscriber may lag behind before
+// events are dropped for it.
+const eventFeedBuffer = 256
+
+// IndexedEvent is an event as published to tail subscribers.
+type IndexedEvent struct {
+	Height     int64             `json:"height"`
+	BlockTime  time.Time         `json:"block_time"`
+	TxHash     string            `json:"tx_hash"`
+	Type       string            `json:"type"`
+	Result     string            `json:"result"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+// eventFeed fans indexed events out to subscribers. Slow subscribers miss
+// events instead of blocking the indexer.
+type eventFeed struct {
+	mtx  sync.Mutex
+	subs map[chan IndexedEvent]struct{}
+}
+
+func newEventFeed() *eventFeed {
+	return &eventFeed{subs: make(map[chan IndexedEvent]struct{})}
+}
+
+func (f *eventFeed) subscribe() chan IndexedEvent {
+	ch := make(chan IndexedEvent, eventFeedBuffer)
+	f.mtx.Lock()
+	f.subs[ch] = struct{}{}
+	f.mtx.Unlock()
+	return ch
+}
+
+func (f *eventFeed) unsubscribe(ch chan IndexedEvent) {
+	f.mtx.Lock()
+	delete(f.subs, ch)
+	f.mtx.Unlock()
+}
+
+func (f *eventFeed) publish(event abci.Event, height int64, blockTime time.Time, txHash string, result string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if len(f.subs) == 0 {
+		return
+	}
+	ev := IndexedEvent{
+		Height:     height,
+		BlockTime:  blockTime,
+		TxHash:     txHash,
+		Type:       event.Type,
+		Result:     result,
+		Attributes: make(map[string]string, len(event.Attributes)),
+	}
+	for _, attr := range event.Attributes {
+		ev.Attributes[attr.Key] = attr.Value
+	}
+	for ch := range f.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"context"
+	"database/sql"
+)
+
+// HeightGap is a range of heights below the cursor that were never recorded
+// as indexed, inclusive on both ends.
+type HeightGap struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// findHeightGaps returns the gaps in indexed_blocks up to cursor. Heights
+// before the first recorded block are not reported since databases created
+// before blocks were tracked have no records for them.
+func (c *ChainIndexer) findHeightGaps(cursor uint64) ([]HeightGap, error) {
+	gaps := make([]HeightGap, 0)
+	rows, err := c.db.Raw("SELECT t.height + 1 FROM indexed_blocks t WHERE t.height < ? AND NOT EXISTS "+
+		"(SELECT 1 FROM indexed_blocks u WHERE u.height = t.height + 1) ORDER BY t.height", cursor).Rows()
+	if err != nil {
+		return nil, err
+	}
+	starts, err := scanHeights(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, start := range starts {
+		var next sql.NullInt64
+		if err := c.db.Raw("SELECT MIN(height) FROM indexed_blocks WHERE height > ?", start).Row().Scan(&next); err != nil {
+			return nil, err
+		}
+		end := cursor
+		if next.Valid && uint64(next.Int64)-1 < end {
+			end = uint64(next.Int64) - 1
+		}
+		gaps = append(gaps, HeightGap{From: start, To: end})
+	}
+	return gaps, nil
+}
+
+// partialHeights returns heights above cursor that already have rows, left
+// behind by a crash between indexing a block and saving the cursor. They are
+// replayed by the normal sync loop.
+func (c *ChainIndexer) partialHeights(cursor uint64) ([]uint64, error) {
+	rows, err := c.db.Raw("SELECT new_height FROM proposals WHERE new_height > ? "+
+		"UNION SELECT height FROM discussions WHERE height > ? "+
+		"UNION SELECT height FROM grants WHERE height > ? "+
+		"UNION SELECT height FROM indexed_blocks WHERE height > ? ORDER BY 1", cursor, cursor, cursor, cursor).Rows()
+	if err != nil {
+		return nil, err
+	}
+	return scanHeights(rows)
+}
+
+func scanHeights(rows *sql.Rows) ([]uint64, error) {
+	defer rows.Close()
+	heights := make([]uint64, 0)
+	for rows.Next() {
+		var h uint64
+		if err := rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		heights = append(heights, h)
+	}
+	return heights, rows.Err()
+}
+
+// repairGaps reports heights missing below the stored cursor and heights
+// partially indexed above it, then replays the missing ones.
+func (c *ChainIndexer) repairGaps(ctx context.Context) error {
+	cursor := uint64(c.Height - 1)
+	partial, err := c.partialHeights(cursor)
+	if err != nil {
+		return err
+	}
+	if len(partial) > 0 {
+		c.logger.Info("found partially indexed heights above cursor", "cursor", cursor, "heights", partial)
+	}
+	gaps, err := c.findHeightGaps(cursor)
+	if err != nil {
+		return err
+	}
+	for _, gap := range gaps {
+		c.logger.Info("replay height gap", "from", gap.From, "to", gap.To)
+		for h := gap.From; h <= gap.To; h++ {
+			if err := c.indexHeight(ctx, int64(h)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of agent.proto. They are encoded by hand with protowire, the
+// wire format is the one of the code generated from agent.proto in any
+// language.
+
+type VoteProposalRequest struct {
+	ProposalId       uint64
+	ValidatorAddress string
+	Text             string
+	Expedited        bool
+}
+
+type VoteGrantRequest struct {
+	GrantId          uint64
+	ValidatorAddress string
+	Amount           uint64
+	Text             string
+}
+
+type VoteReply struct {
+	Vote       string
+	Reason     string
+	Confidence *float64
+}
+
+type CommentRequest struct {
+	ProposalId       uint64
+	ValidatorAddress string
+	Text             string
+}
+
+type CommentChunk struct {
+	Text string
+}
+
+type AddProposalRequest struct {
+	ProposalId       uint64
+	ValidatorAddress string
+	Text             string
+}
+
+type AddDiscussionRequest struct {
+	ProposalId       uint64
+	ValidatorAddress string
+	Text             string
+}
+
+type NotifyActivationRequest struct {
+	ProposalId       uint64
+	ActivationHeight uint64
+}
+
+type NotifyMisbehaviorRequest struct {
+	ValidatorAddress string
+	Kind             string
+	Height           uint64
+}
+
+type IntroRequest struct{}
+
+type IntroReply struct {
+	Character string
+	HeadPhoto string
+}
+
+type Ack struct{}
+
+// wireField is a field of a message, exactly one of the pointers is set.
+type wireField struct {
+	num  protowire.Number
+	str  *string
+	u64  *uint64
+	flag *bool
+	// opt is an optional double, nil when it is not set
+	opt **float64
+}
+
+// wireMessage is a message of agent.proto listing its fields by number.
+type wireMessage interface {
+	wireFields() []wireField
+}
+
+func (m *VoteProposalRequest) wireFields() []wireField {
+	return []wireField{{num: 1, u64: &m.ProposalId}, {num: 2, str: &m.ValidatorAddress}, {num: 3, str: &m.Text}, {num: 4, flag: &m.Expedited}}
+}
+
+func (m *VoteGrantRequest) wireFields() []wireField {
+	return []wireField{{num: 1, u64: &m.GrantId}, {num: 2, str: &m.ValidatorAddress}, {num: 3, u64: &m.Amount}, {num: 4, str: &m.Text}}
+}
+
+func (m *VoteReply) wireFields() []wireField {
+	return []wireField{{num: 1, str: &m.Vote}, {num: 2, str: &m.Reason}, {num: 3, opt: &m.Confidence}}
+}
+
+func (m *CommentRequest) wireFields() []wireField {
+	return []wireField{{num: 1, u64: &m.ProposalId}, {num: 2, str: &m.ValidatorAddress}, {num: 3, str: &m.Text}}
+}
+
+func (m *CommentChunk) wireFields() []wireField {
+	return []wireField{{num: 1, str: &m.Text}}
+}
+
+func (m *AddProposalRequest) wireFields() []wireField {
+	return []wireField{{num: 1, u64: &m.ProposalId}, {num: 2, str: &m.ValidatorAddress}, {num: 3, str: &m.Text}}
+}
+
+func (m *AddDiscussionRequest) wireFields() []wireField {
+	return []wireField{{num: 1, u64: &m.ProposalId}, {num: 2, str: &m.ValidatorAddress}, {num: 3, str: &m.Text}}
+}
+
+func (m *NotifyActivationRequest) wireFields() []wireField {
+	return []wireField{{num: 1, u64: &m.ProposalId}, {num: 2, u64: &m.ActivationHeight}}
+}
+
+func (m *NotifyMisbehaviorRequest) wireFields() []wireField {
+	return []wireField{{num: 1, str: &m.ValidatorAddress}, {num: 2, str: &m.Kind}, {num: 3, u64: &m.Height}}
+}
+
+func (m *IntroRequest) wireFields() []wireField {
+	return nil
+}
+
+func (m *IntroReply) wireFields() []wireField {
+	return []wireField{{num: 1, str: &m.Character}, {num: 2, str: &m.HeadPhoto}}
+}
+
+func (m *Ack) wireFields() []wireField {
+	return nil
+}
+
+// wireCodec is the grpc codec of the wireMessages. It is named proto so
+// peers using generated code see the usual content type.
+type wireCodec struct{}
+
+func (wireCodec) Name() string {
+	return "proto"
+}
+
+// Marshal leaves out the zero values as proto3 does.
+func (wireCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(wireMessage)
+	if !ok {
+		return nil, fmt.Errorf("grpc agent: cannot marshal %T", v)
+	}
+	var b []byte
+	for _, f := range m.wireFields() {
+		switch {
+		case f.str != nil && *f.str != "":
+			b = protowire.AppendTag(b, f.num, protowire.BytesType)
+			b = protowire.AppendString(b, *f.str)
+		case f.u64 != nil && *f.u64 != 0:
+			b = protowire.AppendTag(b, f.num, protowire.VarintType)
+			b = protowire.AppendVarint(b, *f.u64)
+		case f.flag != nil && *f.flag:
+			b = protowire.AppendTag(b, f.num, protowire.VarintType)
+			b = protowire.AppendVarint(b, protowire.EncodeBool(true))
+		case f.opt != nil && *f.opt != nil:
+			b = protowire.AppendTag(b, f.num, protowire.Fixed64Type)
+			b = protowire.AppendFixed64(b, math.Float64bits(**f.opt))
+		}
+	}
+	return b, nil
+}
+
+// Unmarshal skips the fields it does not know, so peers built from a newer
+// agent.proto stay compatible.
+func (wireCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(wireMessage)
+	if !ok {
+		return fmt.Errorf("grpc agent: cannot unmarshal %T", v)
+	}
+	fields := make(map[protowire.Number]wireField)
+	for _, f := range m.wireFields() {
+		fields[f.num] = f
+	}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		f, known := fields[num]
+		switch {
+		case known && f.str != nil && typ == protowire.BytesType:
+			*f.str, n = protowire.ConsumeString(data)
+		case known && f.u64 != nil && typ == protowire.VarintType:
+			*f.u64, n = protowire.ConsumeVarint(data)
+		case known && f.flag != nil && typ == protowire.VarintType:
+			var x uint64
+			x, n = protowire.ConsumeVarint(data)
+			*f.flag = protowire.DecodeBool(x)
+		case known && f.opt != nil && typ == protowire.Fixed64Type:
+			var x uint64
+			x, n = protowire.ConsumeFixed64(data)
+			v := math.Float64frombits(x)
+			*f.opt = &v
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+const agentServiceName = "agent.AgentService"
+
+// AgentServer is implemented by the agent frameworks serving AgentService.
+// Embed UnimplementedAgentServer to serve only some of the methods.
+type AgentServer interface {
+	VoteProposal(ctx context.Context, req *VoteProposalRequest) (*VoteReply, error)
+	VoteGrant(ctx context.Context, req *VoteGrantRequest) (*VoteReply, error)
+	Comment(req *CommentRequest, stream CommentStream) error
+	AddProposal(ctx context.Context, req *AddProposalRequest) (*Ack, error)
+	AddDiscussion(ctx context.Context, req *AddDiscussionRequest) (*Ack, error)
+	NotifyActivation(ctx context.Context, req *NotifyActivationRequest) (*Ack, error)
+	NotifyMisbehavior(ctx context.Context, req *NotifyMisbehaviorRequest) (*Ack, error)
+	Intro(ctx context.Context, req *IntroRequest) (*IntroReply, error)
+}
+
+// CommentStream sends the chunks of a comment to the node.
+type CommentStream interface {
+	Send(chunk *CommentChunk) error
+	Context() context.Context
+}
+
+type commentStream struct {
+	grpc.ServerStream
+}
+
+func (s commentStream) Send(chunk *CommentChunk) error {
+	return s.SendMsg(chunk)
+}
+
+// UnimplementedAgentServer fails the decisions and acknowledges the data and
+// the notifications without keeping them.
+type UnimplementedAgentServer struct{}
+
+func (UnimplementedAgentServer) VoteProposal(context.Context, *VoteProposalRequest) (*VoteReply, error) {
+	return nil, status.Error(codes.Unimplemented, "VoteProposal not implemented")
+}
+
+func (UnimplementedAgentServer) VoteGrant(context.Context, *VoteGrantRequest) (*VoteReply, error) {
+	return nil, status.Error(codes.Unimplemented, "VoteGrant not implemented")
+}
+
+func (UnimplementedAgentServer) Comment(*CommentRequest, CommentStream) error {
+	return status.Error(codes.Unimplemented, "Comment not implemented")
+}
+
+func (UnimplementedAgentServer) AddProposal(context.Context, *AddProposalRequest) (*Ack, error) {
+	return &Ack{}, nil
+}
+
+func (UnimplementedAgentServer) AddDiscussion(context.Context, *AddDiscussionRequest) (*Ack, error) {
+	return &Ack{}, nil
+}
+
+func (UnimplementedAgentServer) NotifyActivation(context.Context, *NotifyActivationRequest) (*Ack, error) {
+	return &Ack{}, nil
+}
+
+func (UnimplementedAgentServer) NotifyMisbehavior(context.Context, *NotifyMisbehaviorRequest) (*Ack, error) {
+	return &Ack{}, nil
+}
+
+func (UnimplementedAgentServer) Intro(context.Context, *IntroRequest) (*IntroReply, error) {
+	return &IntroReply{}, nil
+}
+
+// unaryHandler adapts an AgentServer method to a grpc method handler.
+func unaryHandler[Req any, Reply any](name string, call func(srv AgentServer, ctx context.Context, req *Req) (*Reply, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := new(Req)
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(AgentServer), ctx, req.(*Req))
+			}
+			if interceptor == nil {
+				return handler(ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + agentServiceName + "/" + name}
+			return interceptor(ctx, req, info, handler)
+		},
+	}
+}
+
+var commentStreamDesc = grpc.StreamDesc{
+	StreamName:    "Comment",
+	ServerStreams: true,
+	Handler: func(srv interface{}, stream grpc.ServerStream) error {
+		req := new(CommentRequest)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		return srv.(AgentServer).Comment(req, commentStream{stream})
+	},
+}
+
+var agentServiceDesc = grpc.ServiceDesc{
+	ServiceName: agentServiceName,
+	HandlerType: (*AgentServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryHandler("VoteProposal", AgentServer.VoteProposal),
+		unaryHandler("VoteGrant", AgentServer.VoteGrant),
+		unaryHandler("AddProposal", AgentServer.AddProposal),
+		unaryHandler("AddDiscussion", AgentServer.AddDiscussion),
+		unaryHandler("NotifyActivation", AgentServer.NotifyActivation),
+		unaryHandler("NotifyMisbehavior", AgentServer.NotifyMisbehavior),
+		unaryHandler("Intro", AgentServer.Intro),
+	},
+	Streams:  []grpc.StreamDesc{commentStreamDesc},
+	Metadata: "agent.proto",
+}
+
+// NewAgentGRPCServer returns a grpc server encoding the agent.proto
+// messages, register an AgentServer on it with RegisterAgentServer.
+func NewAgentGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	return grpc.NewServer(append(opts, grpc.ForceServerCodec(wireCodec{}))...)
+}
+
+func RegisterAgentServer(s *grpc.Server, srv AgentServer) {
+	s.RegisterService(&agentServiceDesc, srv)
+}
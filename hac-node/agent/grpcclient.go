@@ -0,0 +1,246 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+var _ Client = &GRPCAgentClient{}
+
+// GRPCAgentClient is an agent backend calling an AgentService of agent.proto,
+// for agent frameworks written in other languages. The prompts carry the
+// same context as the ones sent to Eliza.
+type GRPCAgentClient struct {
+	voteContext
+	Url         string
+	conn        *grpc.ClientConn
+	logger      cmtlog.Logger
+	transcripts *TranscriptRecorder
+	drafts      CommentDraftStore
+}
+
+// NewGRPCAgentClient connects to the AgentService at url, host:port with an
+// optional grpc:// scheme. The connection is made on the first call.
+func NewGRPCAgentClient(url string, logger cmtlog.Logger) (*GRPCAgentClient, error) {
+	target := strings.TrimPrefix(url, "grpc://")
+	conn, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(wireCodec{})),
+	)
+	if err != nil {
+		return nil, err
+	}
+	l := logger.With("module", "grpc-agent")
+	return &GRPCAgentClient{
+		voteContext: voteContext{logger: l},
+		Url:         target,
+		conn:        conn,
+		logger:      l,
+	}, nil
+}
+
+// SetTranscripts keeps the transcripts of the voting decisions with r.
+func (g *GRPCAgentClient) SetTranscripts(r *TranscriptRecorder) {
+	g.transcripts = r
+}
+
+func (g *GRPCAgentClient) Close() error {
+	return g.conn.Close()
+}
+
+func (g *GRPCAgentClient) invoke(ctx context.Context, method string, req wireMessage, reply wireMessage) error {
+	if err := g.conn.Invoke(ctx, "/"+agentServiceName+"/"+method, req, reply); err != nil {
+		return grpcAgentError(method, err)
+	}
+	return nil
+}
+
+// grpcAgentError classifies the status of a failed call like the http
+// status of the other backends.
+func grpcAgentError(method string, err error) error {
+	class := ErrAgentUnavailable
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		class = ErrAgentDecisionTimeout
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.FailedPrecondition, codes.OutOfRange, codes.Unimplemented, codes.Unauthenticated:
+		class = ErrAgentRejected
+	case codes.DataLoss:
+		class = ErrMalformedAgentResponse
+	}
+	return &AgentError{Class: class, Method: method, Err: err}
+}
+
+// askVote calls a vote method. The exchange is kept as the transcript of the
+// vote of voter on the proposal or grant id.
+func (g *GRPCAgentClient) askVote(ctx context.Context, method string, req wireMessage, prompt string, kind string, id uint64, voter string) (Decision, error) {
+	transcript := Transcript{Kind: kind, RefId: id, Voter: voter, Method: method, Prompt: prompt}
+	started := time.Now()
+	var reply VoteReply
+	var decision Decision
+	err := g.invoke(ctx, method, req, &reply)
+	if err == nil {
+		decision, err = parseDecision(reply.Vote, reply.Confidence, reply.Reason)
+		if err != nil {
+			err = agentResponseError(method, 0, []byte(reply.Vote), err)
+		}
+	}
+	transcript.DurationMs = time.Since(started).Milliseconds()
+	transcript.Vote = string(decision.Vote)
+	transcript.Response = reply.Reason
+	if err != nil {
+		transcript.Error = err.Error()
+	}
+	if err := g.transcripts.record(transcript); err != nil {
+		g.logger.Error("save transcript fail", "err", err)
+	}
+	return decision, err
+}
+
+func (g *GRPCAgentClient) IfProcessProposal(ctx context.Context, proposer uint64, data []byte) (bool, error) {
+	return true, nil
+}
+
+func (g *GRPCAgentClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	g.logger.Info("IfAcceptProposal", "proposal", proposal, "voter", voter)
+	expedited := g.isExpedited(proposal)
+	if expedited {
+		ctx = WithExpedited(ctx)
+	}
+	req := &VoteProposalRequest{
+		ProposalId:       proposal,
+		ValidatorAddress: voter,
+		Text:             g.voteRequestText(proposal, voter, expedited),
+		Expedited:        expedited,
+	}
+	vote, err := g.askVote(ctx, "VoteProposal", req, req.Text, voteKindProposal, proposal, voter)
+	if err != nil {
+		return Decision{}, err
+	}
+	g.logger.Info("vote proposal", "proposal", proposal, "voter", voter, "vote", vote.Vote, "confidence", vote.Confidence, "reason", vote.Reason)
+	return vote, nil
+}
+
+func (g *GRPCAgentClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	g.logger.Info("IfGrantNewMember", "validator", validator, "proposer", proposer, "amount", amount, "statement", statement)
+	req := &VoteGrantRequest{
+		GrantId:          validator,
+		ValidatorAddress: proposer,
+		Amount:           amount,
+		Text:             g.grantRequestText(validator, proposer, amount, statement),
+	}
+	vote, err := g.askVote(ctx, "VoteGrant", req, req.Text, voteKindGrant, validator, "")
+	if err != nil {
+		return Decision{}, err
+	}
+	g.logger.Info("vote grant", "validator", validator, "proposer", proposer, "vote", vote.Vote, "confidence", vote.Confidence, "reason", vote.Reason)
+	return vote, nil
+}
+
+// CommentPropoal joins the chunks of the streamed comment.
+func (g *GRPCAgentClient) CommentPropoal(ctx context.Context, proposal uint64, speaker string) (string, error) {
+	comment, err := g.StreamComment(ctx, proposal, speaker, nil)
+	if err != nil {
+		return "", err
+	}
+	return comment, nil
+}
+
+// SetCommentDraftStore saves the comments while they are streamed.
+func (g *GRPCAgentClient) SetCommentDraftStore(store CommentDraftStore) {
+	g.drafts = store
+}
+
+// StreamComment calls onChunk with every chunk of the comment and saves it
+// as a draft while it arrives. The chunks received before the stream broke
+// are returned with the error.
+func (g *GRPCAgentClient) StreamComment(ctx context.Context, proposal uint64, speaker string, onChunk func(chunk string)) (string, error) {
+	g.logger.Info("CommentPropoal", "proposal", proposal, "speaker", speaker)
+	stream, err := g.conn.NewStream(ctx, &commentStreamDesc, "/"+agentServiceName+"/Comment")
+	if err != nil {
+		return "", grpcAgentError("Comment", err)
+	}
+	req := &CommentRequest{ProposalId: proposal, ValidatorAddress: speaker, Text: g.commentRequestText(ctx, proposal, speaker)}
+	if err := stream.SendMsg(req); err != nil {
+		return "", grpcAgentError("Comment", err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return "", grpcAgentError("Comment", err)
+	}
+	draft := newCommentDraft(g.drafts, proposal, speaker)
+	for {
+		var chunk CommentChunk
+		err := stream.RecvMsg(&chunk)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			err = grpcAgentError("Comment", err)
+			return draft.finish(err), err
+		}
+		draft.write(chunk.Text)
+		if onChunk != nil {
+			onChunk(chunk.Text)
+		}
+	}
+	comment := draft.finish(nil)
+	g.logger.Info("comment proposal", "proposal", proposal, "speaker", speaker, "comment", comment)
+	return comment, nil
+}
+
+func (g *GRPCAgentClient) AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error {
+	g.logger.Info("AddProposal", "proposal", proposal, "proposer", proposer, "text", text)
+	return g.invoke(ctx, "AddProposal", &AddProposalRequest{ProposalId: proposal, ValidatorAddress: proposer, Text: text}, &Ack{})
+}
+
+func (g *GRPCAgentClient) AddDiscussion(ctx context.Context, proposal uint64, speaker string, text string) error {
+	g.logger.Info("AddDiscussion", "proposal", proposal, "speaker", speaker, "text", text)
+	return g.invoke(ctx, "AddDiscussion", &AddDiscussionRequest{ProposalId: proposal, ValidatorAddress: speaker, Text: text}, &Ack{})
+}
+
+// AddDiscussions sends msgs one by one, the agent service has no batch call.
+// It stops at the first failure, the discussions sent before it are sent again
+// with the batch.
+func (g *GRPCAgentClient) AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error {
+	for _, msg := range msgs {
+		if err := g.AddDiscussion(ctx, msg.Proposal, msg.Speaker, msg.Text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GRPCAgentClient) NotifyActivation(ctx context.Context, proposal uint64, activationHeight uint64) error {
+	g.logger.Info("NotifyActivation", "proposal", proposal, "activationHeight", activationHeight)
+	return g.invoke(ctx, "NotifyActivation", &NotifyActivationRequest{ProposalId: proposal, ActivationHeight: activationHeight}, &Ack{})
+}
+
+func (g *GRPCAgentClient) NotifyMisbehavior(ctx context.Context, validator string, kind string, height uint64) error {
+	g.logger.Info("NotifyMisbehavior", "validator", validator, "kind", kind, "height", height)
+	return g.invoke(ctx, "NotifyMisbehavior", &NotifyMisbehaviorRequest{ValidatorAddress: validator, Kind: kind, Height: height}, &Ack{})
+}
+
+func (g *GRPCAgentClient) GetSelfIntro(ctx context.Context) (string, error) {
+	var reply IntroReply
+	if err := g.invoke(ctx, "Intro", &IntroRequest{}, &reply); err != nil {
+		return "", err
+	}
+	return reply.Character, nil
+}
+
+func (g *GRPCAgentClient) GetHeadPhoto(ctx context.Context) (string, error) {
+	var reply IntroReply
+	if err := g.invoke(ctx, "Intro", &IntroRequest{}, &reply); err != nil {
+		return "", err
+	}
+	return reply.HeadPhoto, nil
+}
@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// agentHealthRetention is how long the probes of the agent are kept.
+const agentHealthRetention = 7 * 24 * time.Hour
+
+// AgentHealthCheck records a probe of the agent.
+type AgentHealthCheck struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Url       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	LatencyMs int64     `json:"latency_ms"`
+	Error     string    `json:"error"`
+	CheckedAt time.Time `gorm:"index" json:"checked_at"`
+}
+
+// HealthStore persists the probes of the agent.
+type HealthStore interface {
+	SaveAgentHealthCheck(check *AgentHealthCheck) error
+}
+
+// Pinger is an agent backend that can be probed.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+var _ Pinger = &ElizaClient{}
+
+// Ping lists the agents of the service, it fails when the service is down.
+func (e *ElizaClient) Ping(ctx context.Context) error {
+	_, err := e.GetAgents(ctx)
+	return err
+}
+
+// AgentHealth probes the agent every interval and tells whether it answered
+// the last probe, so a caller can skip blocking on an agent that is down.
+type AgentHealth struct {
+	url      string
+	pinger   Pinger
+	interval time.Duration
+	timeout  time.Duration
+	logger   cmtlog.Logger
+	store    HealthStore
+
+	mtx  sync.RWMutex
+	last *AgentHealthCheck
+}
+
+// NewAgentHealth probes pinger, the agent at url, every interval, a probe
+// failing after timeout.
+func NewAgentHealth(url string, pinger Pinger, interval time.Duration, timeout time.Duration, logger cmtlog.Logger) *AgentHealth {
+	return &AgentHealth{
+		url:      url,
+		pinger:   pinger,
+		interval: interval,
+		timeout:  timeout,
+		logger:   logger.With("module", "agent-health"),
+	}
+}
+
+func (h *AgentHealth) SetHealthStore(store HealthStore) {
+	h.store = store
+}
+
+// IsHealthy tells whether the agent answered the last probe. The agent is
+// healthy until a probe fails.
+func (h *AgentHealth) IsHealthy() bool {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	return h.last == nil || h.last.Healthy
+}
+
+// Last returns the last probe, nil before the first one.
+func (h *AgentHealth) Last() *AgentHealthCheck {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	return h.last
+}
+
+// Run probes the agent at once and then every interval until ctx is done.
+func (h *AgentHealth) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		h.probe(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *AgentHealth) probe(ctx context.Context) {
+	probeCtx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	started := time.Now()
+	err := h.pinger.Ping(probeCtx)
+	if ctx.Err() != nil {
+		return
+	}
+	check := &AgentHealthCheck{
+		Url:       h.url,
+		Healthy:   err == nil,
+		LatencyMs: time.Since(started).Milliseconds(),
+		CheckedAt: started.UTC(),
+	}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	h.mtx.Lock()
+	previous := h.last
+	h.last = check
+	h.mtx.Unlock()
+	if previous == nil || previous.Healthy != check.Healthy {
+		if check.Healthy {
+			h.logger.Info("agent available", "url", h.url, "latency_ms", check.LatencyMs)
+		} else {
+			h.logger.Error("agent unavailable", "url", h.url, "err", err)
+		}
+	}
+	available := 0.0
+	if check.Healthy {
+		available = 1
+	}
+	agentAvailable.WithLabelValues(h.url).Set(available)
+	agentProbeLatency.WithLabelValues(h.url).Set(time.Since(started).Seconds())
+	if h.store != nil {
+		if err := h.store.SaveAgentHealthCheck(check); err != nil {
+			h.logger.Error("save agent health check fail", "err", err)
+		}
+	}
+}
+
+var activeHealth struct {
+	sync.RWMutex
+	health *AgentHealth
+}
+
+// SetActiveHealth makes h the health of the agent consulted by AgentHealthy.
+func SetActiveHealth(h *AgentHealth) {
+	activeHealth.Lock()
+	defer activeHealth.Unlock()
+	activeHealth.health = h
+}
+
+// ActiveHealth returns the health set by SetActiveHealth, nil when the agent
+// is not probed.
+func ActiveHealth() *AgentHealth {
+	activeHealth.RLock()
+	defer activeHealth.RUnlock()
+	return activeHealth.health
+}
+
+// AgentHealthy tells whether the agent answered its last probe, true when
+// the agent is not probed.
+func AgentHealthy() bool {
+	h := ActiveHealth()
+	return h == nil || h.IsHealthy()
+}
+
+// SaveAgentHealthCheck stores check and drops the probes older than
+// agentHealthRetention.
+func (c *ChainIndexer) SaveAgentHealthCheck(check *AgentHealthCheck) error {
+	if err := c.db.Create(check).Error; err != nil {
+		return err
+	}
+	return c.db.Where("checked_at < ?", time.Now().Add(-agentHealthRetention).UTC()).Delete(&AgentHealthCheck{}).Error
+}
+
+func (c *ChainIndexer) getAgentHealthChecks(page int, pageSize int) (Page[AgentHealthCheck], error) {
+	return paginate[AgentHealthCheck](c.db.Model(&AgentHealthCheck{}), "id desc", page, pageSize)
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// HTTPConfig configures the http client calling the agent. A zero timeout
+// does not bound that step, a call is then bounded by its context only. An
+// empty Proxy uses the proxy of the environment. CAFile is a pem bundle
+// trusted instead of the system roots, CertFile and KeyFile the client
+// certificate presented to an agent requiring mutual tls, and ServerName the
+// name checked in the agent certificate when it differs from the url host.
+type HTTPConfig struct {
+	Timeout        time.Duration
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	KeepAlive      time.Duration
+	Proxy          string
+	CAFile         string
+	CertFile       string
+	KeyFile        string
+	ServerName     string
+}
+
+// NewHTTPClient returns the http client of cfg. ReadTimeout bounds the wait
+// for the response headers once the request is sent, KeepAlive how long an
+// idle connection to the agent is kept, a negative KeepAlive disables
+// keep-alive.
+func NewHTTPClient(cfg HTTPConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: cfg.KeepAlive}
+	transport.DialContext = dialer.DialContext
+	transport.ResponseHeaderTimeout = cfg.ReadTimeout
+	if cfg.KeepAlive < 0 {
+		transport.DisableKeepAlives = true
+	} else if cfg.KeepAlive > 0 {
+		transport.IdleConnTimeout = cfg.KeepAlive
+	}
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agent proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	tlsConfig, err := agentTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Transport: transport, Timeout: cfg.Timeout}, nil
+}
+
+// agentTLSConfig returns the tls config of cfg, nil when it sets none.
+func agentTLSConfig(cfg HTTPConfig) (*tls.Config, error) {
+	if cfg.CAFile == "" && cfg.CertFile == "" && cfg.KeyFile == "" && cfg.ServerName == "" {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: cfg.ServerName}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agent ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid agent ca: no certificate in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("invalid agent client certificate: both the cert and key files are required")
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid agent client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// httpClientOrDefault is c, http.DefaultClient when c is nil.
+func httpClientOrDefault(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
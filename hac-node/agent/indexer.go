@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	app_config "github.com/calehh/hac-app/config"
@@ -18,25 +20,59 @@ import (
 	abci "github.com/cometbft/cometbft/abci/types"
 	cmtlog "github.com/cometbft/cometbft/libs/log"
 	comethttp "github.com/cometbft/cometbft/rpc/client/http"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
 	"github.com/cometbft/cometbft/store"
 	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/sqlite"
 )
 
 type ChainIndexer struct {
-	logger        cmtlog.Logger
-	Url           string
-	Height        int64
-	db            *gorm.DB
-	cli           *comethttp.HTTP
-	eventHandlers map[string]eventHandler
-	elizaClients  map[string]Client
-	BlockStore    *store.BlockStore
-	appConfig     *app_config.Config
-	pv            *crypto.PV
-	localAddress  string
-	ChainId       string
-	chainUrl      string
+	logger         cmtlog.Logger
+	Url            string
+	Height         int64
+	db             *gorm.DB
+	cli            *comethttp.HTTP
+	eventHandlers  map[string]eventHandler
+	elizaClients   map[string]Client
+	BlockStore     *store.BlockStore
+	appConfig      *app_config.Config
+	pv             *crypto.PV
+	localAddress   string
+	rpcBackoff     *backoff
+	maxRpcFailures int
+	ChainId        string
+	chainUrl       string
+	verifyCli      *comethttp.HTTP
+	verifyStrict   bool
+	importState    bool
+	audit          auditor
+	maintenance    maintainer
+	eventStats     *eventStats
+	indexOnly      bool
+	feed           *eventFeed
+	indexed        *heightWatch
+	notifier       *notifier
+	social         *socialPoster
+	ipfs           *ipfsClient
+	bridge         *evmBridge
+	webhook        proposalWebhook
+	pushSecret     string
+	apiKeys        apiKeyAuth
+	replicas       []*ChainIndexer
+	nextReplica    atomic.Uint64
+	digest         *digestMailer
+	bus            *eventBus
+	leader         *leaderElector
+	outboxWake     chan struct{}
+	anchorInterval time.Duration
+	proposer       agentProposer
+	activateNotice uint64
+	expediteWindow time.Duration
+	treasuryFunds  float64
+	uptimeWindow   uint64
+	debate         debate
+	commentary     commentSchedule
+	summarizer     ProposalSummarizer
+	typeHandlers   map[string]proposalHandler
 }
 
 func NewChainIndexer(logger cmtlog.Logger, dbPath string, chainUrl string, bs *store.BlockStore, appConfig *app_config.Config) (*ChainIndexer, error) {
@@ -45,17 +81,33 @@ func NewChainIndexer(logger cmtlog.Logger, dbPath string, chainUrl string, bs *s
 	if err != nil {
 		return nil, err
 	}
-	db, err := gorm.Open("sqlite3", dbPath)
+	db, err := openIndexDB(dbPath)
 	if err != nil {
 		return nil, err
 	}
-	if err := db.AutoMigrate(&Grant{}, &Discussion{}, &Proposal{}, &Height{}, &GrantVote{}, &ProposalVote{}, &ValidatorAgent{}).Error; err != nil {
+	if err := db.AutoMigrate(indexerModels...).Error; err != nil {
 		return nil, err
 	}
-	h := Height{Id: 1}
-	if err = db.First(&h).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+	orphans, err := countOrphans(db)
+	if err != nil {
 		return nil, err
 	}
+	for _, o := range orphans {
+		if o.rows > 0 {
+			logger.Info("rows referencing unindexed parents", "table", o.fk.table, "column", o.fk.column, "parent", o.fk.parentTable, "rows", o.rows)
+		}
+	}
+	h := Height{Id: 1}
+	fresh := false
+	if err = db.First(&h).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		fresh = true
+	}
 
 	if DiscussionRate > 0 {
 		DiscussionTrigger = rand.New(rand.NewSource(time.Now().UnixNano())).Intn(DiscussionRate)
@@ -88,30 +140,203 @@ func NewChainIndexer(logger cmtlog.Logger, dbPath string, chainUrl string, bs *s
 		localAddress:  localAddress,
 		chainUrl:      chainUrl,
 		ChainId:       chainId,
+		rpcBackoff:    newBackoff(500*time.Millisecond, 30*time.Second),
+		eventStats:    newEventStats(),
+		feed:          newEventFeed(),
+		outboxWake:    make(chan struct{}, 1),
+	}
+	c.activateNotice = ActivationNoticeDefault
+	if appConfig.App != nil && appConfig.App.ActivationNotice > 0 {
+		c.activateNotice = uint64(appConfig.App.ActivationNotice)
+	}
+	c.expediteWindow = ExpeditedWindowDefault
+	if appConfig.App != nil && appConfig.App.ExpeditedWindow > 0 {
+		c.expediteWindow = time.Duration(appConfig.App.ExpeditedWindow) * time.Hour
+	}
+	if appConfig.App != nil {
+		c.treasuryFunds = appConfig.App.TreasuryBalance
+	}
+	c.uptimeWindow = UptimeWindowDefault
+	if appConfig.App != nil && appConfig.App.UptimeWindow > 0 {
+		c.uptimeWindow = uint64(appConfig.App.UptimeWindow)
+	}
+	if appConfig.App != nil && appConfig.App.DebateRounds > 0 {
+		c.debate.rounds = appConfig.App.DebateRounds
+		c.debate.roundBlocks = DebateRoundBlocksDefault
+		if appConfig.App.DebateRoundBlocks > 0 {
+			c.debate.roundBlocks = uint64(appConfig.App.DebateRoundBlocks)
+		}
+	}
+	c.commentary = commentSchedule{minGap: CommentMinGapDefault, maxFollowUps: CommentMaxFollowUpsDefault}
+	if appConfig.App != nil {
+		c.commentary.interval = uint64(appConfig.App.CommentInterval)
+		c.commentary.onDiscussion = appConfig.App.CommentOnDiscussion
+		if appConfig.App.CommentMinGap > 0 {
+			c.commentary.minGap = uint64(appConfig.App.CommentMinGap)
+		}
+		if appConfig.App.CommentMaxFollowUps > 0 {
+			c.commentary.maxFollowUps = appConfig.App.CommentMaxFollowUps
+		}
+	}
+	if fresh && appConfig.App != nil && appConfig.App.StartHeight > 1 {
+		c.Height = appConfig.App.StartHeight
+		c.importState = appConfig.App.ImportState
+	}
+	c.indexed = newHeightWatch(uint64(c.Height - 1))
+	if appConfig.App != nil {
+		c.indexOnly = appConfig.App.IndexOnly
+	}
+	if appConfig.App != nil && appConfig.App.AuditInterval > 0 {
+		c.audit = auditor{
+			interval: time.Duration(appConfig.App.AuditInterval) * time.Second,
+			sample:   appConfig.App.AuditSample,
+			repair:   appConfig.App.AuditRepair,
+		}
+		if c.audit.sample <= 0 {
+			c.audit.sample = 20
+		}
+	}
+	if appConfig.App != nil && appConfig.App.AnchorInterval > 0 {
+		if !appConfig.App.AgentTranscripts {
+			return nil, errors.New("decision_anchor_interval needs agent_transcripts, the transcripts are the decision log anchored")
+		}
+		c.anchorInterval = time.Duration(appConfig.App.AnchorInterval) * time.Second
+	}
+	if appConfig.App != nil && appConfig.App.AgentProposeInterval > 0 {
+		c.proposer.interval = time.Duration(appConfig.App.AgentProposeInterval) * time.Second
+	}
+	if appConfig.App != nil && appConfig.App.MaintenanceInterval > 0 {
+		c.maintenance.interval = time.Duration(appConfig.App.MaintenanceInterval) * time.Second
+	}
+	if appConfig.App != nil {
+		c.notifier, err = newNotifier(c.logger, appConfig.App.Notifiers)
+		if err != nil {
+			return nil, err
+		}
+		c.social, err = newSocialPoster(appConfig.App)
+		if err != nil {
+			return nil, err
+		}
+		c.bridge, err = newEvmBridge(appConfig.App)
+		if err != nil {
+			return nil, err
+		}
+		c.digest, err = newDigestMailer(appConfig.App)
+		if err != nil {
+			return nil, err
+		}
+		c.bus, err = newEventBus(c.logger, appConfig.App, chainId)
+		if err != nil {
+			return nil, err
+		}
+		c.leader, err = newLeaderElector(c.logger, appConfig.App, db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if appConfig.App != nil {
+		c.webhook.secret = appConfig.App.ProposalWebhookSecret
+		c.pushSecret = appConfig.App.AgentPushSecret
+		c.apiKeys.adminKey = appConfig.App.ApiAdminKey
+		c.apiKeys.requireKey = appConfig.App.ApiRequireKey
+	}
+	if appConfig.App != nil && appConfig.App.IpfsApiUrl != "" {
+		c.ipfs = newIpfsClient(appConfig.App.IpfsApiUrl, appConfig.App.IpfsToken)
+	}
+	if appConfig.App != nil && appConfig.App.VerifyRpcUrl != "" {
+		verifyCli, err := comethttp.New(appConfig.App.VerifyRpcUrl, "/websocket")
+		if err != nil {
+			return nil, err
+		}
+		c.verifyCli = verifyCli
+		c.verifyStrict = appConfig.App.VerifyRpcStrict
 	}
 
-	c.eventHandlers = map[string]eventHandler{
+	if appConfig.App != nil {
+		for _, dsn := range appConfig.App.DatabaseReplicaUrls {
+			replica, err := openIndexDB(dsn)
+			if err != nil {
+				return nil, fmt.Errorf("open replica db: %w", err)
+			}
+			c.replicas = append(c.replicas, &ChainIndexer{logger: c.logger, db: replica, ChainId: chainId, treasuryFunds: c.treasuryFunds})
+		}
+	}
+
+	c.eventHandlers = c.newEventHandlers()
+	c.typeHandlers = c.newProposalHandlers()
+	return &c, nil
+}
+
+// reader returns the indexer to run api queries on: a read-only view of the
+// next replica db in turn, or the indexer itself without replicas. Replicas
+// lag behind the primary, so reads feeding indexing, long polls waiting for
+// the indexed height and node-local admin data stay on the primary.
+func (c *ChainIndexer) reader() *ChainIndexer {
+	if len(c.replicas) == 0 {
+		return c
+	}
+	n := c.nextReplica.Add(1)
+	return c.replicas[n%uint64(len(c.replicas))]
+}
+
+func (c *ChainIndexer) newEventHandlers() map[string]eventHandler {
+	return map[string]eventHandler{
 		hac_types.EventGrantType:          c.handleEventGrant,
 		hac_types.EventDiscussionType:     c.handleEventDiscussion,
 		hac_types.EventSettleProposalType: c.handleEventSettleProposal,
 		hac_types.EventProposalType:       c.handleEventProposal,
+		hac_types.EventUnStakeType:        c.handleEventUnStake,
+	}
+}
+
+// SupportedEventTypes returns the sorted types of the block events indexed,
+// events of other types are skipped.
+func SupportedEventTypes() []string {
+	handlers := (&ChainIndexer{}).newEventHandlers()
+	types := make([]string, 0, len(handlers))
+	for t := range handlers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+type eventHandler func(ctx context.Context, event abci.Event, height int64, blockTime time.Time, txHash string) error
+
+// proposalHandler indexes what a payload type adds to a new proposal, in the
+// transaction saving it and before it is saved.
+type proposalHandler func(tx *gorm.DB, proposal *Proposal, height uint64, blockTime time.Time) error
+
+func (c *ChainIndexer) newProposalHandlers() map[string]proposalHandler {
+	return map[string]proposalHandler{
+		PayloadTypeExpedited: c.handleExpeditedProposal,
+		PayloadTypeSpend:     c.handleSpendProposal,
 	}
-	return &c, nil
 }
 
-type eventHandler func(ctx context.Context, event abci.Event, height int64)
+func (c *ChainIndexer) handleEvent(ctx context.Context, event abci.Event, height int64, blockTime time.Time, txHash string) {
+	result := c.dispatchEvent(ctx, event, height, blockTime, txHash)
+	c.eventStats.record(height, event.Type, result)
+	c.feed.publish(event, height, blockTime, txHash, result)
+}
 
-func (c *ChainIndexer) handleEvent(ctx context.Context, event abci.Event, height int64) {
-	if h, ok := c.eventHandlers[event.Type]; ok {
-		h(ctx, event, height)
+func (c *ChainIndexer) dispatchEvent(ctx context.Context, event abci.Event, height int64, blockTime time.Time, txHash string) string {
+	h, ok := c.eventHandlers[event.Type]
+	if !ok {
+		return eventResultSkipped
 	}
+	if err := h(ctx, event, height, blockTime, txHash); err != nil {
+		c.logger.Error("handle event fail", "type", event.Type, "height", height, "err", err)
+		c.saveFailedEvent(event, height, blockTime, txHash, err)
+		return eventResultFailed
+	}
+	return eventResultHandled
 }
 
-func (c *ChainIndexer) handleEventGrant(ctx context.Context, event abci.Event, height int64) {
+func (c *ChainIndexer) handleEventGrant(ctx context.Context, event abci.Event, height int64, blockTime time.Time, txHash string) error {
 	ev := hac_types.ParseEventGrant(event)
 	if ev == nil {
-		c.logger.Error("decode event fail", "event", event)
-		return
+		return errDecodeEvent
 	}
 	grant := Grant{
 		Id:              ev.Validator,
@@ -121,17 +346,21 @@ func (c *ChainIndexer) handleEventGrant(ctx context.Context, event abci.Event, h
 		Proposer:        ev.ProposerIndex,
 		ProposerAddress: ev.ProposerAddress,
 		Grant:           ev.Grant,
+		BlockTime:       blockTime,
 	}
 	if err := c.db.Save(&grant).Error; err != nil {
-		c.logger.Error("save account fail", "err", err)
+		return err
 	}
+	c.bus.emit(BusGrant, grant.Id, grant.Height, blockTime, txHash, grant)
 
 	val := ValidatorAgent{
-		Id:       ev.Validator,
-		Address:  ev.Address,
-		Stake:    ev.Amount,
-		AgentUrl: ev.AgentUrl,
-		Name:     ev.Name,
+		Id:              ev.Validator,
+		Address:         ev.Address,
+		Stake:           ev.Amount,
+		AgentUrl:        ev.AgentUrl,
+		Name:            ev.Name,
+		LastGrantHeight: uint64(height),
+		Active:          ev.Grant,
 	}
 
 	cli, err := NewElizaClient(ev.AgentUrl, c.logger)
@@ -146,81 +375,193 @@ func (c *ChainIndexer) handleEventGrant(ctx context.Context, event abci.Event, h
 	}
 
 	if err := c.db.Save(&val).Error; err != nil {
-		c.logger.Error("save validator fail", "err", err)
+		return err
 	}
+	return recordStake(c.db, val.Id, val.Address, uint64(height), val.Stake)
 }
 
-func (c *ChainIndexer) handleEventDiscussion(ctx context.Context, event abci.Event, height int64) {
+func (c *ChainIndexer) handleEventUnStake(ctx context.Context, event abci.Event, height int64, blockTime time.Time, txHash string) error {
+	ev := hac_types.ParseEventUnStake(event)
+	if ev == nil {
+		return errDecodeEvent
+	}
+	var val ValidatorAgent
+	if err := c.db.First(&val, ev.Validator).Error; err != nil {
+		return err
+	}
+	if ev.Amount >= val.Stake {
+		val.Stake = 0
+		val.Active = false
+	} else {
+		val.Stake -= ev.Amount
+	}
+	if err := c.db.Save(&val).Error; err != nil {
+		return err
+	}
+	if err := recordStake(c.db, val.Id, val.Address, uint64(height), val.Stake); err != nil {
+		return err
+	}
+	c.bus.emit(BusUnstake, val.Id, uint64(height), blockTime, txHash, val)
+	return nil
+}
+
+func (c *ChainIndexer) handleEventDiscussion(ctx context.Context, event abci.Event, height int64, blockTime time.Time, txHash string) error {
 	ev := hac_types.DecodeEventDiscussion(event)
 	if ev == nil {
-		c.logger.Error("decode event fail", "event", event)
-		return
+		return errDecodeEvent
 	}
 	speaker, err := c.getValidatorByAddress(ev.SpeakerAddress)
 	if err != nil {
-		c.logger.Error("get validator fail", "err", err)
-		return
+		return err
 	}
 	if speaker.Id == 0 {
-		c.logger.Error("speaker not found", "address", ev.SpeakerAddress)
-		return
+		return fmt.Errorf("speaker %s not found", ev.SpeakerAddress)
+	}
+	// a replayed height must not duplicate discussions already indexed
+	var existing Discussion
+	if txHash != "" {
+		if err := c.db.Where("tx_hash = ?", txHash).First(&existing).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
 	}
 	discusstion := Discussion{
-		Id:              0,
+		Id:              existing.Id,
 		Proposal:        ev.Proposal,
 		SpeakerIndex:    ev.Speaker,
 		SpeakerAddress:  ev.SpeakerAddress,
 		SpeakerName:     speaker.Name,
 		Data:            string(ev.Data),
 		Height:          uint64(height),
-		CreateTimestamp: time.Now().Unix(),
+		CreateTimestamp: blockTime.Unix(),
+		BlockTime:       blockTime,
+		TxHash:          txHash,
 	}
-	if err := c.db.Save(&discusstion).Error; err != nil {
-		c.logger.Error("save discusstion fail", "err", err)
-	}
-	err = ElizaCli.AddDiscussion(ctx, ev.Proposal, ev.SpeakerAddress, string(ev.Data))
+	err = c.db.Transaction(func(tx *gorm.DB) error {
+		if existing.Id == 0 {
+			if err := c.checkDiscussionLifecycle(tx, ev.Proposal, uint64(height), txHash); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&discusstion).Error; err != nil {
+			return err
+		}
+		return c.enqueueAgentCall(tx, eventKey(event, height, txHash), AgentOutbox{
+			Method:    outboxAddDiscussion,
+			Proposal:  ev.Proposal,
+			Address:   ev.SpeakerAddress,
+			Text:      string(ev.Data),
+			Height:    uint64(height),
+			Expedited: c.IsExpedited(ev.Proposal),
+		})
+	})
 	if err != nil {
-		c.logger.Error("add discussion fail", "err", err)
+		return err
+	}
+	c.wakeOutbox()
+	if existing.Id == 0 {
+		c.bus.emit(BusDiscussion, discusstion.Proposal, discusstion.Height, blockTime, txHash, discusstion)
 	}
+	return nil
 }
 
-func (c *ChainIndexer) handleEventSettleProposal(ctx context.Context, event abci.Event, height int64) {
+func (c *ChainIndexer) handleEventSettleProposal(ctx context.Context, event abci.Event, height int64, blockTime time.Time, txHash string) error {
 	ev := hac_types.DecodeEventSettleProposal(event)
 	if ev == nil {
-		c.logger.Error("decode event fail", "event", event)
-		return
+		return errDecodeEvent
 	}
 	var proposal Proposal
 	if err := c.db.First(&proposal, ev.Proposal).Error; err != nil {
-		c.logger.Error("get proposal fail", "err", err)
-		return
+		return err
+	}
+	status := ProposalStatus(ev.State)
+	if status == ProposalStatusRejected && proposal.ExpireTimestamp > 0 && blockTime.Unix() > proposal.ExpireTimestamp {
+		status = ProposalStatusExpired
+	}
+	if !status.Valid() {
+		return fmt.Errorf("invalid status %d of proposal %d", ev.State, ev.Proposal)
 	}
-	proposal.Status = uint64(ev.State)
 	proposal.SettleHeight = uint64(height)
-	if err := c.db.Save(&proposal).Error; err != nil {
-		c.logger.Error("save proposal fail", "err", err)
+	var changes []ProposalStatusChange
+	err := c.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		changes, err = c.transitionProposal(tx, &proposal, LifecycleEventSettlement,
+			[]ProposalStatus{ProposalStatusVoting, status}, uint64(height), blockTime, txHash)
+		if err != nil {
+			return err
+		}
+		if status != ProposalStatusPassed {
+			if err := c.cancelActivation(tx, proposal.Id, uint64(height)); err != nil {
+				return err
+			}
+		}
+		if err := c.settleSpend(tx, proposal, uint64(height), blockTime); err != nil {
+			return err
+		}
+		if status.Settled() && c.summarizer != nil {
+			err := c.enqueueAgentCall(tx, eventKey(event, height, txHash), AgentOutbox{
+				Method:    outboxSummarizeProposal,
+				Proposal:  proposal.Id,
+				Height:    uint64(height),
+				Expedited: proposal.Expedited,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Save(&proposal).Error
+	})
+	if err != nil {
+		return err
+	}
+	c.bus.emit(BusSettlement, proposal.Id, uint64(height), blockTime, txHash, BusSettlementData{Proposal: proposal, Change: changes[len(changes)-1]})
+	c.notifier.notify(Notification{
+		Event:      NotifyProposalSettled,
+		Kind:       voteKindProposal,
+		ProposalId: proposal.Id,
+		Title:      proposal.Title,
+		Proposer:   proposal.ProposerName,
+		Status:     status.String(),
+		Expedited:  proposal.Expedited,
+		Height:     uint64(height),
+		BlockTime:  blockTime,
+	})
+	if status.Settled() {
+		c.social.announce(proposal, blockTime)
+		c.wakeOutbox()
 	}
+	return nil
 }
 
-func (c *ChainIndexer) handleEventProposal(ctx context.Context, event abci.Event, height int64) {
+func (c *ChainIndexer) handleEventProposal(ctx context.Context, event abci.Event, height int64, blockTime time.Time, txHash string) error {
 	ev := hac_types.DecodeEventProposal(event)
 	if ev == nil {
-		c.logger.Error("decode event fail", "event", event)
-		return
+		return errDecodeEvent
+	}
+	title, summary := parseProposalPayload(ev.Data, ev.Title)
+	payloadType, payloadData, err := validatePayload(ev.Data)
+	payloadError := ""
+	if err != nil {
+		c.logger.Info("invalid proposal payload", "proposal", ev.ProposalIndex, "err", err)
+		payloadError = err.Error()
 	}
-	now := time.Now()
 	proposal := Proposal{
-		Id:              ev.ProposalIndex,
-		ProposerIndex:   ev.Proposer,
-		ProposerAddress: ev.ProposerAddress,
-		Data:            string(ev.Data),
-		NewHeight:       uint64(height),
-		Status:          ev.Status,
-		Title:           ev.Title,
-		Link:            ev.Link,
-		ImageUrl:        ev.ImageUrl,
-		CreateTimestamp: now.Unix(),
-		ExpireTimestamp: now.Add(time.Hour * 24 * 365).Unix(),
+		Id:               ev.ProposalIndex,
+		ProposerIndex:    ev.Proposer,
+		ProposerAddress:  ev.ProposerAddress,
+		Data:             string(ev.Data),
+		NewHeight:        uint64(height),
+		Status:           ProposalStatusProposed,
+		ActivationHeight: parseActivationHeight(ev.Data),
+		Title:            title,
+		Summary:          summary,
+		PayloadType:      payloadType,
+		PayloadData:      payloadData,
+		PayloadError:     payloadError,
+		Link:             ev.Link,
+		ImageUrl:         ev.ImageUrl,
+		CreateTimestamp:  blockTime.Unix(),
+		ExpireTimestamp:  blockTime.Add(time.Hour * 24 * 365).Unix(),
+		BlockTime:        blockTime,
 	}
 	validator, err := c.getValidatorByAddress(ev.ProposerAddress)
 	if err != nil {
@@ -231,35 +572,104 @@ func (c *ChainIndexer) handleEventProposal(ctx context.Context, event abci.Event
 	}
 	proposal.ProposerName = validator.Name
 
-	if err := c.db.Save(&proposal).Error; err != nil {
-		c.logger.Error("save proposal fail", "err", err)
-	}
-	err = ElizaCli.AddProposal(ctx, ev.ProposalIndex, ev.ProposerAddress, string(ev.Data))
-	if err != nil {
-		c.logger.Error("add proposal fail", "err", err)
-	}
-	comment, err := ElizaCli.CommentPropoal(ctx, ev.ProposalIndex, ev.ProposerAddress)
+	key := eventKey(event, height, txHash)
+	err = c.db.Transaction(func(tx *gorm.DB) error {
+		var existing Proposal
+		if err := tx.First(&existing, proposal.Id).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if existing.Id != 0 && existing.NewHeight != proposal.NewHeight {
+			err := c.recordAnomaly(tx, ProposalAnomaly{
+				Proposal:   proposal.Id,
+				Event:      LifecycleEventProposal,
+				FromStatus: existing.Status,
+				ToStatus:   ProposalStatus(ev.Status),
+				Height:     uint64(height),
+				TxHash:     txHash,
+				Reason:     fmt.Sprintf("proposal proposed again, first at height %d", existing.NewHeight),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		_, err := c.transitionProposal(tx, &proposal, LifecycleEventProposal,
+			[]ProposalStatus{ProposalStatus(ev.Status)}, uint64(height), blockTime, txHash)
+		if err != nil {
+			return err
+		}
+		if h, ok := c.typeHandlers[payloadType]; ok && payloadError == "" {
+			if err := h(tx, &proposal, uint64(height), blockTime); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&proposal).Error; err != nil {
+			return err
+		}
+		if err := c.scheduleActivation(tx, proposal, uint64(height)); err != nil {
+			return err
+		}
+		if c.summarizer != nil && needsDataSummary(proposal) {
+			// queued first, so the comment asked next gets the summary
+			err := c.enqueueAgentCall(tx, key, AgentOutbox{
+				Method:    outboxSummarizeData,
+				Proposal:  ev.ProposalIndex,
+				Height:    uint64(height),
+				Expedited: proposal.Expedited,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		err = c.enqueueAgentCall(tx, key, AgentOutbox{
+			Method:    outboxAddProposal,
+			Proposal:  ev.ProposalIndex,
+			Address:   ev.ProposerAddress,
+			Text:      string(ev.Data),
+			Height:    uint64(height),
+			Expedited: proposal.Expedited,
+		})
+		if err != nil {
+			return err
+		}
+		return c.enqueueAgentCall(tx, key, AgentOutbox{
+			Method:    outboxCommentProposal,
+			Proposal:  ev.ProposalIndex,
+			Address:   ev.ProposerAddress,
+			Height:    uint64(height),
+			Expedited: proposal.Expedited,
+		})
+	})
 	if err != nil {
-		c.logger.Error("comment proposal fail", "err", err)
-	} else {
-		c.logger.Info("comment proposal", "comment", comment)
-	}
+		return err
+	}
+	c.wakeOutbox()
+	c.bus.emit(BusProposal, proposal.Id, uint64(height), blockTime, txHash, proposal)
+	c.notifier.notify(Notification{
+		Event:      NotifyProposalCreated,
+		Kind:       voteKindProposal,
+		ProposalId: proposal.Id,
+		Title:      proposal.Title,
+		Proposer:   proposal.ProposerName,
+		Status:     proposal.Status.String(),
+		Expedited:  proposal.Expedited,
+		Height:     uint64(height),
+		BlockTime:  blockTime,
+	})
+	return nil
 }
 
 func (c *ChainIndexer) handleVote(ctx context.Context, height int64) error {
 	res, err := c.cli.Commit(ctx, &height)
 	if err != nil {
 		c.logger.Error("get Commit fail", "err", err)
-		if !c.cli.IsRunning() {
-			c.cli.Stop()
-			c.cli, err = comethttp.New(c.Url, "/websocket")
-			if err != nil {
-				c.logger.Error("reconnect fail", "err", err)
-				return err
-			}
-		}
+		c.reconnect()
+		return err
 	}
 	voteHeight := res.Height
+	voteTime := res.Header.Time
+	if err := c.recordSignatures(ctx, voteHeight, res.Commit.Signatures, voteTime); err != nil {
+		return err
+	}
 	// new proposal
 	newProposel := Proposal{}
 	if err := c.db.Where("new_height = ?", voteHeight).First(&newProposel).Error; err != nil {
@@ -276,21 +686,19 @@ func (c *ChainIndexer) handleVote(ctx context.Context, height int64) error {
 			if acc == nil {
 				return fmt.Errorf("commit sig address not exist address:%s", v.ValidatorAddress.String())
 			}
-			if err := c.db.Where("height = ? And voter_index = ?", voteHeight, acc.Index).First(&ProposalVote{}).Error; err != nil {
-				if err != gorm.ErrRecordNotFound {
-					return err
-				}
-				vote := ProposalVote{
-					Proposal:     newProposel.Id,
-					VoterIndex:   acc.Index,
-					VoterAddress: v.ValidatorAddress.String(),
-					Height:       uint64(voteHeight),
-					Vote:         uint64(v.VoteCode),
-				}
-				if err := c.db.Create(&vote).Error; err != nil {
-					return err
-				}
+			vote := ProposalVote{
+				Proposal:     newProposel.Id,
+				VoterIndex:   acc.Index,
+				VoterAddress: v.ValidatorAddress.String(),
+				Height:       uint64(voteHeight),
+				Vote:         VoteCode(v.VoteCode),
+				BlockTime:    voteTime,
+			}
+			if err := c.upsertProposalVote(&vote); err != nil {
+				return err
 			}
+			c.bus.emit(BusProposalVote, vote.Proposal, vote.Height, voteTime, "", vote)
+			c.notifyLocalVote(voteKindProposal, vote.Proposal, vote.VoterAddress, vote.Vote, vote.Reason, vote.Height, voteTime)
 		}
 		return nil
 	}
@@ -310,21 +718,24 @@ func (c *ChainIndexer) handleVote(ctx context.Context, height int64) error {
 			if acc == nil {
 				return fmt.Errorf("commit sig address not exist address:%s", v.ValidatorAddress.String())
 			}
-			if err := c.db.Where("height = ? And voter_index = ?", voteHeight, acc.Index).First(&ProposalVote{}).Error; err != nil {
-				if err != gorm.ErrRecordNotFound {
-					return err
-				}
-				vote := ProposalVote{
-					Proposal:     settleProposel.Id,
-					VoterIndex:   acc.Index,
-					VoterAddress: v.ValidatorAddress.String(),
-					Height:       uint64(voteHeight),
-					Vote:         uint64(v.VoteCode),
-				}
-				if err := c.db.Create(&vote).Error; err != nil {
-					return err
-				}
+			reason, err := c.localVoteReason(voteKindProposal, settleProposel.Id, v.ValidatorAddress.String())
+			if err != nil {
+				return err
 			}
+			vote := ProposalVote{
+				Proposal:     settleProposel.Id,
+				VoterIndex:   acc.Index,
+				VoterAddress: v.ValidatorAddress.String(),
+				Height:       uint64(voteHeight),
+				Vote:         VoteCode(v.VoteCode),
+				Reason:       reason,
+				BlockTime:    voteTime,
+			}
+			if err := c.upsertProposalVote(&vote); err != nil {
+				return err
+			}
+			c.bus.emit(BusProposalVote, vote.Proposal, vote.Height, voteTime, "", vote)
+			c.notifyLocalVote(voteKindProposal, vote.Proposal, vote.VoterAddress, vote.Vote, vote.Reason, vote.Height, voteTime)
 		}
 		return nil
 	}
@@ -344,30 +755,110 @@ func (c *ChainIndexer) handleVote(ctx context.Context, height int64) error {
 			if acc == nil {
 				return fmt.Errorf("commit sig address not exist address:%s", v.ValidatorAddress.String())
 			}
-			if err := c.db.Where("height = ? And voter_index = ?", voteHeight, acc.Index).First(&GrantVote{}).Error; err != nil {
-				if err != gorm.ErrRecordNotFound {
-					return err
-				}
-				vote := GrantVote{
-					ProposerIndex:   grant.Proposer,
-					ProposerAddress: grant.ProposerAddress,
-					AccountIndex:    grant.Id,
-					AccountAddr:     grant.Address,
-					VoterIndex:      acc.Index,
-					VoterAddress:    acc.Address(),
-					Height:          uint64(voteHeight),
-					Vote:            uint64(v.VoteCode),
-				}
-				if err := c.db.Create(&vote).Error; err != nil {
-					return err
-				}
+			reason, err := c.localVoteReason(voteKindGrant, grant.Id, acc.Address())
+			if err != nil {
+				return err
 			}
+			vote := GrantVote{
+				ProposerIndex:   grant.Proposer,
+				ProposerAddress: grant.ProposerAddress,
+				AccountIndex:    grant.Id,
+				AccountAddr:     grant.Address,
+				VoterIndex:      acc.Index,
+				VoterAddress:    acc.Address(),
+				Height:          uint64(voteHeight),
+				Vote:            VoteCode(v.VoteCode),
+				Reason:          reason,
+				BlockTime:       voteTime,
+			}
+			if err := c.upsertGrantVote(&vote); err != nil {
+				return err
+			}
+			c.bus.emit(BusGrantVote, vote.AccountIndex, vote.Height, voteTime, "", vote)
+			c.notifyLocalVote(voteKindGrant, vote.AccountIndex, vote.VoterAddress, vote.Vote, vote.Reason, vote.Height, voteTime)
 		}
 		return nil
 	}
 	return nil
 }
 
+// localVoteReason returns the reason the local agent gave for a vote, which
+// is only known for the local validator's own vote rows. It is the reason of
+// the latest decision the agent made on kind id, see DecisionLogClient, or
+// the dry run policy that voted instead of the agent, and is kept on the
+// vote row once stored.
+func (c *ChainIndexer) localVoteReason(kind string, id uint64, voter string) (string, error) {
+	if voter != c.localAddress {
+		return "", nil
+	}
+	var dryRun DryRunDecision
+	err := c.db.Where("kind = ? AND ref_id = ?", kind, id).Order("id desc").First(&dryRun).Error
+	if err == nil {
+		policy := DryRunPolicyReject
+		if dryRun.PolicyPass {
+			policy = DryRunPolicyAccept
+		}
+		return fmt.Sprintf("dry run, %s policy", policy), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	// the voter of a logged decision is the account settling the proposal,
+	// not the local validator, every logged decision is the local agent's.
+	// Cache hits repeat the decision they were cached from.
+	var decision AgentDecision
+	err = c.db.Where("kind = ? AND ref_id = ? AND error = ? AND (cached IS NULL OR cached = ?)", kind, id, "", false).
+		Order("id desc").First(&decision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return decision.Reason, nil
+}
+
+func (c *ChainIndexer) notifyLocalVote(kind string, id uint64, voter string, vote VoteCode, reason string, height uint64, blockTime time.Time) {
+	if voter != c.localAddress {
+		return
+	}
+	n := Notification{
+		Event:      NotifyLocalVote,
+		Kind:       kind,
+		ProposalId: id,
+		Vote:       vote.String(),
+		Reason:     reason,
+		Height:     height,
+		BlockTime:  blockTime,
+	}
+	if kind == voteKindProposal {
+		if p, err := c.getProposalById(id); err == nil {
+			n.Title = p.Title
+			n.Proposer = p.ProposerName
+		}
+	}
+	c.notifier.notify(n)
+}
+
+// upsertProposalVote inserts a vote row or refreshes the row already stored
+// for the same (height, voter_index), so re-indexing a block is idempotent.
+// An empty reason never overwrites a stored one.
+func (c *ChainIndexer) upsertProposalVote(vote *ProposalVote) error {
+	return c.db.Set("gorm:insert_option", "ON CONFLICT (height, voter_index) DO UPDATE SET "+
+		"proposal = excluded.proposal, voter_address = excluded.voter_address, vote = excluded.vote, "+
+		"reason = CASE WHEN excluded.reason = '' THEN proposal_votes.reason ELSE excluded.reason END, "+
+		"block_time = excluded.block_time, updated_at = excluded.updated_at").Create(vote).Error
+}
+
+func (c *ChainIndexer) upsertGrantVote(vote *GrantVote) error {
+	return c.db.Set("gorm:insert_option", "ON CONFLICT (height, voter_index) DO UPDATE SET "+
+		"proposer_index = excluded.proposer_index, proposer_address = excluded.proposer_address, "+
+		"account_index = excluded.account_index, account_addr = excluded.account_addr, "+
+		"voter_address = excluded.voter_address, vote = excluded.vote, "+
+		"reason = CASE WHEN excluded.reason = '' THEN grant_votes.reason ELSE excluded.reason END, "+
+		"block_time = excluded.block_time, updated_at = excluded.updated_at").Create(vote).Error
+}
+
 func (c *ChainIndexer) Start(ctx context.Context) {
 	var err error
 	ticker := time.NewTicker(time.Second)
@@ -396,6 +887,7 @@ func (c *ChainIndexer) Start(ctx context.Context) {
 			Stake:    acc.Stake,
 			AgentUrl: acc.AgentUrl,
 			Name:     acc.Name,
+			Active:   true,
 		}
 
 		cli, err := NewElizaClient(val.AgentUrl, c.logger)
@@ -412,6 +904,19 @@ func (c *ChainIndexer) Start(ctx context.Context) {
 		if err := c.db.Save(val).Error; err != nil {
 			panic(err)
 		}
+		if err := recordStake(c.db, val.Id, val.Address, 0, val.Stake); err != nil {
+			panic(err)
+		}
+	}
+
+	if c.importState {
+		if err := c.importChainState(ctx); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := c.repairGaps(ctx); err != nil {
+		c.logger.Error("repair height gaps fail", "err", err)
 	}
 
 	go func() {
@@ -421,69 +926,100 @@ func (c *ChainIndexer) Start(ctx context.Context) {
 		}
 	}()
 
+	if c.audit.interval > 0 {
+		go c.runAudit(ctx)
+	}
+
+	if c.maintenance.interval > 0 {
+		go c.runMaintenance(ctx)
+	}
+
+	go c.notifier.run(ctx)
+
+	go c.bus.run(ctx)
+
+	go c.leader.run(ctx)
+
+	go c.runOutbox(ctx)
+
+	if c.anchorInterval > 0 {
+		go c.runAnchor(ctx)
+	}
+
+	if c.proposer.interval > 0 {
+		go c.runProposer(ctx)
+	}
+
+	if c.social != nil {
+		go c.runSocialPoster(ctx)
+	}
+
+	if c.ipfs != nil {
+		go c.runArchiver(ctx)
+	}
+
+	if c.bridge != nil {
+		go c.runBridge(ctx)
+	}
+
+	if c.digest != nil {
+		go c.runDigests(ctx)
+	}
+
 	defer ticker.Stop()
+	// failed events are retried from this loop, so their handlers never run
+	// concurrently with the ones of the height being indexed
+	retryTicker := time.NewTicker(FailedEventInterval)
+	defer retryTicker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-retryTicker.C:
+			c.reprocessFailedEvents(ctx)
 		case <-ticker.C:
 			if c.cli == nil {
-				c.cli, err = comethttp.New(c.Url, "/websocket")
-				if err != nil {
-					c.logger.Error("connect fail", "err", err)
-					continue
-				}
+				c.reconnect()
+				continue
 			}
 			b, err := c.cli.Status(context.TODO())
 			if err != nil {
 				c.logger.Error("get status fail", "err", err)
-				if !c.cli.IsRunning() {
-					c.cli.Stop()
-					c.cli, err = comethttp.New(c.Url, "/websocket")
-					if err != nil {
-						c.logger.Error("reconnect fail", "err", err)
-						continue
-					}
-				}
+				c.reconnect()
+				continue
 			}
+			c.rpcRecovered()
 			for b.SyncInfo.LatestBlockHeight > c.Height {
 				time.Sleep(time.Millisecond * 100)
 				c.logger.Info("indexer syncing", "height", c.Height)
-				events, err := c.cli.BlockResults(ctx, &c.Height)
-				if err != nil {
-					c.logger.Error("get status fail", "err", err)
-					if !c.cli.IsRunning() {
-						c.cli.Stop()
-						c.cli, err = comethttp.New(c.Url, "/websocket")
-						if err != nil {
-							c.logger.Error("reconnect fail", "err", err)
-							continue
-						}
-					}
-				}
-				for _, res := range events.TxsResults {
-					for _, event := range res.Events {
-						c.handleEvent(ctx, event, c.Height)
-					}
-				}
-				err = c.handleVote(ctx, c.Height)
-				if err != nil {
-					c.logger.Error("handleVote fail", "height", c.Height, "err", err)
-					continue
+				if err := c.indexHeight(ctx, c.Height); err != nil {
+					// retry the same height on the next tick
+					c.logger.Error("index height fail", "height", c.Height, "err", err)
+					break
 				}
 				if err := c.db.Save(Height{
 					Id:     1,
 					Height: uint64(c.Height),
 				}).Error; err != nil {
 					c.logger.Error("save height fail", "err", err)
-					continue
-				}
-				// random discuss if latest block height is current height + 1
-				if b.SyncInfo.LatestBlockHeight == c.Height+1 {
-					c.randomDiscuss()
+					break
 				}
-				if c.Height%5 == 0 {
-					c.settlePR()
+				c.indexed.advance(uint64(c.Height))
+				// an index-only node has no agent to discuss or settle with, and
+				// only the leader of redundant indexers submits txs
+				if !c.follower() {
+					// random discuss if latest block height is current height + 1,
+					// debate the rounds due instead in debate mode
+					if b.SyncInfo.LatestBlockHeight == c.Height+1 {
+						if c.debate.rounds > 0 {
+							c.runDebates(ctx)
+						} else {
+							c.randomDiscuss()
+						}
+					}
+					if c.Height%5 == 0 {
+						c.settlePR()
+					}
 				}
 				c.Height++
 			}
@@ -491,16 +1027,129 @@ func (c *ChainIndexer) Start(ctx context.Context) {
 	}
 }
 
+// fetchBlock fetches the block and its results at height, retrying the same
+// height up to FetchBlockAttempts times before giving up.
+func (c *ChainIndexer) fetchBlock(ctx context.Context, height int64) (*coretypes.ResultBlock, *coretypes.ResultBlockResults, error) {
+	var err error
+	for attempt := 1; attempt <= FetchBlockAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		var events *coretypes.ResultBlockResults
+		events, err = c.cli.BlockResults(ctx, &height)
+		if err != nil || events == nil {
+			c.logger.Error("get block results fail", "height", height, "attempt", attempt, "err", err)
+			c.reconnect()
+			continue
+		}
+		var block *coretypes.ResultBlock
+		block, err = c.cli.Block(ctx, &height)
+		if err != nil || block == nil || block.Block == nil {
+			c.logger.Error("get block fail", "height", height, "attempt", attempt, "err", err)
+			c.reconnect()
+			continue
+		}
+		c.rpcRecovered()
+		return block, events, nil
+	}
+	if err == nil {
+		err = errors.New("empty response")
+	}
+	return nil, nil, fmt.Errorf("fetch block %d failed after %d attempts: %w", height, FetchBlockAttempts, err)
+}
+
+// reconnect is called after a failed chain RPC call. It waits for the next
+// backoff delay so a recovering node is not flooded, then replaces the rpc
+// client when it is no longer running.
+func (c *ChainIndexer) reconnect() {
+	delay := c.rpcBackoff.Next()
+	attempt := c.rpcBackoff.Attempt()
+	rpcConsecutiveFailures.Set(float64(attempt))
+	if attempt > c.maxRpcFailures {
+		c.maxRpcFailures = attempt
+		rpcMaxConsecutiveFailures.Set(float64(attempt))
+	}
+	c.logger.Info("reconnect chain rpc", "attempt", attempt, "delay", delay)
+	time.Sleep(delay)
+	if c.cli != nil && c.cli.IsRunning() {
+		return
+	}
+	rpcReconnects.Inc()
+	if c.cli != nil {
+		c.cli.Stop()
+	}
+	cli, err := comethttp.New(c.Url, "/websocket")
+	if err != nil {
+		rpcReconnectFailures.Inc()
+		c.logger.Error("reconnect fail", "err", err)
+		return
+	}
+	c.cli = cli
+}
+
+// rpcRecovered resets the reconnection backoff after a successful call.
+func (c *ChainIndexer) rpcRecovered() {
+	if c.rpcBackoff.Attempt() == 0 {
+		return
+	}
+	c.rpcBackoff.Reset()
+	rpcConsecutiveFailures.Set(0)
+}
+
+// indexHeight handles the events and commit votes of one block and records
+// the block as indexed.
+func (c *ChainIndexer) indexHeight(ctx context.Context, height int64) error {
+	block, events, err := c.fetchBlock(ctx, height)
+	if err != nil {
+		return err
+	}
+	if err := c.verifyBlockResults(ctx, height, events); err != nil {
+		return err
+	}
+	eventCnt := 0
+	for i, res := range events.TxsResults {
+		txHash := ""
+		if i < len(block.Block.Txs) {
+			txHash = fmt.Sprintf("%X", block.Block.Txs[i].Hash())
+		}
+		for _, event := range res.Events {
+			c.handleEvent(ctx, event, height, block.Block.Time, txHash)
+			eventCnt++
+		}
+	}
+	if err := c.handleVote(ctx, height); err != nil {
+		return err
+	}
+	if err := c.indexEvidence(block.Block); err != nil {
+		return err
+	}
+	if err := c.advanceActivations(uint64(height), block.Block.Time); err != nil {
+		return err
+	}
+	if err := c.scheduleComments(uint64(height)); err != nil {
+		return err
+	}
+	return c.db.Save(&IndexedBlock{
+		Height:     uint64(height),
+		TxCount:    len(block.Block.Txs),
+		EventCount: eventCnt,
+		BlockTime:  block.Block.Time,
+	}).Error
+}
+
 func (c *ChainIndexer) settlePR() {
 	c.logger.Info("start settle PR")
-	proposals, err := c.getProposalsByStatus(uint64(hac_types.ProposalStatusProcessing), 0, 100)
+	proposals, err := c.getProposalsByStatus(ProposalStatusDiscussing, 0, 100)
 	if err != nil {
 		c.logger.Error("get proposals fail", "err", err)
 	}
-	for _, p := range proposals {
+	for _, p := range proposals.Items {
 		if p.ProposerAddress == c.localAddress {
-			_, cnt, err := c.getDiscussionByProposal(p.Id, 0, 1)
-			if cnt < 15 {
+			if c.debate.rounds > 0 && !c.debate.over(p, uint64(c.Height)) {
+				continue
+			}
+			discussions, err := c.getDiscussionByProposal(p.Id, 0, 1)
+			if discussions.Total < settleDiscussions(p) {
 				continue
 			}
 			cli, err := comethttp.New(c.chainUrl, "/websocket")
@@ -554,18 +1203,18 @@ func (c *ChainIndexer) randomDiscuss() {
 	if (c.Height+int64(DiscussionTrigger))%int64(DiscussionRate) != 0 {
 		return
 	}
-	proposals, err := c.getProposalsByStatus(uint64(hac_types.ProposalStatusProcessing), 0, 10)
+	proposals, err := c.getProposalsByStatus(ProposalStatusDiscussing, 0, 10)
 	if err != nil {
 		c.logger.Error("get proposals fail", "err", err)
 		return
 	}
-	if len(proposals) == 0 {
+	if len(proposals.Items) == 0 {
 		return
 	}
 	suitePrs := make([]Proposal, 0)
-	for _, p := range proposals {
-		_, cnt, err := c.getDiscussionByProposal(p.Id, 0, 1)
-		if err == nil && cnt < 15 {
+	for _, p := range proposals.Items {
+		discussions, err := c.getDiscussionByProposal(p.Id, 0, 1)
+		if err == nil && discussions.Total < settleDiscussions(p) {
 			suitePrs = append(suitePrs, p)
 		}
 	}
@@ -573,7 +1222,7 @@ func (c *ChainIndexer) randomDiscuss() {
 		return
 	}
 	randProposal := suitePrs[rand.Intn(len(suitePrs))]
-	comment, err := ElizaCli.CommentPropoal(context.Background(), randProposal.Id, randProposal.ProposerAddress)
+	comment, err := ActiveClient().CommentPropoal(context.Background(), randProposal.Id, randProposal.ProposerAddress)
 	if err != nil {
 		c.logger.Error("comment proposal fail", "err", err)
 		return
@@ -631,14 +1280,8 @@ func (c *ChainIndexer) queryAccount(ctx context.Context, index uint64, address s
 	res, err := c.cli.ABCIQuery(ctx, "/accounts/", dat)
 	if err != nil {
 		c.logger.Error("ABCIQuery fail", "err", err)
-		if !c.cli.IsRunning() {
-			c.cli.Stop()
-			c.cli, err = comethttp.New(c.Url, "/websocket")
-			if err != nil {
-				c.logger.Error("reconnect fail", "err", err)
-				return nil, err
-			}
-		}
+		c.reconnect()
+		return nil, err
 	}
 	if res.Response.Code != 0 {
 		fmt.Printf("%#v\n", res)
@@ -652,18 +1295,13 @@ func (c *ChainIndexer) queryAccount(ctx context.Context, index uint64, address s
 	return &act, err
 }
 
-func (c *ChainIndexer) getProposalsByStatus(status uint64, page int, pageSize int) ([]Proposal, error) {
-	var proposals []Proposal
-	err := c.db.Where("status = ?", status).Order("id desc").Offset(page * pageSize).Limit(pageSize).Find(&proposals).Error
-	if err != nil {
-		return nil, err
-	}
-	return proposals, nil
+func (c *ChainIndexer) getProposalsByStatus(status ProposalStatus, page int, pageSize int) (Page[Proposal], error) {
+	return paginate[Proposal](c.db.Model(&Proposal{}).Where("status = ?", status), "id desc", page, pageSize)
 }
 
 func (c *ChainIndexer) getProposalsInProcess() (uint64, error) {
 	var total uint64
-	err := c.db.Model(&Proposal{}).Where("status = ?", hac_types.ProposalStatusProcessing).Count(&total).Error
+	err := c.db.Model(&Proposal{}).Where("status = ?", ProposalStatusDiscussing).Count(&total).Error
 	if err != nil {
 		return 0, err
 	}
@@ -672,26 +1310,16 @@ func (c *ChainIndexer) getProposalsInProcess() (uint64, error) {
 
 func (c *ChainIndexer) getProposalsDecided() (uint64, error) {
 	var total uint64
-	err := c.db.Model(&Proposal{}).Where("status > ?", hac_types.ProposalStatusProcessing).Count(&total).Error
+	err := c.db.Model(&Proposal{}).Where("status IN (?)",
+		[]ProposalStatus{ProposalStatusPassed, ProposalStatusRejected, ProposalStatusExpired}).Count(&total).Error
 	if err != nil {
 		return 0, err
 	}
 	return total, nil
 }
 
-func (c *ChainIndexer) getProposals(page int, pageSize int) ([]Proposal, uint64, error) {
-	var proposals []Proposal
-	err := c.db.Order("id desc").Offset(page * pageSize).Limit(pageSize).Find(&proposals).Error
-	if err != nil {
-		return nil, 0, err
-	}
-	// get total proposals
-	var total uint64
-	err = c.db.Model(&Proposal{}).Count(&total).Error
-	if err != nil {
-		return nil, 0, err
-	}
-	return proposals, total, nil
+func (c *ChainIndexer) getProposals(page int, pageSize int) (Page[Proposal], error) {
+	return paginate[Proposal](c.db.Model(&Proposal{}), "id desc", page, pageSize)
 }
 
 func (c *ChainIndexer) getProposalById(proposalId uint64) (Proposal, error) {
@@ -703,32 +1331,41 @@ func (c *ChainIndexer) getProposalById(proposalId uint64) (Proposal, error) {
 	return proposal, nil
 }
 
-func (c *ChainIndexer) getProposalsByProposerAddr(proposerAddr string, page int, pageSize int) ([]Proposal, uint64, error) {
-	var proposals []Proposal
-	err := c.db.Where("proposer_address = ?", proposerAddr).Order("id desc").Offset(page * pageSize).Limit(pageSize).Find(&proposals).Error
-	if err != nil {
-		return nil, 0, err
+// IndexedProposal returns proposal id, the agent backends without a memory
+// of their own read the proposal voted on with it.
+func (c *ChainIndexer) IndexedProposal(id uint64) (Proposal, error) {
+	return c.getProposalById(id)
+}
+
+// AgentCharacter returns the character the agent of validator address
+// introduced itself with, "" for one not introduced yet.
+func (c *ChainIndexer) AgentCharacter(address string) (string, error) {
+	var agent ValidatorAgent
+	err := c.db.Where("address = ?", address).First(&agent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
 	}
-	var total uint64
-	err = c.db.Model(&Proposal{}).Where("proposer_address = ?", proposerAddr).Count(&total).Error
 	if err != nil {
-		return nil, 0, err
+		return "", err
 	}
-	return proposals, total, nil
+	return agent.SelfIntro, nil
 }
 
-func (c *ChainIndexer) getDiscussionByProposal(proposal uint64, page int, pageSize int) ([]Discussion, uint64, error) {
-	var discussions []Discussion
-	err := c.db.Where("proposal = ?", proposal).Order("id desc").Offset(page * pageSize).Limit(pageSize).Find(&discussions).Error
-	if err != nil {
-		return nil, 0, err
-	}
-	var total uint64
-	err = c.db.Model(&Discussion{}).Where("proposal = ?", proposal).Count(&total).Error
+func (c *ChainIndexer) getProposalsByProposerAddr(proposerAddr string, page int, pageSize int) (Page[Proposal], error) {
+	return paginate[Proposal](c.db.Model(&Proposal{}).Where("proposer_address = ?", proposerAddr), "id desc", page, pageSize)
+}
+
+func (c *ChainIndexer) getDiscussionByProposal(proposal uint64, page int, pageSize int) (Page[Discussion], error) {
+	return paginate[Discussion](c.db.Model(&Discussion{}).Where("proposal = ?", proposal), "id desc", page, pageSize)
+}
+
+func (c *ChainIndexer) getProposalStatusChanges(proposal uint64) ([]ProposalStatusChange, error) {
+	var changes []ProposalStatusChange
+	err := c.db.Where("proposal = ?", proposal).Order("height asc, id asc").Find(&changes).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	return discussions, total, nil
+	return changes, nil
 }
 
 func (c *ChainIndexer) getDiscussionCntByHeight(height uint64) (uint64, error) {
@@ -758,6 +1395,34 @@ func (c *ChainIndexer) getValidators() ([]ValidatorAgent, error) {
 	return validators, nil
 }
 
+func (c *ChainIndexer) getValidatorsPage(activeOnly bool, page int, pageSize int) (Page[ValidatorAgent], error) {
+	query := c.db.Model(&ValidatorAgent{})
+	if activeOnly {
+		query = query.Where("active = ?", true)
+	}
+	return paginate[ValidatorAgent](query, "id asc", page, pageSize)
+}
+
+func (c *ChainIndexer) getBlockDiscrepancies(page int, pageSize int) (Page[BlockDiscrepancy], error) {
+	return paginate[BlockDiscrepancy](c.db.Model(&BlockDiscrepancy{}), "height desc, id desc", page, pageSize)
+}
+
+func (c *ChainIndexer) SaveDryRunDecision(d *DryRunDecision) error {
+	return c.db.Create(d).Error
+}
+
+func (c *ChainIndexer) getDryRunDecisions(page int, pageSize int) (Page[DryRunDecision], error) {
+	return paginate[DryRunDecision](c.db.Model(&DryRunDecision{}), "id desc", page, pageSize)
+}
+
+func (c *ChainIndexer) SaveVoteFallback(f *VoteFallback) error {
+	return c.db.Create(f).Error
+}
+
+func (c *ChainIndexer) getVoteFallbacks(page int, pageSize int) (Page[VoteFallback], error) {
+	return paginate[VoteFallback](c.db.Model(&VoteFallback{}), "id desc", page, pageSize)
+}
+
 func (c *ChainIndexer) getValidatorByAddress(address string) (*ValidatorAgent, error) {
 	var val ValidatorAgent
 	err := c.db.Where("address = ?", address).First(&val).Error
@@ -767,18 +1432,8 @@ func (c *ChainIndexer) getValidatorByAddress(address string) (*ValidatorAgent, e
 	return &val, nil
 }
 
-func (c *ChainIndexer) getGrants(page int, pageSize int) ([]Grant, uint64, error) {
-	var grants []Grant
-	err := c.db.Order("id desc").Offset(page * pageSize).Limit(pageSize).Find(&grants).Error
-	if err != nil {
-		return nil, 0, err
-	}
-	var total uint64
-	err = c.db.Model(&Grant{}).Count(&total).Error
-	if err != nil {
-		return nil, 0, err
-	}
-	return grants, total, nil
+func (c *ChainIndexer) getGrants(page int, pageSize int) (Page[Grant], error) {
+	return paginate[Grant](c.db.Model(&Grant{}), "id desc", page, pageSize)
 }
 
 func (c *ChainIndexer) getProposalByHeight(height uint64) (*Proposal, error) {
@@ -790,40 +1445,34 @@ func (c *ChainIndexer) getProposalByHeight(height uint64) (*Proposal, error) {
 	return &proposal, nil
 }
 
-func (c *ChainIndexer) getProposalVotesByProposal(proposal uint64, page int, pageSize int) ([]ProposalVote, error) {
-	var votes []ProposalVote
-	err := c.db.Where("proposal = ?", proposal).Order("id desc").Offset(page * pageSize).Limit(pageSize).Find(&votes).Error
-	if err != nil {
-		return nil, err
-	}
-	return votes, nil
+func (c *ChainIndexer) getProposalVotesByProposal(proposal uint64, page int, pageSize int) (Page[ProposalVote], error) {
+	return paginate[ProposalVote](c.db.Model(&ProposalVote{}).Where("proposal = ?", proposal), "id desc", page, pageSize)
 }
 
-func (c *ChainIndexer) getGrantVotesByGrant(grant uint64, page int, pageSize int) ([]GrantVote, error) {
-	var votes []GrantVote
-	err := c.db.Where("account_index = ?", grant).Order("id desc").Offset(page * pageSize).Limit(pageSize).Find(&votes).Error
-	if err != nil {
-		return nil, err
-	}
-	return votes, nil
+func (c *ChainIndexer) getGrantVotesByGrant(grant uint64, page int, pageSize int) (Page[GrantVote], error) {
+	return paginate[GrantVote](c.db.Model(&GrantVote{}).Where("account_index = ?", grant), "id desc", page, pageSize)
 }
 
-func (c *ChainIndexer) getProposalVotesByVoter(voter string, page int, pageSize int) ([]ProposalVote, error) {
-	var votes []ProposalVote
-	err := c.db.Where("voter_address = ?", voter).Order("id desc").Offset(page * pageSize).Limit(pageSize).Find(&votes).Error
-	if err != nil {
-		return nil, err
-	}
-	return votes, nil
+func (c *ChainIndexer) getProposalVotesByVoter(voter string, page int, pageSize int) (Page[ProposalVote], error) {
+	return paginate[ProposalVote](c.db.Model(&ProposalVote{}).Where("voter_address = ?", voter), "id desc", page, pageSize)
 }
 
-func (c *ChainIndexer) getGrantVotesByVoter(voter string, page int, pageSize int) ([]GrantVote, error) {
-	var votes []GrantVote
-	err := c.db.Where("voter_address = ?", voter).Order("id desc").Offset(page * pageSize).Limit(pageSize).Find(&votes).Error
+func (c *ChainIndexer) getVotesByVoter(voter string, page int, pageSize int) (GetVotesResponse, error) {
+	var response GetVotesResponse
+	var err error
+	response.ProposalVotes, err = c.getProposalVotesByVoter(voter, page, pageSize)
 	if err != nil {
-		return nil, err
+		return response, err
+	}
+	response.GrantVotes, err = c.getGrantVotesByVoter(voter, page, pageSize)
+	if err != nil {
+		return response, err
 	}
-	return votes, nil
+	return response, nil
+}
+
+func (c *ChainIndexer) getGrantVotesByVoter(voter string, page int, pageSize int) (Page[GrantVote], error) {
+	return paginate[GrantVote](c.db.Model(&GrantVote{}).Where("voter_address = ?", voter), "id desc", page, pageSize)
 }
 
 func queryAccount(cli *comethttp.HTTP, index uint64, address string) (*state.Account, error) {
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProposalInitiator is asked from time to time whether it wants to propose
+// anything, a nil draft declines. It is implemented by ElizaClient.
+type ProposalInitiator interface {
+	InitiateProposal(ctx context.Context, proposer string) (*ProposalDraftReq, error)
+}
+
+var _ ProposalInitiator = &ElizaClient{}
+
+// InitiateProposalReq asks the agent whether it wants to propose anything as
+// validator ValidatorAddress.
+type InitiateProposalReq struct {
+	ValidatorAddress string `json:"validatorAddress"`
+	Text             string `json:"text"`
+}
+
+// InitiateProposalResponse is the answer of the agent, the draft it wants to
+// submit when Propose is set.
+type InitiateProposalResponse struct {
+	Propose bool `json:"propose"`
+	ProposalDraftReq
+}
+
+// InitiateProposal asks the agent whether it wants to propose anything. A
+// service without the endpoint never initiates, it returns nil and no error.
+func (e *ElizaClient) InitiateProposal(ctx context.Context, proposer string) (*ProposalDraftReq, error) {
+	e.logger.Info("InitiateProposal", "proposer", proposer)
+	url := fmt.Sprintf("%s/%s/initiateproposal", e.Url, e.agent())
+	data, _ := json.Marshal(InitiateProposalReq{ValidatorAddress: proposer, Text: "do you want to propose anything?"})
+	res, err := e.post(ctx, "InitiateProposal", url, data)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	bodyBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, agentCallError("InitiateProposal", err)
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := agentStatusError("InitiateProposal", res.StatusCode, bodyBytes); err != nil {
+		return nil, err
+	}
+	var answer InitiateProposalResponse
+	if err := json.Unmarshal(bodyBytes, &answer); err != nil {
+		return nil, agentResponseError("InitiateProposal", res.StatusCode, bodyBytes, err)
+	}
+	if !answer.Propose || strings.TrimSpace(answer.Body) == "" {
+		return nil, nil
+	}
+	return &answer.ProposalDraftReq, nil
+}
+
+// agentProposer asks initiator every interval whether it wants to propose
+// anything.
+type agentProposer struct {
+	initiator ProposalInitiator
+	interval  time.Duration
+}
+
+// SetProposalInitiator sets the agent asked every agent_propose_interval
+// whether it wants to propose anything.
+func (c *ChainIndexer) SetProposalInitiator(i ProposalInitiator) {
+	c.proposer.initiator = i
+}
+
+func (c *ChainIndexer) runProposer(ctx context.Context) {
+	ticker := time.NewTicker(c.proposer.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if c.follower() || c.proposer.initiator == nil {
+			continue
+		}
+		if err := c.initiateProposal(ctx); err != nil {
+			c.logger.Error("agent proposal fail", "err", err)
+		}
+	}
+}
+
+// initiateProposal asks the local agent whether it wants to propose anything
+// and submits its draft from the account of the node.
+func (c *ChainIndexer) initiateProposal(ctx context.Context) error {
+	draft, err := c.proposer.initiator.InitiateProposal(ctx, c.localAddress)
+	if err != nil {
+		return err
+	}
+	if draft == nil {
+		return nil
+	}
+	draft.Source = "agent"
+	// the agent wrote the draft, it has nothing to refine
+	draft.Refine = false
+	res, err := c.submitProposalDraft(ctx, *draft)
+	if err != nil {
+		return err
+	}
+	agentInitiatedProposals.Inc()
+	c.logger.Info("agent initiated proposal", "proposal", res.ProposalId, "title", res.Title, "height", res.Height)
+	return nil
+}
@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	app_config "github.com/calehh/hac-app/config"
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+	"github.com/jinzhu/gorm"
+)
+
+const leaderLeaseName = "indexer"
+
+var ErrNotLeader = errors.New("this indexer is a follower, send the request to the leader")
+
+// LeaderLease is the row indexer instances compete for. The holder renews it
+// before ExpiresAt, another instance takes it over once it expired.
+type LeaderLease struct {
+	Name      string    `gorm:"primary_key" json:"name"`
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// leaderElector holds the leader lease of a group of redundant indexers
+// sharing a db. Every instance indexes, only the leader forwards events to
+// the agent and submits txs. A nil elector is always the leader.
+//
+// Leadership ends at the expiry the instance itself set, not when a renewal
+// fails, so two instances are never leaders at the same time as long as
+// their clocks agree within the ttl.
+type leaderElector struct {
+	logger   cmtlog.Logger
+	db       *gorm.DB
+	holder   string
+	ttl      time.Duration
+	mtx      sync.Mutex
+	leaderTo time.Time
+}
+
+func newLeaderElector(logger cmtlog.Logger, cfg *app_config.HACAppConfig, db *gorm.DB) (*leaderElector, error) {
+	if !cfg.LeaderElection {
+		return nil, nil
+	}
+	if cfg.LeaderLeaseUrl != "" {
+		var err error
+		if db, err = openIndexDB(cfg.LeaderLeaseUrl); err != nil {
+			return nil, fmt.Errorf("open leader lease db: %w", err)
+		}
+	}
+	if err := db.AutoMigrate(&LeaderLease{}).Error; err != nil {
+		return nil, err
+	}
+	e := &leaderElector{
+		logger: logger.With("module", "leader"),
+		db:     db,
+		holder: cfg.InstanceId,
+		ttl:    time.Duration(cfg.LeaderLeaseTtl) * time.Second,
+	}
+	if e.holder == "" {
+		host, _ := os.Hostname()
+		e.holder = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if e.ttl <= 0 {
+		e.ttl = 15 * time.Second
+	}
+	return e, nil
+}
+
+// isLeader reports whether the lease is held and not expired.
+func (e *leaderElector) isLeader() bool {
+	if e == nil {
+		return true
+	}
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return time.Now().Before(e.leaderTo)
+}
+
+// acquire takes or renews the lease. The instance stays a follower while
+// another one holds an unexpired lease.
+func (e *leaderElector) acquire() error {
+	now := time.Now().UTC()
+	expires := now.Add(e.ttl)
+	res := e.db.Model(&LeaderLease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", leaderLeaseName, e.holder, now).
+		Updates(map[string]interface{}{"holder": e.holder, "expires_at": expires})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		var lease LeaderLease
+		err := e.db.Where("name = ?", leaderLeaseName).First(&lease).Error
+		if err == nil {
+			// held by another instance
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// the first instance creates the lease, a concurrent insert fails on
+		// the primary key and the loser stays a follower
+		if err := e.db.Create(&LeaderLease{Name: leaderLeaseName, Holder: e.holder, ExpiresAt: expires}).Error; err != nil {
+			return nil
+		}
+	}
+	e.mtx.Lock()
+	e.leaderTo = expires
+	e.mtx.Unlock()
+	return nil
+}
+
+// release gives the lease up on shutdown so a follower takes over at once.
+func (e *leaderElector) release() {
+	e.mtx.Lock()
+	e.leaderTo = time.Time{}
+	e.mtx.Unlock()
+	err := e.db.Model(&LeaderLease{}).Where("name = ? AND holder = ?", leaderLeaseName, e.holder).
+		Update("expires_at", time.Now().UTC()).Error
+	if err != nil {
+		e.logger.Error("release leader lease fail", "err", err)
+	}
+}
+
+func (e *leaderElector) run(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		was := e.isLeader()
+		if err := e.acquire(); err != nil {
+			e.logger.Error("acquire leader lease fail", "err", err)
+		}
+		leader := e.isLeader()
+		if leader != was {
+			e.logger.Info("leadership changed", "holder", e.holder, "leader", leader)
+		}
+		if leader {
+			leaderGauge.Set(1)
+		} else {
+			leaderGauge.Set(0)
+		}
+		select {
+		case <-ctx.Done():
+			if e.isLeader() {
+				e.release()
+			}
+			leaderGauge.Set(0)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// follower reports whether the indexer must not forward events to the agent
+// nor submit txs, because it is index only or not the leader.
+func (c *ChainIndexer) follower() bool {
+	return c.indexOnly || !c.leader.isLeader()
+}
@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Chain events checked against the proposal lifecycle.
+const (
+	LifecycleEventProposal   = "proposal"
+	LifecycleEventSettlement = "settlement"
+	LifecycleEventDiscussion = "discussion"
+)
+
+// proposalTransitions is the lifecycle of a proposal: the draft vote of the
+// block proposing it ignores it or opens its discussion, the decision vote
+// settles it.
+var proposalTransitions = map[ProposalStatus][]ProposalStatus{
+	ProposalStatusProposed:   {ProposalStatusIgnored, ProposalStatusDiscussing},
+	ProposalStatusDiscussing: {ProposalStatusVoting},
+	ProposalStatusVoting:     {ProposalStatusPassed, ProposalStatusRejected, ProposalStatusExpired},
+}
+
+// Next returns the statuses a proposal in s can move to.
+func (s ProposalStatus) Next() []ProposalStatus {
+	return proposalTransitions[s]
+}
+
+func (s ProposalStatus) CanTransition(to ProposalStatus) bool {
+	for _, next := range proposalTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ProposalAnomaly is a chain event that does not fit the lifecycle of its
+// proposal. The event is applied all the same since the chain decides, the
+// anomaly points operators at a chain or indexer bug.
+type ProposalAnomaly struct {
+	Id         uint64         `gorm:"primary_key" json:"id"`
+	Proposal   uint64         `gorm:"index" json:"proposal"`
+	Event      string         `json:"event"`
+	FromStatus ProposalStatus `json:"from_status"`
+	ToStatus   ProposalStatus `json:"to_status"`
+	Height     uint64         `json:"height"`
+	TxHash     string         `json:"tx_hash"`
+	Reason     string         `json:"reason"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// ProposalLifecycle is the canonical status of a proposal, the statuses it
+// can still move to and how it got there.
+type ProposalLifecycle struct {
+	Proposal    uint64                 `json:"proposal"`
+	Status      ProposalStatus         `json:"status"`
+	StatusName  string                 `json:"statusName"`
+	Next        []string               `json:"next"`
+	Transitions []ProposalStatusChange `json:"transitions"`
+	Anomalies   []ProposalAnomaly      `json:"anomalies"`
+}
+
+// transitionProposal moves proposal through the statuses of path in tx and
+// records each step, flagging the steps the lifecycle does not allow. A
+// replayed height finds its steps recorded and only restores the status.
+// The caller saves the proposal.
+func (c *ChainIndexer) transitionProposal(tx *gorm.DB, proposal *Proposal, event string, path []ProposalStatus, height uint64, blockTime time.Time, txHash string) ([]ProposalStatusChange, error) {
+	var recorded []ProposalStatusChange
+	if err := tx.Where("proposal = ? AND height = ?", proposal.Id, height).Order("id asc").Find(&recorded).Error; err != nil {
+		return nil, err
+	}
+	final := path[len(path)-1]
+	if len(recorded) > 0 && recorded[len(recorded)-1].ToStatus == final {
+		proposal.Status = final
+		return recorded, nil
+	}
+	changes := make([]ProposalStatusChange, 0, len(path))
+	for _, to := range path {
+		from := proposal.Status
+		if !from.CanTransition(to) {
+			err := c.recordAnomaly(tx, ProposalAnomaly{
+				Proposal:   proposal.Id,
+				Event:      event,
+				FromStatus: from,
+				ToStatus:   to,
+				Height:     height,
+				TxHash:     txHash,
+				Reason:     fmt.Sprintf("%s to %s is not a lifecycle transition", from, to),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		change := ProposalStatusChange{
+			Proposal:   proposal.Id,
+			FromStatus: from,
+			ToStatus:   to,
+			Height:     height,
+			BlockTime:  blockTime,
+		}
+		if err := tx.Create(&change).Error; err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+		proposal.Status = to
+	}
+	return changes, nil
+}
+
+func (c *ChainIndexer) recordAnomaly(tx *gorm.DB, anomaly ProposalAnomaly) error {
+	c.logger.Error("proposal lifecycle anomaly", "proposal", anomaly.Proposal, "event", anomaly.Event, "height", anomaly.Height, "reason", anomaly.Reason)
+	proposalAnomalies.WithLabelValues(anomaly.Event).Inc()
+	return tx.Create(&anomaly).Error
+}
+
+func (c *ChainIndexer) getProposalLifecycle(proposalId uint64) (ProposalLifecycle, error) {
+	var proposal Proposal
+	err := c.db.First(&proposal, proposalId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ProposalLifecycle{}, errProposalNotIndexed
+	}
+	if err != nil {
+		return ProposalLifecycle{}, err
+	}
+	lifecycle := ProposalLifecycle{
+		Proposal:   proposal.Id,
+		Status:     proposal.Status,
+		StatusName: proposal.Status.String(),
+		Next:       []string{},
+		Anomalies:  []ProposalAnomaly{},
+	}
+	for _, next := range proposal.Status.Next() {
+		lifecycle.Next = append(lifecycle.Next, next.String())
+	}
+	if lifecycle.Transitions, err = c.getProposalStatusChanges(proposalId); err != nil {
+		return ProposalLifecycle{}, err
+	}
+	if err := c.db.Where("proposal = ?", proposalId).Order("height asc, id asc").Find(&lifecycle.Anomalies).Error; err != nil {
+		return ProposalLifecycle{}, err
+	}
+	return lifecycle, nil
+}
+
+func (c *ChainIndexer) getProposalAnomalies(proposalId uint64, page int, pageSize int) (Page[ProposalAnomaly], error) {
+	db := c.db.Model(&ProposalAnomaly{})
+	if proposalId != 0 {
+		db = db.Where("proposal = ?", proposalId)
+	}
+	return paginate[ProposalAnomaly](db, "id desc", page, pageSize)
+}
+
+// checkDiscussionLifecycle flags a discussion on a proposal that is not open
+// for discussion.
+func (c *ChainIndexer) checkDiscussionLifecycle(tx *gorm.DB, proposalId uint64, height uint64, txHash string) error {
+	var proposal Proposal
+	err := tx.First(&proposal, proposalId).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	reason := ""
+	switch {
+	case proposal.Id == 0:
+		reason = "discussion on a proposal not indexed"
+	case proposal.Status != ProposalStatusDiscussing:
+		reason = fmt.Sprintf("discussion on a %s proposal", proposal.Status)
+	}
+	if reason == "" {
+		return nil
+	}
+	return c.recordAnomaly(tx, ProposalAnomaly{
+		Proposal:   proposalId,
+		Event:      LifecycleEventDiscussion,
+		FromStatus: proposal.Status,
+		ToStatus:   proposal.Status,
+		Height:     height,
+		TxHash:     txHash,
+		Reason:     reason,
+	})
+}
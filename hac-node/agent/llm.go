@@ -0,0 +1,311 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// Names of the prompts of the model backends, besides the agent methods.
+const (
+	PromptSystem = "system"
+	PromptIntro  = "intro"
+)
+
+// DefaultLLMPrompts are the prompt templates of the backends calling a model
+// directly, by agent method. agent_prompts overrides them one by one.
+var DefaultLLMPrompts = map[string]string{
+	PromptSystem: `You are the governance agent of a validator on an agent-run chain. You read proposals, weigh the debate between validators and vote in the interest of the network.`,
+	PromptIntro:  `A governance agent voting in the interest of the network.`,
+	"IfAcceptProposal": `Proposal #{{ .Id }} by {{ .Proposer }}{{ if .Title }}: {{ .Title }}{{ end }}
+{{ if .Summary }}
+Summary: {{ .Summary }}
+{{ end }}
+{{ .Body }}
+
+You vote as validator {{ .Voter }}. {{ .Context }}
+
+Answer with the vote, yes to accept the proposal, no to reject it or abstain, how confident you are from 0 to 1, and its reason.`,
+	"IfGrantNewMember": `Validator {{ .Proposer }} asks to join the validator set with a stake of {{ .Amount }} as member #{{ .Id }}.
+
+Statement: {{ .Body }}{{ .Context }}
+
+Answer with the vote, yes to grant the membership, no to refuse it or abstain, how confident you are from 0 to 1, and its reason.`,
+	"CommentPropoal": `Proposal #{{ .Id }} by {{ .Proposer }}{{ if .Title }}: {{ .Title }}{{ end }}
+{{ if .Summary }}
+Summary: {{ .Summary }}
+{{ end }}
+{{ .Body }}
+{{ .Context }}
+
+{{ if .Round }}This is round {{ .Round }} of {{ .Rounds }} of the debate on the proposal. Answer the arguments the other validators made so far, as validator {{ .Voter }}. Answer with the rebuttal only.{{ else }}Write a short comment on the proposal for the other validators, as validator {{ .Voter }}. Answer with the comment only.{{ end }}`,
+}
+
+// llmVoteSchema is the json schema of a vote answer, it decodes to a
+// VoteResponse.
+var llmVoteSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"vote":       map[string]interface{}{"type": "string", "enum": []string{"yes", "no", "abstain"}},
+		"confidence": map[string]interface{}{"type": "number", "minimum": 0, "maximum": 1},
+		"reason":     map[string]interface{}{"type": "string"},
+	},
+	"required": []string{"vote", "reason"},
+}
+
+// ProposalSource provides the indexed proposals.
+type ProposalSource interface {
+	IndexedProposal(id uint64) (Proposal, error)
+}
+
+var _ VoteContextClient = &OpenAIClient{}
+var _ VoteContextClient = &OllamaClient{}
+
+// llmPrompt is the data of a prompt template.
+type llmPrompt struct {
+	Id       uint64
+	Title    string
+	Summary  string
+	Body     string
+	Proposer string
+	Voter    string
+	Amount   uint64
+	Context  string
+	Round    int
+	Rounds   int
+}
+
+// llmAnswer is the answer of a model. Vote is the json of a vote answered
+// apart from the content, as the arguments of a tool call, Response the raw
+// answer kept in transcripts.
+type llmAnswer struct {
+	Content  string
+	Vote     string
+	Response string
+	Status   int
+}
+
+// llmChat sends a system and a user prompt to a model. A vote asks the model
+// to answer in the shape of llmVoteSchema.
+type llmChat func(ctx context.Context, system string, user string, vote bool) (llmAnswer, error)
+
+// llmBackend implements the Client methods of the backends calling a model
+// directly with chat. The model keeps no memory, proposals and discussions
+// are read from the indexer when a decision is asked, so AddProposal,
+// AddDiscussion and the notifications have nothing to do.
+type llmBackend struct {
+	voteContext
+	chat        llmChat
+	prompts     map[string]*template.Template
+	logger      cmtlog.Logger
+	transcripts *TranscriptRecorder
+}
+
+func newLLMBackend(chat llmChat, prompts map[string]string, logger cmtlog.Logger) (llmBackend, error) {
+	b := llmBackend{
+		voteContext: voteContext{logger: logger},
+		chat:        chat,
+		prompts:     make(map[string]*template.Template),
+		logger:      logger,
+	}
+	for name := range prompts {
+		if _, ok := DefaultLLMPrompts[name]; !ok {
+			return llmBackend{}, fmt.Errorf("unknown prompt %q", name)
+		}
+	}
+	for name, text := range DefaultLLMPrompts {
+		if override, ok := prompts[name]; ok {
+			text = override
+		}
+		t, err := template.New(name).Parse(text)
+		if err != nil {
+			return llmBackend{}, fmt.Errorf("invalid %s prompt: %w", name, err)
+		}
+		b.prompts[name] = t
+	}
+	return b, nil
+}
+
+// SetTranscripts keeps the transcripts of the voting decisions with r.
+func (b *llmBackend) SetTranscripts(r *TranscriptRecorder) {
+	b.transcripts = r
+}
+
+func (b *llmBackend) prompt(name string, data llmPrompt) (string, error) {
+	var s strings.Builder
+	if err := b.prompts[name].Execute(&s, data); err != nil {
+		return "", fmt.Errorf("render %s prompt: %w", name, err)
+	}
+	return s.String(), nil
+}
+
+// proposalPrompt is the prompt data of proposal, only its id when it is not
+// indexed.
+func (b *llmBackend) proposalPrompt(proposal uint64) llmPrompt {
+	data := llmPrompt{Id: proposal}
+	if b.proposals == nil {
+		return data
+	}
+	p, err := b.proposals.IndexedProposal(proposal)
+	if err != nil {
+		b.logger.Error("get proposal fail", "proposal", proposal, "err", err)
+		return data
+	}
+	data.Title = p.Title
+	data.Summary = p.Summary
+	data.Body = p.agentBody()
+	data.Proposer = p.ProposerName
+	if data.Proposer == "" {
+		data.Proposer = p.ProposerAddress
+	}
+	return data
+}
+
+func (b *llmBackend) complete(ctx context.Context, user string, vote bool) (llmAnswer, error) {
+	system, err := b.prompt(PromptSystem, llmPrompt{})
+	if err != nil {
+		return llmAnswer{}, err
+	}
+	return b.chat(ctx, system, user, vote)
+}
+
+// parseVote reads the vote of answer, from its content for a model not
+// answering the vote apart.
+func parseVote(answer llmAnswer) (Decision, error) {
+	var vote VoteResponse
+	text := answer.Vote
+	if text == "" {
+		text = strings.TrimSpace(answer.Content)
+	}
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimSpace(strings.Trim(text, "`"))
+	if err := json.Unmarshal([]byte(text), &vote); err != nil {
+		return Decision{}, agentResponseError("parse vote", answer.Status, []byte(answer.Response), err)
+	}
+	decision, err := parseDecision(vote.Vote, vote.Confidence, vote.Reason)
+	if err != nil {
+		return decision, agentResponseError("parse vote", answer.Status, []byte(answer.Response), err)
+	}
+	return decision, nil
+}
+
+// askVote asks the model to vote on the prompt. The exchange is kept as the
+// transcript of the vote of voter on the proposal or grant id.
+func (b *llmBackend) askVote(ctx context.Context, method string, prompt string, kind string, id uint64, voter string) (Decision, error) {
+	transcript := Transcript{Kind: kind, RefId: id, Voter: voter, Method: method, Prompt: prompt}
+	started := time.Now()
+	answer, err := b.complete(ctx, prompt, true)
+	transcript.StatusCode = answer.Status
+	transcript.Response = answer.Response
+	var vote Decision
+	if err == nil {
+		vote, err = parseVote(answer)
+	}
+	transcript.DurationMs = time.Since(started).Milliseconds()
+	transcript.Vote = string(vote.Vote)
+	if err != nil {
+		transcript.Error = err.Error()
+	}
+	if err := b.transcripts.record(transcript); err != nil {
+		b.logger.Error("save transcript fail", "err", err)
+	}
+	return vote, err
+}
+
+func (b *llmBackend) IfProcessProposal(ctx context.Context, proposer uint64, data []byte) (bool, error) {
+	return true, nil
+}
+
+func (b *llmBackend) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	b.logger.Info("IfAcceptProposal", "proposal", proposal, "voter", voter)
+	expedited := b.isExpedited(proposal)
+	data := b.proposalPrompt(proposal)
+	data.Voter = voter
+	data.Context = b.voteProposalText(proposal, voter, expedited)
+	prompt, err := b.prompt("IfAcceptProposal", data)
+	if err != nil {
+		return Decision{}, err
+	}
+	vote, err := b.askVote(ctx, "IfAcceptProposal", prompt, voteKindProposal, proposal, voter)
+	if err != nil {
+		return Decision{}, err
+	}
+	b.logger.Info("vote proposal", "proposal", proposal, "voter", voter, "vote", vote.Vote, "confidence", vote.Confidence, "reason", vote.Reason)
+	return vote, nil
+}
+
+func (b *llmBackend) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	b.logger.Info("IfGrantNewMember", "validator", validator, "proposer", proposer, "amount", amount, "statement", statement)
+	prompt, err := b.prompt("IfGrantNewMember", llmPrompt{
+		Id:       validator,
+		Body:     statement,
+		Proposer: proposer,
+		Amount:   amount,
+		Context:  b.uptimeText(proposer) + dossierText(b.dossier(proposer)),
+	})
+	if err != nil {
+		return Decision{}, err
+	}
+	vote, err := b.askVote(ctx, "IfGrantNewMember", prompt, voteKindGrant, validator, "")
+	if err != nil {
+		return Decision{}, err
+	}
+	b.logger.Info("vote grant", "validator", validator, "proposer", proposer, "vote", vote.Vote, "confidence", vote.Confidence, "reason", vote.Reason)
+	return vote, nil
+}
+
+func (b *llmBackend) CommentPropoal(ctx context.Context, proposal uint64, speaker string) (string, error) {
+	b.logger.Info("CommentPropoal", "proposal", proposal, "speaker", speaker)
+	data := b.proposalPrompt(proposal)
+	data.Voter = speaker
+	data.Context = b.voteProposalText(proposal, speaker, false)
+	data.Round, data.Rounds = debateRound(ctx)
+	prompt, err := b.prompt("CommentPropoal", data)
+	if err != nil {
+		return "", err
+	}
+	answer, err := b.complete(ctx, prompt, false)
+	if err != nil {
+		return "", err
+	}
+	comment := strings.TrimSpace(answer.Content)
+	if comment == "" {
+		return "", errors.New("comment proposal: empty answer")
+	}
+	b.logger.Info("comment proposal", "proposal", proposal, "speaker", speaker, "comment", comment)
+	return comment, nil
+}
+
+func (b *llmBackend) AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error {
+	return nil
+}
+
+func (b *llmBackend) AddDiscussion(ctx context.Context, proposal uint64, speaker string, text string) error {
+	return nil
+}
+
+func (b *llmBackend) AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error {
+	return nil
+}
+
+func (b *llmBackend) NotifyActivation(ctx context.Context, proposal uint64, activationHeight uint64) error {
+	return nil
+}
+
+func (b *llmBackend) NotifyMisbehavior(ctx context.Context, validator string, kind string, height uint64) error {
+	return nil
+}
+
+func (b *llmBackend) GetSelfIntro(ctx context.Context) (string, error) {
+	return b.prompt(PromptIntro, llmPrompt{})
+}
+
+func (b *llmBackend) GetHeadPhoto(ctx context.Context) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	longPollTimeoutDefault = 30 * time.Second
+	longPollTimeoutMax     = 60 * time.Second
+)
+
+// heightWatch tracks the last fully indexed height and wakes the waiters
+// when it moves.
+type heightWatch struct {
+	mtx     sync.Mutex
+	height  uint64
+	changed chan struct{}
+}
+
+func newHeightWatch(height uint64) *heightWatch {
+	return &heightWatch{height: height, changed: make(chan struct{})}
+}
+
+func (w *heightWatch) advance(height uint64) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.height = height
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
+
+// current returns the last indexed height and a channel closed once a
+// later height is indexed.
+func (w *heightWatch) current() (uint64, <-chan struct{}) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.height, w.changed
+}
+
+// ProposalUpdates holds the timeline entries of a proposal after a height.
+// Height is the last indexed height, to send back as since on the next poll.
+type ProposalUpdates struct {
+	Updates Page[TimelineEntry] `json:"updates"`
+	Height  uint64              `json:"height"`
+}
+
+// waitProposalUpdates returns the timeline entries of a proposal above since
+// as soon as there are some, waiting up to timeout for new blocks to be
+// indexed. Entries of the block being indexed are held back until the block
+// is complete, so a poll never returns half a block.
+func (c *ChainIndexer) waitProposalUpdates(ctx context.Context, proposalId uint64, since uint64, timeout time.Duration) (ProposalUpdates, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		height, changed := c.indexed.current()
+		entries, err := c.getProposalTimeline(proposalId)
+		if err != nil {
+			return ProposalUpdates{}, err
+		}
+		updates := make([]TimelineEntry, 0)
+		for _, e := range entries {
+			if e.Height > since && e.Height <= height {
+				updates = append(updates, e)
+			}
+		}
+		if len(updates) > 0 {
+			return ProposalUpdates{Updates: fullPage(updates), Height: height}, nil
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return ProposalUpdates{Updates: fullPage(updates), Height: height}, nil
+		case <-ctx.Done():
+			return ProposalUpdates{Updates: fullPage(updates), Height: height}, nil
+		}
+	}
+}
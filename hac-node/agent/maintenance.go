@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var ErrMaintenanceRunning = errors.New("maintenance already running")
+
+// TableSize is the row count and, on postgres, the disk size of one table.
+// sqlite does not report sizes per table.
+type TableSize struct {
+	Name  string `json:"name"`
+	Rows  uint64 `json:"rows"`
+	Bytes int64  `json:"bytes,omitempty"`
+}
+
+// MaintenanceReport describes one vacuum run. Sizes are of the whole db.
+type MaintenanceReport struct {
+	Dialect    string        `json:"dialect"`
+	StartedAt  time.Time     `json:"startedAt"`
+	Duration   time.Duration `json:"duration"`
+	SizeBefore int64         `json:"sizeBefore"`
+	SizeAfter  int64         `json:"sizeAfter"`
+	Reclaimed  int64         `json:"reclaimed"`
+	Tables     []TableSize   `json:"tables"`
+}
+
+// maintainer runs vacuums on a schedule and on request, one at a time.
+type maintainer struct {
+	interval time.Duration
+	mu       sync.Mutex
+}
+
+// MaintainIndexDB vacuums and analyzes the indexer db at dsn. A sqlite db
+// must not be used by a running node, which holds it open, use the admin
+// endpoint of the node instead.
+func MaintainIndexDB(dsn string) (MaintenanceReport, error) {
+	db, err := openIndexDB(dsn)
+	if err != nil {
+		return MaintenanceReport{}, err
+	}
+	defer db.Close()
+	return maintainDB(db)
+}
+
+func (c *ChainIndexer) runMaintenance(ctx context.Context) {
+	ticker := time.NewTicker(c.maintenance.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := c.maintain()
+			if err != nil {
+				c.logger.Error("db maintenance fail", "err", err)
+				continue
+			}
+			c.logger.Info("db maintenance done", "reclaimed", report.Reclaimed, "size", report.SizeAfter, "duration", report.Duration)
+		}
+	}
+}
+
+func (c *ChainIndexer) maintain() (MaintenanceReport, error) {
+	if !c.maintenance.mu.TryLock() {
+		return MaintenanceReport{}, ErrMaintenanceRunning
+	}
+	defer c.maintenance.mu.Unlock()
+	report, err := maintainDB(c.db)
+	if err != nil {
+		return report, err
+	}
+	dbSizeBytes.Set(float64(report.SizeAfter))
+	dbReclaimedBytes.Add(float64(report.Reclaimed))
+	return report, nil
+}
+
+// maintainDB runs VACUUM and ANALYZE, or VACUUM ANALYZE on postgres, and
+// reports the db size before and after together with the table sizes.
+func maintainDB(db *gorm.DB) (MaintenanceReport, error) {
+	report := MaintenanceReport{
+		Dialect:   db.Dialect().GetName(),
+		StartedAt: time.Now(),
+	}
+	postgres := report.Dialect == "postgres"
+	var err error
+	if report.SizeBefore, err = dbSize(db, postgres); err != nil {
+		return report, err
+	}
+	if postgres {
+		err = db.Exec("VACUUM ANALYZE").Error
+	} else {
+		err = db.Exec("VACUUM").Error
+		if err == nil {
+			err = db.Exec("ANALYZE").Error
+		}
+	}
+	if err != nil {
+		return report, err
+	}
+	if report.SizeAfter, err = dbSize(db, postgres); err != nil {
+		return report, err
+	}
+	report.Reclaimed = report.SizeBefore - report.SizeAfter
+	if report.Tables, err = tableSizes(db, postgres); err != nil {
+		return report, err
+	}
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+func dbSize(db *gorm.DB, postgres bool) (int64, error) {
+	var size int64
+	if postgres {
+		err := db.Raw("SELECT pg_database_size(current_database())").Row().Scan(&size)
+		return size, err
+	}
+	var pages, pageSize int64
+	if err := db.Raw("PRAGMA page_count").Row().Scan(&pages); err != nil {
+		return 0, err
+	}
+	if err := db.Raw("PRAGMA page_size").Row().Scan(&pageSize); err != nil {
+		return 0, err
+	}
+	return pages * pageSize, nil
+}
+
+func tableSizes(db *gorm.DB, postgres bool) ([]TableSize, error) {
+	tables := make([]TableSize, 0, len(indexerModels))
+	for _, model := range indexerModels {
+		t := TableSize{Name: db.NewScope(model).TableName()}
+		if err := db.Model(model).Count(&t.Rows).Error; err != nil {
+			return nil, err
+		}
+		if postgres {
+			if err := db.Raw("SELECT pg_total_relation_size(?::regclass)", t.Name).Row().Scan(&t.Bytes); err != nil {
+				return nil, err
+			}
+		}
+		tables = append(tables, t)
+	}
+	return tables, nil
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "hac"
+
+var (
+	rpcReconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "rpc_reconnects_total",
+		Help:      "Number of chain RPC reconnection attempts.",
+	})
+	rpcReconnectFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "rpc_reconnect_failures_total",
+		Help:      "Number of chain RPC reconnection attempts that failed.",
+	})
+	rpcConsecutiveFailures = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "rpc_consecutive_failures",
+		Help:      "Chain RPC failures since the last successful call.",
+	})
+	rpcMaxConsecutiveFailures = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "rpc_max_consecutive_failures",
+		Help:      "Highest number of consecutive chain RPC failures seen since start.",
+	})
+	verifyDiscrepancies = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "verify_discrepancies_total",
+		Help:      "Number of heights whose block results differ between the primary and verification RPC.",
+	})
+	verifyFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "verify_failures_total",
+		Help:      "Number of heights that could not be fetched from the verification RPC.",
+	})
+	auditDivergences = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "audit_divergences_total",
+		Help:      "Number of indexed fields found to differ from chain state by the audit.",
+	}, []string{"kind"})
+	eventsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "events_total",
+		Help:      "Number of block events by type and result (handled, skipped or failed).",
+	}, []string{"type", "result"})
+	dbSizeBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "db_size_bytes",
+		Help:      "Size of the indexer db after the last maintenance run.",
+	})
+	dbReclaimedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "db_reclaimed_bytes_total",
+		Help:      "Bytes freed by db maintenance runs.",
+	})
+	eventBusPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "event_bus_published_total",
+		Help:      "Number of messages published to the event bus by topic.",
+	}, []string{"topic"})
+	eventBusDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "event_bus_dropped_total",
+		Help:      "Number of event bus messages dropped because the queue was full.",
+	})
+	leaderGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "leader",
+		Help:      "1 while this indexer holds the leader lease, always 1 without leader election.",
+	})
+	agentOutboxPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_outbox_pending",
+		Help:      "Number of agent calls in the outbox waiting for delivery.",
+	})
+	agentOutboxDelivered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_outbox_delivered_total",
+		Help:      "Number of agent calls delivered from the outbox by method.",
+	}, []string{"method"})
+	agentOutboxFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_outbox_failed_total",
+		Help:      "Number of agent calls given up after OutboxMaxAttempts by method.",
+	}, []string{"method"})
+	agentQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_queue_depth",
+		Help:      "Number of agent calls waiting for a slot by backend.",
+	}, []string{"backend"})
+	agentCallsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_calls_in_flight",
+		Help:      "Number of agent calls running by backend.",
+	}, []string{"backend"})
+	agentQueueOverflows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_queue_overflows_total",
+		Help:      "Number of agent calls failed because the queue of their backend was full, by backend and method.",
+	}, []string{"backend", "method"})
+	proposalAnomalies = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "proposal_lifecycle_anomalies_total",
+		Help:      "Number of chain events not fitting the lifecycle of their proposal by event.",
+	}, []string{"event"})
+	decisionAnchors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "decision_anchors_total",
+		Help:      "Number of decision log roots anchored on-chain.",
+	})
+	agentInitiatedProposals = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_initiated_proposals_total",
+		Help:      "Number of proposals the local agent initiated and the node submitted.",
+	})
+	agentVoteDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_vote_decisions_total",
+		Help:      "Number of voting decisions asked with a timeout by backend and method.",
+	}, []string{"backend", "method"})
+	agentVoteTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_vote_timeouts_total",
+		Help:      "Number of voting decisions cast with the timeout fallback by backend and method.",
+	}, []string{"backend", "method"})
+	agentCallRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_call_retries_total",
+		Help:      "Number of agent calls sent again after a transient failure by method.",
+	}, []string{"method"})
+	agentBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_breaker_state",
+		Help:      "State of the agent circuit breaker by backend, 0 closed, 1 half-open, 2 open.",
+	}, []string{"backend"})
+	agentBreakerShortCircuits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_breaker_short_circuits_total",
+		Help:      "Number of agent calls not sent because the circuit breaker was open by backend and method.",
+	}, []string{"backend", "method"})
+	agentDecisionCacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_decision_cache_hits_total",
+		Help:      "Number of voting decisions answered from the decision cache by kind.",
+	}, []string{"kind"})
+	agentClientCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_client_calls_total",
+		Help:      "Number of agent calls by backend, method and result.",
+	}, []string{"backend", "method", "result"})
+	agentClientCallSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_client_call_seconds",
+		Help:      "Duration of the agent calls by backend and method.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"backend", "method"})
+	agentRateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_rate_limited_total",
+		Help:      "Number of agent calls that waited for the rate limit by method.",
+	}, []string{"method"})
+	agentUnsupportedCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_unsupported_calls_total",
+		Help:      "Number of agent calls skipped as the agent does not support them by method.",
+	}, []string{"method"})
+	agentAvailable = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_available",
+		Help:      "Whether the agent answered its last health probe by backend, 1 or 0.",
+	}, []string{"backend"})
+	agentProbeLatency = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_probe_latency_seconds",
+		Help:      "Duration of the last health probe of the agent by backend.",
+	}, []string{"backend"})
+	agentEndpointActive = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "agent_endpoint_active",
+		Help:      "Whether the agent calls are sent to the url, 1 for the active url of a failover and 0 for the others.",
+	}, []string{"url"})
+	validatorMisbehaviors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "indexer",
+		Name:      "validator_misbehaviors_total",
+		Help:      "Number of validators named by evidence included in blocks by kind.",
+	}, []string{"kind"})
+)
@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// Middleware wraps a Client with another Client adding a behaviour, the
+// way an http.RoundTripper wraps another.
+type Middleware func(Client) Client
+
+// Wrap wraps base with mws, the first middleware is the outermost:
+// Wrap(base, WithRetry(p), WithMetrics(b)) retries the calls measured by the
+// metrics middleware.
+func Wrap(base Client, mws ...Middleware) Client {
+	cli := base
+	for i := len(mws) - 1; i >= 0; i-- {
+		cli = mws[i](cli)
+	}
+	return cli
+}
+
+// unwrapper is a Client wrapping another.
+type unwrapper interface {
+	Unwrap() Client
+}
+
+// FindClient returns the first client of type T in the chain of cli, cli
+// itself included, so the stores of a middleware can be set once the chain
+// is built.
+func FindClient[T Client](cli Client) (T, bool) {
+	for cli != nil {
+		if t, ok := cli.(T); ok {
+			return t, true
+		}
+		u, ok := cli.(unwrapper)
+		if !ok {
+			break
+		}
+		cli = u.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// WithRetry retries the calls failing with an unavailable or timed out
+// agent following p.
+func WithRetry(p RetryPolicy) Middleware {
+	return func(next Client) Client {
+		return NewRetryClient(next, p)
+	}
+}
+
+// WithMetrics counts and times the calls of backend.
+func WithMetrics(backend string) Middleware {
+	return func(next Client) Client {
+		return NewMetricsClient(next, backend)
+	}
+}
+
+// WithLogging logs every call with its duration and error.
+func WithLogging(logger cmtlog.Logger) Middleware {
+	return func(next Client) Client {
+		return NewLoggingClient(next, logger)
+	}
+}
+
+// WithCache caches the voting decisions for ttl, see NewCacheClient.
+func WithCache(ttl time.Duration, logger cmtlog.Logger) Middleware {
+	return func(next Client) Client {
+		return NewCacheClient(next, ttl, logger)
+	}
+}
+
+// WithBreaker short-circuits the calls of backend while it keeps failing,
+// see NewBreakerClient.
+func WithBreaker(backend string, cfg BreakerConfig, logger cmtlog.Logger) Middleware {
+	return func(next Client) Client {
+		return NewBreakerClient(next, backend, cfg, logger)
+	}
+}
+
+// WithTimeout bounds the voting decisions, see NewTimeoutClient.
+func WithTimeout(backend string, timeout time.Duration, decision TimeoutDecision, logger cmtlog.Logger) Middleware {
+	return func(next Client) Client {
+		return NewTimeoutClient(next, backend, timeout, decision, logger)
+	}
+}
+
+// WithDryRun votes with policy instead of the agent, see NewDryRunClient.
+func WithDryRun(policy DryRunPolicy, logger cmtlog.Logger) Middleware {
+	return func(next Client) Client {
+		return NewDryRunClient(next, policy, logger)
+	}
+}
+
+func (c *RetryClient) Unwrap() Client   { return c.Client }
+func (c *MetricsClient) Unwrap() Client { return c.Client }
+func (c *LoggingClient) Unwrap() Client { return c.Client }
+func (c *CacheClient) Unwrap() Client   { return c.Client }
+func (b *BreakerClient) Unwrap() Client { return b.Client }
+func (t *TimeoutClient) Unwrap() Client { return t.Client }
+func (d *DryRunClient) Unwrap() Client  { return d.Client }
+func (p *PushClient) Unwrap() Client    { return p.Client }
+func (s *ScoringClient) Unwrap() Client { return s.Client }
+func (r *RoutingClient) Unwrap() Client { return r.Client }
+
+// intercept calls send as the method of a middleware client.
+type intercept func(ctx context.Context, method string, send func() error) error
+
+// interceptedClient runs every call of the wrapped client through its
+// intercept.
+type interceptedClient struct {
+	Client
+	intercept intercept
+}
+
+func (c *interceptedClient) IfProcessProposal(ctx context.Context, proposer uint64, data []byte) (bool, error) {
+	var pass bool
+	err := c.intercept(ctx, "IfProcessProposal", func() (err error) {
+		pass, err = c.Client.IfProcessProposal(ctx, proposer, data)
+		return err
+	})
+	return pass, err
+}
+
+func (c *interceptedClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	var decision Decision
+	err := c.intercept(ctx, "IfAcceptProposal", func() (err error) {
+		decision, err = c.Client.IfAcceptProposal(ctx, proposal, voter)
+		return err
+	})
+	return decision, err
+}
+
+func (c *interceptedClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	var decision Decision
+	err := c.intercept(ctx, "IfGrantNewMember", func() (err error) {
+		decision, err = c.Client.IfGrantNewMember(ctx, validator, proposer, amount, statement)
+		return err
+	})
+	return decision, err
+}
+
+func (c *interceptedClient) CommentPropoal(ctx context.Context, proposal uint64, speaker string) (string, error) {
+	var comment string
+	err := c.intercept(ctx, "CommentPropoal", func() (err error) {
+		comment, err = c.Client.CommentPropoal(ctx, proposal, speaker)
+		return err
+	})
+	return comment, err
+}
+
+func (c *interceptedClient) AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error {
+	return c.intercept(ctx, "AddProposal", func() error {
+		return c.Client.AddProposal(ctx, proposal, proposer, text)
+	})
+}
+
+func (c *interceptedClient) AddDiscussion(ctx context.Context, proposal uint64, speaker string, text string) error {
+	return c.intercept(ctx, "AddDiscussion", func() error {
+		return c.Client.AddDiscussion(ctx, proposal, speaker, text)
+	})
+}
+
+func (c *interceptedClient) AddDiscussions(ctx context.Context, msgs []DiscussionMsg) error {
+	return c.intercept(ctx, "AddDiscussions", func() error {
+		return c.Client.AddDiscussions(ctx, msgs)
+	})
+}
+
+func (c *interceptedClient) NotifyActivation(ctx context.Context, proposal uint64, activationHeight uint64) error {
+	return c.intercept(ctx, "NotifyActivation", func() error {
+		return c.Client.NotifyActivation(ctx, proposal, activationHeight)
+	})
+}
+
+func (c *interceptedClient) NotifyMisbehavior(ctx context.Context, validator string, kind string, height uint64) error {
+	return c.intercept(ctx, "NotifyMisbehavior", func() error {
+		return c.Client.NotifyMisbehavior(ctx, validator, kind, height)
+	})
+}
+
+func (c *interceptedClient) GetSelfIntro(ctx context.Context) (string, error) {
+	var intro string
+	err := c.intercept(ctx, "GetSelfIntro", func() (err error) {
+		intro, err = c.Client.GetSelfIntro(ctx)
+		return err
+	})
+	return intro, err
+}
+
+func (c *interceptedClient) GetHeadPhoto(ctx context.Context) (string, error) {
+	var photo string
+	err := c.intercept(ctx, "GetHeadPhoto", func() (err error) {
+		photo, err = c.Client.GetHeadPhoto(ctx)
+		return err
+	})
+	return photo, err
+}
+
+var _ Client = &MetricsClient{}
+
+// MetricsClient counts the calls of the wrapped agent by result and times
+// them.
+type MetricsClient struct {
+	interceptedClient
+	backend string
+}
+
+func NewMetricsClient(inner Client, backend string) *MetricsClient {
+	m := &MetricsClient{backend: backend}
+	m.interceptedClient = interceptedClient{Client: inner, intercept: m.observe}
+	return m
+}
+
+func (m *MetricsClient) observe(ctx context.Context, method string, send func() error) error {
+	start := time.Now()
+	err := send()
+	agentClientCallSeconds.WithLabelValues(m.backend, method).Observe(time.Since(start).Seconds())
+	agentClientCalls.WithLabelValues(m.backend, method, callResult(err)).Inc()
+	return err
+}
+
+// callResult is the label of the outcome of a call, the class of an agent
+// error.
+func callResult(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case errors.Is(err, ErrAgentDecisionTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrAgentUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrMalformedAgentResponse):
+		return "malformed"
+	case errors.Is(err, ErrAgentRejected):
+		return "rejected"
+	}
+	return "error"
+}
+
+var _ Client = &LoggingClient{}
+
+// LoggingClient logs the calls of the wrapped agent, at debug level when
+// they succeed.
+type LoggingClient struct {
+	interceptedClient
+	logger cmtlog.Logger
+}
+
+func NewLoggingClient(inner Client, logger cmtlog.Logger) *LoggingClient {
+	l := &LoggingClient{logger: logger.With("module", "agent-calls")}
+	l.interceptedClient = interceptedClient{Client: inner, intercept: l.log}
+	return l
+}
+
+func (l *LoggingClient) log(ctx context.Context, method string, send func() error) error {
+	start := time.Now()
+	err := send()
+	if err != nil {
+		l.logger.Error("agent call fail", "method", method, "duration", time.Since(start), "err", err)
+	} else {
+		l.logger.Debug("agent call", "method", method, "duration", time.Since(start))
+	}
+	return err
+}
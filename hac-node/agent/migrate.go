@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SchemaVersion stores the last data migration applied to the indexer db.
+type SchemaVersion struct {
+	Id      uint64 `gorm:"primaryKey" json:"id"`
+	Version uint64 `json:"version"`
+}
+
+type migration struct {
+	version uint64
+	name    string
+	up      func(tx *gorm.DB) error
+}
+
+// migrations run in order after AutoMigrate has created tables and columns.
+// They only move data, so they must be safe on an empty database.
+var migrations = []migration{
+	{1, "backfill validator grant height and active flag", migrateValidatorAgentActive},
+	{2, "parse proposal title and summary", migrateProposalSummary},
+	{3, "unique vote rows per height and voter", migrateUniqueVotes},
+	{4, "foreign keys between governance tables", migrateForeignKeys},
+	{5, "validate structured proposal payloads", migrateProposalPayloads},
+	{6, "prefix search indexes", migrateSearchIndexes},
+	{7, "move agent deliveries to the outbox", migrateAgentDeliveries},
+	{8, "stake history of indexed validators", migrateStakeHistory},
+}
+
+// SchemaVersionLatest is the version of a fully migrated indexer db.
+func SchemaVersionLatest() uint64 {
+	return migrations[len(migrations)-1].version
+}
+
+func getSchemaVersion(db *gorm.DB) (uint64, error) {
+	v := SchemaVersion{Id: 1}
+	if err := db.First(&v).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return v.Version, nil
+}
+
+// DBSchemaVersion reads the schema version of the indexer db at dbPath
+// without migrating it.
+func DBSchemaVersion(dbPath string) (uint64, error) {
+	if !IsPostgresDSN(dbPath) {
+		if _, err := os.Stat(dbPath); err != nil {
+			return 0, err
+		}
+	}
+	db, err := openIndexDB(dbPath)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	if !db.HasTable(&SchemaVersion{}) {
+		return 0, nil
+	}
+	return getSchemaVersion(db)
+}
+
+func migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&SchemaVersion{}).Error; err != nil {
+		return err
+	}
+	current, err := getSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			return tx.Save(&SchemaVersion{Id: 1, Version: m.version}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateValidatorAgentActive fills the last_grant_height and active columns
+// for validators indexed before they existed. Every stored validator came
+// from genesis or a grant event, so it is active unless its grant was rejected.
+func migrateValidatorAgentActive(tx *gorm.DB) error {
+	if err := tx.Model(&ValidatorAgent{}).Update("active", true).Error; err != nil {
+		return err
+	}
+	var grants []Grant
+	if err := tx.Find(&grants).Error; err != nil {
+		return err
+	}
+	for _, g := range grants {
+		err := tx.Model(&ValidatorAgent{}).Where("id = ?", g.Id).Updates(map[string]interface{}{
+			"last_grant_height": g.Height,
+			"active":            g.Grant,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateProposalSummary(tx *gorm.DB) error {
+	var proposals []Proposal
+	if err := tx.Where("summary = ? OR summary IS NULL", "").Find(&proposals).Error; err != nil {
+		return err
+	}
+	for _, p := range proposals {
+		title, summary := parseProposalPayload([]byte(p.Data), p.Title)
+		err := tx.Model(&Proposal{}).Where("id = ?", p.Id).Updates(map[string]interface{}{
+			"title":   title,
+			"summary": summary,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateUniqueVotes drops duplicated vote rows, keeping the first one, and
+// adds the (height, voter_index) unique indexes the vote upserts rely on.
+func migrateUniqueVotes(tx *gorm.DB) error {
+	for _, table := range []string{"proposal_votes", "grant_votes"} {
+		err := tx.Exec("DELETE FROM " + table + " WHERE id NOT IN (SELECT MIN(id) FROM " + table + " GROUP BY height, voter_index)").Error
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&ProposalVote{}).AddUniqueIndex("idx_proposal_votes_height_voter", "height", "voter_index").Error; err != nil {
+		return err
+	}
+	return tx.Model(&GrantVote{}).AddUniqueIndex("idx_grant_votes_height_voter", "height", "voter_index").Error
+}
+
+// foreignKey describes a child column referencing a parent primary key.
+// Deleting a parent that still has children is rejected. A child whose
+// parent was never indexed, a discussion or vote on a proposal from before
+// start_height, is kept: countOrphans reports them instead.
+type foreignKey struct {
+	table       string
+	column      string
+	parentTable string
+}
+
+var foreignKeys = []foreignKey{
+	{"discussions", "proposal", "proposals"},
+	{"proposal_votes", "proposal", "proposals"},
+	{"grant_votes", "account_index", "grants"},
+}
+
+// migrateForeignKeys restricts deleting the parents of foreignKeys with
+// triggers, as a constraint would also reject the orphan children.
+func migrateForeignKeys(tx *gorm.DB) error {
+	for _, fk := range foreignKeys {
+		stmts := sqliteForeignKeyTriggers(fk)
+		if tx.Dialect().GetName() == "postgres" {
+			stmts = postgresForeignKeyTriggers(fk)
+		}
+		for _, stmt := range stmts {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func foreignKeyRestrictMessage(fk foreignKey) string {
+	return fmt.Sprintf("%s row is referenced by %s.%s", fk.parentTable, fk.table, fk.column)
+}
+
+func sqliteForeignKeyTriggers(fk foreignKey) []string {
+	return []string{
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS fk_%s_%s_delete BEFORE DELETE ON %s FOR EACH ROW "+
+			"WHEN EXISTS (SELECT 1 FROM %s WHERE %s = OLD.id) BEGIN SELECT RAISE(ABORT, '%s'); END",
+			fk.table, fk.column, fk.parentTable, fk.table, fk.column, foreignKeyRestrictMessage(fk)),
+	}
+}
+
+func postgresForeignKeyTriggers(fk foreignKey) []string {
+	name := fmt.Sprintf("fk_%s_%s_delete", fk.table, fk.column)
+	return []string{
+		fmt.Sprintf("CREATE OR REPLACE FUNCTION %s() RETURNS trigger LANGUAGE plpgsql AS $$ BEGIN "+
+			"IF EXISTS (SELECT 1 FROM %s WHERE %s = OLD.id) THEN RAISE EXCEPTION '%s'; END IF; RETURN OLD; END $$",
+			name, fk.table, fk.column, foreignKeyRestrictMessage(fk)),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", name, fk.parentTable),
+		fmt.Sprintf("CREATE TRIGGER %s BEFORE DELETE ON %s FOR EACH ROW EXECUTE PROCEDURE %s()", name, fk.parentTable, name),
+	}
+}
+
+// orphanCount is the number of children of fk whose parent is not indexed.
+type orphanCount struct {
+	fk   foreignKey
+	rows uint64
+}
+
+// countOrphans counts the children without a parent of every relation in
+// foreignKeys. Children of parents from before start_height are expected,
+// more of them points at an indexing bug.
+func countOrphans(db *gorm.DB) ([]orphanCount, error) {
+	counts := make([]orphanCount, 0, len(foreignKeys))
+	for _, fk := range foreignKeys {
+		var rows uint64
+		err := db.Table(fk.table).Where(fmt.Sprintf("%s NOT IN (SELECT id FROM %s)", fk.column, fk.parentTable)).Count(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, orphanCount{fk: fk, rows: rows})
+	}
+	return counts, nil
+}
+
+func migrateProposalPayloads(tx *gorm.DB) error {
+	var proposals []Proposal
+	if err := tx.Find(&proposals).Error; err != nil {
+		return err
+	}
+	for _, p := range proposals {
+		typ, structured, err := validatePayload([]byte(p.Data))
+		payloadError := ""
+		if err != nil {
+			payloadError = err.Error()
+		}
+		err = tx.Model(&Proposal{}).Where("id = ?", p.Id).Updates(map[string]interface{}{
+			"payload_type":  typ,
+			"payload_data":  structured,
+			"payload_error": payloadError,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,5 +1,10 @@
 package agent
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // sqlite models
 
 type Height struct {
@@ -7,72 +12,254 @@ type Height struct {
 	Height uint64 `json:"height"`
 }
 
-type ValidatorAgent struct {
-	Id        uint64 `gorm:"primaryKey" json:"id"`
-	Address   string `json:"address"`
-	Stake     uint64 `json:"stake"`
-	AgentUrl  string `json:"agentUrl"`
-	Name      string `json:"name"`
-	SelfIntro string `json:"self_intro"`
-	HeadPhoto string `json:"head_photo"`
+// IndexedBlock marks a height whose events and votes were fully indexed.
+type IndexedBlock struct {
+	Height     uint64    `gorm:"primary_key;auto_increment:false" json:"height"`
+	TxCount    int       `json:"tx_count"`
+	EventCount int       `json:"event_count"`
+	BlockTime  time.Time `json:"block_time"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
-type Proposal struct {
-	Id              uint64 `gorm:"primaryKey" json:"id"`
-	ProposerIndex   uint64 `json:"proposer_index"`
-	ProposerAddress string `json:"proposer_address"`
-	ProposerName    string `json:"proposer_name"`
-	HeadPhoto       string `json:"head_photo"`
-	Data            string `json:"data"`
-	NewHeight       uint64 `json:"new_height"`
-	SettleHeight    uint64 `json:"settle_height"`
-	Status          uint64 `json:"status"`
-	Title           string `json:"title"`
-	Link            string `json:"link"`
-	ImageUrl        string `json:"image_url"`
-	CreateTimestamp int64  `json:"create_timestamp"`
-	ExpireTimestamp int64  `json:"expire_timestamp"`
+type FailedEvent struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Height    uint64    `gorm:"index" json:"height"`
+	TxHash    string    `json:"tx_hash"`
+	EventType string    `json:"event_type"`
+	Payload   string    `json:"payload"`
+	BlockTime time.Time `json:"block_time"`
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-type Grant struct {
+type AuditDivergence struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Kind      string    `json:"kind"`
+	RefId     uint64    `json:"ref_id"`
+	Field     string    `json:"field"`
+	Indexed   string    `json:"indexed"`
+	Chain     string    `json:"chain"`
+	Height    uint64    `json:"height"`
+	Repaired  bool      `json:"repaired"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DryRunDecision struct {
+	Id         uint64    `gorm:"primary_key" json:"id"`
+	Kind       string    `json:"kind"`
+	RefId      uint64    `json:"ref_id"`
+	Question   string    `json:"question"`
+	AgentPass  bool      `json:"agent_pass"`
+	AgentVote  string    `json:"agent_vote"`
+	AgentError string    `json:"agent_error"`
+	Reason     string    `json:"reason"`
+	PolicyPass bool      `json:"policy_pass"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ProposalScore is the composite score a proposal vote was decided with.
+// Scores holds the json of the ScorerResult of every scorer.
+type ProposalScore struct {
+	Id        uint64         `gorm:"primary_key" json:"id"`
+	Proposal  uint64         `gorm:"index" json:"proposal"`
+	Voter     string         `json:"voter"`
+	Composite float64        `json:"composite"`
+	Threshold float64        `json:"threshold"`
+	Pass      bool           `json:"pass"`
+	Scores    string         `json:"-"`
+	Results   []ScorerResult `gorm:"-" json:"scores"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type BlockDiscrepancy struct {
+	Id              uint64    `gorm:"primary_key" json:"id"`
+	Height          uint64    `gorm:"index" json:"height"`
+	PrimaryDigest   string    `json:"primary_digest"`
+	SecondaryDigest string    `json:"secondary_digest"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+type ValidatorAgent struct {
 	Id              uint64 `gorm:"primaryKey" json:"id"`
 	Address         string `json:"address"`
-	Height          uint64 `json:"height"`
 	Stake           uint64 `json:"stake"`
-	Proposer        uint64 `json:"proposer"`
-	ProposerAddress string `json:"proposer_address"`
-	Grant           bool   `json:"grant"`
+	AgentUrl        string `json:"agentUrl"`
+	Name            string `json:"name"`
+	SelfIntro       string `json:"self_intro"`
+	HeadPhoto       string `json:"head_photo"`
+	LastGrantHeight uint64 `json:"last_grant_height"`
+	Active          bool   `json:"active"`
+}
+
+type Proposal struct {
+	Id               uint64          `gorm:"primaryKey" json:"id"`
+	ProposerIndex    uint64          `json:"proposer_index"`
+	ProposerAddress  string          `json:"proposer_address"`
+	ProposerName     string          `json:"proposer_name"`
+	HeadPhoto        string          `json:"head_photo"`
+	Data             string          `json:"data"`
+	DataSummary      string          `json:"data_summary"`
+	NewHeight        uint64          `json:"new_height"`
+	SettleHeight     uint64          `json:"settle_height"`
+	ActivationHeight uint64          `json:"activation_height"`
+	Expedited        bool            `gorm:"index" json:"expedited"`
+	Status           ProposalStatus  `json:"status"`
+	StatusName       string          `gorm:"-" json:"status_name"`
+	Title            string          `json:"title"`
+	Summary          string          `json:"summary"`
+	PayloadType      string          `json:"payload_type"`
+	PayloadData      string          `json:"-"`
+	Payload          json.RawMessage `gorm:"-" json:"payload,omitempty"`
+	BodyHtml         string          `gorm:"-" json:"body_html,omitempty"`
+	BodyText         string          `gorm:"-" json:"body_text,omitempty"`
+	PayloadError     string          `json:"payload_error"`
+	Link             string          `json:"link"`
+	ImageUrl         string          `json:"image_url"`
+	CreateTimestamp  int64           `json:"create_timestamp"`
+	ExpireTimestamp  int64           `json:"expire_timestamp"`
+	BlockTime        time.Time       `json:"block_time"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
 }
 
+type Grant struct {
+	Id              uint64    `gorm:"primaryKey" json:"id"`
+	Address         string    `json:"address"`
+	Height          uint64    `json:"height"`
+	Stake           uint64    `json:"stake"`
+	Proposer        uint64    `json:"proposer"`
+	ProposerAddress string    `json:"proposer_address"`
+	Grant           bool      `json:"grant"`
+	BlockTime       time.Time `json:"block_time"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ProposalVote and GrantVote are unique per (height, voter_index), see
+// migrateUniqueVotes.
 type ProposalVote struct {
-	Id           uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
-	Proposal     uint64 `json:"proposal"`
-	VoterIndex   uint64 `json:"voter_index"`
-	VoterAddress string `json:"voter_address"`
-	Height       uint64 `json:"height"`
-	Vote         uint64 `json:"vote"`
+	Id           uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	Proposal     uint64    `json:"proposal"`
+	VoterIndex   uint64    `json:"voter_index"`
+	VoterAddress string    `json:"voter_address"`
+	Height       uint64    `json:"height"`
+	Vote         VoteCode  `json:"vote"`
+	Reason       string    `json:"reason"`
+	BlockTime    time.Time `json:"block_time"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 type GrantVote struct {
-	Id              uint64 `gorm:"primaryKey;autoIncrement" json:"id"`
-	ProposerIndex   uint64 `json:"proposer_index"`
-	ProposerAddress string `json:"proposer_address"`
-	AccountIndex    uint64 `json:"account_index"`
-	AccountAddr     string `json:"account_addr"`
-	VoterIndex      uint64 `json:"voter_index"`
-	VoterAddress    string `json:"voter_address"`
-	Height          uint64 `json:"height"`
-	Vote            uint64 `json:"vote"`
+	Id              uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProposerIndex   uint64    `json:"proposer_index"`
+	ProposerAddress string    `json:"proposer_address"`
+	AccountIndex    uint64    `json:"account_index"`
+	AccountAddr     string    `json:"account_addr"`
+	VoterIndex      uint64    `json:"voter_index"`
+	VoterAddress    string    `json:"voter_address"`
+	Height          uint64    `json:"height"`
+	Vote            VoteCode  `json:"vote"`
+	Reason          string    `json:"reason"`
+	BlockTime       time.Time `json:"block_time"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 type Discussion struct {
-	Id              uint64 `gorm:"primaryKey" json:"id"`
-	Proposal        uint64 `json:"proposal"`
-	SpeakerIndex    uint64 `json:"speaker_index"`
-	SpeakerAddress  string `json:"speaker_address"`
-	SpeakerName     string `json:"speaker_name"`
-	HeadPhoto       string `json:"head_photo"`
-	Data            string `json:"data"`
-	Height          uint64 `json:"height"`
-	CreateTimestamp int64  `json:"create_timestamp"`
+	Id              uint64    `gorm:"primaryKey" json:"id"`
+	Proposal        uint64    `json:"proposal"`
+	SpeakerIndex    uint64    `json:"speaker_index"`
+	SpeakerAddress  string    `json:"speaker_address"`
+	SpeakerName     string    `json:"speaker_name"`
+	HeadPhoto       string    `json:"head_photo"`
+	Data            string    `json:"data"`
+	DataHtml        string    `gorm:"-" json:"data_html,omitempty"`
+	DataText        string    `gorm:"-" json:"data_text,omitempty"`
+	Height          uint64    `json:"height"`
+	TxHash          string    `gorm:"index" json:"tx_hash"`
+	CreateTimestamp int64     `json:"create_timestamp"`
+	BlockTime       time.Time `json:"block_time"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// SocialPost is an announcement of a settled proposal posted to a social
+// account. PostId is empty and Error set when posting failed.
+type SocialPost struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Proposal  uint64    `gorm:"index" json:"proposal"`
+	Platform  string    `json:"platform"`
+	PostId    string    `json:"post_id"`
+	Text      string    `json:"text"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IpfsArchive is the cid of a proposal document or discussion thread
+// snapshot pinned to ipfs, see ArchivedProposal and ArchivedThread.
+type IpfsArchive struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Proposal  uint64    `gorm:"index" json:"proposal"`
+	Kind      string    `json:"kind"`
+	Cid       string    `json:"cid"`
+	Height    uint64    `json:"height"`
+	Size      uint64    `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BridgeMirror is a tx mirroring the outcome of a settled proposal to the
+// evm contract. A proposal has more than one row when its txs reverted.
+type BridgeMirror struct {
+	Id           uint64    `gorm:"primary_key" json:"id"`
+	Proposal     uint64    `gorm:"index" json:"proposal"`
+	ProposalHash string    `json:"proposal_hash"`
+	Result       string    `json:"result"`
+	TxHash       string    `json:"tx_hash"`
+	Nonce        uint64    `json:"nonce"`
+	Status       string    `gorm:"index" json:"status"`
+	BlockNumber  uint64    `json:"block_number"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// DigestSubscriber receives governance digests by email. Frequency is daily
+// or weekly.
+type DigestSubscriber struct {
+	Id         uint64    `gorm:"primary_key" json:"id"`
+	Email      string    `gorm:"unique_index" json:"email"`
+	Frequency  string    `json:"frequency"`
+	LastSentAt time.Time `json:"last_sent_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ApiKey grants access to the query api. Only the sha256 of the key is
+// stored, Prefix identifies it in listings. Scopes is a comma separated list
+// of read-governance, read-votes and admin. RateLimit is in requests per
+// minute and DailyQuota in requests per UTC day, 0 is unlimited.
+type ApiKey struct {
+	Id         uint64    `gorm:"primary_key" json:"id"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	KeyHash    string    `gorm:"unique_index" json:"-"`
+	Scopes     string    `json:"scopes"`
+	RateLimit  int       `json:"rate_limit"`
+	DailyQuota int       `json:"daily_quota"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ProposalStatusChange records every status transition of a proposal so its
+// lifecycle can be reconstructed after the Proposal row is overwritten.
+type ProposalStatusChange struct {
+	Id         uint64         `gorm:"primaryKey;autoIncrement" json:"id"`
+	Proposal   uint64         `gorm:"index" json:"proposal"`
+	FromStatus ProposalStatus `json:"from_status"`
+	ToStatus   ProposalStatus `json:"to_status"`
+	Height     uint64         `json:"height"`
+	BlockTime  time.Time      `json:"block_time"`
+	CreatedAt  time.Time      `json:"created_at"`
 }
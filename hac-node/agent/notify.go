@@ -0,0 +1,230 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	app_config "github.com/calehh/hac-app/config"
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+const (
+	NotifyProposalCreated    = "proposal_created"
+	NotifyProposalSettled    = "proposal_settled"
+	NotifyLocalVote          = "local_vote"
+	NotifyProposalActivating = "proposal_activating"
+	NotifyMisbehavior        = "validator_misbehavior"
+)
+
+// notifyMaxAge skips notifications of old blocks, so an indexer catching up
+// does not flood the channels.
+const notifyMaxAge = 10 * time.Minute
+
+var defaultNotifyTemplates = map[string]string{
+	NotifyProposalCreated:    `{{if .Expedited}}[EXPEDITED] {{end}}New proposal #{{.ProposalId}} "{{.Title}}" by {{.Proposer}} at height {{.Height}}`,
+	NotifyProposalSettled:    `{{if .Expedited}}[EXPEDITED] {{end}}Proposal #{{.ProposalId}} "{{.Title}}" settled as {{.Status}} at height {{.Height}}`,
+	NotifyLocalVote:          `Our agent voted {{.Vote}} on {{.Kind}} #{{.ProposalId}} at height {{.Height}}{{if .Reason}}: {{.Reason}}{{end}}`,
+	NotifyProposalActivating: `Proposal #{{.ProposalId}} "{{.Title}}" ({{.Status}}) activates at height {{.ActivationHeight}}, now at {{.Height}}`,
+	NotifyMisbehavior:        `Member {{.Validator}} misbehaved: {{.Misbehavior}}, evidence included at height {{.Height}}`,
+}
+
+// Notification is the data passed to the message templates.
+type Notification struct {
+	Event            string
+	Kind             string
+	ProposalId       uint64
+	Title            string
+	Proposer         string
+	Status           string
+	Expedited        bool
+	Vote             string
+	Reason           string
+	Height           uint64
+	BlockTime        time.Time
+	ActivationHeight uint64
+	Validator        string
+	Misbehavior      string
+}
+
+// notifyChannel posts a rendered message to one chat service.
+type notifyChannel interface {
+	send(ctx context.Context, text string) error
+}
+
+type channelNotifier struct {
+	name      string
+	channel   notifyChannel
+	events    map[string]bool
+	templates map[string]*template.Template
+}
+
+// notifier renders notifications for every configured channel and posts them
+// from a single goroutine, so slow webhooks never block indexing. A nil
+// notifier drops everything.
+type notifier struct {
+	logger   cmtlog.Logger
+	channels []channelNotifier
+	queue    chan Notification
+}
+
+func newNotifier(logger cmtlog.Logger, configs []app_config.NotifierConfig) (*notifier, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	n := &notifier{
+		logger: logger.With("module", "notifier"),
+		queue:  make(chan Notification, 100),
+	}
+	cli := &http.Client{Timeout: 10 * time.Second}
+	for i, cfg := range configs {
+		name := fmt.Sprintf("%s#%d", cfg.Type, i)
+		var ch notifyChannel
+		switch cfg.Type {
+		case "discord":
+			ch = &webhookChannel{cli: cli, url: cfg.Url, field: "content"}
+		case "slack":
+			ch = &webhookChannel{cli: cli, url: cfg.Url, field: "text"}
+		case "telegram":
+			if cfg.Token == "" || cfg.ChatId == "" {
+				return nil, fmt.Errorf("notifier %s: token and chat_id are required", name)
+			}
+			ch = &telegramChannel{cli: cli, token: cfg.Token, chatId: cfg.ChatId}
+		default:
+			return nil, fmt.Errorf("notifier %s: unknown type %q, expected discord, slack or telegram", name, cfg.Type)
+		}
+		if cfg.Type != "telegram" && cfg.Url == "" {
+			return nil, fmt.Errorf("notifier %s: url is required", name)
+		}
+		cn := channelNotifier{
+			name:      name,
+			channel:   ch,
+			events:    make(map[string]bool),
+			templates: make(map[string]*template.Template),
+		}
+		events := cfg.Events
+		if len(events) == 0 {
+			events = []string{NotifyProposalCreated, NotifyProposalSettled, NotifyLocalVote, NotifyProposalActivating, NotifyMisbehavior}
+		}
+		for _, ev := range events {
+			if _, ok := defaultNotifyTemplates[ev]; !ok {
+				return nil, fmt.Errorf("notifier %s: unknown event %q", name, ev)
+			}
+			cn.events[ev] = true
+		}
+		for ev, text := range defaultNotifyTemplates {
+			if custom, ok := cfg.Templates[ev]; ok {
+				text = custom
+			}
+			tmpl, err := template.New(ev).Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("notifier %s: template %s: %w", name, ev, err)
+			}
+			cn.templates[ev] = tmpl
+		}
+		n.channels = append(n.channels, cn)
+	}
+	return n, nil
+}
+
+// notify queues a notification, dropping it when the queue is full.
+func (n *notifier) notify(notification Notification) {
+	if n == nil || time.Since(notification.BlockTime) > notifyMaxAge {
+		return
+	}
+	select {
+	case n.queue <- notification:
+	default:
+		n.logger.Error("notification queue full, dropping", "event", notification.Event, "height", notification.Height)
+	}
+}
+
+func (n *notifier) run(ctx context.Context) {
+	if n == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-n.queue:
+			for _, ch := range n.channels {
+				if !ch.events[notification.Event] {
+					continue
+				}
+				var buf bytes.Buffer
+				if err := ch.templates[notification.Event].Execute(&buf, notification); err != nil {
+					n.logger.Error("render notification fail", "channel", ch.name, "event", notification.Event, "err", err)
+					continue
+				}
+				sendCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+				err := ch.channel.send(sendCtx, buf.String())
+				cancel()
+				if err != nil {
+					n.logger.Error("send notification fail", "channel", ch.name, "event", notification.Event, "err", err)
+				}
+			}
+		}
+	}
+}
+
+// webhookChannel posts {field: text} to an incoming webhook, which is the
+// format of both discord and slack.
+type webhookChannel struct {
+	cli   *http.Client
+	url   string
+	field string
+}
+
+func (w *webhookChannel) send(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]string{w.field: text})
+	if err != nil {
+		return err
+	}
+	return postNotification(ctx, w.cli, w.url, body)
+}
+
+type telegramChannel struct {
+	cli    *http.Client
+	token  string
+	chatId string
+}
+
+func (t *telegramChannel) send(ctx context.Context, text string) error {
+	body, err := json.Marshal(map[string]string{"chat_id": t.chatId, "text": text})
+	if err != nil {
+		return err
+	}
+	return postNotification(ctx, t.cli, fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.token), body)
+}
+
+func postNotification(ctx context.Context, cli *http.Client, endpoint string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("invalid notifier url")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := cli.Do(req)
+	if err != nil {
+		// the url holds the webhook secret or bot token, keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
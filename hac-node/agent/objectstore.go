@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ObjectStore is a client of an S3 compatible object storage, signing
+// requests with AWS signature v4 and addressing buckets by path. GCS is
+// supported through its interoperability api with HMAC keys, with endpoint
+// https://storage.googleapis.com and region auto.
+type ObjectStore struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	cli       *http.Client
+}
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+func NewObjectStore(endpoint string, region string, bucket string, accessKey string, secretKey string) *ObjectStore {
+	return &ObjectStore{
+		Endpoint:  strings.TrimRight(endpoint, "/"),
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		cli:       &http.Client{Timeout: time.Hour},
+	}
+}
+
+// PutFile uploads the file at path as key.
+func (s *ObjectStore) PutFile(ctx context.Context, key string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodPut, key, nil, f, hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	_, err = s.do(req)
+	return err
+}
+
+// List returns the objects under prefix, oldest first.
+func (s *ObjectStore) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := s.newRequest(ctx, http.MethodGet, "", query, nil, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		body, err := s.do(req)
+		if err != nil {
+			return nil, err
+		}
+		var res struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(body, &res); err != nil {
+			return nil, err
+		}
+		for _, c := range res.Contents {
+			objects = append(objects, ObjectInfo{Key: c.Key, Size: c.Size, LastModified: c.LastModified})
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			break
+		}
+		token = res.NextContinuationToken
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].LastModified.Before(objects[j].LastModified) })
+	return objects, nil
+}
+
+func (s *ObjectStore) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil, nil, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	_, err = s.do(req)
+	return err
+}
+
+func (s *ObjectStore) do(req *http.Request) ([]byte, error) {
+	res, err := s.cli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode/100 != 2 {
+		var apiErr struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Message"`
+		}
+		if xml.Unmarshal(body, &apiErr) == nil && apiErr.Code != "" {
+			return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, apiErr.Code, apiErr.Message)
+		}
+		return nil, fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, res.Status)
+	}
+	return body, nil
+}
+
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// newRequest builds a request signed with AWS signature v4.
+func (s *ObjectStore) newRequest(ctx context.Context, method string, key string, query url.Values, body io.Reader, payloadHash string) (*http.Request, error) {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/" + s.Bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", u.Host, payloadHash, amzDate)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{method, u.EscapedPath(), u.RawQuery, canonicalHeaders, signedHeaders, payloadHash}, "\n")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", day, s.Region)
+	crHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(crHash[:])}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.SecretKey), day)
+	signingKey = hmacSHA256(signingKey, s.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.AccessKey, scope, signedHeaders, signature))
+	return req, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	Seed        int64   `json:"seed,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   interface{}     `json:"format,omitempty"`
+	Options  ollamaOptions   `json:"options"`
+}
+
+type ollamaResponse struct {
+	Message ollamaMessage `json:"message"`
+	Error   string        `json:"error"`
+}
+
+var _ Client = &OllamaClient{}
+
+// OllamaClient is an agent backend calling a local Ollama server, so a
+// validator decides fully offline. Votes are answered as json constrained by
+// the vote schema. With a seed and a temperature of 0 the same prompt gets the
+// same vote.
+type OllamaClient struct {
+	llmBackend
+	Url         string
+	Model       string
+	temperature float64
+	seed        int64
+	http        *http.Client
+}
+
+func NewOllamaClient(cfg BackendConfig, logger cmtlog.Logger) (*OllamaClient, error) {
+	if cfg.Model == "" {
+		return nil, errors.New("ollama backend needs agent_model")
+	}
+	client := &OllamaClient{
+		Url:         strings.TrimRight(cfg.Url, "/"),
+		Model:       cfg.Model,
+		temperature: cfg.Temperature,
+		seed:        cfg.Seed,
+		http:        cfg.HTTP,
+	}
+	backend, err := newLLMBackend(client.chat, cfg.Prompts, logger.With("module", "ollama"))
+	if err != nil {
+		return nil, err
+	}
+	client.llmBackend = backend
+	return client, nil
+}
+
+func (o *OllamaClient) chat(ctx context.Context, system string, user string, vote bool) (llmAnswer, error) {
+	req := ollamaRequest{
+		Model: o.Model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		Options: ollamaOptions{
+			Temperature: o.temperature,
+			Seed:        o.seed,
+		},
+	}
+	if vote {
+		req.Format = llmVoteSchema
+	}
+	data, _ := json.Marshal(req)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.Url+"/api/chat", bytes.NewReader(data))
+	if err != nil {
+		return llmAnswer{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	res, err := httpClientOrDefault(o.http).Do(httpReq)
+	if err != nil {
+		return llmAnswer{}, agentCallError("ollama chat", err)
+	}
+	defer res.Body.Close()
+	answer := llmAnswer{Status: res.StatusCode}
+	bodyBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return answer, agentCallError("ollama chat", err)
+	}
+	answer.Response = string(bodyBytes)
+	if err := agentStatusError("ollama chat", res.StatusCode, bodyBytes); err != nil {
+		return answer, err
+	}
+	var chat ollamaResponse
+	if err := json.Unmarshal(bodyBytes, &chat); err != nil {
+		return answer, agentResponseError("ollama chat", res.StatusCode, bodyBytes, err)
+	}
+	if chat.Error != "" {
+		return answer, agentResponseError("ollama chat", res.StatusCode, bodyBytes, errors.New(chat.Error))
+	}
+	answer.Content = chat.Message.Content
+	return answer, nil
+}
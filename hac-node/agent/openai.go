@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+type openAIMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
+}
+
+type openAIToolCall struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Temperature float64         `json:"temperature"`
+	Seed        int64           `json:"seed,omitempty"`
+	Tools       []interface{}   `json:"tools,omitempty"`
+	ToolChoice  interface{}     `json:"tool_choice,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// openAIVoteTool is the function the model calls to answer a vote.
+var openAIVoteTool = map[string]interface{}{
+	"type": "function",
+	"function": map[string]interface{}{
+		"name":        "vote",
+		"description": "Cast the vote with its reason.",
+		"parameters":  llmVoteSchema,
+	},
+}
+
+var _ Client = &OpenAIClient{}
+
+// OpenAIClient is an agent backend calling an OpenAI-compatible chat
+// completions endpoint. Votes are answered with a forced call of the vote
+// tool.
+type OpenAIClient struct {
+	llmBackend
+	Url         string
+	Model       string
+	apiKey      string
+	temperature float64
+	seed        int64
+	http        *http.Client
+}
+
+func NewOpenAIClient(cfg BackendConfig, logger cmtlog.Logger) (*OpenAIClient, error) {
+	if cfg.Model == "" {
+		return nil, errors.New("openai backend needs agent_model")
+	}
+	apiKey := cfg.ApiKey
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	client := &OpenAIClient{
+		Url:         strings.TrimRight(cfg.Url, "/"),
+		Model:       cfg.Model,
+		apiKey:      apiKey,
+		temperature: cfg.Temperature,
+		seed:        cfg.Seed,
+		http:        cfg.HTTP,
+	}
+	backend, err := newLLMBackend(client.chat, cfg.Prompts, logger.With("module", "openai"))
+	if err != nil {
+		return nil, err
+	}
+	client.llmBackend = backend
+	return client, nil
+}
+
+func (o *OpenAIClient) chat(ctx context.Context, system string, user string, vote bool) (llmAnswer, error) {
+	req := openAIRequest{
+		Model: o.Model,
+		Messages: []openAIMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		Temperature: o.temperature,
+		Seed:        o.seed,
+	}
+	if vote {
+		req.Tools = []interface{}{openAIVoteTool}
+		req.ToolChoice = map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": "vote"},
+		}
+	}
+	data, _ := json.Marshal(req)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.Url+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return llmAnswer{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	res, err := httpClientOrDefault(o.http).Do(httpReq)
+	if err != nil {
+		return llmAnswer{}, agentCallError("chat completion", err)
+	}
+	defer res.Body.Close()
+	answer := llmAnswer{Status: res.StatusCode}
+	bodyBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return answer, agentCallError("chat completion", err)
+	}
+	answer.Response = string(bodyBytes)
+	if err := agentStatusError("chat completion", res.StatusCode, bodyBytes); err != nil {
+		return answer, err
+	}
+	var completion openAIResponse
+	if err := json.Unmarshal(bodyBytes, &completion); err != nil {
+		return answer, agentResponseError("chat completion", res.StatusCode, bodyBytes, err)
+	}
+	if completion.Error != nil {
+		return answer, agentResponseError("chat completion", res.StatusCode, bodyBytes, errors.New(completion.Error.Message))
+	}
+	if len(completion.Choices) == 0 {
+		return answer, agentResponseError("chat completion", res.StatusCode, bodyBytes, errors.New("no choice"))
+	}
+	msg := completion.Choices[0].Message
+	answer.Content = msg.Content
+	for _, call := range msg.ToolCalls {
+		if call.Function.Name == "vote" {
+			answer.Vote = call.Function.Arguments
+			break
+		}
+	}
+	return answer, nil
+}
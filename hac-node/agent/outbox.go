@@ -0,0 +1,281 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+	OutboxStatusFailed    = "failed"
+)
+
+// OutboxMaxAttempts is the number of failed deliveries after which an outbox
+// entry is given up and the entries queued behind it go on.
+const OutboxMaxAttempts = 20
+
+var OutboxInterval = 5 * time.Second
+
+const outboxBatch = 100
+
+// Agent methods delivered through the outbox.
+const (
+	outboxAddProposal       = "AddProposal"
+	outboxCommentProposal   = "CommentPropoal"
+	outboxAddDiscussion     = "AddDiscussion"
+	outboxNotifyActivation  = "NotifyActivation"
+	outboxMisbehavior       = "NotifyMisbehavior"
+	outboxSummarizeProposal = "SummarizeProposal"
+	outboxSummarizeData     = "SummarizeProposalData"
+)
+
+// AgentOutbox is an agent call queued in the transaction indexing its chain
+// event. The dispatcher delivers the pending entries in id order with their
+// idempotency key and marks them delivered, so an event indexed is delivered
+// even if the node crashed right after, and a crash between the delivery and
+// the mark sends the same key again, which the agent deduplicates.
+type AgentOutbox struct {
+	Id             uint64     `gorm:"primary_key" json:"id"`
+	IdempotencyKey string     `gorm:"unique_index" json:"idempotency_key"`
+	Method         string     `json:"method"`
+	Proposal       uint64     `gorm:"index" json:"proposal"`
+	Address        string     `json:"address"`
+	Text           string     `json:"text"`
+	Height         uint64     `json:"height"`
+	Expedited      bool       `json:"expedited"`
+	Status         string     `gorm:"index" json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastError      string     `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// enqueueAgentCall adds the call for the event hashed as event to the outbox
+// in tx. A replayed height finds the entry already queued and adds nothing.
+func (c *ChainIndexer) enqueueAgentCall(tx *gorm.DB, event string, call AgentOutbox) error {
+	if c.indexOnly {
+		return nil
+	}
+	call.IdempotencyKey = event + ":" + call.Method
+	call.Status = OutboxStatusPending
+	var existing AgentOutbox
+	err := tx.Where("idempotency_key = ?", call.IdempotencyKey).First(&existing).Error
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return tx.Create(&call).Error
+}
+
+// wakeOutbox starts a dispatch pass without waiting for the next tick, once
+// the transaction queuing entries committed.
+func (c *ChainIndexer) wakeOutbox() {
+	select {
+	case c.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *ChainIndexer) runOutbox(ctx context.Context) {
+	ticker := time.NewTicker(OutboxInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.outboxWake:
+		}
+		// only the leader of redundant indexers delivers, a follower taking
+		// over goes on with the entries left pending
+		if c.follower() {
+			continue
+		}
+		if err := c.dispatchOutbox(ctx); err != nil {
+			c.logger.Error("dispatch agent outbox fail", "err", err)
+		}
+	}
+}
+
+// dispatchOutbox delivers the pending entries in order. It stops at the first
+// failure so the agent never sees a discussion before its proposal, unless the
+// entry ran out of attempts or the agent rejected it. The discussions of a
+// block queued one after the other are delivered in a single batch.
+func (c *ChainIndexer) dispatchOutbox(ctx context.Context) error {
+	defer c.updateOutboxGauge()
+	for {
+		var pending []AgentOutbox
+		if err := c.db.Where("status = ?", OutboxStatusPending).Order("id asc").Limit(outboxBatch).Find(&pending).Error; err != nil {
+			return err
+		}
+		for i := 0; i < len(pending); {
+			if ctx.Err() != nil || c.follower() {
+				return nil
+			}
+			batch := outboxDiscussionBatch(pending[i:])
+			var err error
+			if len(batch) > 1 {
+				err = c.deliverDiscussions(ctx, batch)
+			} else {
+				err = c.deliverAgentCall(WithIdempotencyKey(ctx, batch[0].IdempotencyKey), batch[0])
+			}
+			i += len(batch)
+			if err := c.markOutbox(batch, err); err != nil {
+				return err
+			}
+		}
+		if len(pending) < outboxBatch {
+			return nil
+		}
+	}
+}
+
+// outboxDiscussionBatch returns the first entry of pending, with the
+// discussions of the same block queued right after it when it is one.
+func outboxDiscussionBatch(pending []AgentOutbox) []AgentOutbox {
+	first := pending[0]
+	n := 1
+	if first.Method == outboxAddDiscussion {
+		for n < len(pending) && pending[n].Method == outboxAddDiscussion &&
+			pending[n].Height == first.Height && pending[n].Expedited == first.Expedited {
+			n++
+		}
+	}
+	return pending[:n]
+}
+
+// markOutbox records the outcome err of the delivery of entries. It returns
+// err when the entries are to be delivered again, so the dispatch stops.
+func (c *ChainIndexer) markOutbox(entries []AgentOutbox, err error) error {
+	if err == nil {
+		now := time.Now().UTC()
+		for _, entry := range entries {
+			entry.Status = OutboxStatusDelivered
+			entry.DeliveredAt = &now
+			entry.LastError = ""
+			if err := c.db.Save(&entry).Error; err != nil {
+				return err
+			}
+			agentOutboxDelivered.WithLabelValues(entry.Method).Inc()
+		}
+		return nil
+	}
+	retry := false
+	for _, entry := range entries {
+		entry.Attempts++
+		entry.LastError = err.Error()
+		// a rejected call fails the same way on every attempt
+		if entry.Attempts >= OutboxMaxAttempts || errors.Is(err, ErrAgentRejected) {
+			entry.Status = OutboxStatusFailed
+			c.logger.Error("give up agent call", "method", entry.Method, "proposal", entry.Proposal, "attempts", entry.Attempts, "err", err)
+		}
+		if err := c.db.Save(&entry).Error; err != nil {
+			return err
+		}
+		if entry.Status == OutboxStatusFailed {
+			agentOutboxFailed.WithLabelValues(entry.Method).Inc()
+			continue
+		}
+		retry = true
+	}
+	if retry {
+		return fmt.Errorf("%s proposal %d: %w", entries[0].Method, entries[0].Proposal, err)
+	}
+	return nil
+}
+
+// deliverDiscussions sends the discussions of entries in one call, keyed by
+// the first and the last entry so a batch sent again after a crash carries
+// the same key.
+func (c *ChainIndexer) deliverDiscussions(ctx context.Context, entries []AgentOutbox) error {
+	first, last := entries[0], entries[len(entries)-1]
+	ctx = WithIdempotencyKey(ctx, first.IdempotencyKey+".."+last.IdempotencyKey)
+	if first.Expedited {
+		ctx = WithExpedited(ctx)
+	}
+	msgs := make([]DiscussionMsg, 0, len(entries))
+	for _, entry := range entries {
+		msgs = append(msgs, DiscussionMsg{Proposal: entry.Proposal, Speaker: entry.Address, Text: entry.Text})
+	}
+	return ActiveClient().AddDiscussions(ctx, msgs)
+}
+
+func (c *ChainIndexer) deliverAgentCall(ctx context.Context, entry AgentOutbox) error {
+	if entry.Expedited {
+		ctx = WithExpedited(ctx)
+	}
+	switch entry.Method {
+	case outboxAddProposal:
+		return ActiveClient().AddProposal(ctx, entry.Proposal, entry.Address, entry.Text)
+	case outboxCommentProposal:
+		comment, err := ActiveClient().CommentPropoal(ctx, entry.Proposal, entry.Address)
+		if err == nil {
+			c.logger.Info("comment proposal", "comment", comment)
+		}
+		return err
+	case outboxAddDiscussion:
+		return ActiveClient().AddDiscussion(ctx, entry.Proposal, entry.Address, entry.Text)
+	case outboxNotifyActivation:
+		// the height of an activation notice is the activation height
+		return ActiveClient().NotifyActivation(ctx, entry.Proposal, entry.Height)
+	case outboxMisbehavior:
+		// the text of a misbehavior alert is its kind
+		return ActiveClient().NotifyMisbehavior(ctx, entry.Address, entry.Text, entry.Height)
+	case outboxSummarizeProposal:
+		return c.summarizeProposal(ctx, entry)
+	case outboxSummarizeData:
+		return c.summarizeProposalData(ctx, entry)
+	}
+	return fmt.Errorf("unknown agent method %s", entry.Method)
+}
+
+func (c *ChainIndexer) getAgentOutbox(status string, page int, pageSize int) (Page[AgentOutbox], error) {
+	query := c.db.Model(&AgentOutbox{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	return paginate[AgentOutbox](query, "id desc", page, pageSize)
+}
+
+// requeueAgentOutbox sends the failed entries with ids, or all of them, to
+// the agent again with a fresh count of attempts.
+func (c *ChainIndexer) requeueAgentOutbox(ids []uint64) (int64, error) {
+	query := c.db.Model(&AgentOutbox{}).Where("status = ?", OutboxStatusFailed)
+	if len(ids) > 0 {
+		query = query.Where("id IN (?)", ids)
+	}
+	res := query.Updates(map[string]interface{}{"status": OutboxStatusPending, "attempts": 0})
+	if res.Error == nil && res.RowsAffected > 0 {
+		c.wakeOutbox()
+	}
+	return res.RowsAffected, res.Error
+}
+
+func (c *ChainIndexer) updateOutboxGauge() {
+	var pending uint64
+	if err := c.db.Model(&AgentOutbox{}).Where("status = ?", OutboxStatusPending).Count(&pending).Error; err != nil {
+		return
+	}
+	agentOutboxPending.Set(float64(pending))
+}
+
+// migrateAgentDeliveries keeps the agent calls acknowledged before the outbox
+// as delivered entries, so replaying their heights does not send them again.
+func migrateAgentDeliveries(tx *gorm.DB) error {
+	if !tx.HasTable("agent_deliveries") {
+		return nil
+	}
+	err := tx.Exec("INSERT INTO agent_outboxes (idempotency_key, method, proposal, address, text, height, status, attempts, last_error, delivered_at, created_at) "+
+		"SELECT idempotency_key, method, proposal, '', '', 0, ?, 0, '', created_at, created_at FROM agent_deliveries", OutboxStatusDelivered).Error
+	if err != nil {
+		return err
+	}
+	return tx.DropTable("agent_deliveries").Error
+}
@@ -0,0 +1,94 @@
+package agent
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page is the envelope of every list, returned by the get* methods and by
+// every list endpoint. Page numbers start at 1. NextCursor is empty on the
+// last page, otherwise it is sent back as cursor to fetch the next one.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      uint64 `json:"total"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// PageReq holds the paging fields of list requests. A cursor takes
+// precedence over page.
+type PageReq struct {
+	Page     int    `json:"page"`
+	PageSize int    `json:"pageSize"`
+	Cursor   string `json:"cursor"`
+}
+
+// index returns the page to query, starting at 0.
+func (r PageReq) index() (int, error) {
+	if r.Cursor != "" {
+		return decodeCursor(r.Cursor)
+	}
+	if r.Page < 1 {
+		return 0, nil
+	}
+	return r.Page - 1, nil
+}
+
+func encodeCursor(index int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("p:" + strconv.Itoa(index)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	n, ok := strings.CutPrefix(string(raw), "p:")
+	if !ok {
+		return 0, ErrInvalidCursor
+	}
+	index, err := strconv.Atoi(n)
+	if err != nil || index < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return index, nil
+}
+
+// newPage wraps one page of items, page starting at 0.
+func newPage[T any](items []T, total uint64, page int, pageSize int) Page[T] {
+	p := Page[T]{Items: make([]T, 0, len(items)), Total: total, Page: page + 1, PageSize: pageSize}
+	p.Items = append(p.Items, items...)
+	if pageSize > 0 && uint64(page+1)*uint64(pageSize) < total {
+		p.NextCursor = encodeCursor(page + 1)
+	}
+	return p
+}
+
+// fullPage wraps a list that is returned whole.
+func fullPage[T any](items []T) Page[T] {
+	return newPage(items, uint64(len(items)), 0, len(items))
+}
+
+// paginate counts the rows matched by query, which must have a model set,
+// and loads page, starting at 0, in order.
+func paginate[T any](query *gorm.DB, order string, page int, pageSize int) (Page[T], error) {
+	if page < 0 {
+		page = 0
+	}
+	var total uint64
+	if err := query.Count(&total).Error; err != nil {
+		return Page[T]{}, err
+	}
+	var items []T
+	if err := query.Order(order).Offset(page * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return Page[T]{}, err
+	}
+	return newPage(items, total, page, pageSize), nil
+}
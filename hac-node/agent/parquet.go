@@ -0,0 +1,333 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Parquet physical types, converted types and encodings of the columns
+// written by parquetWriter, see the parquet-format thrift definitions.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetPlain = 0
+	parquetRLE   = 3
+)
+
+// ParquetRowGroupRows is the number of rows buffered in a row group before it
+// is written.
+const ParquetRowGroupRows = 50000
+
+// parquetColumn is a nullable column of a parquet file. A converted type
+// below 0 is none.
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32
+	defs      []bool
+	values    bytes.Buffer
+	bools     []bool
+}
+
+func (c *parquetColumn) appendNull() {
+	c.defs = append(c.defs, false)
+}
+
+func (c *parquetColumn) appendInt64(v int64) {
+	c.defs = append(c.defs, true)
+	binary.Write(&c.values, binary.LittleEndian, v)
+}
+
+func (c *parquetColumn) appendDouble(v float64) {
+	c.defs = append(c.defs, true)
+	binary.Write(&c.values, binary.LittleEndian, math.Float64bits(v))
+}
+
+func (c *parquetColumn) appendBool(v bool) {
+	c.defs = append(c.defs, true)
+	c.bools = append(c.bools, v)
+}
+
+func (c *parquetColumn) appendBytes(v []byte) {
+	c.defs = append(c.defs, true)
+	binary.Write(&c.values, binary.LittleEndian, uint32(len(v)))
+	c.values.Write(v)
+}
+
+// page encodes the buffered values as the data of a v1 data page: the
+// definition levels as a length prefixed RLE run list, then the plain
+// encoded values of the rows that are not null.
+func (c *parquetColumn) page() []byte {
+	var levels bytes.Buffer
+	for i := 0; i < len(c.defs); {
+		j := i
+		for j < len(c.defs) && c.defs[j] == c.defs[i] {
+			j++
+		}
+		levels.Write(binary.AppendUvarint(nil, uint64(j-i)<<1))
+		if c.defs[i] {
+			levels.WriteByte(1)
+		} else {
+			levels.WriteByte(0)
+		}
+		i = j
+	}
+	var page bytes.Buffer
+	binary.Write(&page, binary.LittleEndian, uint32(levels.Len()))
+	page.Write(levels.Bytes())
+	if c.typ == parquetBoolean {
+		packed := make([]byte, (len(c.bools)+7)/8)
+		for i, b := range c.bools {
+			if b {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		page.Write(packed)
+	} else {
+		page.Write(c.values.Bytes())
+	}
+	return page.Bytes()
+}
+
+func (c *parquetColumn) reset() {
+	c.defs = c.defs[:0]
+	c.values.Reset()
+	c.bools = c.bools[:0]
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+type parquetRowGroup struct {
+	rows   int64
+	size   int64
+	chunks []parquetChunk
+}
+
+// parquetWriter writes a parquet file of nullable flat columns, one
+// uncompressed plain encoded data page per column and row group. Rows are
+// added column by column with the append methods of columns, then
+// committed with endRow.
+type parquetWriter struct {
+	w         io.Writer
+	offset    int64
+	columns   []*parquetColumn
+	rows      int64
+	groupRows int64
+	groups    []parquetRowGroup
+}
+
+func newParquetWriter(w io.Writer, columns []*parquetColumn) (*parquetWriter, error) {
+	pw := &parquetWriter{w: w, columns: columns}
+	return pw, pw.write([]byte("PAR1"))
+}
+
+func (pw *parquetWriter) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+func (pw *parquetWriter) endRow() error {
+	pw.rows++
+	pw.groupRows++
+	if pw.groupRows >= ParquetRowGroupRows {
+		return pw.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group.
+func (pw *parquetWriter) flush() error {
+	if pw.groupRows == 0 {
+		return nil
+	}
+	group := parquetRowGroup{rows: pw.groupRows}
+	for _, c := range pw.columns {
+		data := c.page()
+		var t thriftWriter
+		t.structBegin()
+		t.i32(1, 0) // DATA_PAGE
+		t.i32(2, int32(len(data)))
+		t.i32(3, int32(len(data)))
+		t.structField(5)
+		t.i32(1, int32(pw.groupRows))
+		t.i32(2, parquetPlain)
+		t.i32(3, parquetRLE)
+		t.i32(4, parquetRLE)
+		t.structEnd()
+		t.structEnd()
+		chunk := parquetChunk{offset: pw.offset, size: int64(t.buf.Len() + len(data)), values: pw.groupRows}
+		if err := pw.write(t.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := pw.write(data); err != nil {
+			return err
+		}
+		group.size += chunk.size
+		group.chunks = append(group.chunks, chunk)
+		c.reset()
+	}
+	pw.groups = append(pw.groups, group)
+	pw.groupRows = 0
+	return nil
+}
+
+// Close writes the last row group and the footer.
+func (pw *parquetWriter) Close() error {
+	if err := pw.flush(); err != nil {
+		return err
+	}
+	var t thriftWriter
+	t.structBegin()
+	t.i32(1, 1)
+	t.listBegin(2, thriftStruct, len(pw.columns)+1)
+	t.structBegin()
+	t.binary(4, "schema")
+	t.i32(5, int32(len(pw.columns)))
+	t.structEnd()
+	for _, c := range pw.columns {
+		t.structBegin()
+		t.i32(1, c.typ)
+		t.i32(3, 1) // OPTIONAL
+		t.binary(4, c.name)
+		if c.converted >= 0 {
+			t.i32(6, c.converted)
+		}
+		t.structEnd()
+	}
+	t.i64(3, pw.rows)
+	t.listBegin(4, thriftStruct, len(pw.groups))
+	for _, g := range pw.groups {
+		t.structBegin()
+		t.listBegin(1, thriftStruct, len(g.chunks))
+		for i, chunk := range g.chunks {
+			c := pw.columns[i]
+			t.structBegin()
+			t.i64(2, chunk.offset)
+			t.structField(3)
+			t.i32(1, c.typ)
+			t.listBegin(2, thriftI32, 2)
+			t.listI32(parquetPlain)
+			t.listI32(parquetRLE)
+			t.listBegin(3, thriftBinary, 1)
+			t.listBinary(c.name)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, chunk.values)
+			t.i64(6, chunk.size)
+			t.i64(7, chunk.size)
+			t.i64(9, chunk.offset)
+			t.structEnd()
+			t.structEnd()
+		}
+		t.i64(2, g.size)
+		t.i64(3, g.rows)
+		t.structEnd()
+	}
+	t.binary(6, "hac indexer")
+	t.structEnd()
+	if err := pw.write(t.buf.Bytes()); err != nil {
+		return err
+	}
+	if err := pw.write(binary.LittleEndian.AppendUint32(nil, uint32(t.buf.Len()))); err != nil {
+		return err
+	}
+	return pw.write([]byte("PAR1"))
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the parquet metadata with the thrift compact
+// protocol. Fields of a struct are written in increasing id order.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) structBegin() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) structEnd() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+// structField starts the struct field id, ended with structEnd.
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, thriftStruct)
+	t.structBegin()
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.listBinary(s)
+}
+
+// listBegin starts the list field id of n elements of typ, the elements
+// follow without field headers.
+func (t *thriftWriter) listBegin(id int16, typ byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | typ)
+		return
+	}
+	t.buf.WriteByte(0xf0 | typ)
+	t.varint(uint64(n))
+}
+
+func (t *thriftWriter) listI32(v int32) {
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) listBinary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
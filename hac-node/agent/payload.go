@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxTitleLen   = 120
+	maxSummaryLen = 280
+)
+
+// ProposalEnvelope is the optional JSON envelope a proposal Data can carry.
+// ActivationHeight schedules a passed proposal to take effect at a later
+// height. Type selects a structured payload, the expedited one explains its
+// urgency in Reason, the spend one pays Amount to Recipient.
+type ProposalEnvelope struct {
+	Type             string  `json:"type,omitempty"`
+	Title            string  `json:"title"`
+	Summary          string  `json:"summary"`
+	Body             string  `json:"body"`
+	ActivationHeight uint64  `json:"activation_height,omitempty"`
+	Reason           string  `json:"reason,omitempty"`
+	Recipient        string  `json:"recipient,omitempty"`
+	Amount           float64 `json:"amount,omitempty"`
+}
+
+// parseProposalPayload extracts a title and summary from a proposal payload.
+// Payloads that are not a JSON envelope are treated as plain text: the first
+// line becomes the title and the first paragraph the summary. A title set on
+// the proposal tx takes precedence over anything parsed from the payload.
+func parseProposalPayload(data []byte, txTitle string) (title string, summary string) {
+	var env ProposalEnvelope
+	body := string(data)
+	if err := json.Unmarshal(data, &env); err == nil && (env.Title != "" || env.Body != "" || env.Summary != "") {
+		body = env.Body
+		title = env.Title
+		summary = env.Summary
+	}
+	body = strings.TrimSpace(body)
+	if title == "" {
+		title, _, _ = strings.Cut(body, "\n")
+	}
+	if summary == "" {
+		summary, _, _ = strings.Cut(body, "\n\n")
+	}
+	if strings.TrimSpace(txTitle) != "" {
+		title = txTitle
+	}
+	title = truncateText(strings.TrimSpace(title), maxTitleLen)
+	summary = truncateText(strings.Join(strings.Fields(summary), " "), maxSummaryLen)
+	return title, summary
+}
+
+// parseActivationHeight returns the activation height of a proposal payload,
+// 0 for payloads without one.
+func parseActivationHeight(data []byte) uint64 {
+	var env ProposalEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return 0
+	}
+	return env.ActivationHeight
+}
+
+// truncateText cuts s to at most n runes, marking the cut with an ellipsis.
+func truncateText(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
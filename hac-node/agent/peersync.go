@@ -0,0 +1,323 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Kinds of the frames of a peer sync stream: a header, the batches of every
+// table each followed by a table frame, and an end frame.
+const (
+	SyncFrameHeader = "header"
+	SyncFrameBatch  = "batch"
+	SyncFrameTable  = "table"
+	SyncFrameEnd    = "end"
+)
+
+const syncBatchRows = 500
+
+// peerSyncSkip are the tables belonging to a node rather than to the chain,
+// they are not handed to peers. A peer did its own agent deliveries, the
+// transcripts of its agent may hold what the operator redacts, it anchors
+// its own transcripts, its agent timed out on its own, answers its own
+// questions and its ensemble is its own.
+var peerSyncSkip = map[string]bool{"digest_subscribers": true, "api_keys": true, "agent_outboxes": true, "transcripts": true, "decision_anchors": true, "vote_fallbacks": true, "agent_questions": true, "ensemble_votes": true}
+
+// SyncFrame is one json line of a peer sync stream. Batch frames carry the
+// gob encoded rows of a table and their sha256. A table frame closes a table
+// with its row count and the sha256 of its batch hashes, the end frame holds
+// the sha256 of the table hashes.
+type SyncFrame struct {
+	Kind          string `json:"kind"`
+	SchemaVersion uint64 `json:"schema_version,omitempty"`
+	ChainId       string `json:"chain_id,omitempty"`
+	Height        uint64 `json:"height,omitempty"`
+	Table         string `json:"table,omitempty"`
+	Data          []byte `json:"data,omitempty"`
+	Rows          uint64 `json:"rows,omitempty"`
+	Sha256        string `json:"sha256"`
+}
+
+// SyncReport is passed to the progress callback of SyncIndexDB.
+type SyncReport struct {
+	Table  string
+	Copied uint64
+	Done   bool
+}
+
+// syncSource returns a consistent read view of the indexer db while indexing
+// goes on: a VACUUM INTO copy of a sqlite db, or a repeatable read
+// transaction on postgres.
+func (c *ChainIndexer) syncSource(ctx context.Context) (*gorm.DB, func(), error) {
+	if c.db.Dialect().GetName() == "postgres" {
+		tx := c.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+		if tx.Error != nil {
+			return nil, nil, tx.Error
+		}
+		return tx, func() { tx.Rollback() }, nil
+	}
+	dir, err := os.MkdirTemp("", "hac-sync-")
+	if err != nil {
+		return nil, nil, err
+	}
+	path := filepath.Join(dir, "indexer.db")
+	if err := c.db.Exec("VACUUM INTO ?", path).Error; err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	db, err := openIndexDB(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// writeSyncSnapshot streams the chain tables of the indexer db with the
+// height they were indexed up to. The peer resumes indexing after it.
+func (c *ChainIndexer) writeSyncSnapshot(ctx context.Context, w io.Writer) error {
+	src, done, err := c.syncSource(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	h := Height{Id: 1}
+	if err := src.First(&h).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	version, err := getSchemaVersion(src)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	flush := func() {
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	if err := enc.Encode(SyncFrame{Kind: SyncFrameHeader, SchemaVersion: version, ChainId: c.ChainId, Height: h.Height}); err != nil {
+		return err
+	}
+	total := sha256.New()
+	for _, t := range copyTables {
+		table := src.NewScope(t.model).TableName()
+		if peerSyncSkip[table] {
+			continue
+		}
+		sum := sha256.New()
+		rows := uint64(0)
+		var last uint64
+		elem := reflect.TypeOf(t.model).Elem()
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			batch := reflect.New(reflect.SliceOf(elem))
+			query := src.Order(t.key + " asc").Limit(syncBatchRows)
+			if rows > 0 {
+				query = query.Where(t.key+" > ?", last)
+			}
+			if err := query.Find(batch.Interface()).Error; err != nil {
+				return fmt.Errorf("read %s: %w", table, err)
+			}
+			n := batch.Elem().Len()
+			if n == 0 {
+				break
+			}
+			var data bytes.Buffer
+			if err := gob.NewEncoder(&data).Encode(batch.Interface()); err != nil {
+				return err
+			}
+			batchSum := sha256.Sum256(data.Bytes())
+			sum.Write(batchSum[:])
+			if err := enc.Encode(SyncFrame{Kind: SyncFrameBatch, Table: table, Data: data.Bytes(), Rows: uint64(n), Sha256: hex.EncodeToString(batchSum[:])}); err != nil {
+				return err
+			}
+			flush()
+			key, ok := src.NewScope(batch.Elem().Index(n - 1).Addr().Interface()).FieldByName(t.key)
+			if !ok {
+				return fmt.Errorf("no field %s", t.key)
+			}
+			last = key.Field.Uint()
+			rows += uint64(n)
+			if n < syncBatchRows {
+				break
+			}
+		}
+		tableSum := hex.EncodeToString(sum.Sum(nil))
+		total.Write([]byte(tableSum))
+		if err := enc.Encode(SyncFrame{Kind: SyncFrameTable, Table: table, Rows: rows, Sha256: tableSum}); err != nil {
+			return err
+		}
+	}
+	err = enc.Encode(SyncFrame{Kind: SyncFrameEnd, Height: h.Height, Sha256: hex.EncodeToString(total.Sum(nil))})
+	flush()
+	return err
+}
+
+// SyncIndexDB fills the fresh indexer db at dst from the sync snapshot of a
+// trusted peer indexer at peer, an api address, and returns the height the
+// peer had indexed. Every batch, table and the whole stream are checked
+// against their hashes. The height cursor is written last, so a node started
+// on an interrupted sync indexes from scratch and running the sync again
+// overwrites the rows already copied.
+func SyncIndexDB(ctx context.Context, peer string, apiKey string, dst string, progress func(SyncReport)) (uint64, error) {
+	to, err := openIndexDB(dst)
+	if err != nil {
+		return 0, fmt.Errorf("open target: %w", err)
+	}
+	defer to.Close()
+	if err := to.AutoMigrate(indexerModels...).Error; err != nil {
+		return 0, err
+	}
+	if err := migrate(to); err != nil {
+		return 0, err
+	}
+	if err := to.First(&Height{Id: 1}).Error; err == nil {
+		return 0, errors.New("target db has already indexed blocks, peer sync needs a fresh db")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(peer, "/")+"/api/admin/sync/snapshot", nil)
+	if err != nil {
+		return 0, err
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return 0, fmt.Errorf("peer: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	models := make(map[string]copyTable, len(copyTables))
+	for _, t := range copyTables {
+		models[to.NewScope(t.model).TableName()] = t
+	}
+	raw := to.Set("gorm:update_column", true).Set("gorm:save_associations", false)
+	dec := json.NewDecoder(bufio.NewReader(resp.Body))
+	var header SyncFrame
+	if err := dec.Decode(&header); err != nil {
+		return 0, fmt.Errorf("read header: %w", err)
+	}
+	if header.Kind != SyncFrameHeader {
+		return 0, fmt.Errorf("unexpected %s frame, expected header", header.Kind)
+	}
+	if header.SchemaVersion != SchemaVersionLatest() {
+		return 0, fmt.Errorf("peer schema version %d, expected %d, run the same release on both", header.SchemaVersion, SchemaVersionLatest())
+	}
+	var heights []Height
+	total := sha256.New()
+	sums := make(map[string]hash.Hash)
+	copied := make(map[string]uint64)
+	for {
+		var frame SyncFrame
+		if err := dec.Decode(&frame); err != nil {
+			return 0, fmt.Errorf("read snapshot: %w", err)
+		}
+		switch frame.Kind {
+		case SyncFrameBatch:
+			t, ok := models[frame.Table]
+			if !ok || peerSyncSkip[frame.Table] {
+				return 0, fmt.Errorf("unknown table %s", frame.Table)
+			}
+			batchSum := sha256.Sum256(frame.Data)
+			if hex.EncodeToString(batchSum[:]) != frame.Sha256 {
+				return 0, fmt.Errorf("%s batch hash mismatch", frame.Table)
+			}
+			if sums[frame.Table] == nil {
+				sums[frame.Table] = sha256.New()
+			}
+			sums[frame.Table].Write(batchSum[:])
+			batch := reflect.New(reflect.SliceOf(reflect.TypeOf(t.model).Elem()))
+			if err := gob.NewDecoder(bytes.NewReader(frame.Data)).Decode(batch.Interface()); err != nil {
+				return 0, fmt.Errorf("decode %s: %w", frame.Table, err)
+			}
+			if hs, ok := batch.Elem().Interface().([]Height); ok {
+				// the cursor is written once the whole stream is verified
+				heights = append(heights, hs...)
+				continue
+			}
+			err := raw.Transaction(func(tx *gorm.DB) error {
+				for i := 0; i < batch.Elem().Len(); i++ {
+					if err := tx.Save(batch.Elem().Index(i).Addr().Interface()).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return 0, fmt.Errorf("write %s: %w", frame.Table, err)
+			}
+			copied[frame.Table] += uint64(batch.Elem().Len())
+			if progress != nil {
+				progress(SyncReport{Table: frame.Table, Copied: copied[frame.Table]})
+			}
+		case SyncFrameTable:
+			t, ok := models[frame.Table]
+			if !ok {
+				return 0, fmt.Errorf("unknown table %s", frame.Table)
+			}
+			sum, ok := sums[frame.Table]
+			if !ok {
+				// a table without rows
+				sum = sha256.New()
+			}
+			got := hex.EncodeToString(sum.Sum(nil))
+			if got != frame.Sha256 {
+				return 0, fmt.Errorf("%s table hash mismatch", frame.Table)
+			}
+			total.Write([]byte(got))
+			if to.Dialect().GetName() == "postgres" && t.key == "id" {
+				if err := advanceSequence(to, frame.Table); err != nil {
+					return 0, err
+				}
+			}
+			if progress != nil {
+				progress(SyncReport{Table: frame.Table, Copied: copied[frame.Table], Done: true})
+			}
+		case SyncFrameEnd:
+			if hex.EncodeToString(total.Sum(nil)) != frame.Sha256 {
+				return 0, errors.New("snapshot hash mismatch")
+			}
+			if frame.Height != header.Height {
+				return 0, errors.New("snapshot height mismatch")
+			}
+			for i := range heights {
+				if err := raw.Save(&heights[i]).Error; err != nil {
+					return 0, err
+				}
+			}
+			return header.Height, nil
+		default:
+			return 0, fmt.Errorf("unexpected %s frame", frame.Kind)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+	"github.com/jinzhu/gorm"
+)
+
+// ErrDecisionPending is returned for a voting decision the agent has not
+// pushed yet. The node then casts no vote on the block.
+var ErrDecisionPending = errors.New("agent decision pending")
+
+// ErrQuestionNotFound is returned for an answer to an unknown question.
+var ErrQuestionNotFound = errors.New("agent question not found")
+
+// ErrQuestionClosed is returned for an answer to a question already answered
+// or past its deadline.
+var ErrQuestionClosed = errors.New("agent question closed")
+
+// Statuses of an AgentQuestion.
+const (
+	QuestionPending  = "pending"
+	QuestionAnswered = "answered"
+	QuestionExpired  = "expired"
+)
+
+// pushPollInterval is how often a vote waiting for an answer reads its
+// question again.
+const pushPollInterval = 500 * time.Millisecond
+
+// AgentQuestion is a voting decision asked to an agent in push mode. The
+// agent answers it on the decision callback before Deadline, one question
+// per proposal or grant.
+type AgentQuestion struct {
+	Id         uint64     `gorm:"primary_key" json:"id"`
+	Kind       string     `gorm:"unique_index:idx_agent_question_ref" json:"kind"`
+	RefId      uint64     `gorm:"unique_index:idx_agent_question_ref" json:"ref_id"`
+	Voter      string     `json:"voter"`
+	Text       string     `json:"text"`
+	Status     string     `gorm:"index" json:"status"`
+	Vote       string     `json:"vote"`
+	Confidence *float64   `json:"confidence"`
+	Reason     string     `json:"reason"`
+	Delivered  bool       `json:"delivered"`
+	Deadline   time.Time  `json:"deadline"`
+	AnsweredAt *time.Time `json:"answered_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// QuestionStore persists the questions of the push mode.
+type QuestionStore interface {
+	// AskAgentQuestion saves q unless a question with its kind and ref id
+	// exists, q is then set to the stored one. created reports a new
+	// question.
+	AskAgentQuestion(q *AgentQuestion) (created bool, err error)
+	GetAgentQuestion(kind string, id uint64) (AgentQuestion, error)
+	// UpdatePendingQuestion updates the fields of question id while it is
+	// pending, so an answer is never overwritten.
+	UpdatePendingQuestion(id uint64, fields map[string]interface{}) error
+}
+
+// PushQuestion is posted to agent_push_url for every new question. The agent
+// posts its decision to CallbackUrl any time before Deadline.
+type PushQuestion struct {
+	QuestionId  uint64    `json:"questionId"`
+	Kind        string    `json:"kind"`
+	RefId       uint64    `json:"refId"`
+	Voter       string    `json:"voter"`
+	Text        string    `json:"text"`
+	Deadline    time.Time `json:"deadline"`
+	CallbackUrl string    `json:"callbackUrl"`
+}
+
+// AgentDecisionReq is the decision an agent pushes for a question.
+type AgentDecisionReq struct {
+	QuestionId uint64   `json:"questionId"`
+	Vote       string   `json:"vote"`
+	Confidence *float64 `json:"confidence,omitempty"`
+	Reason     string   `json:"reason"`
+}
+
+// PushConfig configures the push mode. Url receives the questions, empty
+// leaves the agent polling them. Wait bounds how long a vote waits for the
+// answer before it is left pending.
+type PushConfig struct {
+	Url         string
+	CallbackUrl string
+	Deadline    time.Duration
+	Wait        time.Duration
+}
+
+var _ Client = &PushClient{}
+
+// PushClient turns the voting decisions of the wrapped agent into questions
+// the agent answers asynchronously, for agents deliberating longer than a
+// consensus round. A proposal is asked as soon as it is added, a decision
+// not answered yet is left pending and one past its deadline expires. The
+// other calls go to the wrapped agent.
+type PushClient struct {
+	Client
+	cfg       PushConfig
+	logger    cmtlog.Logger
+	store     QuestionStore
+	proposals ProposalSource
+}
+
+func NewPushClient(inner Client, cfg PushConfig, logger cmtlog.Logger) *PushClient {
+	return &PushClient{
+		Client: inner,
+		cfg:    cfg,
+		logger: logger.With("module", "agent-push"),
+	}
+}
+
+func (p *PushClient) SetQuestionStore(store QuestionStore) {
+	p.store = store
+}
+
+// SetProposalSource gives the text of the proposals asked after a restart,
+// when AddProposal is not called again.
+func (p *PushClient) SetProposalSource(src ProposalSource) {
+	p.proposals = src
+}
+
+func (p *PushClient) AddProposal(ctx context.Context, proposal uint64, proposer string, text string) error {
+	if err := p.Client.AddProposal(ctx, proposal, proposer, text); err != nil {
+		return err
+	}
+	if _, err := p.ask(ctx, voteKindProposal, proposal, "", text); err != nil {
+		p.logger.Error("ask proposal fail", "proposal", proposal, "err", err)
+	}
+	return nil
+}
+
+func (p *PushClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	return p.vote(ctx, voteKindProposal, proposal, voter, func() string {
+		if p.proposals == nil {
+			return ""
+		}
+		indexed, err := p.proposals.IndexedProposal(proposal)
+		if err != nil {
+			p.logger.Error("get proposal fail", "proposal", proposal, "err", err)
+			return ""
+		}
+		return indexed.agentBody()
+	})
+}
+
+func (p *PushClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	return p.vote(ctx, voteKindGrant, validator, proposer, func() string {
+		return fmt.Sprintf("%s asks to join the validator set with a stake of %d: %s", proposer, amount, statement)
+	})
+}
+
+// vote asks the question of the decision when it is new and waits for its
+// answer, at most the configured wait.
+func (p *PushClient) vote(ctx context.Context, kind string, id uint64, voter string, text func() string) (Decision, error) {
+	if p.store == nil {
+		return Decision{}, errors.New("agent push: question store not set")
+	}
+	q, err := p.store.GetAgentQuestion(kind, id)
+	if err != nil {
+		q, err = p.ask(ctx, kind, id, voter, text())
+		if err != nil {
+			return Decision{}, err
+		}
+	}
+	wait := time.NewTimer(p.cfg.Wait)
+	defer wait.Stop()
+	poll := time.NewTicker(pushPollInterval)
+	defer poll.Stop()
+	for {
+		switch {
+		case q.Status == QuestionAnswered:
+			p.logger.Info("pushed decision", "kind", kind, "id", id, "vote", q.Vote, "confidence", q.Confidence, "reason", q.Reason)
+			return parseDecision(q.Vote, q.Confidence, q.Reason)
+		case q.Status == QuestionExpired:
+			return Decision{}, fmt.Errorf("%w: question %d expired", ErrDecisionPending, q.Id)
+		case time.Now().After(q.Deadline):
+			if err := p.store.UpdatePendingQuestion(q.Id, map[string]interface{}{"status": QuestionExpired}); err != nil {
+				p.logger.Error("expire question fail", "question", q.Id, "err", err)
+			}
+			p.logger.Error("agent question expired", "kind", kind, "id", id, "question", q.Id)
+			return Decision{}, fmt.Errorf("%w: question %d expired", ErrDecisionPending, q.Id)
+		}
+		select {
+		case <-ctx.Done():
+			return Decision{}, ctx.Err()
+		case <-wait.C:
+			return Decision{}, fmt.Errorf("%w: question %d", ErrDecisionPending, q.Id)
+		case <-poll.C:
+		}
+		q, err = p.store.GetAgentQuestion(kind, id)
+		if err != nil {
+			return Decision{}, err
+		}
+	}
+}
+
+// ask saves the question of a decision once and delivers it to the agent.
+// A failed delivery is left to the agent polling the questions.
+func (p *PushClient) ask(ctx context.Context, kind string, id uint64, voter string, text string) (AgentQuestion, error) {
+	if p.store == nil {
+		return AgentQuestion{}, errors.New("agent push: question store not set")
+	}
+	q := AgentQuestion{
+		Kind:     kind,
+		RefId:    id,
+		Voter:    voter,
+		Text:     text,
+		Status:   QuestionPending,
+		Deadline: time.Now().Add(p.cfg.Deadline),
+	}
+	created, err := p.store.AskAgentQuestion(&q)
+	if err != nil || !created || p.cfg.Url == "" {
+		return q, err
+	}
+	if err := p.deliver(ctx, q); err != nil {
+		p.logger.Error("deliver question fail", "question", q.Id, "err", err)
+		return q, nil
+	}
+	q.Delivered = true
+	if err := p.store.UpdatePendingQuestion(q.Id, map[string]interface{}{"delivered": true}); err != nil {
+		p.logger.Error("save question fail", "question", q.Id, "err", err)
+	}
+	return q, nil
+}
+
+func (p *PushClient) deliver(ctx context.Context, q AgentQuestion) error {
+	data, _ := json.Marshal(PushQuestion{
+		QuestionId:  q.Id,
+		Kind:        q.Kind,
+		RefId:       q.RefId,
+		Voter:       q.Voter,
+		Text:        q.Text,
+		Deadline:    q.Deadline,
+		CallbackUrl: p.cfg.CallbackUrl,
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.Url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("deliver question: %s", res.Status)
+	}
+	return nil
+}
+
+func (c *ChainIndexer) AskAgentQuestion(q *AgentQuestion) (bool, error) {
+	var existing AgentQuestion
+	err := c.db.Where("kind = ? AND ref_id = ?", q.Kind, q.RefId).First(&existing).Error
+	if err == nil {
+		*q = existing
+		return false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	if err := c.db.Create(q).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *ChainIndexer) GetAgentQuestion(kind string, id uint64) (AgentQuestion, error) {
+	var q AgentQuestion
+	err := c.db.Where("kind = ? AND ref_id = ?", kind, id).First(&q).Error
+	return q, err
+}
+
+func (c *ChainIndexer) UpdatePendingQuestion(id uint64, fields map[string]interface{}) error {
+	return c.db.Model(&AgentQuestion{}).Where("id = ? AND status = ?", id, QuestionPending).Updates(fields).Error
+}
+
+func (c *ChainIndexer) getAgentQuestions(status string, page int, pageSize int) (Page[AgentQuestion], error) {
+	db := c.db.Model(&AgentQuestion{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	return paginate[AgentQuestion](db, "id desc", page, pageSize)
+}
+
+// answerAgentQuestion keeps the decision pushed by the agent, the next vote
+// on the question casts it.
+func (c *ChainIndexer) answerAgentQuestion(req AgentDecisionReq) (AgentQuestion, error) {
+	var q AgentQuestion
+	if err := c.db.First(&q, req.QuestionId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return q, ErrQuestionNotFound
+		}
+		return q, err
+	}
+	if q.Status != QuestionPending || time.Now().After(q.Deadline) {
+		return q, ErrQuestionClosed
+	}
+	now := time.Now()
+	q.Status = QuestionAnswered
+	q.Vote = strings.ToLower(strings.TrimSpace(req.Vote))
+	q.Confidence = req.Confidence
+	q.Reason = req.Reason
+	q.AnsweredAt = &now
+	res := c.db.Model(&AgentQuestion{}).Where("id = ? AND status = ?", q.Id, QuestionPending).Updates(map[string]interface{}{
+		"status":      q.Status,
+		"vote":        q.Vote,
+		"confidence":  q.Confidence,
+		"reason":      q.Reason,
+		"answered_at": now,
+	})
+	if res.Error != nil {
+		return q, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return q, ErrQuestionClosed
+	}
+	return q, nil
+}
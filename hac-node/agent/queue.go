@@ -0,0 +1,284 @@
+package agent
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCallPriorities rank the agent requests when the agent is backlogged.
+// Votes decide blocks and come first, then the data the agent needs for
+// later votes, then cosmetic work.
+var DefaultCallPriorities = map[string]int{
+	"IfAcceptProposal":  100,
+	"IfGrantNewMember":  100,
+	"NotifyMisbehavior": 80,
+	"NotifyActivation":  70,
+	"RefineProposal":    60,
+	"AddProposal":       50,
+	"AddDiscussion":     40,
+	"AddDiscussions":    40,
+	"DraftAnnouncement": 20,
+	"CommentPropoal":    10,
+	"InitiateProposal":  5,
+}
+
+// OverflowPolicy decides what happens to a call arriving at a full queue.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits however long the queue is, max depth is ignored.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowReject fails the new call.
+	OverflowReject OverflowPolicy = "reject"
+	// OverflowShed fails the lowest ranked waiting call to make room, or the
+	// new call if it ranks lowest.
+	OverflowShed OverflowPolicy = "shed"
+)
+
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch p := OverflowPolicy(s); p {
+	case "":
+		return OverflowBlock, nil
+	case OverflowBlock, OverflowReject, OverflowShed:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown agent queue overflow policy %q, expected block, reject or shed", s)
+}
+
+var ErrAgentQueueFull = errors.New("agent call queue full")
+
+type callDeadlineCtx struct{}
+
+// WithCallDeadline tells the queue the decision asked with ctx is due at
+// deadline. Among calls of the same priority the earliest deadline runs
+// first, calls without one after them.
+func WithCallDeadline(ctx context.Context, deadline time.Time) context.Context {
+	return context.WithValue(ctx, callDeadlineCtx{}, deadline)
+}
+
+type expeditedCtx struct{}
+
+// ExpeditedPriorityBoost is added to the priority of a call about an
+// expedited proposal, ahead of every regular call.
+const ExpeditedPriorityBoost = 1000
+
+// WithExpedited tells the queue the decision asked with ctx is about an
+// expedited proposal.
+func WithExpedited(ctx context.Context) context.Context {
+	return context.WithValue(ctx, expeditedCtx{}, true)
+}
+
+func isExpedited(ctx context.Context) bool {
+	expedited, _ := ctx.Value(expeditedCtx{}).(bool)
+	return expedited
+}
+
+// AgentQueueConfig configures the queue of one agent backend. Priorities
+// override DefaultCallPriorities by method name, case insensitive since
+// config keys are lowercased. MaxDepth 0 lets any number of calls wait.
+type AgentQueueConfig struct {
+	Workers    int
+	MaxDepth   int
+	Overflow   OverflowPolicy
+	Priorities map[string]int
+}
+
+// AgentQueue is a semaphore of the requests in flight to one agent backend.
+// Calls beyond Workers wait and are started by priority, so a burst of
+// events neither opens hundreds of concurrent llm requests nor holds up a
+// vote behind a backlog of comments.
+type AgentQueue struct {
+	backend    string
+	workers    int
+	maxDepth   int
+	overflow   OverflowPolicy
+	priorities map[string]int
+	mtx        sync.Mutex
+	running    int
+	seq        uint64
+	waiting    agentWaiters
+}
+
+func NewAgentQueue(backend string, cfg AgentQueueConfig) (*AgentQueue, error) {
+	if cfg.Workers <= 0 {
+		return nil, fmt.Errorf("agent queue needs at least one worker, got %d", cfg.Workers)
+	}
+	if cfg.MaxDepth < 0 {
+		return nil, fmt.Errorf("invalid agent queue max depth %d", cfg.MaxDepth)
+	}
+	if cfg.Overflow == "" {
+		cfg.Overflow = OverflowBlock
+	}
+	q := &AgentQueue{
+		backend:    backend,
+		workers:    cfg.Workers,
+		maxDepth:   cfg.MaxDepth,
+		overflow:   cfg.Overflow,
+		priorities: make(map[string]int, len(DefaultCallPriorities)),
+	}
+	for method, prio := range DefaultCallPriorities {
+		q.priorities[strings.ToLower(method)] = prio
+	}
+	for method, prio := range cfg.Priorities {
+		key := strings.ToLower(method)
+		if _, ok := q.priorities[key]; !ok {
+			methods := make([]string, 0, len(DefaultCallPriorities))
+			for m := range DefaultCallPriorities {
+				methods = append(methods, m)
+			}
+			sort.Strings(methods)
+			return nil, fmt.Errorf("unknown agent method %q, expected one of %s", method, strings.Join(methods, ", "))
+		}
+		q.priorities[key] = prio
+	}
+	return q, nil
+}
+
+type agentWaiter struct {
+	method   string
+	priority int
+	deadline time.Time
+	seq      uint64
+	index    int
+	err      error
+	ready    chan struct{}
+}
+
+// agentWaiters is a heap of the waiting calls, highest priority first.
+type agentWaiters []*agentWaiter
+
+func (w agentWaiters) Len() int { return len(w) }
+
+func (w agentWaiters) Less(i, j int) bool {
+	return w[i].before(w[j])
+}
+
+func (w agentWaiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *agentWaiters) Push(x any) {
+	call := x.(*agentWaiter)
+	call.index = len(*w)
+	*w = append(*w, call)
+}
+
+func (w *agentWaiters) Pop() any {
+	old := *w
+	n := len(old)
+	call := old[n-1]
+	old[n-1] = nil
+	call.index = -1
+	*w = old[:n-1]
+	return call
+}
+
+// before reports whether a starts before b.
+func (a *agentWaiter) before(b *agentWaiter) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if !a.deadline.Equal(b.deadline) {
+		if a.deadline.IsZero() || b.deadline.IsZero() {
+			return b.deadline.IsZero()
+		}
+		return a.deadline.Before(b.deadline)
+	}
+	return a.seq < b.seq
+}
+
+// acquire waits for a slot for a call of method and returns the function
+// handing it back.
+func (q *AgentQueue) acquire(ctx context.Context, method string) (func(), error) {
+	q.mtx.Lock()
+	if q.running < q.workers && q.waiting.Len() == 0 {
+		q.running++
+		q.updateGauges()
+		q.mtx.Unlock()
+		return q.release, nil
+	}
+	call := &agentWaiter{method: method, priority: q.priorities[strings.ToLower(method)], seq: q.seq, ready: make(chan struct{})}
+	if deadline, ok := ctx.Value(callDeadlineCtx{}).(time.Time); ok {
+		call.deadline = deadline
+	}
+	if isExpedited(ctx) {
+		call.priority += ExpeditedPriorityBoost
+	}
+	q.seq++
+	if q.overflow != OverflowBlock && q.maxDepth > 0 && q.waiting.Len() >= q.maxDepth {
+		if err := q.overflowLocked(call); err != nil {
+			q.mtx.Unlock()
+			return nil, err
+		}
+	}
+	heap.Push(&q.waiting, call)
+	q.updateGauges()
+	q.mtx.Unlock()
+
+	select {
+	case <-call.ready:
+		if call.err != nil {
+			return nil, call.err
+		}
+		return q.release, nil
+	case <-ctx.Done():
+	}
+	q.mtx.Lock()
+	queued := call.index >= 0
+	if queued {
+		heap.Remove(&q.waiting, call.index)
+		q.updateGauges()
+	}
+	q.mtx.Unlock()
+	if !queued && call.err == nil {
+		// the slot was handed over while ctx ended
+		q.release()
+	}
+	return nil, ctx.Err()
+}
+
+// overflowLocked makes room for call in the full queue or fails it.
+func (q *AgentQueue) overflowLocked(call *agentWaiter) error {
+	if q.overflow == OverflowShed {
+		last := q.waiting[0]
+		for _, w := range q.waiting[1:] {
+			if last.before(w) {
+				last = w
+			}
+		}
+		if call.before(last) {
+			heap.Remove(&q.waiting, last.index)
+			last.err = ErrAgentQueueFull
+			close(last.ready)
+			agentQueueOverflows.WithLabelValues(q.backend, last.method).Inc()
+			return nil
+		}
+	}
+	agentQueueOverflows.WithLabelValues(q.backend, call.method).Inc()
+	return ErrAgentQueueFull
+}
+
+// release hands the slot to the first waiting call, or frees it.
+func (q *AgentQueue) release() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if q.waiting.Len() > 0 {
+		close(heap.Pop(&q.waiting).(*agentWaiter).ready)
+	} else {
+		q.running--
+	}
+	q.updateGauges()
+}
+
+func (q *AgentQueue) updateGauges() {
+	agentQueueDepth.WithLabelValues(q.backend).Set(float64(q.waiting.Len()))
+	agentCallsInFlight.WithLabelValues(q.backend).Set(float64(q.running))
+}
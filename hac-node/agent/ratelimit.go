@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket lets rate calls a second through on average and bursts of up
+// to burst calls.
+type tokenBucket struct {
+	mtx    sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve takes a token and returns how long to wait before it is available.
+// The tokens go negative while calls wait, so the calls run in the order they
+// reserved.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel gives back a token reserved by a call that did not wait for it.
+func (b *tokenBucket) cancel() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.tokens++
+}
+
+// wait blocks until a token is available or ctx is done, waited tells
+// whether the token was not available at once.
+func (b *tokenBucket) wait(ctx context.Context) (waited bool, err error) {
+	d := b.reserve()
+	if d == 0 {
+		return false, nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		b.cancel()
+		return true, ctx.Err()
+	case <-timer.C:
+		return true, nil
+	}
+}
+
+var _ Client = &RateLimitClient{}
+
+// RateLimitClient spaces the calls to the wrapped agent so a burst of
+// indexing, like replaying old proposals, does not overwhelm it. Calls over
+// the rate wait for their turn until their context is done. Voting decisions
+// are not limited, they decide blocks and must not wait behind a replay.
+type RateLimitClient struct {
+	interceptedClient
+	bucket *tokenBucket
+}
+
+// NewRateLimitClient limits inner to rate calls a second with bursts of
+// burst calls.
+func NewRateLimitClient(inner Client, rate float64, burst int) *RateLimitClient {
+	r := &RateLimitClient{bucket: newTokenBucket(rate, burst)}
+	r.interceptedClient = interceptedClient{Client: inner, intercept: r.limit}
+	return r
+}
+
+func (r *RateLimitClient) limit(ctx context.Context, method string, send func() error) error {
+	switch method {
+	case "IfProcessProposal", "IfAcceptProposal", "IfGrantNewMember":
+		return send()
+	}
+	waited, err := r.bucket.wait(ctx)
+	if waited {
+		agentRateLimited.WithLabelValues(method).Inc()
+	}
+	if err != nil {
+		return agentCallError(method, err)
+	}
+	return send()
+}
+
+func (r *RateLimitClient) Unwrap() Client { return r.Client }
+
+// WithRateLimit limits the calls to rate a second with bursts of burst
+// calls, see NewRateLimitClient.
+func WithRateLimit(rate float64, burst int) Middleware {
+	return func(next Client) Client {
+		return NewRateLimitClient(next, rate, burst)
+	}
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"os"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// IndexReader gives read-only access to an indexer db, for tools running on
+// the same host as a node. Pages start at 0.
+type IndexReader struct {
+	c *ChainIndexer
+}
+
+func OpenIndexReader(dbPath string) (*IndexReader, error) {
+	if !IsPostgresDSN(dbPath) {
+		if _, err := os.Stat(dbPath); err != nil {
+			return nil, err
+		}
+	}
+	db, err := openIndexDB(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &IndexReader{c: &ChainIndexer{logger: cmtlog.NewNopLogger(), db: db}}, nil
+}
+
+func (r *IndexReader) Close() error {
+	return r.c.db.Close()
+}
+
+func (r *IndexReader) Proposals(page int, pageSize int) (Page[Proposal], error) {
+	return r.c.getProposals(page, pageSize)
+}
+
+func (r *IndexReader) Proposal(id uint64) (Proposal, error) {
+	return r.c.getProposalById(id)
+}
+
+func (r *IndexReader) Grants(page int, pageSize int) (Page[Grant], error) {
+	return r.c.getGrants(page, pageSize)
+}
+
+func (r *IndexReader) Grant(id uint64) (Grant, error) {
+	return r.c.getGrantById(id)
+}
+
+func (r *IndexReader) Votes(voter string, page int, pageSize int) (GetVotesResponse, error) {
+	return r.c.getVotesByVoter(voter, page, pageSize)
+}
+
+func (r *IndexReader) Discussions(proposal uint64, page int, pageSize int) (Page[Discussion], error) {
+	return r.c.getDiscussionByProposal(proposal, page, pageSize)
+}
+
+// ProposalsAfter returns up to limit proposals with an id above after, in id
+// order.
+func (r *IndexReader) ProposalsAfter(after uint64, limit int) ([]Proposal, error) {
+	var proposals []Proposal
+	err := r.c.db.Where("id > ?", after).Order("id asc").Limit(limit).Find(&proposals).Error
+	return proposals, err
+}
+
+// ProposalDiscussions returns all discussions of proposal in the order they
+// were posted.
+func (r *IndexReader) ProposalDiscussions(proposal uint64) ([]Discussion, error) {
+	var discussions []Discussion
+	err := r.c.db.Where("proposal = ?", proposal).Order("height asc, id asc").Find(&discussions).Error
+	return discussions, err
+}
@@ -0,0 +1,394 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// The markdown renderer supports the subset agents and proposers write:
+// headings, paragraphs, lists, block quotes, code, emphasis and links. Input
+// html is always escaped and only the tags written below are emitted, so the
+// output is safe to insert into a page as is. Links are limited to http,
+// https and mailto and images are rendered as links, so rendered text cannot
+// load anything by itself.
+
+// maxRenderDepth bounds nested quotes, lists and emphasis.
+const maxRenderDepth = 8
+
+const linkRel = `rel="nofollow noopener noreferrer"`
+
+const markdownPunct = "\\`*_{}[]()#+-.!>~|"
+
+// renderBody fills the rendered fields of the proposal from the body of its
+// payload.
+func (p *Proposal) renderBody() {
+	body := p.Data
+	var env ProposalEnvelope
+	if err := json.Unmarshal([]byte(p.Data), &env); err == nil && env.Body != "" {
+		body = env.Body
+	}
+	p.BodyHtml = renderMarkdown(body)
+	p.BodyText = htmlText(p.BodyHtml)
+}
+
+func (d *Discussion) renderBody() {
+	d.DataHtml = renderMarkdown(d.Data)
+	d.DataText = htmlText(d.DataHtml)
+}
+
+// renderMarkdown renders markdown to sanitized html.
+func renderMarkdown(src string) string {
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n"), 0)
+	return b.String()
+}
+
+func renderBlocks(b *strings.Builder, lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case trimmed == "":
+			i++
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence := trimmed[:3]
+			j := i + 1
+			for j < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[j]), fence) {
+				j++
+			}
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(strings.Join(lines[i+1:j], "\n")))
+			b.WriteString("</code></pre>\n")
+			i = j + 1
+		case headingLevel(trimmed) > 0:
+			n := headingLevel(trimmed)
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", n, renderInline(strings.TrimSpace(trimmed[n:]), depth), n)
+			i++
+		case isRule(trimmed):
+			b.WriteString("<hr>\n")
+			i++
+		case strings.HasPrefix(trimmed, ">"):
+			var quote []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(q, " "))
+				i++
+			}
+			b.WriteString("<blockquote>\n")
+			if depth < maxRenderDepth {
+				renderBlocks(b, quote, depth+1)
+			} else {
+				writeParagraph(b, quote, depth)
+			}
+			b.WriteString("</blockquote>\n")
+		case isListItem(trimmed):
+			i = renderList(b, lines, i, depth)
+		default:
+			j := i + 1
+			for j < len(lines) && !startsBlock(lines[j]) {
+				j++
+			}
+			writeParagraph(b, lines[i:j], depth)
+			i = j
+		}
+	}
+}
+
+func writeParagraph(b *strings.Builder, lines []string, depth int) {
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	b.WriteString("<p>")
+	b.WriteString(renderInline(strings.Join(lines, "\n"), depth))
+	b.WriteString("</p>\n")
+}
+
+// startsBlock reports whether line ends the paragraph before it.
+func startsBlock(line string) bool {
+	t := strings.TrimSpace(line)
+	return t == "" || strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") || headingLevel(t) > 0 ||
+		isRule(t) || strings.HasPrefix(t, ">") || isListItem(t)
+}
+
+func headingLevel(line string) int {
+	n := 0
+	for n < len(line) && n < 7 && line[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || (n < len(line) && line[n] != ' ') {
+		return 0
+	}
+	return n
+}
+
+func isRule(line string) bool {
+	s := strings.ReplaceAll(line, " ", "")
+	if len(s) < 3 || (s[0] != '-' && s[0] != '*' && s[0] != '_') {
+		return false
+	}
+	return strings.Count(s, s[:1]) == len(s)
+}
+
+// listItem splits a list item line into its marker type and content.
+func listItem(line string) (ordered bool, content string, ok bool) {
+	if len(line) >= 2 && (line[0] == '-' || line[0] == '*' || line[0] == '+') && line[1] == ' ' {
+		return false, strings.TrimSpace(line[2:]), true
+	}
+	n := 0
+	for n < len(line) && n < 10 && line[n] >= '0' && line[n] <= '9' {
+		n++
+	}
+	if n > 0 && n+1 < len(line) && (line[n] == '.' || line[n] == ')') && line[n+1] == ' ' {
+		return true, strings.TrimSpace(line[n+2:]), true
+	}
+	return false, "", false
+}
+
+func isListItem(line string) bool {
+	_, _, ok := listItem(line)
+	return ok
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// renderList renders the list starting at lines[i] and returns the index of
+// the first line after it. Lines indented deeper than an item belong to it.
+func renderList(b *strings.Builder, lines []string, i int, depth int) int {
+	ordered, _, _ := listItem(strings.TrimSpace(lines[i]))
+	indent := indentOf(lines[i])
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	b.WriteString("<" + tag + ">\n")
+	for i < len(lines) {
+		o, content, ok := listItem(strings.TrimSpace(lines[i]))
+		if !ok || o != ordered || indentOf(lines[i]) != indent {
+			break
+		}
+		i++
+		var children []string
+		for i < len(lines) {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// a blank line continues the list only when an item follows
+				j := i + 1
+				for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+					j++
+				}
+				if j < len(lines) && indentOf(lines[j]) > indent {
+					children = append(children, "")
+					i = j
+					continue
+				}
+				if j < len(lines) && indentOf(lines[j]) == indent && isListItem(strings.TrimSpace(lines[j])) {
+					i = j
+				}
+				break
+			}
+			if indentOf(line) <= indent {
+				break
+			}
+			children = append(children, line[indent:])
+			i++
+		}
+		b.WriteString("<li>")
+		b.WriteString(renderInline(content, depth))
+		if len(children) > 0 {
+			b.WriteString("\n")
+			if depth < maxRenderDepth {
+				renderBlocks(b, dedent(children), depth+1)
+			} else {
+				writeParagraph(b, children, depth)
+			}
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// dedent removes the indentation common to all non blank lines.
+func dedent(lines []string) []string {
+	least := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		if n := indentOf(l); least < 0 || n < least {
+			least = n
+		}
+	}
+	for i, l := range lines {
+		if len(l) >= least && least > 0 {
+			lines[i] = l[least:]
+		}
+	}
+	return lines
+}
+
+func renderInline(s string, depth int) string {
+	var b strings.Builder
+	writeInline(&b, s, depth, false)
+	return b.String()
+}
+
+func writeInline(b *strings.Builder, s string, depth int, inLink bool) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte(markdownPunct, s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				b.WriteString("<code>" + html.EscapeString(s[i+1:i+1+end]) + "</code>")
+				i += end + 2
+				continue
+			}
+		case !inLink && (c == '[' || (c == '!' && strings.HasPrefix(s[i+1:], "["))):
+			if text, href, n, ok := parseLink(s[i:]); ok {
+				if safe := safeHref(href); safe != "" {
+					fmt.Fprintf(b, `<a href="%s" %s>`, html.EscapeString(safe), linkRel)
+					writeInline(b, text, depth+1, true)
+					b.WriteString("</a>")
+				} else {
+					writeInline(b, text, depth+1, inLink)
+				}
+				i += n
+				continue
+			}
+		case (c == '*' || c == '_') && depth < maxRenderDepth:
+			if n := writeEmphasis(b, s, i, depth, inLink); n > 0 {
+				i += n
+				continue
+			}
+		case !inLink && c == 'h' && (i == 0 || !isWordByte(s[i-1])) &&
+			(strings.HasPrefix(s[i:], "http://") || strings.HasPrefix(s[i:], "https://")):
+			end := i
+			for end < len(s) && s[end] != ' ' && s[end] != '\n' && s[end] != '\t' && s[end] != '<' && s[end] != '>' {
+				end++
+			}
+			raw := strings.TrimRight(s[i:end], ".,;:!?)'\"")
+			if safe := safeHref(raw); safe != "" {
+				fmt.Fprintf(b, `<a href="%s" %s>%s</a>`, html.EscapeString(safe), linkRel, html.EscapeString(raw))
+				i += len(raw)
+				continue
+			}
+		}
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+}
+
+// writeEmphasis writes the emphasis opened by the delimiter at s[i] and
+// returns the length consumed, or 0 when the delimiter is not closed.
+func writeEmphasis(b *strings.Builder, s string, i int, depth int, inLink bool) int {
+	c := s[i]
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return 0
+	}
+	delim := s[i : i+1]
+	tag := "em"
+	if strings.HasPrefix(s[i:], delim+delim) {
+		delim += delim
+		tag = "strong"
+	}
+	start := i + len(delim)
+	end := strings.Index(s[start:], delim)
+	if end <= 0 {
+		return 0
+	}
+	inner := s[start : start+end]
+	after := start + end + len(delim)
+	if strings.TrimSpace(inner) != inner || (c == '_' && after < len(s) && isWordByte(s[after])) {
+		return 0
+	}
+	b.WriteString("<" + tag + ">")
+	writeInline(b, inner, depth+1, inLink)
+	b.WriteString("</" + tag + ">")
+	return after - i
+}
+
+// parseLink parses [text](href) or ![alt](href) at the start of s.
+func parseLink(s string) (text string, href string, n int, ok bool) {
+	start := 1
+	if s[0] == '!' {
+		start = 2
+	}
+	close := strings.IndexByte(s[start:], ']')
+	if close < 0 || start+close+1 >= len(s) || s[start+close+1] != '(' {
+		return "", "", 0, false
+	}
+	text = s[start : start+close]
+	rest := s[start+close+2:]
+	end := strings.IndexByte(rest, ')')
+	if end < 0 || strings.ContainsAny(rest[:end], " \n\t") {
+		return "", "", 0, false
+	}
+	return text, rest[:end], start + close + 2 + end + 1, true
+}
+
+// safeHref returns the normalized href, or "" when its scheme is not
+// allowed.
+func safeHref(href string) string {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return ""
+		}
+	case "mailto":
+	default:
+		return ""
+	}
+	return u.String()
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// htmlText extracts the plain text of html written by renderMarkdown, one
+// block per line.
+func htmlText(h string) string {
+	var b strings.Builder
+	for len(h) > 0 {
+		lt := strings.IndexByte(h, '<')
+		if lt < 0 {
+			b.WriteString(h)
+			break
+		}
+		b.WriteString(h[:lt])
+		gt := strings.IndexByte(h[lt:], '>')
+		if gt < 0 {
+			break
+		}
+		tag, _, _ := strings.Cut(h[lt+1:lt+gt], " ")
+		switch tag {
+		case "li":
+			b.WriteString("- ")
+		case "/p", "/h1", "/h2", "/h3", "/h4", "/h5", "/h6", "/pre", "/blockquote", "/ul", "/ol", "hr":
+			b.WriteString("\n")
+		}
+		h = h[lt+gt+1:]
+	}
+	lines := strings.Split(html.UnescapeString(b.String()), "\n")
+	out := make([]string, 0, len(lines))
+	for _, l := range lines {
+		l = strings.TrimRight(l, " \t")
+		if l == "" && (len(out) == 0 || out[len(out)-1] == "") {
+			continue
+		}
+		out = append(out, l)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
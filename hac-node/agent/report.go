@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"sort"
+	"time"
+)
+
+// ReportNotableDiscussions is the number of discussions quoted per proposal in
+// a governance report.
+const ReportNotableDiscussions = 3
+
+// ReportRange selects the proposals of a report by creation height and block
+// time. Zero values leave that side of the range open.
+type ReportRange struct {
+	FromHeight uint64    `json:"from_height"`
+	ToHeight   uint64    `json:"to_height"`
+	Since      time.Time `json:"since"`
+	Until      time.Time `json:"until"`
+}
+
+type ReportProposal struct {
+	Proposal       Proposal     `json:"proposal"`
+	DraftPass      int          `json:"draft_pass"`
+	DraftReject    int          `json:"draft_reject"`
+	DecisionPass   int          `json:"decision_pass"`
+	DecisionReject int          `json:"decision_reject"`
+	Discussions    int          `json:"discussions"`
+	Notable        []Discussion `json:"notable"`
+}
+
+type ReportParticipation struct {
+	Validator   ValidatorAgent `json:"validator"`
+	Voted       int            `json:"voted"`
+	Discussions int            `json:"discussions"`
+	// Rate is the share of the report's proposals the validator voted on.
+	Rate float64 `json:"rate"`
+}
+
+type GovernanceReport struct {
+	Range         ReportRange           `json:"range"`
+	GeneratedAt   time.Time             `json:"generated_at"`
+	StatusCounts  map[string]int        `json:"status_counts"`
+	Proposals     []ReportProposal      `json:"proposals"`
+	Participation []ReportParticipation `json:"participation"`
+}
+
+// Report collects the proposals created in r with their vote tallies, notable
+// discussions and the participation of every validator.
+func (r *IndexReader) Report(rng ReportRange) (*GovernanceReport, error) {
+	query := r.c.db.Model(&Proposal{})
+	if rng.FromHeight > 0 {
+		query = query.Where("new_height >= ?", rng.FromHeight)
+	}
+	if rng.ToHeight > 0 {
+		query = query.Where("new_height <= ?", rng.ToHeight)
+	}
+	if !rng.Since.IsZero() {
+		query = query.Where("block_time >= ?", rng.Since)
+	}
+	if !rng.Until.IsZero() {
+		query = query.Where("block_time < ?", rng.Until)
+	}
+	var proposals []Proposal
+	if err := query.Order("id asc").Find(&proposals).Error; err != nil {
+		return nil, err
+	}
+
+	report := &GovernanceReport{
+		Range:         rng,
+		GeneratedAt:   time.Now().UTC(),
+		StatusCounts:  make(map[string]int),
+		Proposals:     make([]ReportProposal, 0, len(proposals)),
+		Participation: make([]ReportParticipation, 0),
+	}
+	voted := make(map[string]map[uint64]bool)
+	spoke := make(map[string]int)
+	for _, p := range proposals {
+		report.StatusCounts[p.Status.String()]++
+		votes, err := r.c.getProposalVotesByProposal(p.Id, 0, 1000)
+		if err != nil {
+			return nil, err
+		}
+		item := ReportProposal{Proposal: p}
+		draft, decision := ProposalVotesToVoteInfo(votes.Items)
+		for _, v := range draft {
+			if v.Pass {
+				item.DraftPass++
+			} else {
+				item.DraftReject++
+			}
+		}
+		for _, v := range decision {
+			if v.Pass {
+				item.DecisionPass++
+			} else {
+				item.DecisionReject++
+			}
+		}
+		for _, v := range votes.Items {
+			if voted[v.VoterAddress] == nil {
+				voted[v.VoterAddress] = make(map[uint64]bool)
+			}
+			voted[v.VoterAddress][p.Id] = true
+		}
+
+		var discussions []Discussion
+		if err := r.c.db.Where("proposal = ?", p.Id).Order("height desc, id desc").Find(&discussions).Error; err != nil {
+			return nil, err
+		}
+		for _, d := range discussions {
+			spoke[d.SpeakerAddress]++
+		}
+		item.Discussions = len(discussions)
+		item.Notable = rankDiscussions(discussions, ReportNotableDiscussions)
+		report.Proposals = append(report.Proposals, item)
+	}
+
+	validators, err := r.c.getValidators()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range validators {
+		part := ReportParticipation{
+			Validator:   v,
+			Voted:       len(voted[v.Address]),
+			Discussions: spoke[v.Address],
+		}
+		if len(proposals) > 0 {
+			part.Rate = float64(part.Voted) / float64(len(proposals))
+		}
+		report.Participation = append(report.Participation, part)
+	}
+	sort.SliceStable(report.Participation, func(i, j int) bool {
+		return report.Participation[i].Rate > report.Participation[j].Rate
+	})
+	return report, nil
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/jinzhu/gorm"
+)
+
+var errDecodeEvent = errors.New("decode event fail")
+
+// saveFailedEvent stores an event whose handler failed so it can be retried
+// instead of being dropped. The same event failing again only updates the
+// existing row.
+func (c *ChainIndexer) saveFailedEvent(event abci.Event, height int64, blockTime time.Time, txHash string, cause error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		c.logger.Error("encode failed event fail", "err", err)
+		return
+	}
+	var failed FailedEvent
+	err = c.db.Where("height = ? AND tx_hash = ? AND event_type = ? AND payload = ?", height, txHash, event.Type, string(payload)).First(&failed).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.logger.Error("get failed event fail", "err", err)
+		return
+	}
+	failed.Height = uint64(height)
+	failed.TxHash = txHash
+	failed.EventType = event.Type
+	failed.Payload = string(payload)
+	failed.BlockTime = blockTime
+	failed.Error = cause.Error()
+	if err := c.db.Save(&failed).Error; err != nil {
+		c.logger.Error("save failed event fail", "err", err)
+	}
+}
+
+// reprocessFailedEvents runs the handler of every failed event that has
+// attempts left. Events handled successfully are removed from the table.
+func (c *ChainIndexer) reprocessFailedEvents(ctx context.Context) {
+	var events []FailedEvent
+	err := c.db.Where("attempts < ?", FailedEventAttempts).Order("height asc, id asc").Find(&events).Error
+	if err != nil {
+		c.logger.Error("find failed events fail", "err", err)
+		return
+	}
+	for _, failed := range events {
+		if ctx.Err() != nil {
+			return
+		}
+		var event abci.Event
+		err := json.Unmarshal([]byte(failed.Payload), &event)
+		if err == nil {
+			h, ok := c.eventHandlers[event.Type]
+			if !ok {
+				err = errors.New("no handler for event type")
+			} else {
+				err = h(ctx, event, int64(failed.Height), failed.BlockTime, failed.TxHash)
+			}
+		}
+		if err == nil {
+			c.logger.Info("failed event reprocessed", "id", failed.Id, "type", failed.EventType, "height", failed.Height)
+			if err := c.db.Delete(&failed).Error; err != nil {
+				c.logger.Error("delete failed event fail", "err", err)
+			}
+			continue
+		}
+		failed.Attempts++
+		failed.Error = err.Error()
+		if err := c.db.Save(&failed).Error; err != nil {
+			c.logger.Error("save failed event fail", "err", err)
+		}
+	}
+}
+
+// requeueFailedEvents resets the attempts of the given failed events, or of
+// all of them when ids is empty, so the reprocessor picks them up again.
+func (c *ChainIndexer) requeueFailedEvents(ids []uint64) (int64, error) {
+	query := c.db.Model(&FailedEvent{})
+	if len(ids) > 0 {
+		query = query.Where("id IN (?)", ids)
+	}
+	res := query.Update("attempts", 0)
+	return res.RowsAffected, res.Error
+}
+
+func (c *ChainIndexer) getFailedEvents(page int, pageSize int) (Page[FailedEvent], error) {
+	return paginate[FailedEvent](c.db.Model(&FailedEvent{}), "height desc, id desc", page, pageSize)
+}
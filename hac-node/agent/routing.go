@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// AgentUrlSource provides the agent url validators registered on-chain with
+// their grant.
+type AgentUrlSource interface {
+	ValidatorAgentUrl(address string) (string, error)
+}
+
+var _ Client = &RoutingClient{}
+
+// RoutingClient sends the calls made for a validator, its votes on
+// proposals and its comments, to the agent the validator registered
+// on-chain. The other calls, and the ones for a validator without a
+// registered agent or whose agent cannot be reached, go to the default
+// agent. With a vote key configured the votes are not routed: only the
+// default agent signs with it, and an unverified vote would count as ours.
+type RoutingClient struct {
+	Client
+	backend string
+	cfg     BackendConfig
+	logger  cmtlog.Logger
+	urls    AgentUrlSource
+	setup   func(c Client)
+	mtx     sync.Mutex
+	clients map[string]Client
+}
+
+// NewRoutingClient routes to clients of backend made with cfg and the url of
+// the validator, inner is the default agent.
+func NewRoutingClient(inner Client, backend string, cfg BackendConfig, logger cmtlog.Logger) *RoutingClient {
+	return &RoutingClient{
+		Client:  inner,
+		backend: backend,
+		cfg:     cfg,
+		logger:  logger.With("module", "agent-routing"),
+		clients: make(map[string]Client),
+	}
+}
+
+func (r *RoutingClient) SetAgentUrlSource(src AgentUrlSource) {
+	r.urls = src
+}
+
+// SetClientSetup sets a function run on every routed client once made, to
+// give it the sources the default agent has.
+func (r *RoutingClient) SetClientSetup(setup func(c Client)) {
+	r.setup = setup
+}
+
+// route returns the agent of the validator at address, the default agent
+// when it has none.
+func (r *RoutingClient) route(address string) Client {
+	if r.urls == nil || address == "" {
+		return r.Client
+	}
+	url, err := r.urls.ValidatorAgentUrl(address)
+	if err != nil {
+		r.logger.Debug("validator agent not found", "address", address, "err", err)
+		return r.Client
+	}
+	url = strings.TrimRight(url, "/")
+	if url == "" || url == r.cfg.Url {
+		return r.Client
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if c, ok := r.clients[url]; ok {
+		return c
+	}
+	cfg := r.cfg
+	// the agent selected on the default service is not the validator's, nor
+	// is the token or the signing key of our agent the one of another operator
+	cfg.Url, cfg.Agent, cfg.Auth, cfg.VotePubKey = url, "", nil, nil
+	c, err := NewBackend(r.backend, cfg, r.logger)
+	if err != nil {
+		// not kept, the agent may be up on the next call
+		r.logger.Error("new validator agent fail", "address", address, "url", url, "err", err)
+		return r.Client
+	}
+	if r.setup != nil {
+		r.setup(c)
+	}
+	r.logger.Info("routing validator agent", "address", address, "url", url)
+	r.clients[url] = c
+	return c
+}
+
+func (r *RoutingClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	if r.cfg.VotePubKey != nil {
+		return r.Client.IfAcceptProposal(ctx, proposal, voter)
+	}
+	return r.route(voter).IfAcceptProposal(ctx, proposal, voter)
+}
+
+func (r *RoutingClient) CommentPropoal(ctx context.Context, proposal uint64, speaker string) (string, error) {
+	return r.route(speaker).CommentPropoal(ctx, proposal, speaker)
+}
+
+func (c *ChainIndexer) ValidatorAgentUrl(address string) (string, error) {
+	val, err := c.getValidatorByAddress(address)
+	if err != nil {
+		return "", err
+	}
+	return val.AgentUrl, nil
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PayloadSchema describes a structured proposal payload variant. Payloads
+// select their variant with a "type" discriminator, e.g.
+//
+//	{"type":"text","title":"...","body":"..."}
+//
+// Only the subset of JSON schema needed here is supported: required fields
+// and the JSON type of each property.
+type PayloadSchema struct {
+	Type       string            `json:"type"`
+	Required   []string          `json:"required"`
+	Properties map[string]string `json:"properties"`
+}
+
+var payloadSchemas = struct {
+	sync.RWMutex
+	schemas map[string]PayloadSchema
+}{schemas: make(map[string]PayloadSchema)}
+
+func init() {
+	RegisterPayloadSchema(PayloadSchema{
+		Type:     "text",
+		Required: []string{"body"},
+		Properties: map[string]string{
+			"title":             "string",
+			"summary":           "string",
+			"body":              "string",
+			"activation_height": "number",
+		},
+	})
+}
+
+// RegisterPayloadSchema adds or replaces the schema for a payload type.
+func RegisterPayloadSchema(schema PayloadSchema) {
+	payloadSchemas.Lock()
+	defer payloadSchemas.Unlock()
+	payloadSchemas.schemas[schema.Type] = schema
+}
+
+func getPayloadSchema(typ string) (PayloadSchema, bool) {
+	payloadSchemas.RLock()
+	defer payloadSchemas.RUnlock()
+	schema, ok := payloadSchemas.schemas[typ]
+	return schema, ok
+}
+
+// PayloadSchemas returns the registered schemas ordered by type.
+func PayloadSchemas() []PayloadSchema {
+	payloadSchemas.RLock()
+	defer payloadSchemas.RUnlock()
+	schemas := make([]PayloadSchema, 0, len(payloadSchemas.schemas))
+	for _, s := range payloadSchemas.schemas {
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Type < schemas[j].Type
+	})
+	return schemas
+}
+
+// validatePayload checks a proposal payload against the schema selected by
+// its type discriminator. It returns the payload type and its compacted JSON
+// form; payloads without a discriminator are plain text and return "".
+func validatePayload(data []byte) (typ string, structured string, err error) {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(data, &fields) != nil {
+		return "", "", nil
+	}
+	raw, ok := fields["type"]
+	if !ok {
+		return "", "", nil
+	}
+	if err := json.Unmarshal(raw, &typ); err != nil || typ == "" {
+		return "", "", fmt.Errorf("payload type must be a non-empty string")
+	}
+	schema, ok := getPayloadSchema(typ)
+	if !ok {
+		return typ, "", fmt.Errorf("unknown payload type %q", typ)
+	}
+	problems := make([]string, 0)
+	for _, name := range schema.Required {
+		if _, ok := fields[name]; !ok {
+			problems = append(problems, fmt.Sprintf("missing field %q", name))
+		}
+	}
+	for name, want := range schema.Properties {
+		value, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if got := jsonType(value); got != want {
+			problems = append(problems, fmt.Sprintf("field %q is %s, want %s", name, got, want))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return typ, "", fmt.Errorf("invalid %s payload: %s", typ, strings.Join(problems, "; "))
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return typ, "", err
+	}
+	return typ, buf.String(), nil
+}
+
+func jsonType(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "null"
+	}
+	switch raw[0] {
+	case '"':
+		return "string"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	default:
+		return "number"
+	}
+}
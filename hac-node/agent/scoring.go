@@ -0,0 +1,407 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// Names of the built-in proposal scorers.
+const (
+	ScorerAgent     = "agent"
+	ScorerRules     = "rules"
+	ScorerDuplicate = "duplicate"
+	ScorerBudget    = "budget"
+)
+
+// DefaultScoringWeights are used when no weights are configured. The agent
+// judgment stays the main input, the other scorers move close calls.
+var DefaultScoringWeights = map[string]float64{
+	ScorerAgent:     0.6,
+	ScorerRules:     0.15,
+	ScorerDuplicate: 0.15,
+	ScorerBudget:    0.1,
+}
+
+// duplicateCandidates is the number of earlier proposals a proposal is
+// compared with.
+const duplicateCandidates = 200
+
+var errProposalNotIndexed = errors.New("proposal not indexed")
+
+// ScoringStore provides the indexed proposals scored and keeps the scores.
+type ScoringStore interface {
+	ScoringProposal(id uint64) (Proposal, error)
+	ProposalsBefore(id uint64, limit int) ([]Proposal, error)
+	SaveProposalScore(s *ProposalScore) error
+}
+
+// ScoringInput is what a scorer rates: the proposal voted on by voter. Agent
+// is the client asked for judgment and Store the indexed proposals, nil when
+// the proposal is not indexed, Proposal then only has its id.
+type ScoringInput struct {
+	Proposal Proposal
+	Voter    string
+	Agent    Client
+	Store    ScoringStore
+}
+
+// ProposalScorer rates a proposal between 0, reject, and 1, accept, and
+// explains the score.
+type ProposalScorer interface {
+	Score(ctx context.Context, in ScoringInput) (float64, string, error)
+}
+
+// ScoringConfig configures the scoring pipeline. A scorer runs when it has
+// a positive weight. A proposal is accepted when the weighted mean of the
+// scores reaches Threshold.
+type ScoringConfig struct {
+	Weights   map[string]float64
+	Threshold float64
+	BudgetCap float64
+}
+
+var proposalScorers = struct {
+	sync.RWMutex
+	scorers map[string]func(cfg ScoringConfig) ProposalScorer
+}{scorers: make(map[string]func(cfg ScoringConfig) ProposalScorer)}
+
+func init() {
+	RegisterProposalScorer(ScorerAgent, func(ScoringConfig) ProposalScorer { return agentScorer{} })
+	RegisterProposalScorer(ScorerRules, func(ScoringConfig) ProposalScorer { return rulesScorer{} })
+	RegisterProposalScorer(ScorerDuplicate, func(ScoringConfig) ProposalScorer { return duplicateScorer{} })
+	RegisterProposalScorer(ScorerBudget, func(cfg ScoringConfig) ProposalScorer { return budgetScorer{cap: cfg.BudgetCap} })
+}
+
+// RegisterProposalScorer adds or replaces the scorer weighted by name.
+func RegisterProposalScorer(name string, newScorer func(cfg ScoringConfig) ProposalScorer) {
+	proposalScorers.Lock()
+	defer proposalScorers.Unlock()
+	proposalScorers.scorers[name] = newScorer
+}
+
+// ScorerResult is the score of one scorer in a ProposalScore.
+type ScorerResult struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+type weightedScorer struct {
+	name   string
+	weight float64
+	scorer ProposalScorer
+}
+
+var _ Client = &ScoringClient{}
+
+// ScoringClient decides proposal votes with the composite score of the
+// configured scorers instead of the agent answer alone, and stores every
+// score so voters can see how a vote came about.
+type ScoringClient struct {
+	Client
+	scorers   []weightedScorer
+	threshold float64
+	logger    cmtlog.Logger
+	store     ScoringStore
+}
+
+func NewScoringClient(inner Client, cfg ScoringConfig, logger cmtlog.Logger) (*ScoringClient, error) {
+	weights := cfg.Weights
+	if len(weights) == 0 {
+		weights = DefaultScoringWeights
+	}
+	if cfg.Threshold <= 0 || cfg.Threshold > 1 {
+		return nil, fmt.Errorf("scoring threshold must be in (0, 1], got %v", cfg.Threshold)
+	}
+	s := &ScoringClient{
+		Client:    inner,
+		threshold: cfg.Threshold,
+		logger:    logger.With("module", "scoring"),
+	}
+	proposalScorers.RLock()
+	defer proposalScorers.RUnlock()
+	for name, weight := range weights {
+		newScorer, ok := proposalScorers.scorers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown proposal scorer %q", name)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("negative weight for proposal scorer %q", name)
+		}
+		if weight == 0 {
+			continue
+		}
+		s.scorers = append(s.scorers, weightedScorer{name: name, weight: weight, scorer: newScorer(cfg)})
+	}
+	if len(s.scorers) == 0 {
+		return nil, errors.New("no proposal scorer with a positive weight")
+	}
+	sort.Slice(s.scorers, func(i, j int) bool {
+		return s.scorers[i].name < s.scorers[j].name
+	})
+	return s, nil
+}
+
+func (s *ScoringClient) SetScoringStore(store ScoringStore) {
+	s.store = store
+}
+
+// IfAcceptProposal runs every scorer and accepts when the weighted mean of
+// the scores reaches the threshold. A failing scorer is left out of the mean,
+// the vote fails only when the agent scorer does, as it did without scoring.
+func (s *ScoringClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	in := ScoringInput{Proposal: Proposal{Id: proposal}, Voter: voter, Agent: s.Client}
+	if s.store != nil {
+		p, err := s.store.ScoringProposal(proposal)
+		if err != nil {
+			// the scorers needing the proposal fail and are left out
+			s.logger.Error("get scored proposal fail", "proposal", proposal, "err", err)
+		} else {
+			in.Proposal, in.Store = p, s.store
+		}
+	}
+	results := make([]ScorerResult, 0, len(s.scorers))
+	var sum, weights float64
+	agentReason := ""
+	for _, ws := range s.scorers {
+		result := ScorerResult{Name: ws.name, Weight: ws.weight}
+		score, reason, err := ws.scorer.Score(ctx, in)
+		if err != nil {
+			if ws.name == ScorerAgent {
+				return Decision{}, err
+			}
+			result.Error = err.Error()
+			s.logger.Error("proposal scorer fail", "scorer", ws.name, "proposal", proposal, "err", err)
+		} else {
+			result.Score = clampScore(score)
+			result.Reason = reason
+			sum += ws.weight * result.Score
+			weights += ws.weight
+			if ws.name == ScorerAgent {
+				agentReason = reason
+			}
+		}
+		results = append(results, result)
+	}
+	if weights == 0 {
+		return Decision{}, errors.New("every proposal scorer failed")
+	}
+	composite := sum / weights
+	pass := composite >= s.threshold
+	summary := make([]string, 0, len(results))
+	for _, r := range results {
+		if r.Error != "" {
+			summary = append(summary, fmt.Sprintf("%s failed", r.Name))
+			continue
+		}
+		summary = append(summary, fmt.Sprintf("%s %.2f", r.Name, r.Score))
+	}
+	reason := fmt.Sprintf("score %.2f, threshold %.2f (%s)", composite, s.threshold, strings.Join(summary, ", "))
+	if agentReason != "" {
+		reason += ": " + agentReason
+	}
+	s.logger.Info("score proposal", "proposal", proposal, "voter", voter, "score", composite, "pass", pass)
+	if s.store != nil {
+		data, _ := json.Marshal(results)
+		score := ProposalScore{
+			Proposal:  proposal,
+			Voter:     voter,
+			Composite: composite,
+			Threshold: s.threshold,
+			Pass:      pass,
+			Scores:    string(data),
+			CreatedAt: time.Now(),
+		}
+		if err := s.store.SaveProposalScore(&score); err != nil {
+			s.logger.Error("save proposal score fail", "err", err)
+		}
+	}
+	return decisionOf(pass, reason), nil
+}
+
+func clampScore(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
+
+// agentScorer is the agent's own yes or no, moved toward 0.5 as the agent
+// is less confident. An abstaining agent scores 0.5.
+type agentScorer struct{}
+
+func (agentScorer) Score(ctx context.Context, in ScoringInput) (float64, string, error) {
+	decision, err := in.Agent.IfAcceptProposal(ctx, in.Proposal.Id, in.Voter)
+	if err != nil {
+		return 0, "", err
+	}
+	score, reason := 0.5, "agent abstains"
+	switch decision.Vote {
+	case VoteYes:
+		score, reason = 0.5+decision.Confidence/2, fmt.Sprintf("agent accepts, confidence %.2f", decision.Confidence)
+	case VoteNo:
+		score, reason = 0.5-decision.Confidence/2, fmt.Sprintf("agent rejects, confidence %.2f", decision.Confidence)
+	}
+	if decision.Reason != "" {
+		reason += ": " + decision.Reason
+	}
+	return score, reason, nil
+}
+
+// rulesScorer is the share of basic quality checks the proposal passes.
+type rulesScorer struct{}
+
+func (rulesScorer) Score(ctx context.Context, in ScoringInput) (float64, string, error) {
+	if in.Store == nil {
+		return 0, "", errProposalNotIndexed
+	}
+	p := in.Proposal
+	checks := []struct {
+		name string
+		ok   bool
+	}{
+		{"has a title", strings.TrimSpace(p.Title) != ""},
+		{"has a summary", strings.TrimSpace(p.Summary) != ""},
+		{"valid payload", p.PayloadError == ""},
+		{"body of 50 characters or more", len(strings.TrimSpace(p.Data)) >= 50},
+	}
+	failed := make([]string, 0)
+	for _, c := range checks {
+		if !c.ok {
+			failed = append(failed, c.name)
+		}
+	}
+	score := float64(len(checks)-len(failed)) / float64(len(checks))
+	if len(failed) == 0 {
+		return score, "all checks pass", nil
+	}
+	return score, "fails: " + strings.Join(failed, ", "), nil
+}
+
+// duplicateScorer lowers the score of a proposal by its word overlap with
+// the most similar earlier proposal.
+type duplicateScorer struct{}
+
+func (duplicateScorer) Score(ctx context.Context, in ScoringInput) (float64, string, error) {
+	if in.Store == nil {
+		return 0, "", errProposalNotIndexed
+	}
+	earlier, err := in.Store.ProposalsBefore(in.Proposal.Id, duplicateCandidates)
+	if err != nil {
+		return 0, "", err
+	}
+	words := proposalWords(in.Proposal)
+	var best float64
+	var bestId uint64
+	for _, p := range earlier {
+		if sim := jaccard(words, proposalWords(p)); sim > best {
+			best, bestId = sim, p.Id
+		}
+	}
+	if bestId == 0 {
+		return 1, "no similar proposal", nil
+	}
+	return 1 - best, fmt.Sprintf("%.0f%% similar to proposal %d", best*100, bestId), nil
+}
+
+func proposalWords(p Proposal) map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(p.Title+" "+p.Data), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if len(w) > 2 {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+func jaccard(a map[string]bool, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for w := range a {
+		if b[w] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// budgetScorer lowers the score of a structured proposal by the share of
+// the budget cap its amount field requests. Proposals without an amount, or
+// without a cap configured, are not penalized.
+type budgetScorer struct {
+	cap float64
+}
+
+func (b budgetScorer) Score(ctx context.Context, in ScoringInput) (float64, string, error) {
+	if in.Store == nil {
+		return 0, "", errProposalNotIndexed
+	}
+	if in.Proposal.PayloadData == "" {
+		return 1, "no budget requested", nil
+	}
+	var payload struct {
+		Amount *float64 `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(in.Proposal.PayloadData), &payload); err != nil || payload.Amount == nil {
+		return 1, "no budget requested", nil
+	}
+	amount := *payload.Amount
+	if b.cap <= 0 {
+		return 1, fmt.Sprintf("requests %v, no budget cap", amount), nil
+	}
+	if amount > b.cap {
+		return 0, fmt.Sprintf("requests %v over the cap of %v", amount, b.cap), nil
+	}
+	return 1 - amount/b.cap, fmt.Sprintf("requests %.0f%% of the cap", amount/b.cap*100), nil
+}
+
+func (c *ChainIndexer) ScoringProposal(id uint64) (Proposal, error) {
+	var p Proposal
+	err := c.db.First(&p, id).Error
+	return p, err
+}
+
+func (c *ChainIndexer) ProposalsBefore(id uint64, limit int) ([]Proposal, error) {
+	var proposals []Proposal
+	err := c.db.Where("id < ?", id).Order("id desc").Limit(limit).Find(&proposals).Error
+	return proposals, err
+}
+
+func (c *ChainIndexer) SaveProposalScore(s *ProposalScore) error {
+	return c.db.Create(s).Error
+}
+
+func (c *ChainIndexer) getProposalScores(proposal uint64, page int, pageSize int) (Page[ProposalScore], error) {
+	db := c.db.Model(&ProposalScore{})
+	if proposal != 0 {
+		db = db.Where("proposal = ?", proposal)
+	}
+	scores, err := paginate[ProposalScore](db, "id desc", page, pageSize)
+	if err != nil {
+		return scores, err
+	}
+	for i := range scores.Items {
+		if err := json.Unmarshal([]byte(scores.Items[i].Scores), &scores.Items[i].Results); err != nil {
+			return scores, err
+		}
+	}
+	return scores, nil
+}
@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	SuggestProposal  = "proposal"
+	SuggestValidator = "validator"
+)
+
+const (
+	suggestLimitDefault = 10
+	suggestLimitMax     = 50
+)
+
+// SearchSuggestion is one typeahead result. Field is the column that matched
+// the query: id or title for proposals, name or address for validators.
+type SearchSuggestion struct {
+	Kind   string `json:"kind"`
+	Id     uint64 `json:"id"`
+	Label  string `json:"label"`
+	Detail string `json:"detail"`
+	Field  string `json:"field"`
+	Exact  bool   `json:"exact"`
+	active bool
+}
+
+// likeEscaper escapes the LIKE wildcards of user input, used with
+// ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// prefixWhere matches the rows whose column starts with query, ignoring
+// case. The conditions are the ones served by the indexes added in
+// migrateSearchIndexes: a NOCASE index on sqlite, where LIKE ignores case,
+// and a lower() text_pattern_ops index on postgres.
+func prefixWhere(db *gorm.DB, column string, query string) *gorm.DB {
+	pattern := likeEscaper.Replace(strings.ToLower(query)) + "%"
+	if db.Dialect().GetName() == "postgres" {
+		return db.Where("lower("+column+") LIKE ? ESCAPE '\\'", pattern)
+	}
+	return db.Where(column+" LIKE ? ESCAPE '\\'", pattern)
+}
+
+// searchSuggestions returns up to limit proposals and validators whose title,
+// name or address starts with query. Exact matches come first, then the
+// shortest labels, so the more of a label is typed the higher it ranks.
+// Ties list active validators first and newer proposals first.
+func (c *ChainIndexer) searchSuggestions(query string, limit int) ([]SearchSuggestion, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []SearchSuggestion{}, nil
+	}
+	if limit <= 0 {
+		limit = suggestLimitDefault
+	}
+	if limit > suggestLimitMax {
+		limit = suggestLimitMax
+	}
+	suggestions := make([]SearchSuggestion, 0, limit)
+
+	if id, err := strconv.ParseUint(strings.TrimPrefix(query, "#"), 10, 64); err == nil {
+		var p Proposal
+		err := c.db.Where("id = ?", id).First(&p).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			suggestions = append(suggestions, proposalSuggestion(p, "id", true))
+		}
+	}
+
+	var proposals []Proposal
+	err := prefixWhere(c.db, "title", query).Order("length(title) asc, id desc").Limit(limit).Find(&proposals).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range proposals {
+		suggestions = append(suggestions, proposalSuggestion(p, "title", strings.EqualFold(p.Title, query)))
+	}
+
+	seen := make(map[uint64]bool)
+	for _, field := range []string{"name", "address"} {
+		var validators []ValidatorAgent
+		err := prefixWhere(c.db, field, query).Order("length(" + field + ") asc, id asc").Limit(limit).Find(&validators).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range validators {
+			if seen[v.Id] {
+				continue
+			}
+			seen[v.Id] = true
+			matched := v.Name
+			if field == "address" {
+				matched = v.Address
+			}
+			suggestions = append(suggestions, SearchSuggestion{
+				Kind:   SuggestValidator,
+				Id:     v.Id,
+				Label:  v.Name,
+				Detail: v.Address,
+				Field:  field,
+				Exact:  strings.EqualFold(matched, query),
+				active: v.Active,
+			})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Exact != b.Exact {
+			return a.Exact
+		}
+		if la, lb := len(a.matched()), len(b.matched()); la != lb {
+			return la < lb
+		}
+		if a.Kind == SuggestValidator && b.Kind == SuggestValidator && a.active != b.active {
+			return a.active
+		}
+		if a.Kind == SuggestProposal && b.Kind == SuggestProposal {
+			return a.Id > b.Id
+		}
+		return false
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+func proposalSuggestion(p Proposal, field string, exact bool) SearchSuggestion {
+	return SearchSuggestion{
+		Kind:   SuggestProposal,
+		Id:     p.Id,
+		Label:  p.Title,
+		Detail: p.ProposerName,
+		Field:  field,
+		Exact:  exact,
+	}
+}
+
+// matched is the text the query was matched against.
+func (s SearchSuggestion) matched() string {
+	switch s.Field {
+	case "id":
+		return strconv.FormatUint(s.Id, 10)
+	case "address":
+		return s.Detail
+	}
+	return s.Label
+}
+
+// searchIndexes are the columns served by searchSuggestions.
+var searchIndexes = []struct {
+	table  string
+	column string
+}{
+	{"proposals", "title"},
+	{"validator_agents", "name"},
+	{"validator_agents", "address"},
+}
+
+// migrateSearchIndexes adds the case insensitive prefix indexes used by
+// searchSuggestions.
+func migrateSearchIndexes(tx *gorm.DB) error {
+	for _, idx := range searchIndexes {
+		name := "idx_" + idx.table + "_" + idx.column + "_prefix"
+		expr := idx.column + " COLLATE NOCASE"
+		if tx.Dialect().GetName() == "postgres" {
+			expr = "lower(" + idx.column + ") text_pattern_ops"
+		}
+		if err := tx.Exec("CREATE INDEX IF NOT EXISTS " + name + " ON " + idx.table + " (" + expr + ")").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
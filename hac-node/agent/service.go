@@ -1,12 +1,17 @@
 package agent
 
 import (
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"sort"
+	"strings"
+	"time"
 
-	"github.com/calehh/hac-app/tx"
+	"github.com/calehh/hac-app/version"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type Service struct {
@@ -23,18 +28,95 @@ func NewService(ListenAddr string, indexer *ChainIndexer) *Service {
 		listenAddr: ListenAddr,
 	}
 	g := s.engine.Group("/api")
-	g.POST("/proposals", s.handleGetProposals)
-	g.POST("/discussions", s.handleGetDiscussions)
-	g.POST("/grants", s.handleGetGrants)
-	g.POST("/agents", s.handleGetAgents)
-	g.POST("/agent-detail", s.handleGetAgentDetail)
-	g.POST("/proposal-detail", s.handleGetProposalDetail)
-	g.GET("/manifesto", s.handleGetManifesto)
-	g.GET("/network-status", s.handleGetNetworkStatus)
-	g.GET("/latest-blocks", s.handleGetLatestBlocks)
+	governance := s.requireScope(ScopeReadGovernance)
+	votes := s.requireScope(ScopeReadVotes)
+	admin := s.requireScope(ScopeAdmin)
+	g.POST("/proposals", governance, s.handleGetProposals)
+	g.POST("/discussions", governance, s.handleGetDiscussions)
+	g.POST("/grants", governance, s.handleGetGrants)
+	g.POST("/agents", governance, s.handleGetAgents)
+	g.POST("/validators", governance, s.handleGetValidators)
+	g.POST("/agent-detail", governance, s.handleGetAgentDetail)
+	g.POST("/proposal-detail", governance, s.handleGetProposalDetail)
+	g.POST("/proposal-history", governance, s.handleGetProposalHistory)
+	g.POST("/proposal-timeline", governance, s.handleGetProposalTimeline)
+	g.POST("/proposal-lifecycle", governance, s.handleGetProposalLifecycle)
+	g.POST("/lifecycle-anomalies", governance, s.handleGetLifecycleAnomalies)
+	g.POST("/upcoming-activations", governance, s.handleGetUpcomingActivations)
+	g.POST("/treasury", governance, s.handleGetTreasury)
+	g.POST("/uptime", governance, s.handleGetUptime)
+	g.POST("/uptime-absences", governance, s.handleGetUptimeAbsences)
+	g.POST("/misbehaviors", governance, s.handleGetMisbehaviors)
+	g.POST("/search-suggest", governance, s.handleSearchSuggest)
+	g.POST("/votes", votes, s.handleGetVotes)
+	g.POST("/discrepancies", admin, s.handleGetDiscrepancies)
+	g.POST("/failed-events", admin, s.handleGetFailedEvents)
+	g.POST("/audit-divergences", admin, s.handleGetAuditDivergences)
+	g.POST("/dry-run-decisions", admin, s.handleGetDryRunDecisions)
+	g.POST("/admin/vote-fallbacks", admin, s.handleGetVoteFallbacks)
+	g.POST("/admin/agent-health-checks", admin, s.handleGetAgentHealthChecks)
+	g.POST("/admin/comment-drafts", admin, s.handleGetCommentDrafts)
+	g.POST("/admin/decision-cache/invalidate", admin, s.handleInvalidateDecisionCache)
+	g.POST("/admin/transcripts", admin, s.handleGetTranscripts)
+	g.POST("/admin/agent-decisions", admin, s.handleGetAgentDecisions)
+	g.POST("/admin/decision-proof", admin, s.handleGetDecisionProof)
+	g.POST("/proposal-scores", governance, s.handleGetProposalScores)
+	g.POST("/ensemble-votes", governance, s.handleGetEnsembleVotes)
+	g.POST("/debate-rounds", governance, s.handleGetDebateRounds)
+	g.POST("/proposal-summary", governance, s.handleGetProposalSummary)
+	g.POST("/proposal-summaries", governance, s.handleGetProposalSummaries)
+	g.POST("/social-posts", governance, s.handleGetSocialPosts)
+	g.POST("/ipfs-archives", governance, s.handleGetIpfsArchives)
+	g.POST("/snapshot-export", admin, s.handleSnapshotExport)
+	g.POST("/bridge-mirrors", governance, s.handleGetBridgeMirrors)
+	g.POST("/admin/failed-events/requeue", admin, s.handleRequeueFailedEvents)
+	g.POST("/admin/agent-outbox", admin, s.handleGetAgentOutbox)
+	g.POST("/admin/agent-outbox/requeue", admin, s.handleRequeueAgentOutbox)
+	g.POST("/admin/maintenance", admin, s.handleMaintenance)
+	g.POST("/webhooks/proposals", s.handleProposalWebhook)
+	g.POST("/webhooks/agent-questions", s.handleGetAgentQuestions)
+	g.POST("/webhooks/agent-decisions", s.handleAgentDecision)
+	g.POST("/admin/digest-subscribers", admin, s.handleGetDigestSubscribers)
+	g.POST("/admin/digest-subscribers/add", admin, s.handleAddDigestSubscriber)
+	g.POST("/admin/digest-subscribers/remove", admin, s.handleRemoveDigestSubscriber)
+	g.POST("/admin/api-keys", admin, s.handleGetApiKeys)
+	g.POST("/admin/api-keys/add", admin, s.handleAddApiKey)
+	g.POST("/admin/api-keys/remove", admin, s.handleRemoveApiKey)
+	g.GET("/admin/sync/snapshot", admin, s.handleSyncSnapshot)
+	g.GET("/manifesto", governance, s.handleGetManifesto)
+	g.GET("/payload-schemas", governance, s.handleGetPayloadSchemas)
+	g.GET("/network-status", governance, s.handleGetNetworkStatus)
+	g.GET("/agent-health", governance, s.handleGetAgentHealth)
+	g.GET("/event-stats", governance, s.handleGetEventStats)
+	g.GET("/events/stream", governance, s.handleStreamEvents)
+	g.GET("/proposals/:id/updates", governance, s.handleGetProposalUpdates)
+	g.GET("/latest-blocks", governance, s.handleGetLatestBlocks)
+	s.engine.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	s.engine.GET("/version", s.handleGetVersion)
 	return s
 }
 
+// requireScope rejects requests whose api key does not grant scope.
+func (s *Service) requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := s.indexer.authorizeApiKey(c.Request.Header, scope)
+		if err == nil {
+			c.Next()
+			return
+		}
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, errUnauthorized):
+			status = http.StatusUnauthorized
+		case errors.Is(err, errForbidden):
+			status = http.StatusForbidden
+		case errors.Is(err, errRateLimited), errors.Is(err, errQuotaExceeded):
+			status = http.StatusTooManyRequests
+		}
+		c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+	}
+}
+
 func (s *Service) Start() {
 	err := s.engine.Run(s.listenAddr)
 	if err != nil {
@@ -43,11 +125,14 @@ func (s *Service) Start() {
 }
 
 type VoteInfo struct {
-	Pass         bool   `json:"pass"`
-	VoterIndex   uint64 `json:"voter_index"`
-	VoterAddress string `json:"voter_address"`
-	Height       uint64 `json:"height"`
-	VoteCode     uint64 `json:"voteCode"`
+	Pass         bool      `json:"pass"`
+	VoterIndex   uint64    `json:"voter_index"`
+	VoterAddress string    `json:"voter_address"`
+	Height       uint64    `json:"height"`
+	VoteCode     VoteCode  `json:"voteCode"`
+	Reason       string    `json:"reason"`
+	BlockTime    time.Time `json:"blockTime"`
+	Power        int64     `json:"power"`
 }
 type ProposalInfo struct {
 	Proposal       Proposal   `json:"proposal"`
@@ -58,6 +143,8 @@ type ProposalInfo struct {
 	DecisionVote   []VoteInfo `json:"decisionVotes"`
 	DecisionPass   uint64     `json:"decisionPass"`
 	DecisionReject uint64     `json:"decisionReject"`
+	DraftTally     VoteTally  `json:"draftTally"`
+	DecisionTally  VoteTally  `json:"decisionTally"`
 }
 
 type ProposalDetail struct {
@@ -70,28 +157,25 @@ type DecisionStep struct {
 	DecisionVote   []VoteInfo   `json:"decisionVotes"`
 	DecisionPass   uint64       `json:"decisionPass"`
 	DecisionReject uint64       `json:"decisionReject"`
+	DecisionTally  VoteTally    `json:"decisionTally"`
 }
 
 type GrantInfo struct {
 	Grant Grant      `json:"grant"`
 	Votes []VoteInfo `json:"votes"`
+	Tally VoteTally  `json:"tally"`
 }
 
 type AgentInfo struct {
-	Agent     ValidatorAgent `json:"agent"`
-	Proposals []ProposalInfo `json:"proposals"`
+	Agent        ValidatorAgent         `json:"agent"`
+	Proposals    []ProposalInfo         `json:"proposals"`
+	Misbehaviors []ValidatorMisbehavior `json:"misbehaviors"`
 }
 
 type GetGrantsReq struct {
-	GrantId  uint64 `json:"grantId"`
-	Address  string `json:"address"`
-	Page     int    `json:"page"`
-	PageSize int    `json:"pageSize"`
-}
-
-type GetGrantResponse struct {
-	Grants []GrantInfo `json:"grants"`
-	Total  uint64      `json:"total"`
+	GrantId uint64 `json:"grantId"`
+	Address string `json:"address"`
+	PageReq
 }
 
 type GetAccountDetailReq struct {
@@ -110,7 +194,7 @@ func (s *Service) handleGetAgentDetail(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	agent, err := s.indexer.getValidatorByAddress(requestData.Address)
+	agent, err := s.indexer.reader().getValidatorByAddress(requestData.Address)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -120,12 +204,12 @@ func (s *Service) handleGetAgentDetail(c *gin.Context) {
 		return
 	}
 	response.AgentInfo.Agent = *agent
-	proposals, _, err := s.indexer.getProposalsByProposerAddr(requestData.Address, 0, 1000)
+	proposals, err := s.indexer.reader().getProposalsByProposerAddr(requestData.Address, 0, 1000)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	for _, proposal := range proposals {
+	for _, proposal := range proposals.Items {
 		proposalInfo, err := s.getProposalInfoById(proposal.Id)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -133,6 +217,12 @@ func (s *Service) handleGetAgentDetail(c *gin.Context) {
 		}
 		response.AgentInfo.Proposals = append(response.AgentInfo.Proposals, proposalInfo)
 	}
+	misbehaviors, err := s.indexer.reader().getMisbehaviors(requestData.Address, 0, 1000)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response.AgentInfo.Misbehaviors = misbehaviors.Items
 	c.JSON(http.StatusOK, response)
 }
 
@@ -144,6 +234,40 @@ func (s *Service) handleGetManifesto(c *gin.Context) {
 	c.JSON(http.StatusOK, GetManifestoResponse{Manifesto: MANIFESTO})
 }
 
+type GetPayloadSchemasResponse struct {
+	Schemas []PayloadSchema `json:"schemas"`
+}
+
+func (s *Service) handleGetPayloadSchemas(c *gin.Context) {
+	c.JSON(http.StatusOK, GetPayloadSchemasResponse{Schemas: PayloadSchemas()})
+}
+
+// VersionResponse lets clients check compatibility with a node. SchemaVersion
+// is the latest schema the binary migrates to, DBSchemaVersion the one of the
+// db in use.
+type VersionResponse struct {
+	version.Info
+	ChainId         string   `json:"chainId"`
+	SchemaVersion   uint64   `json:"schemaVersion"`
+	DBSchemaVersion uint64   `json:"dbSchemaVersion"`
+	EventTypes      []string `json:"eventTypes"`
+}
+
+func (s *Service) handleGetVersion(c *gin.Context) {
+	dbVersion, err := getSchemaVersion(s.indexer.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, VersionResponse{
+		Info:            version.Get(),
+		ChainId:         s.indexer.ChainId,
+		SchemaVersion:   SchemaVersionLatest(),
+		DBSchemaVersion: dbVersion,
+		EventTypes:      SupportedEventTypes(),
+	})
+}
+
 type GetNetworkStatusResponse struct {
 	BlockHeight         uint64 `json:"blockHeight"`
 	LastProposer        string `json:"lastProposer"`
@@ -155,12 +279,12 @@ type GetNetworkStatusResponse struct {
 func (s *Service) handleGetNetworkStatus(c *gin.Context) {
 	var response GetNetworkStatusResponse
 	response.BlockHeight = uint64(s.indexer.Height)
-	proposals, _, err := s.indexer.getProposals(0, 1)
+	proposals, err := s.indexer.reader().getProposals(0, 1)
 	if err != nil {
 		s.indexer.logger.Error("get proposals", "error", err)
 	}
-	if len(proposals) != 0 {
-		validator, err := s.indexer.getValidatorByAddress(proposals[0].ProposerAddress)
+	if len(proposals.Items) != 0 {
+		validator, err := s.indexer.reader().getValidatorByAddress(proposals.Items[0].ProposerAddress)
 		if err != nil {
 			s.indexer.logger.Error("get validator by address", "error", err)
 		}
@@ -169,12 +293,12 @@ func (s *Service) handleGetNetworkStatus(c *gin.Context) {
 			response.LastProposer = validator.Name
 		}
 	}
-	proposalsInProgress, err := s.indexer.getProposalsInProcess()
+	proposalsInProgress, err := s.indexer.reader().getProposalsInProcess()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	proposalsDecided, err := s.indexer.getProposalsDecided()
+	proposalsDecided, err := s.indexer.reader().getProposalsDecided()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -184,6 +308,75 @@ func (s *Service) handleGetNetworkStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+type GetAgentHealthResponse struct {
+	Probed    bool              `json:"probed"`
+	Healthy   bool              `json:"healthy"`
+	Last      *AgentHealthCheck `json:"last"`
+	ActiveUrl string            `json:"activeUrl,omitempty"`
+	// Capabilities is what the agent reported at startup, nil when it did
+	// not and every call is sent.
+	Capabilities *AgentCapabilities `json:"capabilities,omitempty"`
+}
+
+func (s *Service) handleGetAgentHealth(c *gin.Context) {
+	health := ActiveHealth()
+	response := GetAgentHealthResponse{Probed: health != nil, Healthy: true}
+	if health != nil {
+		response.Healthy = health.IsHealthy()
+		response.Last = health.Last()
+	}
+	if failover, ok := FindClient[*FailoverClient](ActiveClient()); ok {
+		response.ActiveUrl = failover.ActiveUrl()
+	}
+	if caps, ok := FindClient[*CapabilityClient](ActiveClient()); ok {
+		response.Capabilities = caps.Capabilities()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Service) handleGetAgentHealthChecks(c *gin.Context) {
+	var requestData PageReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getAgentHealthChecks(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetCommentDraftsReq struct {
+	Proposal uint64 `json:"proposal"`
+	PageReq
+}
+
+func (s *Service) handleGetCommentDrafts(c *gin.Context) {
+	var requestData GetCommentDraftsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getCommentDrafts(requestData.Proposal, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
 type BlockInfo struct {
 	Height          uint64 `json:"height"`
 	Proposer        string `json:"proposer"`
@@ -194,12 +387,7 @@ type BlockInfo struct {
 	TransactionCnt  uint64 `json:"transactionCnt"`
 }
 
-type GetLatestBlocksResponse struct {
-	Blocks []BlockInfo `json:"blocks"`
-}
-
 func (s *Service) handleGetLatestBlocks(c *gin.Context) {
-	response := GetLatestBlocksResponse{make([]BlockInfo, 0)}
 	info := BlockInfo{
 		Height:      uint64(s.indexer.Height),
 		Proposer:    "",
@@ -209,10 +397,10 @@ func (s *Service) handleGetLatestBlocks(c *gin.Context) {
 	}
 	block := s.indexer.BlockStore.LoadBlock(s.indexer.Height)
 	if block == nil {
-		c.JSON(http.StatusOK, response)
+		c.JSON(http.StatusOK, fullPage([]BlockInfo{}))
 		return
 	}
-	validator, err := s.indexer.getValidatorByAddress(block.Header.ProposerAddress.String())
+	validator, err := s.indexer.reader().getValidatorByAddress(block.Header.ProposerAddress.String())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -222,136 +410,992 @@ func (s *Service) handleGetLatestBlocks(c *gin.Context) {
 	info.ProposerAddress = validator.Address
 	info.TransactionCnt = uint64(len(block.Txs))
 
-	proposal, err := s.indexer.getProposalByHeight(uint64(s.indexer.Height))
+	proposal, err := s.indexer.reader().getProposalByHeight(uint64(s.indexer.Height))
 	if proposal == nil || proposal.Id == 0 {
 		info.ProposalId = -1
 	} else {
 		info.ProposalId = int64(proposal.Id)
 	}
 
-	discussions, err := s.indexer.getDiscussionCntByHeight(uint64(s.indexer.Height))
-	info.Discussions = discussions
-	response.Blocks = append(response.Blocks, info)
-	c.JSON(http.StatusOK, response)
+	discussions, err := s.indexer.reader().getDiscussionCntByHeight(uint64(s.indexer.Height))
+	info.Discussions = discussions
+	c.JSON(http.StatusOK, fullPage([]BlockInfo{info}))
+}
+
+type GetAccountsReq struct{}
+
+func (s *Service) handleGetAgents(c *gin.Context) {
+	agents, err := s.indexer.reader().getValidators()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, fullPage(agents))
+}
+
+type GetValidatorsReq struct {
+	ActiveOnly bool `json:"activeOnly"`
+	PageReq
+}
+
+func (s *Service) handleGetValidators(c *gin.Context) {
+	var requestData GetValidatorsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getValidatorsPage(requestData.ActiveOnly, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetDiscrepanciesReq struct {
+	PageReq
+}
+
+func (s *Service) handleGetDiscrepancies(c *gin.Context) {
+	var requestData GetDiscrepanciesReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getBlockDiscrepancies(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetFailedEventsReq struct {
+	PageReq
+}
+
+func (s *Service) handleGetFailedEvents(c *gin.Context) {
+	var requestData GetFailedEventsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getFailedEvents(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetAuditDivergencesReq struct {
+	PageReq
+}
+
+func (s *Service) handleGetAuditDivergences(c *gin.Context) {
+	var requestData GetAuditDivergencesReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getAuditDivergences(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetDryRunDecisionsReq struct {
+	PageReq
+}
+
+type GetSocialPostsReq struct {
+	ProposalId uint64 `json:"proposalId"`
+	PageReq
+}
+
+func (s *Service) handleGetSocialPosts(c *gin.Context) {
+	var requestData GetSocialPostsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getSocialPosts(requestData.ProposalId, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetBridgeMirrorsReq struct {
+	PageReq
+}
+
+func (s *Service) handleGetBridgeMirrors(c *gin.Context) {
+	var requestData GetBridgeMirrorsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getBridgeMirrors(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type SnapshotExportReq struct {
+	ProposalId uint64 `json:"proposalId"`
+	Space      string `json:"space"`
+}
+
+func (s *Service) handleSnapshotExport(c *gin.Context) {
+	var requestData SnapshotExportReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pkg, err := s.indexer.reader().snapshotExport(requestData.ProposalId, requestData.Space)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pkg)
+}
+
+type SearchSuggestReq struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+func (s *Service) handleSearchSuggest(c *gin.Context) {
+	var requestData SearchSuggestReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	suggestions, err := s.indexer.reader().searchSuggestions(requestData.Query, requestData.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, fullPage(suggestions))
+}
+
+type GetIpfsArchivesReq struct {
+	ProposalId uint64 `json:"proposalId"`
+}
+
+func (s *Service) handleGetIpfsArchives(c *gin.Context) {
+	var requestData GetIpfsArchivesReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	archives, err := s.indexer.reader().getIpfsArchives(requestData.ProposalId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, fullPage(archives))
+}
+
+func (s *Service) handleGetDryRunDecisions(c *gin.Context) {
+	var requestData GetDryRunDecisionsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getDryRunDecisions(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Service) handleGetVoteFallbacks(c *gin.Context) {
+	var requestData PageReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getVoteFallbacks(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type InvalidateDecisionCacheReq struct {
+	Kind  string `json:"kind"`
+	RefId uint64 `json:"refId"`
+	Voter string `json:"voter"`
+}
+
+func (s *Service) handleInvalidateDecisionCache(c *gin.Context) {
+	var requestData InvalidateDecisionCacheReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if requestData.Kind != voteKindProposal && requestData.Kind != voteKindGrant {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "kind must be proposal or grant"})
+		return
+	}
+	removed, err := s.indexer.invalidateCachedDecisions(requestData.Kind, requestData.RefId, requestData.Voter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+type GetTranscriptsReq struct {
+	Kind  string `json:"kind"`
+	RefId uint64 `json:"refId"`
+	PageReq
+}
+
+func (s *Service) handleGetTranscripts(c *gin.Context) {
+	var requestData GetTranscriptsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getTranscripts(requestData.Kind, requestData.RefId, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetAgentDecisionsReq struct {
+	Kind  string `json:"kind"`
+	RefId uint64 `json:"refId"`
+	Voter string `json:"voter"`
+	// Fallback is timeout or breaker for the decisions cast by a fallback,
+	// agent for the ones the agent made
+	Fallback string `json:"fallback"`
+	PageReq
+}
+
+func (s *Service) handleGetAgentDecisions(c *gin.Context) {
+	var requestData GetAgentDecisionsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getAgentDecisions(requestData.Kind, requestData.RefId, requestData.Voter, requestData.Fallback, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetDecisionProofReq struct {
+	TranscriptId uint64 `json:"transcriptId"`
+	Height       uint64 `json:"height"`
+}
+
+func (s *Service) handleGetDecisionProof(c *gin.Context) {
+	var requestData GetDecisionProofReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.proveDecision(c.Request.Context(), requestData.TranscriptId, requestData.Height)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrDecisionNotAnchored) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetProposalScoresReq struct {
+	ProposalId uint64 `json:"proposalId"`
+	PageReq
+}
+
+func (s *Service) handleGetProposalScores(c *gin.Context) {
+	var requestData GetProposalScoresReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getProposalScores(requestData.ProposalId, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetEnsembleVotesReq struct {
+	Kind  string `json:"kind"`
+	RefId uint64 `json:"refId"`
+	PageReq
+}
+
+func (s *Service) handleGetEnsembleVotes(c *gin.Context) {
+	var requestData GetEnsembleVotesReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getEnsembleVotes(requestData.Kind, requestData.RefId, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetDebateRoundsReq struct {
+	Proposal uint64 `json:"proposal"`
+	PageReq
+}
+
+func (s *Service) handleGetDebateRounds(c *gin.Context) {
+	var requestData GetDebateRoundsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getDebateRounds(requestData.Proposal, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetProposalSummaryReq struct {
+	Proposal uint64 `json:"proposal"`
+}
+
+func (s *Service) handleGetProposalSummary(c *gin.Context) {
+	var requestData GetProposalSummaryReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	summary, err := s.indexer.reader().getProposalSummary(requestData.Proposal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if summary == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "proposal summary not found"})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+type GetProposalSummariesReq struct {
+	Status string `json:"status"`
+	PageReq
+}
+
+func (s *Service) handleGetProposalSummaries(c *gin.Context) {
+	var requestData GetProposalSummariesReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getProposalSummaries(requestData.Status, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetEventStatsResponse struct {
+	WindowSize uint64                 `json:"window_size"`
+	Windows    []EventStatsWindowInfo `json:"windows"`
+}
+
+func (s *Service) handleGetEventStats(c *gin.Context) {
+	c.JSON(http.StatusOK, GetEventStatsResponse{
+		WindowSize: EventStatsWindow,
+		Windows:    s.indexer.eventStats.snapshot(),
+	})
+}
+
+type GetProposalUpdatesReq struct {
+	ProposalId uint64 `uri:"id"`
+	Since      uint64 `form:"since"`
+	Timeout    int    `form:"timeout"`
+}
+
+// handleGetProposalUpdates long-polls the timeline of a proposal for clients
+// that cannot stream: it answers once entries above since are indexed, or
+// after timeout seconds with none.
+func (s *Service) handleGetProposalUpdates(c *gin.Context) {
+	var requestData GetProposalUpdatesReq
+	if err := c.ShouldBindUri(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := c.ShouldBindQuery(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	timeout := longPollTimeoutDefault
+	if requestData.Timeout > 0 {
+		timeout = time.Duration(requestData.Timeout) * time.Second
+	}
+	if timeout > longPollTimeoutMax {
+		timeout = longPollTimeoutMax
+	}
+	response, err := s.indexer.waitProposalUpdates(c.Request.Context(), requestData.ProposalId, requestData.Since, timeout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// handleStreamEvents streams newly indexed events as JSON lines until the
+// client disconnects. The optional type query parameter filters by event type.
+func (s *Service) handleStreamEvents(c *gin.Context) {
+	typ := c.Query("type")
+	ch := s.indexer.feed.subscribe()
+	defer s.indexer.feed.unsubscribe(ch)
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	enc := json.NewEncoder(c.Writer)
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case ev := <-ch:
+			if typ != "" && ev.Type != typ {
+				continue
+			}
+			if err := enc.Encode(ev); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+type RequeueFailedEventsReq struct {
+	Ids []uint64 `json:"ids"`
+}
+
+type RequeueFailedEventsResponse struct {
+	Requeued int64 `json:"requeued"`
+}
+
+func (s *Service) handleRequeueFailedEvents(c *gin.Context) {
+	var requestData RequeueFailedEventsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	requeued, err := s.indexer.requeueFailedEvents(requestData.Ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, RequeueFailedEventsResponse{Requeued: requeued})
+}
+
+type GetAgentOutboxReq struct {
+	Status string `json:"status"`
+	PageReq
+}
+
+func (s *Service) handleGetAgentOutbox(c *gin.Context) {
+	var requestData GetAgentOutboxReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getAgentOutbox(requestData.Status, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type RequeueAgentOutboxReq struct {
+	Ids []uint64 `json:"ids"`
+}
+
+func (s *Service) handleRequeueAgentOutbox(c *gin.Context) {
+	var requestData RequeueAgentOutboxReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	requeued, err := s.indexer.requeueAgentOutbox(requestData.Ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"requeued": requeued})
+}
+
+func (s *Service) handleProposalWebhook(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.indexer.authorizeWebhook(c.Request.Header, body); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var requestData ProposalDraftReq
+	if err := json.Unmarshal(body, &requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(requestData.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "body is required"})
+		return
+	}
+	if requestData.Expedited && strings.TrimSpace(requestData.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required for an expedited proposal"})
+		return
+	}
+	if requestData.Amount < 0 || (requestData.Amount > 0 && strings.TrimSpace(requestData.Recipient) == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a spend needs a recipient and a positive amount"})
+		return
+	}
+	if requestData.Expedited && requestData.Amount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "an expedited proposal cannot spend"})
+		return
+	}
+	response, err := s.indexer.submitProposalDraft(c.Request.Context(), requestData)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrNotLeader) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetDigestSubscribersReq struct {
+	PageReq
+}
+
+type GetAgentQuestionsReq struct {
+	Status string `json:"status"`
+	PageReq
+}
+
+// handleGetAgentQuestions lists the questions of the push mode for agents
+// polling them rather than receiving them on agent_push_url.
+func (s *Service) handleGetAgentQuestions(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.indexer.authorizeAgentPush(c.Request.Header, body); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var requestData GetAgentQuestionsReq
+	if err := json.Unmarshal(body, &requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getAgentQuestions(requestData.Status, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// handleAgentDecision is the callback the agent pushes its decisions to.
+func (s *Service) handleAgentDecision(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.indexer.authorizeAgentPush(c.Request.Header, body); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	var requestData AgentDecisionReq
+	if err := json.Unmarshal(body, &requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := parseDecision(requestData.Vote, requestData.Confidence, requestData.Reason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error() + ", vote must be yes, no or abstain and confidence in [0, 1]"})
+		return
+	}
+	response, err := s.indexer.answerAgentQuestion(requestData)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrQuestionNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrQuestionClosed):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Service) handleGetDigestSubscribers(c *gin.Context) {
+	var requestData GetDigestSubscribersReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getDigestSubscribers(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type DigestSubscriberReq struct {
+	Email     string `json:"email"`
+	Frequency string `json:"frequency"`
+}
+
+func (s *Service) handleAddDigestSubscriber(c *gin.Context) {
+	var requestData DigestSubscriberReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sub, err := s.indexer.addDigestSubscriber(requestData.Email, requestData.Frequency)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidSubscriber) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+func (s *Service) handleRemoveDigestSubscriber(c *gin.Context) {
+	var requestData DigestSubscriberReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	removed, err := s.indexer.removeDigestSubscriber(requestData.Email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
+
+type GetApiKeysReq struct {
+	PageReq
+}
+
+func (s *Service) handleGetApiKeys(c *gin.Context) {
+	var requestData GetApiKeysReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.getApiKeys(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type AddApiKeyReq struct {
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes"`
+	RateLimit  int      `json:"rateLimit"`
+	DailyQuota int      `json:"dailyQuota"`
+}
+
+func (s *Service) handleAddApiKey(c *gin.Context) {
+	var requestData AddApiKeyReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	key, err := s.indexer.addApiKey(requestData.Name, requestData.Scopes, requestData.RateLimit, requestData.DailyQuota)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidApiKey) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+type RemoveApiKeyReq struct {
+	Id uint64 `json:"id"`
 }
 
-type GetAccountsReq struct{}
+func (s *Service) handleRemoveApiKey(c *gin.Context) {
+	var requestData RemoveApiKeyReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	removed, err := s.indexer.removeApiKey(requestData.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"removed": removed})
+}
 
-type GetAccountsResponse struct {
-	Agents []ValidatorAgent `json:"agents"`
+// handleSyncSnapshot streams the chain tables to a peer indexer, see
+// SyncIndexDB. An error after the stream started cuts it short, which the
+// peer detects from the missing end frame.
+func (s *Service) handleSyncSnapshot(c *gin.Context) {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	err := s.indexer.writeSyncSnapshot(c.Request.Context(), c.Writer)
+	if err == nil {
+		return
+	}
+	if !c.Writer.Written() {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	s.indexer.logger.Error("sync snapshot fail", "error", err)
 }
 
-func (s *Service) handleGetAgents(c *gin.Context) {
-	var response GetAccountsResponse
-	response.Agents = make([]ValidatorAgent, 0)
-	agents, err := s.indexer.getValidators()
+func (s *Service) handleMaintenance(c *gin.Context) {
+	report, err := s.indexer.maintain()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrMaintenanceRunning) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
-	response.Agents = agents
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, report)
 }
 
 func (s *Service) handleGetGrants(c *gin.Context) {
-	var response GetGrantResponse
-	response.Grants = make([]GrantInfo, 0)
 	var requestData GetGrantsReq
 	if err := c.ShouldBindJSON(&requestData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	requestData.Page -= 1
 	if requestData.GrantId != 0 {
-		grant, err := s.indexer.getGrantById(requestData.GrantId)
+		grant, err := s.indexer.reader().getGrantById(requestData.GrantId)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		votes, err := s.indexer.getGrantVotesByGrant(requestData.GrantId, 0, 1000)
+		votes, err := s.indexer.reader().getGrantVotesByGrant(requestData.GrantId, 0, 1000)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		voteInfos := GrantVotesToVoteInfo(votes)
-		grantInfo := GrantInfo{
-			Grant: grant,
-			Votes: voteInfos,
+		grantInfo, err := s.getGrantInfo(grant, votes.Items)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
-		response.Grants = append(response.Grants, grantInfo)
-		c.JSON(http.StatusOK, response)
+		c.JSON(http.StatusOK, fullPage([]GrantInfo{grantInfo}))
 		return
 	}
 
-	grants, grantTotal, err := s.indexer.getGrants(requestData.Page, requestData.PageSize)
+	grants, err := s.indexer.reader().getGrants(page, requestData.PageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	response.Total = grantTotal
-	for _, grant := range grants {
-		votes, err := s.indexer.getGrantVotesByGrant(grant.Id, 0, 1000)
+	grantInfos := make([]GrantInfo, 0, len(grants.Items))
+	for _, grant := range grants.Items {
+		votes, err := s.indexer.reader().getGrantVotesByGrant(grant.Id, 0, 1000)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		voteInfos := GrantVotesToVoteInfo(votes)
-		grantInfo := GrantInfo{
-			Grant: grant,
-			Votes: voteInfos,
+		grantInfo, err := s.getGrantInfo(grant, votes.Items)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
-		response.Grants = append(response.Grants, grantInfo)
+		grantInfos = append(grantInfos, grantInfo)
 	}
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, newPage(grantInfos, grants.Total, page, requestData.PageSize))
 }
 
 type GetDiscussionReq struct {
 	ProposalId uint64 `json:"proposalId"`
-	Page       int    `json:"page"`
-	PageSize   int    `json:"pageSize"`
-}
-
-type GetDiscussionResponse struct {
-	Discussions []Discussion `json:"discussions"`
-	Total       uint64       `json:"total"`
+	Render     bool   `json:"render"`
+	PageReq
 }
 
 func (s *Service) handleGetDiscussions(c *gin.Context) {
-	var response GetDiscussionResponse
-	response.Discussions = make([]Discussion, 0)
 	var requestData GetDiscussionReq
 	if err := c.ShouldBindJSON(&requestData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	requestData.Page -= 1
-	if requestData.ProposalId != 0 {
-		discussions, total, err := s.indexer.getDiscussionByProposal(requestData.ProposalId, requestData.Page, requestData.PageSize)
+	if requestData.ProposalId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "proposalId is required"})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	discussions, err := s.indexer.reader().getDiscussionByProposal(requestData.ProposalId, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range discussions.Items {
+		agent, err := s.indexer.reader().getValidatorByAddress(discussions.Items[i].SpeakerAddress)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		for i, _ := range discussions {
-			agent, err := s.indexer.getValidatorByAddress(discussions[i].SpeakerAddress)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			discussions[i].HeadPhoto = agent.HeadPhoto
+		discussions.Items[i].HeadPhoto = agent.HeadPhoto
+		if requestData.Render {
+			discussions.Items[i].renderBody()
 		}
-		response.Discussions = discussions
-		response.Total = total
-		c.JSON(http.StatusOK, response)
-		return
 	}
-	c.JSON(http.StatusBadRequest, gin.H{"error": "proposalId is required"})
-	return
+	c.JSON(http.StatusOK, discussions)
 }
 
 type GetProposalDetailReq struct {
 	ProposalId uint64 `json:"proposalId"`
+	Render     bool   `json:"render"`
 }
 
 func (s *Service) handleGetProposalDetail(c *gin.Context) {
@@ -375,18 +1419,25 @@ func (s *Service) handleGetProposalDetail(c *gin.Context) {
 		return
 	}
 	response.Proposal = proposalInfo.Proposal
-	discussions, _, err := s.indexer.getDiscussionByProposal(requestData.ProposalId, 0, proposalInfo.DiscussoinCnt+1)
+	if requestData.Render {
+		response.Proposal.renderBody()
+	}
+	discussionPage, err := s.indexer.reader().getDiscussionByProposal(requestData.ProposalId, 0, proposalInfo.DiscussoinCnt+1)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	discussions := discussionPage.Items
 	for i, _ := range discussions {
-		agent, err := s.indexer.getValidatorByAddress(discussions[i].SpeakerAddress)
+		agent, err := s.indexer.reader().getValidatorByAddress(discussions[i].SpeakerAddress)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		discussions[i].HeadPhoto = agent.HeadPhoto
+		if requestData.Render {
+			discussions[i].renderBody()
+		}
 	}
 	votes := proposalInfo.DecisionVote
 	if len(votes) == 0 {
@@ -425,11 +1476,17 @@ func (s *Service) handleGetProposalDetail(c *gin.Context) {
 						reject++
 					}
 				}
+				tally, err := s.indexer.reader().tallyVotes(stepVotes, TallyPassShare)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
 				response.DecisionSteps = append(response.DecisionSteps, DecisionStep{
 					Discussions:    stepDiscussions,
 					DecisionVote:   stepVotes,
 					DecisionPass:   uint64(pass),
 					DecisionReject: uint64(reject),
+					DecisionTally:  tally,
 				})
 			}
 			stepVotes = []VoteInfo{vote}
@@ -448,27 +1505,270 @@ func (s *Service) handleGetProposalDetail(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+type GetProposalHistoryReq struct {
+	ProposalId uint64 `json:"proposalId"`
+}
+
+func (s *Service) handleGetProposalLifecycle(c *gin.Context) {
+	var requestData GetProposalHistoryReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if requestData.ProposalId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "proposalId is required"})
+		return
+	}
+	response, err := s.indexer.reader().getProposalLifecycle(requestData.ProposalId)
+	if errors.Is(err, errProposalNotIndexed) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetLifecycleAnomaliesReq struct {
+	ProposalId uint64 `json:"proposalId"`
+	PageReq
+}
+
+func (s *Service) handleGetLifecycleAnomalies(c *gin.Context) {
+	var requestData GetLifecycleAnomaliesReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getProposalAnomalies(requestData.ProposalId, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Service) handleGetUpcomingActivations(c *gin.Context) {
+	var requestData PageReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getUpcomingActivations(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Service) handleGetUptime(c *gin.Context) {
+	var requestData PageReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getUptimes(page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetUptimeAbsencesReq struct {
+	Address string `json:"address"`
+	PageReq
+}
+
+func (s *Service) handleGetUptimeAbsences(c *gin.Context) {
+	var requestData GetUptimeAbsencesReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getAbsences(requestData.Address, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetMisbehaviorsReq struct {
+	Address string `json:"address"`
+	PageReq
+}
+
+func (s *Service) handleGetMisbehaviors(c *gin.Context) {
+	var requestData GetMisbehaviorsReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getMisbehaviors(requestData.Address, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+type GetTreasuryReq struct {
+	Status    string `json:"status"`
+	Recipient string `json:"recipient"`
+	PageReq
+}
+
+type TreasuryResponse struct {
+	TreasuryBudget
+	Spends Page[TreasurySpend] `json:"spends"`
+}
+
+func (s *Service) handleGetTreasury(c *gin.Context) {
+	var requestData GetTreasuryReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reader := s.indexer.reader()
+	budget, err := reader.getTreasuryBudget(treasuryRecentSpends)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	spends, err := reader.getTreasurySpends(requestData.Status, requestData.Recipient, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, TreasuryResponse{TreasuryBudget: budget, Spends: spends})
+}
+
+func (s *Service) handleGetProposalHistory(c *gin.Context) {
+	var requestData GetProposalHistoryReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if requestData.ProposalId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "proposalId is required"})
+		return
+	}
+	changes, err := s.indexer.reader().getProposalStatusChanges(requestData.ProposalId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, fullPage(changes))
+}
+
+type GetProposalTimelineReq struct {
+	ProposalId uint64 `json:"proposalId"`
+}
+
+func (s *Service) handleGetProposalTimeline(c *gin.Context) {
+	var requestData GetProposalTimelineReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if requestData.ProposalId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "proposalId is required"})
+		return
+	}
+	entries, err := s.indexer.reader().getProposalTimeline(requestData.ProposalId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, fullPage(entries))
+}
+
+type GetVotesReq struct {
+	Voter string `json:"voter"`
+	PageReq
+}
+
+// GetVotesResponse pages the proposal and grant votes of a voter together.
+type GetVotesResponse struct {
+	ProposalVotes Page[ProposalVote] `json:"proposalVotes"`
+	GrantVotes    Page[GrantVote]    `json:"grantVotes"`
+}
+
+func (s *Service) handleGetVotes(c *gin.Context) {
+	var requestData GetVotesReq
+	if err := c.ShouldBindJSON(&requestData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if requestData.Voter == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "voter is required"})
+		return
+	}
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := s.indexer.reader().getVotesByVoter(requestData.Voter, page, requestData.PageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
 type GetProposalsReq struct {
 	ProposalId      uint64 `json:"proposalId"`
 	ProposerAddress string `json:"proposer"`
-	Page            int    `json:"page"`
-	PageSize        int    `json:"pageSize"`
-}
-type GetProposalResponse struct {
-	Proposals []ProposalInfo `json:"proposals"`
-	Total     uint64         `json:"total"`
+	Render          bool   `json:"render"`
+	PageReq
 }
 
 func (s *Service) handleGetProposals(c *gin.Context) {
-	var response GetProposalResponse
-	response.Proposals = make([]ProposalInfo, 0)
-	var err error
 	var requestData GetProposalsReq
 	if err := c.ShouldBindJSON(&requestData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	requestData.Page -= 1
+	page, err := requestData.index()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if requestData.ProposalId != 0 {
 		proposalInfo, err := s.getProposalInfoById(requestData.ProposalId)
@@ -476,60 +1776,60 @@ func (s *Service) handleGetProposals(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		response.Proposals = append(response.Proposals, proposalInfo)
-		c.JSON(http.StatusOK, response)
+		if requestData.Render {
+			proposalInfo.Proposal.renderBody()
+		}
+		c.JSON(http.StatusOK, fullPage([]ProposalInfo{proposalInfo}))
 		return
 	}
-	proposalTotal := uint64(0)
-	proposals := make([]Proposal, 0)
+	var proposals Page[Proposal]
 	if requestData.ProposerAddress != "" {
-		proposals, proposalTotal, err = s.indexer.getProposalsByProposerAddr(requestData.ProposerAddress, requestData.Page, requestData.PageSize)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+		proposals, err = s.indexer.reader().getProposalsByProposerAddr(requestData.ProposerAddress, page, requestData.PageSize)
 	} else {
-		proposals, proposalTotal, err = s.indexer.getProposals(requestData.Page, requestData.PageSize)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+		proposals, err = s.indexer.reader().getProposals(page, requestData.PageSize)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
 
-	response.Total = proposalTotal
-	for _, proposal := range proposals {
+	proposalInfos := make([]ProposalInfo, 0, len(proposals.Items))
+	for _, proposal := range proposals.Items {
 		proposalInfo, err := s.getProposalInfoById(proposal.Id)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		response.Proposals = append(response.Proposals, proposalInfo)
+		if requestData.Render {
+			proposalInfo.Proposal.renderBody()
+		}
+		proposalInfos = append(proposalInfos, proposalInfo)
 	}
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, newPage(proposalInfos, proposals.Total, page, requestData.PageSize))
 }
 
 func (s *Service) getProposalInfoById(proposalId uint64) (ProposalInfo, error) {
-	proposal, err := s.indexer.getProposalById(proposalId)
+	proposal, err := s.indexer.reader().getProposalById(proposalId)
 	if err != nil {
 		return ProposalInfo{}, err
 	}
-	agent, err := s.indexer.getValidatorByAddress(proposal.ProposerAddress)
+	agent, err := s.indexer.reader().getValidatorByAddress(proposal.ProposerAddress)
 	if err != nil {
 		return ProposalInfo{}, err
 	}
 	proposal.HeadPhoto = agent.HeadPhoto
-	_, total, err := s.indexer.getDiscussionByProposal(proposalId, 0, 1)
+	discussions, err := s.indexer.reader().getDiscussionByProposal(proposalId, 0, 1)
 	if err != nil {
 		return ProposalInfo{}, err
 	}
-	votes, err := s.indexer.getProposalVotesByProposal(proposalId, 0, 1000)
+	votes, err := s.indexer.reader().getProposalVotesByProposal(proposalId, 0, 1000)
 	if err != nil {
 		return ProposalInfo{}, err
 	}
-	draftVotes, decisionVotes := ProposalVotesToVoteInfo(votes)
+	draftVotes, decisionVotes := ProposalVotesToVoteInfo(votes.Items)
 	proposalInfo := ProposalInfo{
 		Proposal:       proposal,
-		DiscussoinCnt:  int(total),
+		DiscussoinCnt:  int(discussions.Total),
 		DraftVotes:     draftVotes,
 		DraftPass:      0,
 		DraftReject:    0,
@@ -552,9 +1852,26 @@ func (s *Service) getProposalInfoById(proposalId uint64) (ProposalInfo, error) {
 			proposalInfo.DecisionReject++
 		}
 	}
+	if proposalInfo.DraftTally, err = s.indexer.reader().tallyVotes(draftVotes, TallyPassShare); err != nil {
+		return ProposalInfo{}, err
+	}
+	if proposalInfo.DecisionTally, err = s.indexer.reader().tallyVotes(decisionVotes, TallyPassShare); err != nil {
+		return ProposalInfo{}, err
+	}
+	proposalInfo.DecisionTally.settle(proposal.Status)
 	return proposalInfo, nil
 }
 
+func (s *Service) getGrantInfo(grant Grant, votes []GrantVote) (GrantInfo, error) {
+	info := GrantInfo{
+		Grant: grant,
+		Votes: GrantVotesToVoteInfo(votes),
+	}
+	var err error
+	info.Tally, err = s.indexer.reader().tallyVotes(info.Votes, TallyPassShare)
+	return info, err
+}
+
 func GrantVotesToVoteInfo(votes []GrantVote) []VoteInfo {
 	grantInfo := GrantInfo{
 		Grant: Grant{},
@@ -563,21 +1880,25 @@ func GrantVotesToVoteInfo(votes []GrantVote) []VoteInfo {
 
 	for _, vote := range votes {
 		switch vote.Vote {
-		case uint64(tx.VoteGrantNewMember):
+		case VoteCodeGrantNewMember:
 			grantInfo.Votes = append(grantInfo.Votes, VoteInfo{
 				Pass:         true,
 				VoterIndex:   vote.VoterIndex,
 				VoterAddress: vote.VoterAddress,
 				Height:       vote.Height,
 				VoteCode:     vote.Vote,
+				Reason:       vote.Reason,
+				BlockTime:    vote.BlockTime,
 			})
-		case uint64(tx.VoteRejectNewMember):
+		case VoteCodeRejectNewMember:
 			grantInfo.Votes = append(grantInfo.Votes, VoteInfo{
 				Pass:         false,
 				VoterIndex:   vote.VoterIndex,
 				VoterAddress: vote.VoterAddress,
 				Height:       vote.Height,
 				VoteCode:     vote.Vote,
+				Reason:       vote.Reason,
+				BlockTime:    vote.BlockTime,
 			})
 		}
 	}
@@ -592,37 +1913,45 @@ func ProposalVotesToVoteInfo(votes []ProposalVote) ([]VoteInfo, []VoteInfo) {
 
 	for _, vote := range votes {
 		switch vote.Vote {
-		case uint64(tx.VoteIgnoreProposal):
+		case VoteCodeIgnoreProposal:
 			proposalInfo.DraftVotes = append(proposalInfo.DraftVotes, VoteInfo{
 				Pass:         false,
 				VoterIndex:   vote.VoterIndex,
 				VoterAddress: vote.VoterAddress,
 				Height:       vote.Height,
 				VoteCode:     vote.Vote,
+				Reason:       vote.Reason,
+				BlockTime:    vote.BlockTime,
 			})
-		case uint64(tx.VoteProcessProposal):
+		case VoteCodeProcessProposal:
 			proposalInfo.DraftVotes = append(proposalInfo.DraftVotes, VoteInfo{
 				Pass:         true,
 				VoterIndex:   vote.VoterIndex,
 				VoterAddress: vote.VoterAddress,
 				Height:       vote.Height,
 				VoteCode:     vote.Vote,
+				Reason:       vote.Reason,
+				BlockTime:    vote.BlockTime,
 			})
-		case uint64(tx.VoteRejectProposal):
+		case VoteCodeRejectProposal:
 			proposalInfo.DecisionVote = append(proposalInfo.DecisionVote, VoteInfo{
 				Pass:         false,
 				VoterIndex:   vote.VoterIndex,
 				VoterAddress: vote.VoterAddress,
 				Height:       vote.Height,
 				VoteCode:     vote.Vote,
+				Reason:       vote.Reason,
+				BlockTime:    vote.BlockTime,
 			})
-		case uint64(tx.VoteAcceptProposal):
+		case VoteCodeAcceptProposal:
 			proposalInfo.DecisionVote = append(proposalInfo.DecisionVote, VoteInfo{
 				Pass:         true,
 				VoterIndex:   vote.VoterIndex,
 				VoterAddress: vote.VoterAddress,
 				Height:       vote.Height,
 				VoteCode:     vote.Vote,
+				Reason:       vote.Reason,
+				BlockTime:    vote.BlockTime,
 			})
 		}
 	}
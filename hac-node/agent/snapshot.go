@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	app_config "github.com/calehh/hac-app/config"
+)
+
+// SnapshotChoices are the choices of every exported proposal, choice numbers
+// start at 1 as in Snapshot.
+var SnapshotChoices = []string{"For", "Against", "Abstain"}
+
+// SnapshotProposal follows the proposal object of the Snapshot hub api.
+type SnapshotProposal struct {
+	Id          string    `json:"id"`
+	Space       string    `json:"space"`
+	Type        string    `json:"type"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	Discussion  string    `json:"discussion"`
+	Choices     []string  `json:"choices"`
+	Start       int64     `json:"start"`
+	End         int64     `json:"end"`
+	Snapshot    string    `json:"snapshot"`
+	State       string    `json:"state"`
+	Author      string    `json:"author"`
+	Created     int64     `json:"created"`
+	Scores      []float64 `json:"scores"`
+	ScoresTotal float64   `json:"scores_total"`
+	Votes       int       `json:"votes"`
+}
+
+// SnapshotVote follows the vote object of the Snapshot hub api.
+type SnapshotVote struct {
+	Id       string  `json:"id"`
+	Voter    string  `json:"voter"`
+	Choice   int     `json:"choice"`
+	Vp       float64 `json:"vp"`
+	Reason   string  `json:"reason"`
+	Created  int64   `json:"created"`
+	Proposal string  `json:"proposal"`
+}
+
+// SnapshotPackage is the export of one proposal with its votes and result.
+type SnapshotPackage struct {
+	Proposal SnapshotProposal `json:"proposal"`
+	Votes    []SnapshotVote   `json:"votes"`
+	Result   string           `json:"result"`
+}
+
+// snapshotExport builds the Snapshot package of a proposal. The last vote of
+// every validator counts, with the voting power of its stake at voting time.
+func (c *ChainIndexer) snapshotExport(id uint64, space string) (SnapshotPackage, error) {
+	p, err := c.getProposalById(id)
+	if err != nil {
+		return SnapshotPackage{}, err
+	}
+	if space == "" {
+		space = c.ChainId
+	}
+	if space == "" {
+		space = "hac"
+	}
+	var votes []ProposalVote
+	if err := c.db.Where("proposal = ?", id).Order("height asc, id asc").Find(&votes).Error; err != nil {
+		return SnapshotPackage{}, err
+	}
+	latest := make(map[string]ProposalVote)
+	order := make([]string, 0, len(votes))
+	for _, v := range votes {
+		if _, ok := latest[v.VoterAddress]; !ok {
+			order = append(order, v.VoterAddress)
+		}
+		latest[v.VoterAddress] = v
+	}
+	// votes are weighted with the stake of the voter at the vote height
+	stakes := make(map[uint64]map[string]uint64)
+
+	proposalId := fmt.Sprintf("%s-%d", space, p.Id)
+	end := p.ExpireTimestamp
+	state := "active"
+	if p.Status.Settled() || p.Status == ProposalStatusIgnored {
+		state = "closed"
+		var change ProposalStatusChange
+		if err := c.db.Where("proposal = ?", p.Id).Order("id desc").First(&change).Error; err == nil {
+			end = change.BlockTime.Unix()
+		}
+	}
+	pkg := SnapshotPackage{
+		Proposal: SnapshotProposal{
+			Id:         proposalId,
+			Space:      space,
+			Type:       "single-choice",
+			Title:      p.Title,
+			Body:       snapshotBody(p),
+			Discussion: p.Link,
+			Choices:    SnapshotChoices,
+			Start:      p.CreateTimestamp,
+			End:        end,
+			Snapshot:   fmt.Sprint(p.NewHeight),
+			State:      state,
+			Author:     p.ProposerAddress,
+			Created:    p.CreateTimestamp,
+			Scores:     make([]float64, len(SnapshotChoices)),
+		},
+		Votes:  make([]SnapshotVote, 0, len(order)),
+		Result: p.Status.String(),
+	}
+	for _, voter := range order {
+		v := latest[voter]
+		if _, ok := stakes[v.Height]; !ok {
+			if stakes[v.Height], err = c.stakesAt(v.Height); err != nil {
+				return SnapshotPackage{}, err
+			}
+		}
+		vote := SnapshotVote{
+			Id:       fmt.Sprintf("%s-%d-%s", space, v.Height, v.VoterAddress),
+			Voter:    v.VoterAddress,
+			Choice:   snapshotChoice(v.Vote),
+			Vp:       float64(app_config.PowerPerStake(stakes[v.Height][v.VoterAddress], v.Height)),
+			Reason:   v.Reason,
+			Created:  v.BlockTime.Unix(),
+			Proposal: proposalId,
+		}
+		pkg.Votes = append(pkg.Votes, vote)
+		pkg.Proposal.Scores[vote.Choice-1] += vote.Vp
+		pkg.Proposal.ScoresTotal += vote.Vp
+	}
+	pkg.Proposal.Votes = len(pkg.Votes)
+	return pkg, nil
+}
+
+func snapshotChoice(v VoteCode) int {
+	switch v {
+	case VoteCodeAcceptProposal:
+		return 1
+	case VoteCodeRejectProposal:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func snapshotBody(p Proposal) string {
+	var b strings.Builder
+	if p.Summary != "" {
+		b.WriteString(p.Summary)
+		b.WriteString("\n\n")
+	}
+	b.WriteString(p.Data)
+	return b.String()
+}
+
+// SnapshotExport builds the Snapshot package of a proposal. The reader does
+// not know the chain id, so space defaults to "hac".
+func (r *IndexReader) SnapshotExport(id uint64, space string) (SnapshotPackage, error) {
+	return r.c.snapshotExport(id, space)
+}
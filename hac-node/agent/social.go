@@ -0,0 +1,237 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	app_config "github.com/calehh/hac-app/config"
+)
+
+const (
+	SocialPlatformX         = "x"
+	SocialPlatformFarcaster = "farcaster"
+)
+
+// socialPostMaxLen is the post length limit of X, farcaster allows a bit
+// more.
+const socialPostMaxLen = 280
+
+// Announcer drafts the announcement of a settled proposal. It is implemented
+// by ElizaClient.
+type Announcer interface {
+	DraftAnnouncement(ctx context.Context, proposal uint64, title string, status string) (string, error)
+}
+
+// socialPublisher posts text to one account and returns the post id.
+type socialPublisher interface {
+	publish(ctx context.Context, text string) (string, error)
+}
+
+// socialPoster announces settled proposals on a social account from a single
+// goroutine. A nil poster does nothing.
+type socialPoster struct {
+	platform  string
+	publisher socialPublisher
+	announcer Announcer
+	queue     chan Proposal
+}
+
+func newSocialPoster(cfg *app_config.HACAppConfig) (*socialPoster, error) {
+	if cfg.SocialPlatform == "" {
+		return nil, nil
+	}
+	if cfg.SocialToken == "" {
+		return nil, errors.New("social_token is required when social_platform is set")
+	}
+	cli := &http.Client{Timeout: 15 * time.Second}
+	p := &socialPoster{platform: cfg.SocialPlatform, queue: make(chan Proposal, 100)}
+	switch cfg.SocialPlatform {
+	case SocialPlatformX:
+		apiUrl := cfg.SocialApiUrl
+		if apiUrl == "" {
+			apiUrl = "https://api.twitter.com/2/tweets"
+		}
+		p.publisher = &xPublisher{cli: cli, url: apiUrl, token: cfg.SocialToken}
+	case SocialPlatformFarcaster:
+		if cfg.SocialSigner == "" {
+			return nil, errors.New("social_signer is required for farcaster")
+		}
+		apiUrl := cfg.SocialApiUrl
+		if apiUrl == "" {
+			apiUrl = "https://api.neynar.com/v2/farcaster/cast"
+		}
+		p.publisher = &farcasterPublisher{cli: cli, url: apiUrl, apiKey: cfg.SocialToken, signer: cfg.SocialSigner}
+	default:
+		return nil, fmt.Errorf("unknown social_platform %q, expected x or farcaster", cfg.SocialPlatform)
+	}
+	return p, nil
+}
+
+// SetAnnouncer sets the agent drafting announcements of settled proposals.
+// Without one a fixed text is posted.
+func (c *ChainIndexer) SetAnnouncer(a Announcer) {
+	if c.social != nil {
+		c.social.announcer = a
+	}
+}
+
+// announce queues a settled proposal, proposals of old blocks are skipped so
+// an indexer catching up does not post the whole history.
+func (p *socialPoster) announce(proposal Proposal, blockTime time.Time) {
+	if p == nil || time.Since(blockTime) > notifyMaxAge {
+		return
+	}
+	select {
+	case p.queue <- proposal:
+	default:
+	}
+}
+
+func (c *ChainIndexer) runSocialPoster(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case proposal := <-c.social.queue:
+			if err := c.postOutcome(ctx, proposal); err != nil {
+				c.logger.Error("post proposal outcome fail", "proposal", proposal.Id, "platform", c.social.platform, "err", err)
+			}
+		}
+	}
+}
+
+// postOutcome posts the outcome of a proposal once per platform and records
+// the post, or the error, against the proposal.
+func (c *ChainIndexer) postOutcome(ctx context.Context, proposal Proposal) error {
+	var posted uint64
+	err := c.db.Model(&SocialPost{}).Where("proposal = ? AND platform = ? AND post_id <> ''", proposal.Id, c.social.platform).Count(&posted).Error
+	if err != nil {
+		return err
+	}
+	if posted > 0 {
+		return nil
+	}
+
+	text := ""
+	if c.social.announcer != nil {
+		text, err = c.social.announcer.DraftAnnouncement(ctx, proposal.Id, proposal.Title, proposal.Status.String())
+		if err != nil {
+			c.logger.Error("draft announcement fail", "proposal", proposal.Id, "err", err)
+		}
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		text = fmt.Sprintf("Proposal #%d \"%s\" was %s.", proposal.Id, proposal.Title, proposal.Status)
+	}
+	if r := []rune(text); len(r) > socialPostMaxLen {
+		text = string(r[:socialPostMaxLen-3]) + "..."
+	}
+
+	post := SocialPost{Proposal: proposal.Id, Platform: c.social.platform, Text: text}
+	post.PostId, err = c.social.publisher.publish(ctx, text)
+	if err != nil {
+		post.Error = err.Error()
+	}
+	if err := c.db.Create(&post).Error; err != nil {
+		return err
+	}
+	if post.Error != "" {
+		return errors.New(post.Error)
+	}
+	c.logger.Info("posted proposal outcome", "proposal", proposal.Id, "platform", post.Platform, "post", post.PostId)
+	return nil
+}
+
+func (c *ChainIndexer) getSocialPosts(proposal uint64, page int, pageSize int) (Page[SocialPost], error) {
+	db := c.db.Model(&SocialPost{})
+	if proposal != 0 {
+		db = db.Where("proposal = ?", proposal)
+	}
+	return paginate[SocialPost](db, "id desc", page, pageSize)
+}
+
+// xPublisher posts with the X api v2 using an OAuth 2.0 user access token.
+type xPublisher struct {
+	cli   *http.Client
+	url   string
+	token string
+}
+
+func (x *xPublisher) publish(ctx context.Context, text string) (string, error) {
+	var res struct {
+		Data struct {
+			Id string `json:"id"`
+		} `json:"data"`
+	}
+	header := http.Header{"Authorization": []string{"Bearer " + x.token}}
+	if err := postSocial(ctx, x.cli, x.url, header, map[string]string{"text": text}, &res); err != nil {
+		return "", err
+	}
+	if res.Data.Id == "" {
+		return "", errors.New("no post id in response")
+	}
+	return res.Data.Id, nil
+}
+
+// farcasterPublisher casts through the neynar api, the post id is the cast
+// hash.
+type farcasterPublisher struct {
+	cli    *http.Client
+	url    string
+	apiKey string
+	signer string
+}
+
+func (f *farcasterPublisher) publish(ctx context.Context, text string) (string, error) {
+	var res struct {
+		Cast struct {
+			Hash string `json:"hash"`
+		} `json:"cast"`
+	}
+	header := http.Header{"X-Api-Key": []string{f.apiKey}}
+	if err := postSocial(ctx, f.cli, f.url, header, map[string]string{"signer_uuid": f.signer, "text": text}, &res); err != nil {
+		return "", err
+	}
+	if res.Cast.Hash == "" {
+		return "", errors.New("no cast hash in response")
+	}
+	return res.Cast.Hash, nil
+}
+
+func postSocial(ctx context.Context, cli *http.Client, endpoint string, header http.Header, req interface{}, res interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header = header
+	r.Header.Set("Content-Type", "application/json")
+	resp, err := cli.Do(r)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, res)
+}
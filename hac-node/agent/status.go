@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	hac_types "github.com/calehh/hac-app/types"
+)
+
+// ProposalStatus mirrors the proposal state reported by chain events. The
+// chain values are kept as they are so stored rows stay comparable with
+// on-chain state; Proposed, Voting and Expired are only ever set by the
+// indexer.
+type ProposalStatus uint64
+
+const (
+	ProposalStatusProposed   ProposalStatus = 0
+	ProposalStatusIgnored                   = ProposalStatus(hac_types.ProposalStatusIgnore)
+	ProposalStatusDiscussing                = ProposalStatus(hac_types.ProposalStatusProcessing)
+	ProposalStatusPassed                    = ProposalStatus(hac_types.ProposalStatusAccepted)
+	ProposalStatusRejected                  = ProposalStatus(hac_types.ProposalStatusRejected)
+	ProposalStatusExpired    ProposalStatus = 5
+	// ProposalStatusVoting is a proposal under its decision vote. The chain
+	// votes and settles in the same block, so it only shows in the
+	// transition history between discussing and the settled status.
+	ProposalStatusVoting ProposalStatus = 6
+)
+
+var proposalStatusNames = map[ProposalStatus]string{
+	ProposalStatusProposed:   "proposed",
+	ProposalStatusIgnored:    "ignored",
+	ProposalStatusDiscussing: "discussing",
+	ProposalStatusPassed:     "passed",
+	ProposalStatusRejected:   "rejected",
+	ProposalStatusExpired:    "expired",
+	ProposalStatusVoting:     "voting",
+}
+
+func (s ProposalStatus) Valid() bool {
+	_, ok := proposalStatusNames[s]
+	return ok
+}
+
+func (s ProposalStatus) String() string {
+	if name, ok := proposalStatusNames[s]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(s), 10)
+}
+
+// Settled reports whether the proposal reached a final state.
+func (s ProposalStatus) Settled() bool {
+	return s == ProposalStatusPassed || s == ProposalStatusRejected || s == ProposalStatusExpired
+}
+
+// ParseProposalStatus accepts a status name as returned by the API.
+func ParseProposalStatus(name string) (ProposalStatus, error) {
+	for s, n := range proposalStatusNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown proposal status %q", name)
+}
+
+// BeforeSave rejects proposal rows carrying an unknown status.
+func (p *Proposal) BeforeSave() error {
+	if !p.Status.Valid() {
+		return fmt.Errorf("invalid proposal %d status %d", p.Id, p.Status)
+	}
+	p.StatusName = p.Status.String()
+	return nil
+}
+
+func (p *Proposal) AfterFind() error {
+	p.StatusName = p.Status.String()
+	if p.PayloadData != "" {
+		p.Payload = json.RawMessage(p.PayloadData)
+	}
+	return nil
+}
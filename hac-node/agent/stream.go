@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// commentDraftInterval is how often a comment being streamed is saved.
+const commentDraftInterval = time.Second
+
+// sseDone is the data of the server-sent event ending a stream.
+const sseDone = "[DONE]"
+
+// CommentDraft is a comment of the agent on a proposal saved while it is
+// streamed. A draft left incomplete has the error that cut the stream, its
+// text is what the agent sent until then.
+type CommentDraft struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Proposal  uint64    `gorm:"index" json:"proposal"`
+	Speaker   string    `json:"speaker"`
+	Text      string    `json:"text"`
+	Complete  bool      `json:"complete"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CommentDraftStore persists the comment drafts.
+type CommentDraftStore interface {
+	SaveCommentDraft(d *CommentDraft) error
+}
+
+// CommentStreamer is an agent backend streaming its comments, onChunk is
+// called with every part of the comment as it arrives.
+type CommentStreamer interface {
+	StreamComment(ctx context.Context, proposal uint64, speaker string, onChunk func(chunk string)) (string, error)
+	SetCommentDraftStore(store CommentDraftStore)
+}
+
+var _ CommentStreamer = &ElizaClient{}
+var _ CommentStreamer = &GRPCAgentClient{}
+
+// commentDraft saves a streamed comment at most every commentDraftInterval,
+// and once more when the stream ends. A nil store saves nothing.
+type commentDraft struct {
+	store CommentDraftStore
+	draft CommentDraft
+	text  strings.Builder
+	saved time.Time
+	err   error
+}
+
+func newCommentDraft(store CommentDraftStore, proposal uint64, speaker string) *commentDraft {
+	return &commentDraft{store: store, draft: CommentDraft{Proposal: proposal, Speaker: speaker}}
+}
+
+func (d *commentDraft) write(chunk string) {
+	d.text.WriteString(chunk)
+	if time.Since(d.saved) >= commentDraftInterval {
+		d.save()
+	}
+}
+
+// finish saves the comment, complete when err is nil.
+func (d *commentDraft) finish(err error) string {
+	d.draft.Complete = err == nil
+	if err != nil {
+		d.draft.Error = err.Error()
+	}
+	d.save()
+	return d.text.String()
+}
+
+func (d *commentDraft) save() {
+	d.saved = time.Now()
+	if d.store == nil || d.err != nil {
+		return
+	}
+	d.draft.Text = d.text.String()
+	// a failing store is not retried on every chunk
+	d.err = d.store.SaveCommentDraft(&d.draft)
+}
+
+// readSSE calls onData with the data of every server-sent event of r until
+// the stream ends or sends sseDone. An event named error fails the stream
+// with its data.
+func readSSE(r io.Reader, onData func(data string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				joined := strings.Join(data, "\n")
+				if event == "error" {
+					return errors.New(joined)
+				}
+				if joined == sseDone {
+					return nil
+				}
+				onData(joined)
+			}
+			event, data = "", nil
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// readChunks calls onData with every read of r until it ends.
+func readChunks(r io.Reader, onData func(data string)) error {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			onData(string(buf[:n]))
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *ChainIndexer) SaveCommentDraft(d *CommentDraft) error {
+	return c.db.Save(d).Error
+}
+
+func (c *ChainIndexer) getCommentDrafts(proposal uint64, page int, pageSize int) (Page[CommentDraft], error) {
+	query := c.db.Model(&CommentDraft{})
+	if proposal != 0 {
+		query = query.Where("proposal = ?", proposal)
+	}
+	return paginate[CommentDraft](query, "id desc", page, pageSize)
+}
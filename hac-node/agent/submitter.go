@@ -0,0 +1,24 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/calehh/hac-app/crypto"
+	"github.com/calehh/hac-app/txbuilder"
+	comethttp "github.com/cometbft/cometbft/rpc/client/http"
+)
+
+// TxSubmitter signs and broadcasts txs for the validator account of the
+// node, keeping track of its nonce between submissions.
+type TxSubmitter struct {
+	*txbuilder.Builder
+}
+
+// NewTxSubmitter loads the chain id and the account of pv from the chain.
+func NewTxSubmitter(ctx context.Context, cli *comethttp.HTTP, pv *crypto.PV) (*TxSubmitter, error) {
+	b, err := txbuilder.New(ctx, cli, pv)
+	if err != nil {
+		return nil, err
+	}
+	return &TxSubmitter{Builder: b}, nil
+}
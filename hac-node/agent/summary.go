@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ProposalSummary is the retrospective the agent wrote on a settled
+// proposal: what was decided, why, and the arguments of the dissent.
+type ProposalSummary struct {
+	Proposal  uint64         `gorm:"primary_key;auto_increment:false" json:"proposal"`
+	Status    ProposalStatus `json:"status"`
+	Summary   string         `json:"summary"`
+	Rationale string         `json:"rationale"`
+	Dissent   string         `json:"dissent"`
+	Height    uint64         `json:"height"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// ProposalSummarizer writes the retrospective of a settled proposal and
+// summarizes the parts of a large proposal, a nil or empty answer declines.
+// It is implemented by ElizaClient.
+type ProposalSummarizer interface {
+	SummarizeProposal(ctx context.Context, req SummarizeProposalReq) (*SummarizeProposalResponse, error)
+	SummarizeText(ctx context.Context, req SummarizeTextReq) (string, error)
+}
+
+var _ ProposalSummarizer = &ElizaClient{}
+
+// SummaryVote is a vote cast on the proposal summarized.
+type SummaryVote struct {
+	VoterAddress string `json:"voterAddress"`
+	Vote         string `json:"vote"`
+	Reason       string `json:"reason"`
+}
+
+// SummarizeProposalReq asks the agent for the retrospective of a proposal
+// settled as Status, with its text, the votes and the discussions.
+type SummarizeProposalReq struct {
+	ProposalId  uint64                `json:"proposalId"`
+	Status      string                `json:"status"`
+	Text        string                `json:"text"`
+	Proposal    *VoteProposalContext  `json:"proposal,omitempty"`
+	Votes       []SummaryVote         `json:"votes"`
+	Discussions []VoteDiscussionEntry `json:"discussions"`
+}
+
+type SummarizeProposalResponse struct {
+	Summary   string `json:"summary"`
+	Rationale string `json:"rationale"`
+	Dissent   string `json:"dissent"`
+}
+
+// SummarizeProposal asks the agent for the retrospective of a settled
+// proposal. A service without the endpoint returns nil and no error.
+func (e *ElizaClient) SummarizeProposal(ctx context.Context, req SummarizeProposalReq) (*SummarizeProposalResponse, error) {
+	e.logger.Info("SummarizeProposal", "proposal", req.ProposalId, "status", req.Status)
+	url := fmt.Sprintf("%s/%s/summarizeproposal", e.Url, e.agent())
+	data, _ := json.Marshal(req)
+	res, err := e.post(ctx, "SummarizeProposal", url, data)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	bodyBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, agentCallError("SummarizeProposal", err)
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := agentStatusError("SummarizeProposal", res.StatusCode, bodyBytes); err != nil {
+		return nil, err
+	}
+	var answer SummarizeProposalResponse
+	if err := json.Unmarshal(bodyBytes, &answer); err != nil {
+		return nil, agentResponseError("SummarizeProposal", res.StatusCode, bodyBytes, err)
+	}
+	if strings.TrimSpace(answer.Summary) == "" {
+		return nil, nil
+	}
+	return &answer, nil
+}
+
+// SetProposalSummarizer sets the agent asked for the retrospective of every
+// proposal settled, and for the summary of the large ones when indexed.
+func (c *ChainIndexer) SetProposalSummarizer(s ProposalSummarizer) {
+	c.summarizer = s
+}
+
+// summarizeProposal asks the summarizer for the retrospective of the
+// proposal of entry and stores it. A summary already stored is kept, so a
+// redelivered entry does not ask twice.
+func (c *ChainIndexer) summarizeProposal(ctx context.Context, entry AgentOutbox) error {
+	if c.summarizer == nil {
+		return nil
+	}
+	var existing ProposalSummary
+	err := c.db.First(&existing, entry.Proposal).Error
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	var proposal Proposal
+	if err := c.db.First(&proposal, entry.Proposal).Error; err != nil {
+		return err
+	}
+	req := SummarizeProposalReq{
+		ProposalId: proposal.Id,
+		Status:     proposal.Status.String(),
+		Text:       "summarize what was decided on the proposal, why, and the dissenting arguments",
+		Votes:      []SummaryVote{},
+	}
+	proposer := proposal.ProposerName
+	if proposer == "" {
+		proposer = proposal.ProposerAddress
+	}
+	req.Proposal = &VoteProposalContext{Title: proposal.Title, Summary: proposal.Summary, Body: proposal.agentBody(), Proposer: proposer}
+	var votes []ProposalVote
+	if err := c.db.Where("proposal = ?", proposal.Id).Order("height asc, id asc").Find(&votes).Error; err != nil {
+		return err
+	}
+	for _, v := range votes {
+		req.Votes = append(req.Votes, SummaryVote{VoterAddress: v.VoterAddress, Vote: v.Vote.String(), Reason: v.Reason})
+	}
+	discussions, err := c.DiscussionHistory(proposal.Id, DiscussionHistoryLimit)
+	if err != nil {
+		return err
+	}
+	req.Discussions = make([]VoteDiscussionEntry, 0, len(discussions))
+	for _, d := range textDiscussions(discussions) {
+		req.Discussions = append(req.Discussions, VoteDiscussionEntry{Speaker: d.Speaker, SpeakerAddress: d.Address, Text: d.Text, Height: d.Height})
+	}
+	answer, err := c.summarizer.SummarizeProposal(ctx, req)
+	if err != nil {
+		return err
+	}
+	if answer == nil {
+		c.logger.Info("agent declined to summarize proposal", "proposal", proposal.Id)
+		return nil
+	}
+	return c.db.Save(&ProposalSummary{
+		Proposal:  proposal.Id,
+		Status:    proposal.Status,
+		Summary:   answer.Summary,
+		Rationale: answer.Rationale,
+		Dissent:   answer.Dissent,
+		Height:    entry.Height,
+	}).Error
+}
+
+func (c *ChainIndexer) getProposalSummary(proposal uint64) (*ProposalSummary, error) {
+	var summary ProposalSummary
+	err := c.db.First(&summary, proposal).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+func (c *ChainIndexer) getProposalSummaries(status string, page int, pageSize int) (Page[ProposalSummary], error) {
+	db := c.db.Model(&ProposalSummary{})
+	if status != "" {
+		s, err := ParseProposalStatus(status)
+		if err != nil {
+			return Page[ProposalSummary]{}, err
+		}
+		db = db.Where("status = ?", s)
+	}
+	return paginate[ProposalSummary](db, "proposal desc", page, pageSize)
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"errors"
+	"time"
+
+	app_config "github.com/calehh/hac-app/config"
+	"github.com/jinzhu/gorm"
+)
+
+// TallyPassShare is the share of the total voting power a decision needs,
+// the +2/3 with which the chain commits a block.
+const TallyPassShare = 2.0 / 3.0
+
+// StakeChange records the stake of a validator from Height on, so votes are
+// weighted with the stake the validator had when it voted.
+type StakeChange struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Validator uint64    `gorm:"index" json:"validator"`
+	Address   string    `gorm:"index" json:"address"`
+	Height    uint64    `gorm:"index" json:"height"`
+	Stake     uint64    `json:"stake"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// VoteTally weighs the votes of one decision by voting power at Height, the
+// height of the last vote. Quorum is the share of the total power that
+// voted, Passed whether the accepting power reached Threshold, the pass
+// share of the decision.
+type VoteTally struct {
+	Height        uint64  `json:"height"`
+	PassPower     int64   `json:"pass_power"`
+	RejectPower   int64   `json:"reject_power"`
+	TotalPower    int64   `json:"total_power"`
+	PassPercent   float64 `json:"pass_percent"`
+	RejectPercent float64 `json:"reject_percent"`
+	QuorumPercent float64 `json:"quorum_percent"`
+	Threshold     float64 `json:"threshold"`
+	Passed        bool    `json:"passed"`
+}
+
+// recordStake stores the stake of a validator from height on. A replayed
+// height overwrites its own row.
+func recordStake(db *gorm.DB, validator uint64, address string, height uint64, stake uint64) error {
+	var change StakeChange
+	err := db.Where("validator = ? AND height = ?", validator, height).First(&change).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	change.Validator = validator
+	change.Address = address
+	change.Height = height
+	change.Stake = stake
+	return db.Save(&change).Error
+}
+
+// stakesAt returns the stake of every validator at height by address.
+func (c *ChainIndexer) stakesAt(height uint64) (map[string]uint64, error) {
+	var changes []StakeChange
+	if err := c.db.Where("height <= ?", height).Order("height asc, id asc").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	stakes := make(map[string]uint64)
+	for _, change := range changes {
+		stakes[change.Address] = change.Stake
+	}
+	return stakes, nil
+}
+
+// tallyVotes weighs votes by the power of each voter at the height of the
+// last vote. A voter voting at several heights counts with its last vote.
+// The power of every vote is set in place.
+func (c *ChainIndexer) tallyVotes(votes []VoteInfo, passShare float64) (VoteTally, error) {
+	tally := VoteTally{Threshold: passShare}
+	latest := make(map[string]int)
+	for i, v := range votes {
+		if v.Height > tally.Height {
+			tally.Height = v.Height
+		}
+		if j, ok := latest[v.VoterAddress]; !ok || votes[j].Height <= v.Height {
+			latest[v.VoterAddress] = i
+		}
+	}
+	if len(votes) == 0 {
+		return tally, nil
+	}
+	stakes, err := c.stakesAt(tally.Height)
+	if err != nil {
+		return tally, err
+	}
+	for _, stake := range stakes {
+		tally.TotalPower += app_config.PowerPerStake(stake, tally.Height)
+	}
+	for i := range votes {
+		votes[i].Power = app_config.PowerPerStake(stakes[votes[i].VoterAddress], tally.Height)
+	}
+	for _, i := range latest {
+		if votes[i].Pass {
+			tally.PassPower += votes[i].Power
+		} else {
+			tally.RejectPower += votes[i].Power
+		}
+	}
+	if tally.TotalPower > 0 {
+		total := float64(tally.TotalPower)
+		tally.PassPercent = float64(tally.PassPower) / total * 100
+		tally.RejectPercent = float64(tally.RejectPower) / total * 100
+		tally.QuorumPercent = float64(tally.PassPower+tally.RejectPower) / total * 100
+		tally.Passed = float64(tally.PassPower) > total*passShare
+	}
+	return tally, nil
+}
+
+// settle takes the outcome of a settled decision from the chain, the tally
+// of the indexed votes only estimates it.
+func (t *VoteTally) settle(status ProposalStatus) {
+	if status.Settled() {
+		t.Passed = status == ProposalStatusPassed
+	}
+}
+
+// migrateStakeHistory seeds the stake history of validators indexed before
+// it was kept with their current stake from height 0, so their older votes
+// are weighted with it.
+func migrateStakeHistory(tx *gorm.DB) error {
+	var validators []ValidatorAgent
+	if err := tx.Find(&validators).Error; err != nil {
+		return err
+	}
+	for _, v := range validators {
+		var cnt int
+		if err := tx.Model(&StakeChange{}).Where("validator = ?", v.Id).Count(&cnt).Error; err != nil {
+			return err
+		}
+		if cnt > 0 {
+			continue
+		}
+		if err := tx.Create(&StakeChange{Validator: v.Id, Address: v.Address, Height: 0, Stake: v.Stake}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
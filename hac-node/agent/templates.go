@@ -0,0 +1,219 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultAgentTexts are the templates of the text sent with the requests of
+// the eliza and grpc backends, by agent method. agent_prompts overrides them
+// one by one, so operators tune the context their agent receives.
+var DefaultAgentTexts = map[string]string{
+	"IfAcceptProposal": `{{ if .Expedited }}analyze expedited proposal, it needs {{ printf "%.0f" .PassShare }}% of the voting power to pass{{ else }}analyze proposal{{ end }}{{ if or .Title .Body }}
+
+Proposal #{{ .Id }}{{ if .Proposer }} by {{ .Proposer }}{{ end }}{{ if .Title }}: {{ .Title }}{{ end }}{{ if .Summary }}
+Summary: {{ .Summary }}{{ end }}{{ if .Body }}
+{{ .Body }}{{ end }}{{ end }}{{ .Treasury }}{{ if .History }}
+
+Discussion so far:{{ range .History }}
+- {{ .Speaker }}: {{ .Text }}{{ end }}{{ else if .Discussions }}
+
+Discussions from other validators:{{ range .Discussions }}
+- {{ .Speaker }}: {{ .Text }}{{ end }}{{ end }}`,
+	"IfGrantNewMember": `{{ .Statement }}{{ .Uptime }}{{ .Dossier }}`,
+	"CommentPropoal":   `comment{{ if .Round }}, round {{ .Round }} of {{ .Rounds }} of the debate: answer the arguments of the other validators{{ end }}`,
+}
+
+var defaultAgentTexts = mustParseAgentTexts(DefaultAgentTexts)
+
+// CharacterSource provides the character an agent introduced itself with.
+type CharacterSource interface {
+	AgentCharacter(address string) (string, error)
+}
+
+// agentText is the data of a request text template. Title, Summary, Body
+// and Proposer are the indexed proposal, Character the one of the voter's
+// agent, PassShare the percent of the voting power a proposal needs. History is the debate so far in order, Discussions the top ranked
+// ones of the other validators. Treasury and Uptime are the budget of a
+// spend and the absences of a grant proposer as text, Dossier the track
+// record of the proposer. Round is the round of Rounds of the debate a
+// comment is asked for, 0 outside of a debate.
+type agentText struct {
+	Id          uint64
+	Title       string
+	Summary     string
+	Body        string
+	Proposer    string
+	Voter       string
+	Character   string
+	Amount      uint64
+	Statement   string
+	Expedited   bool
+	PassShare   float64
+	Treasury    string
+	Uptime      string
+	Dossier     string
+	Round       int
+	Rounds      int
+	History     []agentTextDiscussion
+	Discussions []agentTextDiscussion
+
+	dossier *ProposerDossier
+}
+
+// agentTextDiscussion is a discussion on the proposal, Speaker the name of
+// the validator posting it at Height.
+type agentTextDiscussion struct {
+	Speaker string
+	Address string
+	Text    string
+	Height  uint64
+}
+
+func mustParseAgentTexts(texts map[string]string) map[string]*template.Template {
+	parsed, err := parseAgentTexts(texts)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// parseAgentTexts parses the request text templates, the defaults for the
+// methods overrides leaves out.
+func parseAgentTexts(overrides map[string]string) (map[string]*template.Template, error) {
+	for name := range overrides {
+		if _, ok := DefaultAgentTexts[name]; !ok {
+			return nil, fmt.Errorf("unknown prompt %q", name)
+		}
+	}
+	texts := make(map[string]*template.Template)
+	for name, text := range DefaultAgentTexts {
+		if override, ok := overrides[name]; ok {
+			text = override
+		}
+		t, err := template.New(name).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s prompt: %w", name, err)
+		}
+		texts[name] = t
+	}
+	return texts, nil
+}
+
+// SetTexts overrides the templates of the request texts by agent method,
+// see DefaultAgentTexts.
+func (v *voteContext) SetTexts(overrides map[string]string) error {
+	texts, err := parseAgentTexts(overrides)
+	if err != nil {
+		return err
+	}
+	v.texts = texts
+	return nil
+}
+
+// SetCharacterSource gives the templates the character of the voter's agent.
+func (v *voteContext) SetCharacterSource(src CharacterSource) {
+	v.characters = src
+}
+
+// requestText renders the text of the request of method. A template failing
+// falls back to the default one, the agent is still asked.
+func (v *voteContext) requestText(method string, data agentText) string {
+	var s strings.Builder
+	if t, ok := v.texts[method]; ok {
+		err := t.Execute(&s, data)
+		if err == nil {
+			return s.String()
+		}
+		v.logger.Error("render prompt fail, using the default", "method", method, "err", err)
+		s.Reset()
+	}
+	if err := defaultAgentTexts[method].Execute(&s, data); err != nil {
+		v.logger.Error("render default prompt fail", "method", method, "err", err)
+	}
+	return s.String()
+}
+
+// proposalText is the text data of proposal voted or commented on by voter,
+// only its id when it is not indexed.
+func (v *voteContext) proposalText(proposal uint64, voter string) agentText {
+	data := agentText{Id: proposal, Voter: voter, Character: v.character(voter)}
+	if v.proposals == nil {
+		return data
+	}
+	p, err := v.proposals.IndexedProposal(proposal)
+	if err != nil {
+		v.logger.Error("get proposal fail", "proposal", proposal, "err", err)
+		return data
+	}
+	data.Title = p.Title
+	data.Summary = p.Summary
+	data.Body = p.agentBody()
+	data.Proposer = p.ProposerName
+	if data.Proposer == "" {
+		data.Proposer = p.ProposerAddress
+	}
+	return data
+}
+
+func (v *voteContext) character(voter string) string {
+	if v.characters == nil || voter == "" {
+		return ""
+	}
+	character, err := v.characters.AgentCharacter(voter)
+	if err != nil {
+		v.logger.Error("get agent character fail", "voter", voter, "err", err)
+		return ""
+	}
+	return character
+}
+
+// voteRequest is the text data asking the agent to vote on proposal.
+func (v *voteContext) voteRequest(proposal uint64, voter string, expedited bool) agentText {
+	data := v.proposalText(proposal, voter)
+	data.Expedited = expedited
+	data.PassShare = TallyPassShare * 100
+	data.Treasury = v.treasuryText(proposal)
+	data.History = v.discussionHistory(proposal)
+	data.Discussions = v.peerDiscussions(proposal, voter)
+	return data
+}
+
+// voteRequestText renders the text asking the agent to vote on proposal.
+func (v *voteContext) voteRequestText(proposal uint64, voter string, expedited bool) string {
+	return v.requestText("IfAcceptProposal", v.voteRequest(proposal, voter, expedited))
+}
+
+// grantRequest is the text data asking the agent to vote on the grant of
+// member validator.
+func (v *voteContext) grantRequest(validator uint64, proposer string, amount uint64, statement string) agentText {
+	dossier := v.dossier(proposer)
+	return agentText{
+		Id:        validator,
+		Proposer:  proposer,
+		Amount:    amount,
+		Statement: statement,
+		Body:      statement,
+		Uptime:    v.uptimeText(proposer),
+		Dossier:   dossierText(dossier),
+		dossier:   dossier,
+	}
+}
+
+// grantRequestText renders the text asking the agent to vote on the grant
+// of member validator.
+func (v *voteContext) grantRequestText(validator uint64, proposer string, amount uint64, statement string) string {
+	return v.requestText("IfGrantNewMember", v.grantRequest(validator, proposer, amount, statement))
+}
+
+// commentRequestText renders the text asking the agent to comment on
+// proposal as speaker, in the debate round of ctx if any.
+func (v *voteContext) commentRequestText(ctx context.Context, proposal uint64, speaker string) string {
+	data := v.proposalText(proposal, speaker)
+	data.Round, data.Rounds = debateRound(ctx)
+	data.History = v.discussionHistory(proposal)
+	data.Discussions = v.peerDiscussions(proposal, speaker)
+	return v.requestText("CommentPropoal", data)
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	TimelineCreated      = "created"
+	TimelineDiscussion   = "discussion"
+	TimelineVote         = "vote"
+	TimelineStatusChange = "status_change"
+	TimelineSettled      = "settled"
+)
+
+// timelineRank orders entries of the same height the way they happen in a
+// block: the proposal tx, discussions, the votes of the commit, and the
+// settlement last.
+var timelineRank = map[string]int{
+	TimelineCreated:      0,
+	TimelineDiscussion:   1,
+	TimelineVote:         2,
+	TimelineStatusChange: 3,
+	TimelineSettled:      3,
+}
+
+// TimelineEntry is one step in the lifecycle of a proposal. The actor is the
+// proposer, speaker or voter, and is empty for status changes made by the
+// chain.
+type TimelineEntry struct {
+	Kind         string          `json:"kind"`
+	Height       uint64          `json:"height"`
+	BlockTime    time.Time       `json:"blockTime"`
+	TxHash       string          `json:"txHash,omitempty"`
+	ActorIndex   uint64          `json:"actorIndex"`
+	ActorAddress string          `json:"actorAddress"`
+	ActorName    string          `json:"actorName"`
+	Status       *ProposalStatus `json:"status,omitempty"`
+	StatusName   string          `json:"statusName,omitempty"`
+	FromStatus   *ProposalStatus `json:"fromStatus,omitempty"`
+	DiscussionId uint64          `json:"discussionId,omitempty"`
+	Text         string          `json:"text,omitempty"`
+	Vote         *VoteCode       `json:"vote,omitempty"`
+	Reason       string          `json:"reason,omitempty"`
+}
+
+// getProposalTimeline returns the lifecycle of a proposal in chain order.
+func (c *ChainIndexer) getProposalTimeline(proposalId uint64) ([]TimelineEntry, error) {
+	proposal, err := c.getProposalById(proposalId)
+	if err != nil {
+		return nil, err
+	}
+	validators, err := c.getValidators()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(validators))
+	for _, v := range validators {
+		names[v.Address] = v.Name
+	}
+
+	var changes []ProposalStatusChange
+	if changes, err = c.getProposalStatusChanges(proposalId); err != nil {
+		return nil, err
+	}
+	var discussions []Discussion
+	if err := c.db.Where("proposal = ?", proposalId).Order("height asc, id asc").Find(&discussions).Error; err != nil {
+		return nil, err
+	}
+	var votes []ProposalVote
+	if err := c.db.Where("proposal = ?", proposalId).Order("height asc, voter_index asc").Find(&votes).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]TimelineEntry, 0, 1+len(changes)+len(discussions)+len(votes))
+	created := TimelineEntry{
+		Kind:         TimelineCreated,
+		Height:       proposal.NewHeight,
+		BlockTime:    proposal.BlockTime,
+		ActorIndex:   proposal.ProposerIndex,
+		ActorAddress: proposal.ProposerAddress,
+		ActorName:    proposal.ProposerName,
+		Text:         proposal.Title,
+	}
+	for _, change := range changes {
+		status, from := change.ToStatus, change.FromStatus
+		if change.Height == proposal.NewHeight && from == ProposalStatusProposed && created.Status == nil {
+			// the change recorded with the proposal is its initial status
+			created.Status, created.StatusName = &status, status.String()
+			continue
+		}
+		kind := TimelineStatusChange
+		if status.Settled() {
+			kind = TimelineSettled
+		}
+		entries = append(entries, TimelineEntry{
+			Kind:       kind,
+			Height:     change.Height,
+			BlockTime:  change.BlockTime,
+			Status:     &status,
+			StatusName: status.String(),
+			FromStatus: &from,
+		})
+	}
+	entries = append(entries, created)
+	for _, d := range discussions {
+		entries = append(entries, TimelineEntry{
+			Kind:         TimelineDiscussion,
+			Height:       d.Height,
+			BlockTime:    d.BlockTime,
+			TxHash:       d.TxHash,
+			ActorIndex:   d.SpeakerIndex,
+			ActorAddress: d.SpeakerAddress,
+			ActorName:    d.SpeakerName,
+			DiscussionId: d.Id,
+			Text:         d.Data,
+		})
+	}
+	for _, v := range votes {
+		vote := v.Vote
+		entries = append(entries, TimelineEntry{
+			Kind:         TimelineVote,
+			Height:       v.Height,
+			BlockTime:    v.BlockTime,
+			ActorIndex:   v.VoterIndex,
+			ActorAddress: v.VoterAddress,
+			ActorName:    names[v.VoterAddress],
+			Vote:         &vote,
+			Reason:       v.Reason,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Height != entries[j].Height {
+			return entries[i].Height < entries[j].Height
+		}
+		return timelineRank[entries[i].Kind] < timelineRank[entries[j].Kind]
+	})
+	return entries, nil
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// TimeoutDecision is the vote cast when the agent does not answer a voting
+// decision in time.
+type TimeoutDecision string
+
+const (
+	TimeoutDecisionYes     TimeoutDecision = "yes"
+	TimeoutDecisionNo      TimeoutDecision = "no"
+	TimeoutDecisionAbstain TimeoutDecision = "abstain"
+)
+
+func ParseTimeoutDecision(s string) (TimeoutDecision, error) {
+	switch d := TimeoutDecision(s); d {
+	case TimeoutDecisionYes, TimeoutDecisionNo, TimeoutDecisionAbstain:
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown vote timeout decision %q, expected yes, no or abstain", s)
+}
+
+// VoteFallback records a voting decision the agent did not answer in time
+// and the fallback decision cast instead.
+type VoteFallback struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Kind      string    `gorm:"index" json:"kind"`
+	RefId     uint64    `gorm:"index" json:"ref_id"`
+	Method    string    `json:"method"`
+	Backend   string    `json:"backend"`
+	Decision  string    `json:"decision"`
+	TimeoutMs int64     `json:"timeout_ms"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FallbackStore persists the timeout fallbacks.
+type FallbackStore interface {
+	SaveVoteFallback(f *VoteFallback) error
+}
+
+var _ Client = &TimeoutClient{}
+
+// TimeoutClient bounds the voting decisions of the wrapped agent. A decision
+// not answered within the timeout is cast with the configured fallback, and
+// the fallback is recorded and shown as the vote reason.
+type TimeoutClient struct {
+	Client
+	backend  string
+	timeout  time.Duration
+	decision TimeoutDecision
+	logger   cmtlog.Logger
+	store    FallbackStore
+}
+
+func NewTimeoutClient(inner Client, backend string, timeout time.Duration, decision TimeoutDecision, logger cmtlog.Logger) *TimeoutClient {
+	return &TimeoutClient{
+		Client:   inner,
+		backend:  backend,
+		timeout:  timeout,
+		decision: decision,
+		logger:   logger.With("module", "vote-timeout"),
+	}
+}
+
+func (t *TimeoutClient) SetFallbackStore(store FallbackStore) {
+	t.store = store
+}
+
+func (t *TimeoutClient) IfAcceptProposal(ctx context.Context, proposal uint64, voter string) (Decision, error) {
+	return t.vote(ctx, "IfAcceptProposal", voteKindProposal, proposal, func(ctx context.Context) (Decision, error) {
+		return t.Client.IfAcceptProposal(ctx, proposal, voter)
+	})
+}
+
+func (t *TimeoutClient) IfGrantNewMember(ctx context.Context, validator uint64, proposer string, amount uint64, statement string) (Decision, error) {
+	return t.vote(ctx, "IfGrantNewMember", voteKindGrant, validator, func(ctx context.Context) (Decision, error) {
+		return t.Client.IfGrantNewMember(ctx, validator, proposer, amount, statement)
+	})
+}
+
+// vote asks the wrapped agent with the timeout. Only a timeout falls back,
+// the agent's or one the agent reports, another agent error or the caller
+// giving up is returned as is.
+func (t *TimeoutClient) vote(ctx context.Context, method string, kind string, id uint64, ask func(ctx context.Context) (Decision, error)) (Decision, error) {
+	voteCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	agentVoteDecisions.WithLabelValues(t.backend, method).Inc()
+	decision, err := ask(voteCtx)
+	timedOut := errors.Is(voteCtx.Err(), context.DeadlineExceeded) || errors.Is(err, ErrAgentDecisionTimeout)
+	if err == nil || ctx.Err() != nil || !timedOut {
+		return decision, err
+	}
+	agentVoteTimeouts.WithLabelValues(t.backend, method).Inc()
+	t.logger.Error("agent vote timed out", "method", method, "id", id, "timeout", t.timeout, "decision", t.decision)
+	reason := fmt.Sprintf("agent did not answer within %s, timeout fallback %s", t.timeout, t.decision)
+	if t.store != nil {
+		err := t.store.SaveVoteFallback(&VoteFallback{
+			Kind:      kind,
+			RefId:     id,
+			Method:    method,
+			Backend:   t.backend,
+			Decision:  string(t.decision),
+			TimeoutMs: t.timeout.Milliseconds(),
+		})
+		if err != nil {
+			t.logger.Error("save vote fallback fail", "err", err)
+		}
+	}
+	return t.decision.fallback(FallbackTimeout, reason), nil
+}
@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+// TranscriptMaxBytesDefault caps the prompt and the response of a stored
+// transcript when no limit is configured.
+const TranscriptMaxBytesDefault = 64 * 1024
+
+const transcriptRedacted = "[redacted]"
+
+// Transcript is the prompt sent to the agent for a voting decision and the
+// response it gave, for operators auditing why the agent voted the way it
+// did. Kind and RefId link it to the proposal or grant voted on, Voter is
+// set for proposal votes.
+type Transcript struct {
+	Id         uint64    `gorm:"primary_key" json:"id"`
+	Kind       string    `gorm:"index:idx_transcript_ref" json:"kind"`
+	RefId      uint64    `gorm:"index:idx_transcript_ref" json:"ref_id"`
+	Voter      string    `json:"voter"`
+	Method     string    `json:"method"`
+	Prompt     string    `json:"prompt"`
+	Response   string    `json:"response"`
+	StatusCode int       `json:"status_code"`
+	Vote       string    `json:"vote"`
+	Error      string    `json:"error"`
+	Truncated  bool      `json:"truncated"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TranscriptStore persists agent transcripts.
+type TranscriptStore interface {
+	SaveTranscript(t *Transcript) error
+}
+
+// TranscriptRecorder redacts, truncates and stores the transcripts of the
+// agent decisions. A nil recorder stores nothing.
+type TranscriptRecorder struct {
+	store    TranscriptStore
+	maxBytes int
+	redact   []*regexp.Regexp
+}
+
+// NewTranscriptRecorder stores transcripts in store. Every match of the
+// redact patterns is replaced before storing, then the prompt and the
+// response are cut to maxBytes each.
+func NewTranscriptRecorder(store TranscriptStore, maxBytes int, redact []string) (*TranscriptRecorder, error) {
+	if maxBytes <= 0 {
+		maxBytes = TranscriptMaxBytesDefault
+	}
+	r := &TranscriptRecorder{store: store, maxBytes: maxBytes}
+	for _, pattern := range redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transcript redact pattern %q: %w", pattern, err)
+		}
+		r.redact = append(r.redact, re)
+	}
+	return r, nil
+}
+
+func (r *TranscriptRecorder) record(t Transcript) error {
+	if r == nil {
+		return nil
+	}
+	var cut bool
+	t.Prompt, cut = r.clean(t.Prompt)
+	t.Truncated = cut
+	t.Response, cut = r.clean(t.Response)
+	t.Truncated = t.Truncated || cut
+	t.Error, _ = r.clean(t.Error)
+	return r.store.SaveTranscript(&t)
+}
+
+// clean redacts s and cuts it to maxBytes on a rune boundary.
+func (r *TranscriptRecorder) clean(s string) (string, bool) {
+	for _, re := range r.redact {
+		s = re.ReplaceAllString(s, transcriptRedacted)
+	}
+	if len(s) <= r.maxBytes {
+		return s, false
+	}
+	end := r.maxBytes
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end], true
+}
+
+func (c *ChainIndexer) SaveTranscript(t *Transcript) error {
+	return c.db.Create(t).Error
+}
+
+func (c *ChainIndexer) getTranscripts(kind string, refId uint64, page int, pageSize int) (Page[Transcript], error) {
+	db := c.db.Model(&Transcript{})
+	if kind != "" {
+		db = db.Where("kind = ?", kind)
+	}
+	if refId != 0 {
+		db = db.Where("ref_id = ?", refId)
+	}
+	return paginate[Transcript](db, "id desc", page, pageSize)
+}
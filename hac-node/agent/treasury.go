@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PayloadTypeSpend is the payload type of a proposal paying Amount from the
+// treasury to Recipient once passed, or once activated when it carries an
+// activation height.
+const PayloadTypeSpend = "spend"
+
+const (
+	SpendProposed = "proposed"
+	SpendExecuted = "executed"
+	SpendRejected = "rejected"
+)
+
+// treasuryRecentSpends is the number of executed spends shown to the agent
+// voting on a spend.
+const treasuryRecentSpends = 5
+
+func init() {
+	RegisterPayloadSchema(PayloadSchema{
+		Type:     PayloadTypeSpend,
+		Required: []string{"body", "recipient", "amount"},
+		Properties: map[string]string{
+			"title":             "string",
+			"summary":           "string",
+			"body":              "string",
+			"recipient":         "string",
+			"amount":            "number",
+			"activation_height": "number",
+		},
+	})
+}
+
+// TreasurySpend is the spend of a spend proposal. Spent is the running total
+// of the executed spends up to and including this one, the treasury balance
+// after it is the configured treasury_balance less Spent.
+type TreasurySpend struct {
+	Id             uint64     `gorm:"primary_key" json:"id"`
+	Proposal       uint64     `gorm:"unique_index" json:"proposal"`
+	Recipient      string     `gorm:"index" json:"recipient"`
+	Amount         float64    `json:"amount"`
+	Status         string     `gorm:"index" json:"status"`
+	Spent          float64    `json:"spent"`
+	ProposedHeight uint64     `json:"proposed_height"`
+	ClosedHeight   uint64     `gorm:"index" json:"closed_height"`
+	ExecutedAt     *time.Time `json:"executed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// TreasuryBudget is the state of the treasury. Pending is the amount of the
+// spends proposed and not settled yet.
+type TreasuryBudget struct {
+	Funds   float64         `json:"funds"`
+	Spent   float64         `json:"spent"`
+	Balance float64         `json:"balance"`
+	Pending float64         `json:"pending"`
+	Recent  []TreasurySpend `json:"recent"`
+}
+
+// handleSpendProposal indexes the spend of a new spend proposal. A spend
+// without a recipient or a positive amount is kept as a payload error.
+func (c *ChainIndexer) handleSpendProposal(tx *gorm.DB, proposal *Proposal, height uint64, blockTime time.Time) error {
+	var payload struct {
+		Recipient string  `json:"recipient"`
+		Amount    float64 `json:"amount"`
+	}
+	err := json.Unmarshal([]byte(proposal.PayloadData), &payload)
+	recipient := strings.TrimSpace(payload.Recipient)
+	switch {
+	case err != nil:
+		proposal.PayloadError = "invalid spend payload: " + err.Error()
+	case recipient == "":
+		proposal.PayloadError = "invalid spend payload: recipient is empty"
+	case payload.Amount <= 0:
+		proposal.PayloadError = "invalid spend payload: amount must be positive"
+	}
+	if proposal.PayloadError != "" {
+		c.logger.Info("invalid proposal payload", "proposal", proposal.Id, "err", proposal.PayloadError)
+		return nil
+	}
+	var spend TreasurySpend
+	err = tx.Where("proposal = ?", proposal.Id).First(&spend).Error
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return tx.Create(&TreasurySpend{
+		Proposal:       proposal.Id,
+		Recipient:      recipient,
+		Amount:         payload.Amount,
+		Status:         SpendProposed,
+		ProposedHeight: height,
+	}).Error
+}
+
+// settleSpend executes the spend of a passed proposal and rejects the spend
+// of any other. A passed proposal waiting for its activation height executes
+// at activation.
+func (c *ChainIndexer) settleSpend(tx *gorm.DB, proposal Proposal, height uint64, blockTime time.Time) error {
+	if proposal.Status != ProposalStatusPassed {
+		return c.closeSpend(tx, proposal.Id, SpendRejected, height, blockTime)
+	}
+	var pending int
+	err := tx.Model(&ProposalActivation{}).
+		Where("proposal = ? AND status IN (?)", proposal.Id, []string{ActivationScheduled, ActivationNotified}).
+		Count(&pending).Error
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return nil
+	}
+	return c.closeSpend(tx, proposal.Id, SpendExecuted, height, blockTime)
+}
+
+// closeSpend moves a proposed spend to status. A spend already closed is left
+// as it is, so replaying a height does not spend twice.
+func (c *ChainIndexer) closeSpend(tx *gorm.DB, proposalId uint64, status string, height uint64, blockTime time.Time) error {
+	var spend TreasurySpend
+	err := tx.Where("proposal = ?", proposalId).First(&spend).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if spend.Status != SpendProposed {
+		return nil
+	}
+	spend.Status = status
+	spend.ClosedHeight = height
+	if status == SpendExecuted {
+		spent, err := spentTotal(tx)
+		if err != nil {
+			return err
+		}
+		spend.Spent = spent + spend.Amount
+		spend.ExecutedAt = &blockTime
+		if spend.Spent > c.treasuryFunds {
+			c.logger.Error("treasury overspent", "proposal", proposalId, "amount", spend.Amount, "spent", spend.Spent, "funds", c.treasuryFunds)
+		}
+	}
+	if err := tx.Save(&spend).Error; err != nil {
+		return err
+	}
+	c.logger.Info("treasury spend", "proposal", proposalId, "status", status, "recipient", spend.Recipient, "amount", spend.Amount, "height", height)
+	return nil
+}
+
+// spentTotal is the amount of the executed spends.
+func spentTotal(db *gorm.DB) (float64, error) {
+	var total float64
+	err := db.Model(&TreasurySpend{}).Where("status = ?", SpendExecuted).Select("COALESCE(SUM(amount), 0)").Row().Scan(&total)
+	return total, err
+}
+
+// getTreasuryBudget returns the treasury state with the recent executed
+// spends, the latest first.
+func (c *ChainIndexer) getTreasuryBudget(recent int) (TreasuryBudget, error) {
+	budget := TreasuryBudget{Funds: c.treasuryFunds, Recent: []TreasurySpend{}}
+	var err error
+	if budget.Spent, err = spentTotal(c.db); err != nil {
+		return TreasuryBudget{}, err
+	}
+	budget.Balance = budget.Funds - budget.Spent
+	var pending []TreasurySpend
+	if err := c.db.Where("status = ?", SpendProposed).Find(&pending).Error; err != nil {
+		return TreasuryBudget{}, err
+	}
+	for _, spend := range pending {
+		budget.Pending += spend.Amount
+	}
+	if recent > 0 {
+		err := c.db.Where("status = ?", SpendExecuted).Order("closed_height desc, id desc").Limit(recent).Find(&budget.Recent).Error
+		if err != nil {
+			return TreasuryBudget{}, err
+		}
+	}
+	return budget, nil
+}
+
+func (c *ChainIndexer) getTreasurySpends(status string, recipient string, page int, pageSize int) (Page[TreasurySpend], error) {
+	db := c.db.Model(&TreasurySpend{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if recipient != "" {
+		db = db.Where("recipient = ?", recipient)
+	}
+	return paginate[TreasurySpend](db, "id desc", page, pageSize)
+}
+
+// TreasuryContext returns the spend proposed by proposal with the treasury
+// budget, nil for a proposal spending nothing. The agent client adds it to
+// the vote prompt.
+func (c *ChainIndexer) TreasuryContext(proposal uint64) (*TreasurySpend, TreasuryBudget, error) {
+	var spend TreasurySpend
+	err := c.db.Where("proposal = ?", proposal).First(&spend).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, TreasuryBudget{}, nil
+	}
+	if err != nil {
+		return nil, TreasuryBudget{}, err
+	}
+	budget, err := c.getTreasuryBudget(treasuryRecentSpends)
+	if err != nil {
+		return nil, TreasuryBudget{}, err
+	}
+	return &spend, budget, nil
+}
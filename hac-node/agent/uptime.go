@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/jinzhu/gorm"
+)
+
+// UptimeWindowDefault is the number of blocks the rolling uptime covers when
+// no window is configured.
+const UptimeWindowDefault = 1000
+
+// uptimeGrantLowest is the number of least available validators listed in a
+// grant vote prompt.
+const uptimeGrantLowest = 3
+
+// uptimeGrantAbsences is the number of recent absences of the proposer
+// listed in a grant vote prompt.
+const uptimeGrantAbsences = 10
+
+// BlockSignature records whether a validator of the set of Height took part
+// in its commit, a nil vote counting as taking part.
+type BlockSignature struct {
+	Id        uint64    `gorm:"primary_key" json:"id"`
+	Height    uint64    `gorm:"unique_index:idx_block_signature_height_address" json:"height"`
+	Address   string    `gorm:"unique_index:idx_block_signature_height_address;index" json:"address"`
+	Signed    bool      `json:"signed"`
+	BlockTime time.Time `json:"block_time"`
+}
+
+// ValidatorUptime is the signing record of a validator. Signed and Missed
+// count every block indexed, the window counts and Uptime the last
+// uptime_window_blocks up to Height, the latest block indexed.
+type ValidatorUptime struct {
+	Id           uint64    `gorm:"primary_key" json:"id"`
+	Address      string    `gorm:"unique_index" json:"address"`
+	Name         string    `json:"name"`
+	Signed       uint64    `json:"signed"`
+	Missed       uint64    `json:"missed"`
+	WindowSigned uint64    `json:"window_signed"`
+	WindowMissed uint64    `json:"window_missed"`
+	Uptime       float64   `gorm:"index" json:"uptime"`
+	Height       uint64    `json:"height"`
+	LastSigned   uint64    `json:"last_signed"`
+	LastMissed   uint64    `json:"last_missed"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// UptimeReport is the uptime of a validator with its recent absences and
+// the least available validators.
+type UptimeReport struct {
+	Uptime   ValidatorUptime   `json:"uptime"`
+	Absences []BlockSignature  `json:"absences"`
+	Lowest   []ValidatorUptime `json:"lowest"`
+}
+
+// validatorSet returns the addresses of the validators of height.
+func (c *ChainIndexer) validatorSet(ctx context.Context, height int64) ([]string, error) {
+	addresses := make([]string, 0)
+	page, perPage := 1, 100
+	for {
+		res, err := c.cli.Validators(ctx, &height, &page, &perPage)
+		if err != nil {
+			c.reconnect()
+			return nil, err
+		}
+		for _, v := range res.Validators {
+			addresses = append(addresses, v.Address.String())
+		}
+		if len(res.Validators) == 0 || len(addresses) >= res.Total {
+			return addresses, nil
+		}
+		page++
+	}
+}
+
+// recordSignatures records which validators of the set of height signed its
+// commit and updates their uptime. A height already recorded is skipped.
+func (c *ChainIndexer) recordSignatures(ctx context.Context, height int64, sigs []cmttypes.CommitSig, blockTime time.Time) error {
+	var recorded int
+	if err := c.db.Model(&BlockSignature{}).Where("height = ?", height).Count(&recorded).Error; err != nil {
+		return err
+	}
+	if recorded > 0 {
+		return nil
+	}
+	validators, err := c.validatorSet(ctx, height)
+	if err != nil {
+		return err
+	}
+	signed := make(map[string]bool)
+	for _, sig := range sigs {
+		if sig.BlockIDFlag != cmttypes.BlockIDFlagAbsent {
+			signed[sig.ValidatorAddress.String()] = true
+		}
+	}
+	return c.db.Transaction(func(tx *gorm.DB) error {
+		for _, address := range validators {
+			sig := BlockSignature{
+				Height:    uint64(height),
+				Address:   address,
+				Signed:    signed[address],
+				BlockTime: blockTime,
+			}
+			if err := tx.Create(&sig).Error; err != nil {
+				return err
+			}
+			if err := c.updateUptime(tx, sig); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// updateUptime adds sig to the uptime of its validator. The window is
+// counted again rather than shifted since gaps are indexed out of order.
+func (c *ChainIndexer) updateUptime(tx *gorm.DB, sig BlockSignature) error {
+	var uptime ValidatorUptime
+	err := tx.Where("address = ?", sig.Address).First(&uptime).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	uptime.Address = sig.Address
+	if uptime.Name == "" {
+		if agent, err := c.getValidatorByAddress(sig.Address); err == nil && agent != nil {
+			uptime.Name = agent.Name
+		}
+	}
+	if sig.Signed {
+		uptime.Signed++
+		if sig.Height > uptime.LastSigned {
+			uptime.LastSigned = sig.Height
+		}
+	} else {
+		uptime.Missed++
+		if sig.Height > uptime.LastMissed {
+			uptime.LastMissed = sig.Height
+		}
+	}
+	if sig.Height > uptime.Height {
+		uptime.Height = sig.Height
+	}
+	from := uint64(0)
+	if uptime.Height > c.uptimeWindow {
+		from = uptime.Height - c.uptimeWindow
+	}
+	window := tx.Model(&BlockSignature{}).Where("address = ? AND height > ? AND height <= ?", sig.Address, from, uptime.Height)
+	if err := window.Where("signed = ?", true).Count(&uptime.WindowSigned).Error; err != nil {
+		return err
+	}
+	if err := window.Where("signed = ?", false).Count(&uptime.WindowMissed).Error; err != nil {
+		return err
+	}
+	if total := uptime.WindowSigned + uptime.WindowMissed; total > 0 {
+		uptime.Uptime = float64(uptime.WindowSigned) / float64(total) * 100
+	}
+	return tx.Save(&uptime).Error
+}
+
+// getUptimes returns the uptime of every validator, the least available
+// first.
+func (c *ChainIndexer) getUptimes(page int, pageSize int) (Page[ValidatorUptime], error) {
+	return paginate[ValidatorUptime](c.db.Model(&ValidatorUptime{}), "uptime asc, address asc", page, pageSize)
+}
+
+// getAbsences returns the blocks a validator did not sign, the latest first,
+// of every validator without address.
+func (c *ChainIndexer) getAbsences(address string, page int, pageSize int) (Page[BlockSignature], error) {
+	db := c.db.Model(&BlockSignature{}).Where("signed = ?", false)
+	if address != "" {
+		db = db.Where("address = ?", address)
+	}
+	return paginate[BlockSignature](db, "height desc, id desc", page, pageSize)
+}
+
+// UptimeContext returns the uptime and the absences, the latest first, of
+// address with the least available validators. The agent client adds them
+// to grant votes.
+func (c *ChainIndexer) UptimeContext(address string, absences int) (UptimeReport, error) {
+	var report UptimeReport
+	err := c.db.Where("address = ?", address).First(&report.Uptime).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return UptimeReport{}, err
+	}
+	report.Uptime.Address = address
+	recent, err := c.getAbsences(address, 0, absences)
+	if err != nil {
+		return UptimeReport{}, err
+	}
+	lowest, err := c.getUptimes(0, uptimeGrantLowest)
+	if err != nil {
+		return UptimeReport{}, err
+	}
+	report.Absences = recent.Items
+	report.Lowest = lowest.Items
+	return report, nil
+}
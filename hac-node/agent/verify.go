@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+)
+
+// eventDigest hashes the tx results and finalize block events of a block, so
+// results served by different RPC endpoints can be compared cheaply.
+func eventDigest(res *coretypes.ResultBlockResults) string {
+	h := sha256.New()
+	writeUint(h, uint64(len(res.TxsResults)))
+	for _, tx := range res.TxsResults {
+		writeUint(h, uint64(tx.Code))
+		writeEvents(h, tx.Events)
+	}
+	writeEvents(h, res.FinalizeBlockEvents)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeEvents(h hash.Hash, events []abci.Event) {
+	writeUint(h, uint64(len(events)))
+	for _, ev := range events {
+		writeString(h, ev.Type)
+		writeUint(h, uint64(len(ev.Attributes)))
+		for _, attr := range ev.Attributes {
+			writeString(h, attr.Key)
+			writeString(h, attr.Value)
+		}
+	}
+}
+
+func writeUint(h hash.Hash, v uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	h.Write(buf[:])
+}
+
+func writeString(h hash.Hash, s string) {
+	writeUint(h, uint64(len(s)))
+	h.Write([]byte(s))
+}
+
+// verifyBlockResults compares the block results of height with the ones
+// served by the verification endpoint. Discrepancies are recorded and only
+// stop indexing in strict mode; an unreachable verification endpoint never
+// does.
+func (c *ChainIndexer) verifyBlockResults(ctx context.Context, height int64, events *coretypes.ResultBlockResults) error {
+	if c.verifyCli == nil {
+		return nil
+	}
+	other, err := c.verifyCli.BlockResults(ctx, &height)
+	if err != nil || other == nil {
+		verifyFailures.Inc()
+		c.logger.Error("get block results from verify rpc fail", "height", height, "err", err)
+		return nil
+	}
+	primary, secondary := eventDigest(events), eventDigest(other)
+	if primary == secondary {
+		return nil
+	}
+	verifyDiscrepancies.Inc()
+	c.logger.Error("block results discrepancy", "height", height, "primary", primary, "secondary", secondary)
+	if err := c.db.Save(&BlockDiscrepancy{
+		Height:          uint64(height),
+		PrimaryDigest:   primary,
+		SecondaryDigest: secondary,
+	}).Error; err != nil {
+		c.logger.Error("save block discrepancy fail", "err", err)
+	}
+	if c.verifyStrict {
+		return fmt.Errorf("block results of height %d differ between rpc endpoints", height)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/calehh/hac-app/tx"
+)
+
+// VoteCode is the vote a validator attached to its commit signature. It is
+// stored as an integer and serialized by name in the API.
+type VoteCode uint64
+
+const (
+	VoteCodeIgnoreProposal  = VoteCode(tx.VoteIgnoreProposal)
+	VoteCodeProcessProposal = VoteCode(tx.VoteProcessProposal)
+	VoteCodeAcceptProposal  = VoteCode(tx.VoteAcceptProposal)
+	VoteCodeRejectProposal  = VoteCode(tx.VoteRejectProposal)
+	VoteCodeGrantNewMember  = VoteCode(tx.VoteGrantNewMember)
+	VoteCodeRejectNewMember = VoteCode(tx.VoteRejectNewMember)
+)
+
+var voteCodeNames = map[VoteCode]string{
+	VoteCodeIgnoreProposal:  "ignore_proposal",
+	VoteCodeProcessProposal: "process_proposal",
+	VoteCodeAcceptProposal:  "accept_proposal",
+	VoteCodeRejectProposal:  "reject_proposal",
+	VoteCodeGrantNewMember:  "grant_new_member",
+	VoteCodeRejectNewMember: "reject_new_member",
+}
+
+func (v VoteCode) String() string {
+	if name, ok := voteCodeNames[v]; ok {
+		return name
+	}
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+func (v VoteCode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+// UnmarshalJSON accepts both the symbolic name and the raw number.
+func (v *VoteCode) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var code uint64
+		if err := json.Unmarshal(data, &code); err != nil {
+			return err
+		}
+		*v = VoteCode(code)
+		return nil
+	}
+	for code, n := range voteCodeNames {
+		if n == name {
+			*v = code
+			return nil
+		}
+	}
+	code, err := strconv.ParseUint(name, 10, 64)
+	if err != nil {
+		return fmt.Errorf("unknown vote code %q", name)
+	}
+	*v = VoteCode(code)
+	return nil
+}
+
+const (
+	voteKindProposal = "proposal"
+	voteKindGrant    = "grant"
+)
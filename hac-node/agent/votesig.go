@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cometbft/cometbft/crypto"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/secp256k1"
+)
+
+// ErrInvalidAgentSignature is a vote whose signature is missing or does not
+// verify against the public key of the agent. It is a malformed response, the
+// decision is not acted on.
+var ErrInvalidAgentSignature = errors.New("invalid agent vote signature")
+
+// ParseAgentPubKey parses the public key of an agent signing its votes,
+// "ed25519:<hex>" or "secp256k1:<hex>" with a compressed secp256k1 key.
+func ParseAgentPubKey(s string) (crypto.PubKey, error) {
+	kind, key, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid agent public key %q, expected ed25519:<hex> or secp256k1:<hex>", s)
+	}
+	bz, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid agent public key: %w", err)
+	}
+	switch kind {
+	case ed25519.KeyType:
+		if len(bz) != ed25519.PubKeySize {
+			return nil, fmt.Errorf("invalid agent public key: ed25519 key of %d bytes", len(bz))
+		}
+		return ed25519.PubKey(bz), nil
+	case secp256k1.KeyType:
+		if len(bz) != secp256k1.PubKeySize {
+			return nil, fmt.Errorf("invalid agent public key: secp256k1 key of %d bytes", len(bz))
+		}
+		return secp256k1.PubKey(bz), nil
+	}
+	return nil, fmt.Errorf("unknown agent public key type %q, expected ed25519 or secp256k1", kind)
+}
+
+// voteSignBytes are the bytes an agent signs for a vote: the idempotency key
+// of the request, so a signed vote cannot be replayed on another one, the
+// vote, its confidence as the shortest decimal that parses back to it, empty
+// when left out, and its reason, separated by newlines.
+func voteSignBytes(key string, vote VoteResponse) []byte {
+	confidence := ""
+	if vote.Confidence != nil {
+		confidence = strconv.FormatFloat(*vote.Confidence, 'f', -1, 64)
+	}
+	return []byte(key + "\n" + vote.Vote + "\n" + confidence + "\n" + vote.Reason)
+}
+
+// verifyVote checks the hex signature of vote against pk, a nil pk accepts
+// every vote.
+func verifyVote(pk crypto.PubKey, key string, vote VoteResponse) error {
+	if pk == nil {
+		return nil
+	}
+	if vote.Signature == "" {
+		return fmt.Errorf("%w: no signature", ErrInvalidAgentSignature)
+	}
+	sig, err := hex.DecodeString(vote.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAgentSignature, err)
+	}
+	if !pk.VerifySignature(voteSignBytes(key, vote), sig) {
+		return ErrInvalidAgentSignature
+	}
+	return nil
+}
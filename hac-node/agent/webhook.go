@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/calehh/hac-app/tx"
+	hac_types "github.com/calehh/hac-app/types"
+)
+
+var errUnauthorized = errors.New("unauthorized")
+
+// ProposalRefiner rewrites a proposal draft before it is submitted. It is
+// implemented by ElizaClient.
+type ProposalRefiner interface {
+	RefineProposal(ctx context.Context, draft ProposalEnvelope) (ProposalEnvelope, error)
+}
+
+// ProposalDraftReq is a proposal sent by external tooling, such as a forum
+// or a GitHub issue hook. Refine asks the agent to rewrite it first,
+// ActivationHeight schedules it to take effect at that height once passed,
+// Expedited submits it as an emergency proposal justified by Reason, Amount
+// as a treasury spend to Recipient.
+type ProposalDraftReq struct {
+	Title            string  `json:"title"`
+	Summary          string  `json:"summary"`
+	Body             string  `json:"body"`
+	Link             string  `json:"link"`
+	ImageUrl         string  `json:"imageUrl"`
+	Source           string  `json:"source"`
+	Refine           bool    `json:"refine"`
+	ActivationHeight uint64  `json:"activationHeight"`
+	Expedited        bool    `json:"expedited"`
+	Reason           string  `json:"reason"`
+	Recipient        string  `json:"recipient"`
+	Amount           float64 `json:"amount"`
+}
+
+type ProposalDraftResponse struct {
+	ProposalId uint64 `json:"proposalId"`
+	Title      string `json:"title"`
+	Height     int64  `json:"height"`
+	TxHash     string `json:"txHash"`
+}
+
+// proposalWebhook submits drafts on-chain from the account of the node, one
+// at a time.
+type proposalWebhook struct {
+	secret  string
+	refiner ProposalRefiner
+	mtx     sync.Mutex
+}
+
+// SetProposalRefiner sets the agent refining drafts sent to the proposal
+// webhook.
+func (c *ChainIndexer) SetProposalRefiner(r ProposalRefiner) {
+	c.webhook.refiner = r
+}
+
+// authorizeWebhook accepts the secret as a bearer token or as the key of a
+// GitHub style X-Hub-Signature-256 hmac of the body.
+func (c *ChainIndexer) authorizeWebhook(header http.Header, body []byte) error {
+	return authorizeSecret(c.webhook.secret, header, body)
+}
+
+// authorizeAgentPush authorizes the agent calls of the push mode with
+// agent_push_secret, the same way as authorizeWebhook.
+func (c *ChainIndexer) authorizeAgentPush(header http.Header, body []byte) error {
+	return authorizeSecret(c.pushSecret, header, body)
+}
+
+func authorizeSecret(secret string, header http.Header, body []byte) error {
+	if secret == "" {
+		return errUnauthorized
+	}
+	if token, ok := strings.CutPrefix(header.Get("Authorization"), "Bearer "); ok {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+			return nil
+		}
+		return errUnauthorized
+	}
+	if sig, ok := strings.CutPrefix(header.Get("X-Hub-Signature-256"), "sha256="); ok {
+		got, err := hex.DecodeString(sig)
+		if err != nil {
+			return errUnauthorized
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		if hmac.Equal(got, mac.Sum(nil)) {
+			return nil
+		}
+	}
+	return errUnauthorized
+}
+
+// submitProposalDraft optionally refines a draft with the agent, submits it
+// and waits for the block including it.
+func (c *ChainIndexer) submitProposalDraft(ctx context.Context, req ProposalDraftReq) (ProposalDraftResponse, error) {
+	if !c.leader.isLeader() {
+		return ProposalDraftResponse{}, ErrNotLeader
+	}
+	env := ProposalEnvelope{Title: req.Title, Summary: req.Summary, Body: req.Body}
+	if req.Refine && c.webhook.refiner != nil {
+		refined, err := c.webhook.refiner.RefineProposal(ctx, env)
+		if err != nil {
+			c.logger.Error("refine proposal draft fail", "source", req.Source, "err", err)
+		} else if strings.TrimSpace(refined.Body) != "" {
+			env = refined
+		}
+	}
+	title, _ := parseProposalPayload([]byte(env.Body), env.Title)
+	env.Title = title
+	env.ActivationHeight = req.ActivationHeight
+	switch {
+	case req.Expedited:
+		env.Type = PayloadTypeExpedited
+		env.Reason = req.Reason
+	case req.Amount > 0:
+		env.Type = PayloadTypeSpend
+		env.Recipient = req.Recipient
+		env.Amount = req.Amount
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		return ProposalDraftResponse{}, err
+	}
+
+	// the indexer submits discussions and settlements from the same account,
+	// so the nonce is loaded again for every draft
+	c.webhook.mtx.Lock()
+	defer c.webhook.mtx.Unlock()
+	submitter, err := NewTxSubmitter(ctx, c.cli, c.pv)
+	if err != nil {
+		return ProposalDraftResponse{}, err
+	}
+	res, err := submitter.SubmitCommit(ctx, tx.HACTxTypeProposal, &tx.ProposalTx{
+		Proposer:  submitter.Index(),
+		EndHeight: 1000000,
+		Title:     env.Title,
+		Link:      req.Link,
+		ImageUrl:  req.ImageUrl,
+		Data:      data,
+	})
+	if err != nil {
+		return ProposalDraftResponse{}, err
+	}
+	response := ProposalDraftResponse{Title: env.Title, Height: res.Height, TxHash: res.Hash.String()}
+	for _, ev := range res.TxResult.Events {
+		if ev.Type != hac_types.EventProposalType {
+			continue
+		}
+		if p := hac_types.DecodeEventProposal(ev); p != nil {
+			response.ProposalId = p.ProposalIndex
+		}
+	}
+	c.logger.Info("proposal submitted from webhook", "source", req.Source, "proposal", response.ProposalId, "height", response.Height)
+	return response, nil
+}
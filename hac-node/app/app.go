@@ -91,14 +91,17 @@ func (app *HACApp) registerTxHandler() {
 		tx.HACTxTypeProposal:       handler.NewProposalTxHandler(app.logger),
 		tx.HACTxTypeDiscussion:     handler.NewDiscussionTxHandler(app.logger),
 		tx.HACTxTypeGrant:          handler.NewGrantTxHandler(app.logger),
+		tx.HACTxTypeAnchor:         handler.NewAnchorTxHandler(app.logger),
 	}
 }
 
 func (app *HACApp) registerQuerier() {
 	aq := NewAccountQuerier(app.db, app.logger)
 	vq := NewValidatorQuerier(app.db, app.logger)
+	pq := NewProposalQuerier(app.db, app.logger)
 	app.queriers["/accounts/"] = aq
 	app.queriers["/validators/"] = vq
+	app.queriers["/proposals/"] = pq
 }
 
 func (app *HACApp) InitChain(_ context.Context, chain *abcitypes.RequestInitChain) (res *abcitypes.ResponseInitChain, err error) {
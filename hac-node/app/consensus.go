@@ -5,6 +5,7 @@ import (
 	"errors"
 	"time"
 
+	"github.com/calehh/hac-app/agent"
 	"github.com/calehh/hac-app/state"
 	"github.com/calehh/hac-app/tx"
 	hac_types "github.com/calehh/hac-app/types"
@@ -298,11 +299,12 @@ func (app *HACApp) getCode(ctx context.Context, st *state.State, txs [][]byte) (
 			if proposerAct == nil {
 				return 0, errors.New("proposer not found")
 			}
-			pass, err := app.agentCli.IfGrantNewMember(ctx, st.Header().AccountIdx, proposerAct.Address(), stx.Grants[0].Amount, stx.Grants[0].Statement)
+			grantCtx := agent.WithGrantApplicant(ctx, stx.Grants[0].Pubkey)
+			decision, err := app.agentCli.IfGrantNewMember(grantCtx, st.Header().AccountIdx, proposerAct.Address(), stx.Grants[0].Amount, stx.Grants[0].Statement)
 			if err != nil {
 				return 0, err
 			}
-			if pass {
+			if app.decisionPass(decision) {
 				code = tx.VoteGrantNewMember
 			} else {
 				code = tx.VoteRejectNewMember
@@ -314,7 +316,11 @@ func (app *HACApp) getCode(ctx context.Context, st *state.State, txs [][]byte) (
 			}
 			proposerAct = true
 			stx := btx.Tx.(*tx.ProposalTx)
-			pass, err := app.agentCli.IfProcessProposal(ctx, stx.Proposer, stx.Data)
+			draftCtx := ctx
+			if agent.IsExpeditedPayload(stx.Data) {
+				draftCtx = agent.WithExpedited(ctx)
+			}
+			pass, err := app.agentCli.IfProcessProposal(draftCtx, stx.Proposer, stx.Data)
 			if err != nil {
 				return 0, err
 			}
@@ -338,11 +344,13 @@ func (app *HACApp) getCode(ctx context.Context, st *state.State, txs [][]byte) (
 				code = tx.VoteRejectProposal
 				continue
 			}
-			pass, err := app.agentCli.IfAcceptProposal(ctx, stx.Proposal, voterAct.Address())
+			// a proposal close to expiry is voted on before others waiting
+			voteCtx := agent.WithCallDeadline(ctx, time.Unix(int64(stx.ExpireTimestamp), 0))
+			decision, err := app.agentCli.IfAcceptProposal(voteCtx, stx.Proposal, voterAct.Address())
 			if err != nil {
 				return 0, err
 			}
-			if pass {
+			if app.decisionPass(decision) {
 				code = tx.VoteAcceptProposal
 			} else {
 				code = tx.VoteRejectProposal
@@ -352,3 +360,22 @@ func (app *HACApp) getCode(ctx context.Context, st *state.State, txs [][]byte) (
 	}
 	return
 }
+
+// decisionPass tells whether decision accepts. The chain has no vote code
+// for an abstention, so an abstained decision, or a yes or no less confident
+// than the configured minimum, counts as not accepting: the block is voted
+// on with the rejecting code rather than vetoed.
+func (app *HACApp) decisionPass(decision agent.Decision) bool {
+	if decision.Fallback != "" {
+		app.logger.Info("agent decision cast by fallback", "fallback", decision.Fallback, "vote", decision.Vote, "reason", decision.Reason)
+	}
+	if decision.Vote == agent.VoteAbstain {
+		app.logger.Info("agent abstained, not accepting", "reason", decision.Reason)
+		return false
+	}
+	if decision.Confidence < app.cfg.VoteMinConfidence {
+		app.logger.Info("agent decision not confident enough, not accepting", "vote", decision.Vote, "confidence", decision.Confidence, "min", app.cfg.VoteMinConfidence)
+		return false
+	}
+	return decision.Pass()
+}
@@ -89,3 +89,38 @@ func (q *ValidatorQuerier) Query(ctx context.Context, req *abcitypes.RequestQuer
 	res.Value, _ = json.Marshal(validators)
 	return
 }
+
+type ProposalQuerier struct {
+	db     *state.StateDB
+	logger cmtlog.Logger
+}
+
+func NewProposalQuerier(db *state.StateDB, logger cmtlog.Logger) (q *ProposalQuerier) {
+	q = &ProposalQuerier{
+		db:     db,
+		logger: logger,
+	}
+	return
+}
+
+func (q *ProposalQuerier) Query(ctx context.Context, req *abcitypes.RequestQuery) (res *abcitypes.ResponseQuery, err error) {
+	res = &abcitypes.ResponseQuery{}
+	if len(req.Data) > 8 {
+		res.Code = 1
+		return
+	}
+	var idx uint64
+	for _, v := range req.Data {
+		idx <<= 8
+		idx |= uint64(v)
+	}
+	proposal, height, err := q.db.GetProposalByIndex(idx)
+	if err != nil || proposal == nil {
+		res.Code = 1
+		err = nil
+		return
+	}
+	res.Height = int64(height)
+	res.Value, _ = json.Marshal(proposal)
+	return
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/calehh/hac-app/agent"
+	"github.com/spf13/cobra"
+)
+
+type backupArguments struct {
+	DB       string
+	Endpoint string
+	Region   string
+	Bucket   string
+	Prefix   string
+	Keep     int
+	MaxAge   time.Duration
+	Local    string
+	Snapshot bool
+	Parquet  bool
+}
+
+var backupArgs backupArguments
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "upload a snapshot of the indexer db and parquet exports to object storage",
+	Long: `Write a compressed snapshot of the sqlite indexer db and upload it to an S3 compatible bucket,
+then delete the snapshots outside the retention policy. The node can keep running while the snapshot is taken.
+
+With --parquet every table is also exported to a parquet file, uploaded under <prefix>parquet-<time>/,
+and the exports outside the retention policy are deleted the same way. Postgres indexer dbs can be
+exported with --snapshot=false.
+
+Credentials are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY. For GCS create HMAC keys
+and use --endpoint https://storage.googleapis.com --region auto.`,
+	Args: cobra.NoArgs,
+	RunE: backupRun,
+}
+
+func init() {
+	backupCmd.Flags().StringVar(&backupArgs.DB, "db", "", "sqlite indexer db path, or a postgres dsn with --snapshot=false (default $HOME/.hac/indexer.db)")
+	backupCmd.Flags().StringVar(&backupArgs.Endpoint, "endpoint", "https://s3.amazonaws.com", "object storage endpoint")
+	backupCmd.Flags().StringVar(&backupArgs.Region, "region", "us-east-1", "bucket region")
+	backupCmd.Flags().StringVar(&backupArgs.Bucket, "bucket", "", "bucket name")
+	backupCmd.Flags().StringVar(&backupArgs.Prefix, "prefix", "hac/", "key prefix of the snapshots")
+	backupCmd.Flags().IntVar(&backupArgs.Keep, "keep", 7, "number of snapshots kept, 0 keeps all")
+	backupCmd.Flags().DurationVar(&backupArgs.MaxAge, "max-age", 0, "delete snapshots older than this, e.g. 720h, 0 keeps all")
+	backupCmd.Flags().StringVar(&backupArgs.Local, "local", "", "also keep the snapshot in this directory")
+	backupCmd.Flags().BoolVar(&backupArgs.Snapshot, "snapshot", true, "upload a snapshot of the sqlite db")
+	backupCmd.Flags().BoolVar(&backupArgs.Parquet, "parquet", false, "upload a parquet export of every table")
+}
+
+func backupRun(cmd *cobra.Command, args []string) error {
+	if backupArgs.Bucket == "" {
+		return fmt.Errorf("--bucket is required")
+	}
+	accessKey, secretKey := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	dbPath := backupArgs.DB
+	if dbPath == "" {
+		dbPath = path.Join(os.ExpandEnv("$HOME/.hac"), "indexer.db")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if !backupArgs.Snapshot && !backupArgs.Parquet {
+		return fmt.Errorf("nothing to back up, set --snapshot or --parquet")
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	dir := backupArgs.Local
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "hac-backup")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+	store := agent.NewObjectStore(backupArgs.Endpoint, backupArgs.Region, backupArgs.Bucket, accessKey, secretKey)
+	var current []string
+	if backupArgs.Snapshot {
+		name := fmt.Sprintf("indexer-%s.db.gz", stamp)
+		file := filepath.Join(dir, name)
+		if err := agent.SnapshotIndexDB(dbPath, file); err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+		key := backupArgs.Prefix + name
+		if err := uploadBackup(ctx, store, key, file); err != nil {
+			return err
+		}
+		current = append(current, key)
+	}
+	if backupArgs.Parquet {
+		set := "parquet-" + stamp
+		files, err := agent.ExportParquet(dbPath, filepath.Join(dir, set))
+		if err != nil {
+			return fmt.Errorf("parquet export: %w", err)
+		}
+		for _, file := range files {
+			if err := uploadBackup(ctx, store, backupArgs.Prefix+set+"/"+filepath.Base(file), file); err != nil {
+				return err
+			}
+		}
+		current = append(current, backupArgs.Prefix+set+"/")
+	}
+
+	objects, err := store.List(ctx, backupArgs.Prefix)
+	if err != nil {
+		return fmt.Errorf("list backups: %w", err)
+	}
+	// a backup is a snapshot, or the files of one parquet export
+	var snapshots, exports []backupSet
+	exportIndex := make(map[string]int)
+	for _, o := range objects {
+		rel := strings.TrimPrefix(o.Key, backupArgs.Prefix)
+		if strings.HasPrefix(rel, "indexer-") && strings.HasSuffix(rel, ".db.gz") {
+			snapshots = append(snapshots, backupSet{key: o.Key, modified: o.LastModified, objects: []string{o.Key}})
+			continue
+		}
+		set, _, ok := strings.Cut(rel, "/")
+		if !ok || !strings.HasPrefix(set, "parquet-") {
+			continue
+		}
+		key := backupArgs.Prefix + set + "/"
+		if i, ok := exportIndex[key]; ok {
+			exports[i].objects = append(exports[i].objects, o.Key)
+			continue
+		}
+		exportIndex[key] = len(exports)
+		exports = append(exports, backupSet{key: key, modified: o.LastModified, objects: []string{o.Key}})
+	}
+	for _, sets := range [][]backupSet{snapshots, exports} {
+		if err := pruneBackups(ctx, store, sets, current); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupSet is a backup kept or deleted as a whole, listed oldest first.
+type backupSet struct {
+	key      string
+	modified time.Time
+	objects  []string
+}
+
+func uploadBackup(ctx context.Context, store *agent.ObjectStore, key string, file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if err := store.PutFile(ctx, key, file); err != nil {
+		return fmt.Errorf("upload %s: %w", key, err)
+	}
+	fmt.Printf("uploaded %s (%s)\n", key, formatBytes(info.Size()))
+	return nil
+}
+
+// pruneBackups deletes the sets outside the retention policy, never the
+// ones just uploaded.
+func pruneBackups(ctx context.Context, store *agent.ObjectStore, sets []backupSet, current []string) error {
+	for i, set := range sets {
+		expired := backupArgs.MaxAge > 0 && time.Since(set.modified) > backupArgs.MaxAge
+		extra := backupArgs.Keep > 0 && i < len(sets)-backupArgs.Keep
+		if slices.Contains(current, set.key) || !(expired || extra) {
+			continue
+		}
+		for _, key := range set.objects {
+			if err := store.Delete(ctx, key); err != nil {
+				return fmt.Errorf("delete %s: %w", key, err)
+			}
+		}
+		fmt.Printf("deleted %s\n", set.key)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/calehh/hac-app/app"
 	app_config "github.com/calehh/hac-app/config"
 	cmtconfig "github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/crypto"
 	cmtflags "github.com/cometbft/cometbft/libs/cli/flags"
 	cmtlog "github.com/cometbft/cometbft/libs/log"
 	nm "github.com/cometbft/cometbft/node"
@@ -85,17 +86,248 @@ func run(cmd *cobra.Command, args []string) {
 	}
 
 	//new agent client
-	agentUrl := strings.TrimRight(appConfig.App.AgentUrl, "/")
-	logger.Info("agent url: %s", agentUrl)
-	agent.ElizaCli, err = agent.NewElizaClient(agentUrl, logger)
-	if err != nil {
-		log.Fatalf("new eliza client err %s", err.Error())
+	var eliza *agent.ElizaClient
+	var backends []agent.Client
+	var dryRun *agent.DryRunClient
+	var timeouts *agent.TimeoutClient
+	var health *agent.AgentHealth
+	var failover *agent.FailoverClient
+	var scoring *agent.ScoringClient
+	var push *agent.PushClient
+	var ensemble *agent.EnsembleClient
+	var routing *agent.RoutingClient
+	var cli agent.Client
+	if appConfig.App.IndexOnly {
+		logger.Info("index-only mode, agent disabled")
+		cli = agent.NewNoopClient()
+	} else {
+		agentUrl := strings.TrimRight(appConfig.App.AgentUrl, "/")
+		httpClient, err := agent.NewHTTPClient(agent.HTTPConfig{
+			Timeout:        time.Duration(appConfig.App.AgentHttpTimeout) * time.Second,
+			ConnectTimeout: time.Duration(appConfig.App.AgentConnectTimeout) * time.Second,
+			ReadTimeout:    time.Duration(appConfig.App.AgentReadTimeout) * time.Second,
+			KeepAlive:      time.Duration(appConfig.App.AgentKeepAlive) * time.Second,
+			Proxy:          appConfig.App.AgentProxy,
+			CAFile:         appConfig.App.AgentTLSCA,
+			CertFile:       appConfig.App.AgentTLSCert,
+			KeyFile:        appConfig.App.AgentTLSKey,
+			ServerName:     appConfig.App.AgentTLSServerName,
+		})
+		if err != nil {
+			log.Fatalf("invalid agent http config: %v", err)
+		}
+		retry := agent.RetryPolicy{
+			MaxAttempts: appConfig.App.AgentRetryAttempts,
+			Backoff:     time.Duration(appConfig.App.AgentRetryBackoff) * time.Millisecond,
+			MaxBackoff:  time.Duration(appConfig.App.AgentRetryMaxBackoff) * time.Millisecond,
+			Statuses:    appConfig.App.AgentRetryStatuses,
+		}
+		if len(retry.Statuses) == 0 {
+			retry.Statuses = agent.DefaultRetryStatuses
+		}
+		var votePubKey crypto.PubKey
+		if appConfig.App.AgentVotePubKey != "" {
+			votePubKey, err = agent.ParseAgentPubKey(appConfig.App.AgentVotePubKey)
+			if err != nil {
+				log.Fatalf("invalid agent vote public key: %v", err)
+			}
+			logger.Info("agent vote signatures verified", "key", appConfig.App.AgentVotePubKey)
+		}
+		auth := &agent.AgentAuth{
+			Header:    appConfig.App.AgentAuthHeader,
+			Token:     appConfig.App.AgentAuthToken,
+			TokenEnv:  appConfig.App.AgentAuthTokenEnv,
+			TokenFile: appConfig.App.AgentAuthTokenFile,
+		}
+		if len(appConfig.App.AgentEnsemble) > 0 {
+			mode, err := agent.ParseEnsembleMode(appConfig.App.AgentEnsembleMode)
+			if err != nil {
+				log.Fatalf("invalid agent ensemble: %v", err)
+			}
+			members := make([]agent.EnsembleMember, 0, len(appConfig.App.AgentEnsemble))
+			for _, m := range appConfig.App.AgentEnsemble {
+				logger.Info("agent ensemble member", "name", m.Name, "backend", m.Backend, "url", m.Url)
+				members = append(members, agent.EnsembleMember{
+					Name:    m.Name,
+					Backend: m.Backend,
+					Weight:  m.Weight,
+					Config: agent.BackendConfig{
+						Url:         strings.TrimRight(m.Url, "/"),
+						HTTP:        httpClient,
+						Agent:       m.AgentId,
+						Retry:       retry,
+						Auth:        auth,
+						VotePubKey:  votePubKey,
+						Model:       m.Model,
+						ApiKey:      m.ApiKey,
+						Temperature: m.Temperature,
+						Seed:        m.Seed,
+						Prompts:     m.Prompts,
+					},
+				})
+			}
+			ensemble, err = agent.NewEnsembleClient(members, mode, logger)
+			if err != nil {
+				log.Fatalf("invalid agent ensemble: %v", err)
+			}
+			backends = ensemble.Backends()
+			cli = ensemble
+		} else {
+			logger.Info("agent backend", "backend", appConfig.App.AgentBackend, "url", agentUrl)
+			backendConfig := agent.BackendConfig{
+				Url:         agentUrl,
+				HTTP:        httpClient,
+				Agent:       appConfig.App.AgentId,
+				Retry:       retry,
+				Auth:        auth,
+				VotePubKey:  votePubKey,
+				Model:       appConfig.App.AgentModel,
+				ApiKey:      appConfig.App.AgentApiKey,
+				Temperature: appConfig.App.AgentTemperature,
+				Seed:        appConfig.App.AgentSeed,
+				Prompts:     appConfig.App.AgentPrompts,
+			}
+			if len(appConfig.App.AgentFailoverUrls) > 0 {
+				urls := []string{agentUrl}
+				for _, u := range appConfig.App.AgentFailoverUrls {
+					urls = append(urls, strings.TrimRight(u, "/"))
+				}
+				logger.Info("agent failover enabled", "urls", urls)
+				failover, err = agent.NewFailoverClient(appConfig.App.AgentBackend, backendConfig, urls, time.Duration(appConfig.App.AgentFailbackInterval)*time.Second, logger)
+				if err != nil {
+					log.Fatalf("new agent client err %s", err.Error())
+				}
+				cli = failover
+				backends = failover.Backends()
+			} else {
+				cli, err = agent.NewBackend(appConfig.App.AgentBackend, backendConfig, logger)
+				if err != nil {
+					log.Fatalf("new agent client err %s", err.Error())
+				}
+				backends = []agent.Client{cli}
+			}
+			if appConfig.App.AgentRouting {
+				logger.Info("agent routing enabled, validators vote with their registered agent")
+				if backendConfig.VotePubKey != nil {
+					logger.Info("agent vote key set, votes stay with the default agent, only comments are routed")
+				}
+				routing = agent.NewRoutingClient(cli, appConfig.App.AgentBackend, backendConfig, logger)
+				cli = routing
+			}
+		}
+		eliza, _ = backends[0].(*agent.ElizaClient)
+		if pinger, ok := backends[0].(agent.Pinger); ok && appConfig.App.AgentHealthInterval > 0 {
+			interval := time.Duration(appConfig.App.AgentHealthInterval) * time.Second
+			logger.Info("agent health probe enabled", "interval", interval)
+			health = agent.NewAgentHealth(agentUrl, pinger, interval, time.Duration(appConfig.App.AgentHealthTimeout)*time.Second, logger)
+			agent.SetActiveHealth(health)
+		}
+		// the breaker is outside the cache, so its fallbacks are not cached
+		var middlewares []agent.Middleware
+		if prober, ok := backends[0].(agent.CapabilityProber); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(appConfig.App.AgentHealthTimeout)*time.Second)
+			caps, err := prober.Capabilities(ctx)
+			cancel()
+			if err != nil {
+				logger.Error("query agent capabilities fail, sending every call", "err", err)
+			} else if caps != nil {
+				logger.Info("agent capabilities", "version", caps.Version, "methods", caps.Methods)
+				middlewares = append(middlewares, agent.WithCapabilities(caps, logger))
+			}
+		}
+		if appConfig.App.AgentBreakerFailures > 0 {
+			fallback, err := agent.ParseTimeoutDecision(appConfig.App.AgentBreakerFallback)
+			if err != nil {
+				log.Fatalf("invalid agent breaker fallback: %v", err)
+			}
+			cooldown := time.Duration(appConfig.App.AgentBreakerCooldown) * time.Second
+			logger.Info("agent circuit breaker enabled", "failures", appConfig.App.AgentBreakerFailures, "cooldown", cooldown, "fallback", fallback)
+			middlewares = append(middlewares, agent.WithBreaker(agentUrl, agent.BreakerConfig{
+				Failures: appConfig.App.AgentBreakerFailures,
+				Cooldown: cooldown,
+				Fallback: fallback,
+			}, logger))
+		}
+		if appConfig.App.DecisionCache {
+			ttl := time.Duration(appConfig.App.DecisionCacheTTL) * time.Second
+			logger.Info("decision cache enabled", "ttl", ttl)
+			middlewares = append(middlewares, agent.WithCache(ttl, logger))
+		}
+		if appConfig.App.AgentRateLimit > 0 {
+			logger.Info("agent rate limit enabled", "rate", appConfig.App.AgentRateLimit, "burst", appConfig.App.AgentRateBurst)
+			middlewares = append(middlewares, agent.WithRateLimit(appConfig.App.AgentRateLimit, appConfig.App.AgentRateBurst))
+		}
+		middlewares = append(middlewares, agent.WithMetrics(agentUrl))
+		cli = agent.Wrap(cli, middlewares...)
+		if appConfig.App.AgentPush {
+			logger.Info("agent push mode enabled", "url", appConfig.App.AgentPushUrl, "deadline", appConfig.App.AgentPushDeadline)
+			push = agent.NewPushClient(cli, agent.PushConfig{
+				Url:         appConfig.App.AgentPushUrl,
+				CallbackUrl: appConfig.App.AgentPushCallback,
+				Deadline:    time.Duration(appConfig.App.AgentPushDeadline) * time.Second,
+				Wait:        time.Duration(appConfig.App.AgentPushWait) * time.Second,
+			}, logger)
+			cli = push
+		}
+		if appConfig.App.ProposalScoring {
+			scoring, err = agent.NewScoringClient(cli, agent.ScoringConfig{
+				Weights:   appConfig.App.ScoringWeights,
+				Threshold: appConfig.App.ScoringThreshold,
+				BudgetCap: appConfig.App.ScoringBudgetCap,
+			}, logger)
+			if err != nil {
+				log.Fatalf("invalid proposal scoring: %v", err)
+			}
+			cli = scoring
+		}
+		if appConfig.App.VoteTimeout > 0 {
+			decision, err := agent.ParseTimeoutDecision(appConfig.App.VoteTimeoutDecision)
+			if err != nil {
+				log.Fatalf("invalid vote timeout decision: %v", err)
+			}
+			timeout := time.Duration(appConfig.App.VoteTimeout) * time.Second
+			logger.Info("vote timeout enabled", "timeout", timeout, "decision", decision)
+			timeouts = agent.NewTimeoutClient(cli, agentUrl, timeout, decision, logger)
+			cli = timeouts
+		}
+		model := appConfig.App.AgentModel
+		if model == "" {
+			model = appConfig.App.AgentBackend
+		}
+		cli = agent.NewDecisionLogClient(cli, model, logger)
+		if appConfig.App.VoteDryRun {
+			policy, err := agent.ParseDryRunPolicy(appConfig.App.VoteDryRunPolicy)
+			if err != nil {
+				log.Fatalf("invalid vote dry run policy: %v", err)
+			}
+			logger.Info("vote dry run enabled", "policy", policy)
+			dryRun = agent.NewDryRunClient(cli, policy, logger)
+			cli = dryRun
+		}
+		if appConfig.App.AgentQueueWorkers > 0 && eliza != nil {
+			overflow, err := agent.ParseOverflowPolicy(appConfig.App.AgentQueueOverflow)
+			if err != nil {
+				log.Fatalf("invalid agent queue: %v", err)
+			}
+			queue, err := agent.NewAgentQueue(agentUrl, agent.AgentQueueConfig{
+				Workers:    appConfig.App.AgentQueueWorkers,
+				MaxDepth:   appConfig.App.AgentQueueMaxDepth,
+				Overflow:   overflow,
+				Priorities: appConfig.App.AgentPriorities,
+			})
+			if err != nil {
+				log.Fatalf("invalid agent queue: %v", err)
+			}
+			eliza.SetQueue(queue)
+		}
 	}
 
+	agent.SetActiveClient(cli)
+
 	// new app
 	appConfig.App.Home = homeDir
 	appConfig.App.TimeoutCommit = uint64(appConfig.Consensus.TimeoutCommit.Seconds())
-	app, err := app.NewHACApp(appConfig.App, agent.ElizaCli, logger)
+	app, err := app.NewHACApp(appConfig.App, agent.ActiveClient(), logger)
 	if err != nil {
 		log.Fatalf("new App err:%v", err)
 	}
@@ -133,11 +365,90 @@ func run(cmd *cobra.Command, args []string) {
 	}
 	rpcUrl.Scheme = "http"
 	dbPath := path.Join(appConfig.RootDir, "indexer.db")
+	if appConfig.App.DatabaseUrl != "" {
+		dbPath = appConfig.App.DatabaseUrl
+	}
 	node.BlockStore()
 	indexer, err := agent.NewChainIndexer(logger, dbPath, rpcUrl.String(), node.BlockStore(), appConfig)
 	if err != nil {
 		log.Fatalf("new chain indexer err %s", err.Error())
 	}
+	agent.PeerDiscussionLimit = appConfig.App.PeerDiscussionLimit
+	agent.DiscussionHistoryLimit = appConfig.App.DiscussionHistory
+	agent.ProposalSummaryThreshold = appConfig.App.ProposalSummaryBytes
+	if appConfig.App.ProposalChunkBytes > 0 {
+		agent.ProposalChunkSize = appConfig.App.ProposalChunkBytes
+	}
+	var transcripts *agent.TranscriptRecorder
+	if appConfig.App.AgentTranscripts {
+		transcripts, err = agent.NewTranscriptRecorder(indexer, appConfig.App.TranscriptMaxBytes, appConfig.App.TranscriptRedact)
+		if err != nil {
+			log.Fatalf("invalid transcript config: %v", err)
+		}
+	}
+	setupBackend := func(backend agent.Client) {
+		if vc, ok := backend.(agent.VoteContextClient); ok {
+			vc.SetDiscussionSource(indexer)
+			vc.SetExpeditedSource(indexer)
+			vc.SetTreasurySource(indexer)
+			vc.SetUptimeSource(indexer)
+			vc.SetProposalSource(indexer)
+			vc.SetDossierSource(indexer)
+			vc.SetCharacterSource(indexer)
+			if transcripts != nil {
+				vc.SetTranscripts(transcripts)
+			}
+		}
+		if cs, ok := backend.(agent.CommentStreamer); ok {
+			cs.SetCommentDraftStore(indexer)
+		}
+	}
+	for _, backend := range backends {
+		setupBackend(backend)
+		if e, ok := backend.(*agent.ElizaClient); ok && appConfig.App.AgentRefresh > 0 {
+			go e.RefreshAgent(context.TODO(), time.Duration(appConfig.App.AgentRefresh)*time.Second)
+		}
+	}
+	if failover != nil {
+		failover.SetClientSetup(setupBackend)
+		go failover.Run(context.TODO())
+	}
+	if routing != nil {
+		routing.SetClientSetup(setupBackend)
+		routing.SetAgentUrlSource(indexer)
+	}
+	if eliza != nil {
+		indexer.SetAnnouncer(eliza)
+		indexer.SetProposalRefiner(eliza)
+		indexer.SetProposalInitiator(eliza)
+		indexer.SetProposalSummarizer(eliza)
+	}
+	if dryRun != nil {
+		dryRun.SetDecisionStore(indexer)
+	}
+	if decisions, ok := agent.FindClient[*agent.DecisionLogClient](cli); ok {
+		decisions.SetAgentDecisionStore(indexer)
+	}
+	if timeouts != nil {
+		timeouts.SetFallbackStore(indexer)
+	}
+	if cache, ok := agent.FindClient[*agent.CacheClient](cli); ok {
+		cache.SetDecisionCacheStore(indexer)
+	}
+	if scoring != nil {
+		scoring.SetScoringStore(indexer)
+	}
+	if ensemble != nil {
+		ensemble.SetEnsembleStore(indexer)
+	}
+	if push != nil {
+		push.SetQuestionStore(indexer)
+		push.SetProposalSource(indexer)
+	}
+	if health != nil {
+		health.SetHealthStore(indexer)
+		go health.Run(context.TODO())
+	}
 	go indexer.Start(context.TODO())
 
 	service := agent.NewService(appConfig.App.ServiceAddress, indexer)
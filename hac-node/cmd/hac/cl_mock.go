@@ -86,12 +86,12 @@ func run(cmd *cobra.Command, args []string) {
 	//new agent client
 	agentUrl := strings.TrimRight(appConfig.App.AgentUrl, "/")
 	logger.Info("agent url: %s", agentUrl)
-	agent.ElizaCli = agent.NewMockClient()
+	agent.SetActiveClient(agent.NewMockClient())
 
 	// new app
 	appConfig.App.Home = homeDir
 	appConfig.App.TimeoutCommit = uint64(appConfig.Consensus.TimeoutCommit.Seconds())
-	app, err := app.NewHACApp(appConfig.App, agent.ElizaCli, logger)
+	app, err := app.NewHACApp(appConfig.App, agent.ActiveClient(), logger)
 	if err != nil {
 		log.Fatalf("new App err:%v", err)
 	}
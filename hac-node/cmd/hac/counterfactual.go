@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"github.com/calehh/hac-app/agent"
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+	"github.com/spf13/cobra"
+)
+
+type counterfactualArguments struct {
+	DB       string
+	AgentUrl string
+	AgentId  string
+	Voter    string
+	From     uint64
+	To       uint64
+	Delay    time.Duration
+	Format   string
+	Out      string
+}
+
+var counterfactualArgs counterfactualArguments
+
+var counterfactualCmd = &cobra.Command{
+	Use:   "counterfactual",
+	Short: "replay indexed governance through an alternate agent and compare outcomes",
+	Long: `Replay past proposals and their discussions from the indexer db to an alternate agent, in the order they
+happened, and ask it to vote on every settled proposal once its discussions are in. The decisions are compared
+with the recorded outcomes in a markdown or json report, assuming every validator ran the alternate agent.
+
+The replay runs in sandbox: no transaction is sent, the indexer db is only read, and the alternate agent should
+be a separate deployment or a separate agent id so its memory does not mix with the live agent.`,
+	Args: cobra.NoArgs,
+	RunE: counterfactualRun,
+}
+
+func init() {
+	counterfactualCmd.Flags().StringVar(&counterfactualArgs.DB, "db", "", "local indexer db path (default $HOME/.hac/indexer.db)")
+	counterfactualCmd.Flags().StringVar(&counterfactualArgs.AgentUrl, "agent-url", "http://127.0.0.1:3000", "alternate agent service address")
+	counterfactualCmd.Flags().StringVar(&counterfactualArgs.AgentId, "agent-id", "", "id or name of the alternate agent on the service (default the first agent)")
+	counterfactualCmd.Flags().StringVar(&counterfactualArgs.Voter, "voter", "", "validator address the agent votes as, its recorded votes are listed next to the agent decisions")
+	counterfactualCmd.Flags().Uint64Var(&counterfactualArgs.From, "from", 1, "first proposal id replayed")
+	counterfactualCmd.Flags().Uint64Var(&counterfactualArgs.To, "to", 0, "last proposal id replayed, 0 replays up to the latest")
+	counterfactualCmd.Flags().DurationVar(&counterfactualArgs.Delay, "delay", 200*time.Millisecond, "pause between agent calls")
+	counterfactualCmd.Flags().StringVarP(&counterfactualArgs.Format, "format", "f", "markdown", "output format, markdown or json")
+	counterfactualCmd.Flags().StringVarP(&counterfactualArgs.Out, "out", "o", "", "output file (default stdout)")
+}
+
+func counterfactualRun(cmd *cobra.Command, args []string) error {
+	if counterfactualArgs.Format != "markdown" && counterfactualArgs.Format != "md" && counterfactualArgs.Format != "json" {
+		return fmt.Errorf("unknown format %q", counterfactualArgs.Format)
+	}
+	dbPath := counterfactualArgs.DB
+	if dbPath == "" {
+		dbPath = path.Join(os.ExpandEnv("$HOME/.hac"), "indexer.db")
+	}
+	reader, err := agent.OpenIndexReader(dbPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	logger := cmtlog.NewTMLogger(cmtlog.NewSyncWriter(os.Stderr))
+	cli, err := agent.NewElizaAgentClient(strings.TrimRight(counterfactualArgs.AgentUrl, "/"), counterfactualArgs.AgentId, nil, cmtlog.NewFilter(logger, cmtlog.AllowError()))
+	if err != nil {
+		return fmt.Errorf("connect agent: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	report := &agent.CounterfactualReport{
+		Agent:       cli.Url + "/" + cli.AgentId,
+		Voter:       counterfactualArgs.Voter,
+		GeneratedAt: time.Now().UTC(),
+		Proposals:   make([]agent.CounterfactualProposal, 0),
+	}
+	after := uint64(0)
+	if counterfactualArgs.From > 0 {
+		after = counterfactualArgs.From - 1
+	}
+replay:
+	for {
+		batch, err := reader.ProposalsAfter(after, 100)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, p := range batch {
+			if counterfactualArgs.To > 0 && p.Id > counterfactualArgs.To {
+				break replay
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := counterfactualProposal(ctx, cli, reader, report, p); err != nil {
+				return fmt.Errorf("replay proposal %d: %w", p.Id, err)
+			}
+			after = p.Id
+		}
+	}
+	fmt.Fprintf(os.Stderr, "compared %d proposals, %d outcomes changed, %d failed\n", report.Compared, report.Changed, report.Failed)
+
+	var out io.Writer = os.Stdout
+	if counterfactualArgs.Out != "" {
+		f, err := os.Create(counterfactualArgs.Out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	if counterfactualArgs.Format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return markdownCounterfactual.Execute(out, report)
+}
+
+// counterfactualProposal feeds p and its discussions to the agent and, for a
+// settled proposal, asks for its decision. A failed decision is kept in the
+// report, a failed feed stops the replay.
+func counterfactualProposal(ctx context.Context, cli *agent.ElizaClient, reader *agent.IndexReader, report *agent.CounterfactualReport, p agent.Proposal) error {
+	discussions, err := reader.ProposalDiscussions(p.Id)
+	if err != nil {
+		return err
+	}
+	if err := cli.AddProposal(ctx, p.Id, p.ProposerAddress, p.Data); err != nil {
+		return err
+	}
+	time.Sleep(counterfactualArgs.Delay)
+	for _, d := range discussions {
+		if err := cli.AddDiscussion(ctx, d.Proposal, d.SpeakerAddress, d.Data); err != nil {
+			return err
+		}
+		time.Sleep(counterfactualArgs.Delay)
+	}
+	if !p.Status.Settled() {
+		fmt.Fprintf(os.Stderr, "proposal %d %s, %d discussions, not compared\n", p.Id, p.Status, len(discussions))
+		return nil
+	}
+	item, err := reader.CounterfactualBaseline(p, counterfactualArgs.Voter)
+	if err != nil {
+		return err
+	}
+	item.Discussions = len(discussions)
+	decision, err := cli.IfAcceptProposal(ctx, p.Id, counterfactualArgs.Voter)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	report.Add(item, decision.Pass(), err)
+	fmt.Fprintf(os.Stderr, "proposal %d %s, %d discussions, agent %s confidence %.2f\n", p.Id, p.Status, len(discussions), decision.Vote, decision.Confidence)
+	time.Sleep(counterfactualArgs.Delay)
+	return nil
+}
+
+var markdownCounterfactual = template.Must(template.New("counterfactual").Funcs(reportFuncs).Parse(`# Counterfactual governance report
+
+Generated {{ .GeneratedAt.Format "2006-01-02 15:04 MST" }} against agent {{ .Agent }}{{ if .Voter }} voting as {{ .Voter }}{{ end }}.
+Outcomes assume every validator ran the alternate agent.
+
+## Summary
+
+- compared proposals: {{ .Compared }}
+- outcomes kept: {{ percent .Agreement }}
+- outcomes changed: {{ .Changed }}
+- passed, recorded: {{ .Passed }}
+- passed, alternate agent: {{ .WouldPass }}
+- decisions failed: {{ .Failed }}
+
+## Changed outcomes
+
+| ID | Title | Recorded | Alternate | Decision (pass/reject) |
+|----|-------|----------|-----------|------------------------|
+{{- range .Proposals }}{{ if .Changed }}
+| {{ .Proposal.Id }} | {{ oneline .Proposal.Title }} | {{ .Proposal.Status }} | {{ .Outcome }} | {{ .DecisionPass }}/{{ .DecisionReject }} |
+{{- end }}{{ end }}
+
+## Proposals
+
+| ID | Title | Expedited | Discussions | Recorded | Decision (pass/reject) | Recorded vote | Alternate |
+|----|-------|-----------|-------------|----------|------------------------|---------------|-----------|
+{{- range .Proposals }}
+| {{ .Proposal.Id }} | {{ oneline .Proposal.Title }} | {{ .Proposal.Expedited }} | {{ .Discussions }} | {{ .Proposal.Status }} | {{ .DecisionPass }}/{{ .DecisionReject }} | {{ or .Recorded "-" }} | {{ if .Error }}failed: {{ oneline .Error }}{{ else }}{{ .Outcome }}{{ end }} |
+{{- end }}
+`))
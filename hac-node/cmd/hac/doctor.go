@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/calehh/hac-app/agent"
+	app_config "github.com/calehh/hac-app/config"
+	"github.com/cometbft/cometbft/rpc/client/http"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// minFreeDisk is the free space below which doctor reports the disk check as
+// failed.
+const minFreeDisk = 1 << 30
+
+type doctorArguments struct {
+	Home string
+	Url  string
+}
+
+var doctorArgs doctorArguments
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "check node connectivity and indexer schema",
+	Long:  `Check chain rpc and websocket reachability, the indexer db schema version, the agent service and free disk space, printing hints for failed checks.`,
+	Run:   doctorRun,
+}
+
+func init() {
+	urlFlag(doctorCmd, &doctorArgs.Url)
+	doctorCmd.Flags().StringVarP(&doctorArgs.Home, "homedir", "d", "", "home directory")
+}
+
+type doctorCheck struct {
+	name string
+	run  func(ctx context.Context) (detail string, hint string, err error)
+}
+
+func doctorRun(cmd *cobra.Command, args []string) {
+	home := doctorArgs.Home
+	if home == "" {
+		home = os.ExpandEnv("$HOME/.hac")
+	}
+	appConfig := &app_config.Config{
+		Config: app_config.DefaultHACCometConfig(),
+		App:    app_config.DefaultHACAppConfig(home),
+	}
+	appConfig.SetRoot(home)
+	viper.SetConfigFile(path.Join(home, "config/config.toml"))
+	if err := viper.ReadInConfig(); err != nil {
+		fmt.Printf("[WARN] config: %v, using defaults\n", err)
+	} else if err := viper.Unmarshal(appConfig); err != nil {
+		fmt.Printf("[WARN] config: %v, using defaults\n", err)
+	}
+
+	checks := []doctorCheck{
+		{"chain rpc", func(ctx context.Context) (string, string, error) {
+			return checkChainRpc(ctx, doctorArgs.Url)
+		}},
+		{"websocket", func(ctx context.Context) (string, string, error) {
+			return checkWebsocket(ctx, doctorArgs.Url)
+		}},
+		{"indexer schema", func(ctx context.Context) (string, string, error) {
+			return checkSchema(path.Join(appConfig.RootDir, "indexer.db"))
+		}},
+		{"agent", func(ctx context.Context) (string, string, error) {
+			if appConfig.App.IndexOnly {
+				return "index-only mode, agent not used", "", nil
+			}
+			return checkAgent(ctx, strings.TrimRight(appConfig.App.AgentUrl, "/"), appConfig.App.AgentId)
+		}},
+		{"disk space", func(ctx context.Context) (string, string, error) {
+			return checkDisk(appConfig.RootDir)
+		}},
+	}
+
+	failed := 0
+	for _, check := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		detail, hint, err := check.run(ctx)
+		cancel()
+		if err != nil {
+			failed++
+			fmt.Printf("[FAIL] %s: %v\n", check.name, err)
+			if hint != "" {
+				fmt.Printf("       hint: %s\n", hint)
+			}
+			continue
+		}
+		fmt.Printf("[ OK ] %s: %s\n", check.name, detail)
+	}
+	if failed > 0 {
+		fmt.Printf("%d of %d checks failed\n", failed, len(checks))
+		os.Exit(1)
+	}
+}
+
+func checkChainRpc(ctx context.Context, url string) (string, string, error) {
+	hint := "make sure the node is running and rpc.laddr in config.toml is reachable at " + url
+	cli, err := http.New(url, "/websocket")
+	if err != nil {
+		return "", hint, err
+	}
+	status, err := cli.Status(ctx)
+	if err != nil {
+		return "", hint, err
+	}
+	if status.SyncInfo.CatchingUp {
+		return fmt.Sprintf("height %d, still catching up", status.SyncInfo.LatestBlockHeight), "", nil
+	}
+	return fmt.Sprintf("height %d, network %s", status.SyncInfo.LatestBlockHeight, status.NodeInfo.Network), "", nil
+}
+
+func checkWebsocket(ctx context.Context, url string) (string, string, error) {
+	hint := "check that the /websocket endpoint is not blocked by a proxy and rpc.max_subscription_clients is not exhausted"
+	cli, err := http.New(url, "/websocket")
+	if err != nil {
+		return "", hint, err
+	}
+	if err := cli.Start(); err != nil {
+		return "", hint, err
+	}
+	defer cli.Stop()
+	events, err := cli.Subscribe(ctx, "hac-doctor", "tm.event='NewBlock'")
+	if err != nil {
+		return "", hint, err
+	}
+	defer cli.UnsubscribeAll(context.Background(), "hac-doctor")
+	select {
+	case ev := <-events:
+		return "received " + ev.Query, "", nil
+	case <-ctx.Done():
+		return "", "subscription works but no block arrived, check that the chain is producing blocks", ctx.Err()
+	}
+}
+
+func checkSchema(dbPath string) (string, string, error) {
+	version, err := agent.DBSchemaVersion(dbPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "the indexer db is created on the first start of the node", err
+		}
+		return "", "the indexer db may be corrupt, restore it from a backup or remove it to reindex", err
+	}
+	latest := agent.SchemaVersionLatest()
+	if version < latest {
+		return "", "start the node once to apply pending migrations", fmt.Errorf("schema version %d, expected %d", version, latest)
+	}
+	if version > latest {
+		return "", "the db was migrated by a newer release, upgrade this binary", fmt.Errorf("schema version %d, expected %d", version, latest)
+	}
+	return fmt.Sprintf("version %d", version), "", nil
+}
+
+func checkAgent(ctx context.Context, url string, selection string) (string, string, error) {
+	hint := "start the agent service or fix agent_url in the [app] section of config.toml"
+	cli := &agent.ElizaClient{Url: url}
+	agents, err := cli.GetAgents(ctx)
+	if err != nil {
+		return "", hint, err
+	}
+	if len(agents) == 0 {
+		return "", "the agent service is up but has no agent loaded, check its character configuration", fmt.Errorf("no agent at %s", url)
+	}
+	if _, err := agent.SelectElizaAgent(agents, selection); err != nil {
+		return "", "fix agent_id in the [app] section of config.toml", err
+	}
+	return fmt.Sprintf("%d agent(s) at %s", len(agents), url), "", nil
+}
+
+func checkDisk(dir string) (string, string, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return "", "make sure the home directory exists", err
+	}
+	free := uint64(st.Bavail) * uint64(st.Bsize)
+	if free < minFreeDisk {
+		return "", "free disk space or move the home directory to a larger volume", fmt.Errorf("only %d MiB free", free>>20)
+	}
+	return fmt.Sprintf("%d MiB free", free>>20), "", nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/calehh/hac-app/txbuilder"
+	"github.com/cometbft/cometbft/rpc/client/http"
+	"github.com/spf13/cobra"
+)
+
+type keysArguments struct {
+	Dir      string
+	Account  uint32
+	Skey     string
+	Url      string
+	Proposal uint64
+}
+
+var keysArgs keysArguments
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "manage the keys txs are signed with",
+	Long: `Manage a keystore of encrypted ed25519 keys, one json file per key, to sign txs for agents and tools.
+
+The passphrase of a key is read from HAC_KEY_PASSPHRASE, or from the first line of stdin.
+A mnemonic is read from HAC_KEY_MNEMONIC, or from the next line of stdin.`,
+}
+
+var keysAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "generate a new key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  keysAddRun,
+}
+
+var keysImportMnemonicCmd = &cobra.Command{
+	Use:   "import-mnemonic <name>",
+	Short: "import the key derived from a bip39 mnemonic",
+	Long:  `Import the ed25519 key derived from a bip39 mnemonic on the path m/44'/118'/<account>'/0'/0'.`,
+	Args:  cobra.ExactArgs(1),
+	RunE:  keysImportMnemonicRun,
+}
+
+var keysImportPVCmd = &cobra.Command{
+	Use:   "import-pv <name>",
+	Short: "import the key of a priv_validator_key.json file",
+	Args:  cobra.ExactArgs(1),
+	RunE:  keysImportPVRun,
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the keys",
+	Args:  cobra.NoArgs,
+	RunE:  keysListRun,
+}
+
+var keysDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "delete a key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  keysDeleteRun,
+}
+
+var keysDiscussCmd = &cobra.Command{
+	Use:   "discuss <name> <text>",
+	Short: "post a discussion signed with a key",
+	Args:  cobra.ExactArgs(2),
+	RunE:  keysDiscussRun,
+}
+
+func init() {
+	home, _ := os.UserHomeDir()
+	keysCmd.PersistentFlags().StringVar(&keysArgs.Dir, "keystore", filepath.Join(home, ".hac", "keys"), "keystore directory")
+	keysImportMnemonicCmd.Flags().Uint32Var(&keysArgs.Account, "account", 0, "account of the derivation path")
+	keysImportPVCmd.Flags().StringVarP(&keysArgs.Skey, "skeyPath", "s", "./config/priv_validator_key.json", "private key path")
+	urlFlag(keysDiscussCmd, &keysArgs.Url)
+	keysDiscussCmd.Flags().Uint64VarP(&keysArgs.Proposal, "proposal", "p", 0, "proposal index")
+	keysCmd.AddCommand(keysAddCmd, keysImportMnemonicCmd, keysImportPVCmd, keysListCmd, keysDeleteCmd, keysDiscussCmd)
+}
+
+// keysInput reads the secrets of the keys commands from the environment,
+// or line by line from stdin.
+type keysInput struct {
+	stdin *bufio.Reader
+}
+
+func (in *keysInput) read(env string, what string) (string, error) {
+	if v := os.Getenv(env); v != "" {
+		return v, nil
+	}
+	if in.stdin == nil {
+		in.stdin = bufio.NewReader(os.Stdin)
+	}
+	line, err := in.stdin.ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", fmt.Errorf("no %s, set %s or write it to stdin: %v", what, env, err)
+	}
+	return line, nil
+}
+
+func printKey(key txbuilder.KeyInfo) {
+	fmt.Printf("%s\taddress: %s\tpubkey: %s\n", key.Name, key.Address, key.PubKey)
+}
+
+func keysAddRun(cmd *cobra.Command, args []string) error {
+	ks, err := txbuilder.NewKeystore(keysArgs.Dir)
+	if err != nil {
+		return err
+	}
+	var in keysInput
+	passphrase, err := in.read("HAC_KEY_PASSPHRASE", "passphrase")
+	if err != nil {
+		return err
+	}
+	key, err := ks.Add(args[0], passphrase)
+	if err != nil {
+		return err
+	}
+	printKey(key)
+	return nil
+}
+
+func keysImportMnemonicRun(cmd *cobra.Command, args []string) error {
+	ks, err := txbuilder.NewKeystore(keysArgs.Dir)
+	if err != nil {
+		return err
+	}
+	var in keysInput
+	passphrase, err := in.read("HAC_KEY_PASSPHRASE", "passphrase")
+	if err != nil {
+		return err
+	}
+	mnemonic, err := in.read("HAC_KEY_MNEMONIC", "mnemonic")
+	if err != nil {
+		return err
+	}
+	key, err := ks.ImportMnemonic(args[0], mnemonic, "", keysArgs.Account, passphrase)
+	if err != nil {
+		return err
+	}
+	printKey(key)
+	return nil
+}
+
+func keysImportPVRun(cmd *cobra.Command, args []string) error {
+	ks, err := txbuilder.NewKeystore(keysArgs.Dir)
+	if err != nil {
+		return err
+	}
+	var in keysInput
+	passphrase, err := in.read("HAC_KEY_PASSPHRASE", "passphrase")
+	if err != nil {
+		return err
+	}
+	key, err := ks.ImportPV(args[0], keysArgs.Skey, passphrase)
+	if err != nil {
+		return err
+	}
+	printKey(key)
+	return nil
+}
+
+func keysListRun(cmd *cobra.Command, args []string) error {
+	ks, err := txbuilder.NewKeystore(keysArgs.Dir)
+	if err != nil {
+		return err
+	}
+	keys, err := ks.List()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		printKey(key)
+	}
+	return nil
+}
+
+func keysDeleteRun(cmd *cobra.Command, args []string) error {
+	ks, err := txbuilder.NewKeystore(keysArgs.Dir)
+	if err != nil {
+		return err
+	}
+	return ks.Delete(args[0])
+}
+
+func keysDiscussRun(cmd *cobra.Command, args []string) error {
+	ks, err := txbuilder.NewKeystore(keysArgs.Dir)
+	if err != nil {
+		return err
+	}
+	var in keysInput
+	passphrase, err := in.read("HAC_KEY_PASSPHRASE", "passphrase")
+	if err != nil {
+		return err
+	}
+	key, err := ks.Load(args[0], passphrase)
+	if err != nil {
+		return err
+	}
+	cli, err := http.New(keysArgs.Url, "/websocket")
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	b, err := txbuilder.New(ctx, cli, key)
+	if err != nil {
+		return err
+	}
+	res, err := b.Discuss(ctx, keysArgs.Proposal, args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Println("tx hash:", res.Hash.String())
+	return nil
+}
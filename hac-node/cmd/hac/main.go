@@ -15,6 +15,19 @@ func main() {
 	clCmd.AddCommand(grantCmd)
 	clCmd.AddCommand(pubkeyCmd)
 	clCmd.AddCommand(signCmd)
+	clCmd.AddCommand(doctorCmd)
+	clCmd.AddCommand(queryCmd)
+	clCmd.AddCommand(reportCmd)
+	clCmd.AddCommand(migrateDBCmd)
+	clCmd.AddCommand(syncDBCmd)
+	clCmd.AddCommand(replayCmd)
+	clCmd.AddCommand(counterfactualCmd)
+	clCmd.AddCommand(seedCmd)
+	clCmd.AddCommand(tailCmd)
+	clCmd.AddCommand(maintainDBCmd)
+	clCmd.AddCommand(exportSnapshotCmd)
+	clCmd.AddCommand(backupCmd)
+	clCmd.AddCommand(keysCmd)
 	if err := clCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
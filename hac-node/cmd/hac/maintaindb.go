@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calehh/hac-app/agent"
+	"github.com/spf13/cobra"
+)
+
+type maintainDBArguments struct {
+	Api    string
+	ApiKey string
+	DB     string
+	Json   bool
+}
+
+var maintainDBArgs maintainDBArguments
+
+var maintainDBCmd = &cobra.Command{
+	Use:   "maintain-db",
+	Short: "vacuum and analyze the indexer db",
+	Long: `Run VACUUM and ANALYZE on the indexer db and report the reclaimed space and table sizes.
+With --api the maintenance runs inside a running node, which is required for a sqlite db in use by the node.
+Scheduled maintenance is configured with maintenance_interval in the [app] section of config.toml.`,
+	Args: cobra.NoArgs,
+	RunE: maintainDBRun,
+}
+
+func init() {
+	maintainDBCmd.Flags().StringVar(&maintainDBArgs.Api, "api", "", "indexer api address of a running node, e.g. http://127.0.0.1:8631")
+	maintainDBCmd.Flags().StringVar(&maintainDBArgs.ApiKey, "api-key", "", "admin api key of the running node")
+	maintainDBCmd.Flags().StringVar(&maintainDBArgs.DB, "db", "", "indexer db path or postgres url (default $HOME/.hac/indexer.db)")
+	maintainDBCmd.Flags().BoolVar(&maintainDBArgs.Json, "json", false, "print json instead of a table")
+}
+
+func maintainDBRun(cmd *cobra.Command, args []string) error {
+	var report agent.MaintenanceReport
+	if maintainDBArgs.Api != "" {
+		// vacuuming a large db takes a while
+		api := &apiSource{url: strings.TrimRight(maintainDBArgs.Api, "/"), key: maintainDBArgs.ApiKey, cli: &http.Client{Timeout: 30 * time.Minute}}
+		if err := api.post("/admin/maintenance", struct{}{}, &report); err != nil {
+			return err
+		}
+	} else {
+		dbPath := maintainDBArgs.DB
+		if dbPath == "" {
+			dbPath = path.Join(os.ExpandEnv("$HOME/.hac"), "indexer.db")
+		}
+		if !agent.IsPostgresDSN(dbPath) {
+			if _, err := os.Stat(dbPath); err != nil {
+				return err
+			}
+		}
+		var err error
+		if report, err = agent.MaintainIndexDB(dbPath); err != nil {
+			return err
+		}
+	}
+
+	if maintainDBArgs.Json {
+		return printJson(report)
+	}
+	fmt.Printf("%s db vacuumed in %s\n", report.Dialect, report.Duration.Round(time.Millisecond))
+	fmt.Printf("size %s -> %s, reclaimed %s\n\n", formatBytes(report.SizeBefore), formatBytes(report.SizeAfter), formatBytes(report.Reclaimed))
+	rows := make([][]string, 0, len(report.Tables))
+	for _, t := range report.Tables {
+		size := "-"
+		if t.Bytes > 0 {
+			size = formatBytes(t.Bytes)
+		}
+		rows = append(rows, []string{t.Name, strconv.FormatUint(t.Rows, 10), size})
+	}
+	printTable([]string{"TABLE", "ROWS", "SIZE"}, rows)
+	return nil
+}
+
+func formatBytes(n int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+}
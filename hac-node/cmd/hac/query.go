@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/calehh/hac-app/agent"
+	"github.com/spf13/cobra"
+)
+
+type queryArguments struct {
+	Api      string
+	ApiKey   string
+	DB       string
+	Json     bool
+	Page     int
+	PageSize int
+}
+
+var queryArgs queryArguments
+
+var queryCmd = &cobra.Command{
+	Use:     "query",
+	Short:   "query indexed governance data",
+	Long:    `Query proposals, grants, votes and discussions from the local indexer db or from a remote indexer api.`,
+	Aliases: []string{"q"},
+}
+
+var queryProposalsCmd = &cobra.Command{
+	Use:   "proposals",
+	Short: "list or get proposals",
+}
+
+var queryProposalsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list proposals, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  queryProposalsListRun,
+}
+
+var queryProposalsGetCmd = &cobra.Command{
+	Use:   "get <proposal id>",
+	Short: "get one proposal",
+	Args:  cobra.ExactArgs(1),
+	RunE:  queryProposalsGetRun,
+}
+
+var queryGrantsCmd = &cobra.Command{
+	Use:   "grants",
+	Short: "list or get grants",
+}
+
+var queryGrantsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list grants, newest first",
+	Args:  cobra.NoArgs,
+	RunE:  queryGrantsListRun,
+}
+
+var queryGrantsGetCmd = &cobra.Command{
+	Use:   "get <validator index>",
+	Short: "get one grant",
+	Args:  cobra.ExactArgs(1),
+	RunE:  queryGrantsGetRun,
+}
+
+var queryVotesCmd = &cobra.Command{
+	Use:   "votes <voter address>",
+	Short: "list proposal and grant votes of a voter",
+	Args:  cobra.ExactArgs(1),
+	RunE:  queryVotesRun,
+}
+
+var queryDiscussionsCmd = &cobra.Command{
+	Use:   "discussions <proposal id>",
+	Short: "list discussions of a proposal",
+	Args:  cobra.ExactArgs(1),
+	RunE:  queryDiscussionsRun,
+}
+
+func init() {
+	flags := queryCmd.PersistentFlags()
+	flags.StringVar(&queryArgs.Api, "api", "", "remote indexer api address, e.g. http://127.0.0.1:8631")
+	flags.StringVar(&queryArgs.ApiKey, "api-key", "", "api key of the remote indexer, when it requires one")
+	flags.StringVar(&queryArgs.DB, "db", "", "local indexer db path (default $HOME/.hac/indexer.db)")
+	flags.BoolVar(&queryArgs.Json, "json", false, "print json instead of a table")
+	flags.IntVar(&queryArgs.Page, "page", 1, "page number, starting at 1")
+	flags.IntVar(&queryArgs.PageSize, "page-size", 20, "page size")
+
+	queryProposalsCmd.AddCommand(queryProposalsListCmd, queryProposalsGetCmd)
+	queryGrantsCmd.AddCommand(queryGrantsListCmd, queryGrantsGetCmd)
+	queryCmd.AddCommand(queryProposalsCmd, queryGrantsCmd, queryVotesCmd, queryDiscussionsCmd)
+}
+
+// governanceSource is implemented by the local db reader and the remote api
+// client. Pages start at 1.
+type governanceSource interface {
+	Proposals(page int, pageSize int) (agent.Page[agent.Proposal], error)
+	Proposal(id uint64) (agent.Proposal, error)
+	Grants(page int, pageSize int) (agent.Page[agent.Grant], error)
+	Grant(id uint64) (agent.Grant, error)
+	Votes(voter string, page int, pageSize int) (agent.GetVotesResponse, error)
+	Discussions(proposal uint64, page int, pageSize int) (agent.Page[agent.Discussion], error)
+	Close() error
+}
+
+func openGovernanceSource() (governanceSource, error) {
+	if queryArgs.Api != "" {
+		return &apiSource{url: strings.TrimRight(queryArgs.Api, "/"), key: queryArgs.ApiKey, cli: &http.Client{Timeout: 30 * time.Second}}, nil
+	}
+	dbPath := queryArgs.DB
+	if dbPath == "" {
+		dbPath = path.Join(os.ExpandEnv("$HOME/.hac"), "indexer.db")
+	}
+	reader, err := agent.OpenIndexReader(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	return &dbSource{reader}, nil
+}
+
+type dbSource struct {
+	*agent.IndexReader
+}
+
+func (d *dbSource) Proposals(page int, pageSize int) (agent.Page[agent.Proposal], error) {
+	return d.IndexReader.Proposals(page-1, pageSize)
+}
+
+func (d *dbSource) Grants(page int, pageSize int) (agent.Page[agent.Grant], error) {
+	return d.IndexReader.Grants(page-1, pageSize)
+}
+
+func (d *dbSource) Votes(voter string, page int, pageSize int) (agent.GetVotesResponse, error) {
+	return d.IndexReader.Votes(voter, page-1, pageSize)
+}
+
+func (d *dbSource) Discussions(proposal uint64, page int, pageSize int) (agent.Page[agent.Discussion], error) {
+	return d.IndexReader.Discussions(proposal, page-1, pageSize)
+}
+
+type apiSource struct {
+	url string
+	key string
+	cli *http.Client
+}
+
+func (a *apiSource) post(route string, req interface{}, res interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, a.url+"/api"+route, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if a.key != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+a.key)
+	}
+	resp, err := a.cli.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s: %s", resp.Status, apiErr.Error)
+		}
+		return errors.New(resp.Status)
+	}
+	return json.Unmarshal(data, res)
+}
+
+func (a *apiSource) Proposals(page int, pageSize int) (agent.Page[agent.Proposal], error) {
+	var res agent.Page[agent.ProposalInfo]
+	if err := a.post("/proposals", agent.GetProposalsReq{PageReq: agent.PageReq{Page: page, PageSize: pageSize}}, &res); err != nil {
+		return agent.Page[agent.Proposal]{}, err
+	}
+	proposals := agent.Page[agent.Proposal]{Items: make([]agent.Proposal, 0, len(res.Items)), Total: res.Total, Page: res.Page, PageSize: res.PageSize, NextCursor: res.NextCursor}
+	for _, info := range res.Items {
+		proposals.Items = append(proposals.Items, info.Proposal)
+	}
+	return proposals, nil
+}
+
+func (a *apiSource) Proposal(id uint64) (agent.Proposal, error) {
+	var res agent.Page[agent.ProposalInfo]
+	if err := a.post("/proposals", agent.GetProposalsReq{ProposalId: id}, &res); err != nil {
+		return agent.Proposal{}, err
+	}
+	if len(res.Items) == 0 {
+		return agent.Proposal{}, fmt.Errorf("proposal %d not found", id)
+	}
+	return res.Items[0].Proposal, nil
+}
+
+func (a *apiSource) Grants(page int, pageSize int) (agent.Page[agent.Grant], error) {
+	var res agent.Page[agent.GrantInfo]
+	if err := a.post("/grants", agent.GetGrantsReq{PageReq: agent.PageReq{Page: page, PageSize: pageSize}}, &res); err != nil {
+		return agent.Page[agent.Grant]{}, err
+	}
+	grants := agent.Page[agent.Grant]{Items: make([]agent.Grant, 0, len(res.Items)), Total: res.Total, Page: res.Page, PageSize: res.PageSize, NextCursor: res.NextCursor}
+	for _, info := range res.Items {
+		grants.Items = append(grants.Items, info.Grant)
+	}
+	return grants, nil
+}
+
+func (a *apiSource) Grant(id uint64) (agent.Grant, error) {
+	var res agent.Page[agent.GrantInfo]
+	if err := a.post("/grants", agent.GetGrantsReq{GrantId: id}, &res); err != nil {
+		return agent.Grant{}, err
+	}
+	if len(res.Items) == 0 {
+		return agent.Grant{}, fmt.Errorf("grant %d not found", id)
+	}
+	return res.Items[0].Grant, nil
+}
+
+func (a *apiSource) Votes(voter string, page int, pageSize int) (agent.GetVotesResponse, error) {
+	var res agent.GetVotesResponse
+	err := a.post("/votes", agent.GetVotesReq{Voter: voter, PageReq: agent.PageReq{Page: page, PageSize: pageSize}}, &res)
+	return res, err
+}
+
+func (a *apiSource) Discussions(proposal uint64, page int, pageSize int) (agent.Page[agent.Discussion], error) {
+	var res agent.Page[agent.Discussion]
+	err := a.post("/discussions", agent.GetDiscussionReq{ProposalId: proposal, PageReq: agent.PageReq{Page: page, PageSize: pageSize}}, &res)
+	return res, err
+}
+
+func (a *apiSource) Close() error {
+	return nil
+}
+
+func withGovernanceSource(fn func(src governanceSource) error) error {
+	src, err := openGovernanceSource()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return fn(src)
+}
+
+func queryProposalsListRun(cmd *cobra.Command, args []string) error {
+	return withGovernanceSource(func(src governanceSource) error {
+		proposals, err := src.Proposals(queryArgs.Page, queryArgs.PageSize)
+		if err != nil {
+			return err
+		}
+		if queryArgs.Json {
+			return printJson(proposals)
+		}
+		rows := make([][]string, 0, len(proposals.Items))
+		for _, p := range proposals.Items {
+			rows = append(rows, proposalRow(p))
+		}
+		printTable(proposalHeader, rows)
+		fmt.Printf("page %d, %d of %d proposals\n", proposals.Page, len(proposals.Items), proposals.Total)
+		return nil
+	})
+}
+
+func queryProposalsGetRun(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid proposal id %q", args[0])
+	}
+	return withGovernanceSource(func(src governanceSource) error {
+		p, err := src.Proposal(id)
+		if err != nil {
+			return err
+		}
+		if queryArgs.Json {
+			return printJson(p)
+		}
+		printTable(proposalHeader, [][]string{proposalRow(p)})
+		if p.Summary != "" {
+			fmt.Printf("\n%s\n", p.Summary)
+		}
+		return nil
+	})
+}
+
+func queryGrantsListRun(cmd *cobra.Command, args []string) error {
+	return withGovernanceSource(func(src governanceSource) error {
+		grants, err := src.Grants(queryArgs.Page, queryArgs.PageSize)
+		if err != nil {
+			return err
+		}
+		if queryArgs.Json {
+			return printJson(grants)
+		}
+		rows := make([][]string, 0, len(grants.Items))
+		for _, g := range grants.Items {
+			rows = append(rows, grantRow(g))
+		}
+		printTable(grantHeader, rows)
+		fmt.Printf("page %d, %d of %d grants\n", grants.Page, len(grants.Items), grants.Total)
+		return nil
+	})
+}
+
+func queryGrantsGetRun(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid validator index %q", args[0])
+	}
+	return withGovernanceSource(func(src governanceSource) error {
+		g, err := src.Grant(id)
+		if err != nil {
+			return err
+		}
+		if queryArgs.Json {
+			return printJson(g)
+		}
+		printTable(grantHeader, [][]string{grantRow(g)})
+		return nil
+	})
+}
+
+func queryVotesRun(cmd *cobra.Command, args []string) error {
+	return withGovernanceSource(func(src governanceSource) error {
+		votes, err := src.Votes(args[0], queryArgs.Page, queryArgs.PageSize)
+		if err != nil {
+			return err
+		}
+		if queryArgs.Json {
+			return printJson(votes)
+		}
+		rows := make([][]string, 0, len(votes.ProposalVotes.Items)+len(votes.GrantVotes.Items))
+		for _, v := range votes.ProposalVotes.Items {
+			rows = append(rows, []string{"proposal", strconv.FormatUint(v.Proposal, 10), strconv.FormatUint(v.Height, 10), v.Vote.String(), v.Reason})
+		}
+		for _, v := range votes.GrantVotes.Items {
+			rows = append(rows, []string{"grant", strconv.FormatUint(v.AccountIndex, 10), strconv.FormatUint(v.Height, 10), v.Vote.String(), v.Reason})
+		}
+		printTable([]string{"KIND", "ID", "HEIGHT", "VOTE", "REASON"}, rows)
+		return nil
+	})
+}
+
+func queryDiscussionsRun(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid proposal id %q", args[0])
+	}
+	return withGovernanceSource(func(src governanceSource) error {
+		discussions, err := src.Discussions(id, queryArgs.Page, queryArgs.PageSize)
+		if err != nil {
+			return err
+		}
+		if queryArgs.Json {
+			return printJson(discussions)
+		}
+		rows := make([][]string, 0, len(discussions.Items))
+		for _, d := range discussions.Items {
+			rows = append(rows, []string{strconv.FormatUint(d.Id, 10), strconv.FormatUint(d.Height, 10), d.SpeakerName, d.SpeakerAddress, shorten(d.Data, 80)})
+		}
+		printTable([]string{"ID", "HEIGHT", "SPEAKER", "ADDRESS", "TEXT"}, rows)
+		fmt.Printf("page %d, %d of %d discussions\n", discussions.Page, len(discussions.Items), discussions.Total)
+		return nil
+	})
+}
+
+var proposalHeader = []string{"ID", "STATUS", "PROPOSER", "HEIGHT", "TITLE"}
+
+func proposalRow(p agent.Proposal) []string {
+	return []string{strconv.FormatUint(p.Id, 10), p.Status.String(), p.ProposerName, strconv.FormatUint(p.NewHeight, 10), shorten(p.Title, 60)}
+}
+
+var grantHeader = []string{"ID", "ADDRESS", "STAKE", "GRANTED", "HEIGHT"}
+
+func grantRow(g agent.Grant) []string {
+	return []string{strconv.FormatUint(g.Id, 10), g.Address, strconv.FormatUint(g.Stake, 10), strconv.FormatBool(g.Grant), strconv.FormatUint(g.Height, 10)}
+}
+
+func printTable(header []string, rows [][]string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
+
+func printJson(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func shorten(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-3]) + "..."
+}
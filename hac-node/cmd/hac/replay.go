@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/calehh/hac-app/agent"
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+	"github.com/spf13/cobra"
+)
+
+type replayArguments struct {
+	DB       string
+	AgentUrl string
+	From     uint64
+	To       uint64
+	Delay    time.Duration
+	DryRun   bool
+}
+
+var replayArgs replayArguments
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "feed indexed proposals and discussions to an agent",
+	Long: `Send past proposals and their discussions from the indexer db to an agent, in the order they happened,
+to warm up a newly deployed or upgraded agent. Chain state is not touched.`,
+	Args: cobra.NoArgs,
+	RunE: replayRun,
+}
+
+func init() {
+	replayCmd.Flags().StringVar(&replayArgs.DB, "db", "", "local indexer db path (default $HOME/.hac/indexer.db)")
+	replayCmd.Flags().StringVar(&replayArgs.AgentUrl, "agent-url", "http://127.0.0.1:3000", "agent service address")
+	replayCmd.Flags().Uint64Var(&replayArgs.From, "from", 1, "first proposal id replayed, use it to resume an interrupted replay")
+	replayCmd.Flags().Uint64Var(&replayArgs.To, "to", 0, "last proposal id replayed, 0 replays up to the latest")
+	replayCmd.Flags().DurationVar(&replayArgs.Delay, "delay", 200*time.Millisecond, "pause between agent calls")
+	replayCmd.Flags().BoolVar(&replayArgs.DryRun, "dry-run", false, "print what would be sent without calling the agent")
+}
+
+func replayRun(cmd *cobra.Command, args []string) error {
+	dbPath := replayArgs.DB
+	if dbPath == "" {
+		dbPath = path.Join(os.ExpandEnv("$HOME/.hac"), "indexer.db")
+	}
+	reader, err := agent.OpenIndexReader(dbPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var cli agent.Client = agent.NewNoopClient()
+	if !replayArgs.DryRun {
+		logger := cmtlog.NewTMLogger(cmtlog.NewSyncWriter(os.Stderr))
+		cli, err = agent.NewElizaClient(strings.TrimRight(replayArgs.AgentUrl, "/"), cmtlog.NewFilter(logger, cmtlog.AllowError()))
+		if err != nil {
+			return fmt.Errorf("connect agent: %w", err)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	proposals, discussions := 0, 0
+	after := uint64(0)
+	if replayArgs.From > 0 {
+		after = replayArgs.From - 1
+	}
+	for {
+		batch, err := reader.ProposalsAfter(after, 100)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, p := range batch {
+			if replayArgs.To > 0 && p.Id > replayArgs.To {
+				fmt.Printf("replayed %d proposals and %d discussions\n", proposals, discussions)
+				return nil
+			}
+			if err := ctx.Err(); err != nil {
+				fmt.Printf("interrupted, resume with --from %d\n", p.Id)
+				return err
+			}
+			n, err := replayProposal(ctx, cli, reader, p)
+			if err != nil {
+				fmt.Printf("replay proposal %d failed, resume with --from %d\n", p.Id, p.Id)
+				return err
+			}
+			proposals++
+			discussions += n
+			after = p.Id
+		}
+	}
+	fmt.Printf("replayed %d proposals and %d discussions\n", proposals, discussions)
+	return nil
+}
+
+func replayProposal(ctx context.Context, cli agent.Client, reader *agent.IndexReader, p agent.Proposal) (int, error) {
+	discussions, err := reader.ProposalDiscussions(p.Id)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("proposal %d by %s, %d discussions\n", p.Id, p.ProposerAddress, len(discussions))
+	if replayArgs.DryRun {
+		return len(discussions), nil
+	}
+	if err := cli.AddProposal(ctx, p.Id, p.ProposerAddress, p.Data); err != nil {
+		return 0, err
+	}
+	time.Sleep(replayArgs.Delay)
+	for _, d := range discussions {
+		if err := cli.AddDiscussion(ctx, d.Proposal, d.SpeakerAddress, d.Data); err != nil {
+			return 0, err
+		}
+		time.Sleep(replayArgs.Delay)
+	}
+	return len(discussions), nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/calehh/hac-app/agent"
+	"github.com/spf13/cobra"
+)
+
+type reportArguments struct {
+	DB         string
+	FromHeight uint64
+	ToHeight   uint64
+	Since      string
+	Until      string
+	Format     string
+	Out        string
+}
+
+var reportArgs reportArguments
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "render a governance report",
+	Long:  `Render a markdown or html governance report of the proposals created in a height or date range, with outcomes, vote tallies, notable discussions and validator participation.`,
+	Args:  cobra.NoArgs,
+	RunE:  reportRun,
+}
+
+func init() {
+	reportCmd.Flags().StringVar(&reportArgs.DB, "db", "", "local indexer db path (default $HOME/.hac/indexer.db)")
+	reportCmd.Flags().Uint64Var(&reportArgs.FromHeight, "from-height", 0, "first proposal height included")
+	reportCmd.Flags().Uint64Var(&reportArgs.ToHeight, "to-height", 0, "last proposal height included")
+	reportCmd.Flags().StringVar(&reportArgs.Since, "since", "", "first day included, YYYY-MM-DD")
+	reportCmd.Flags().StringVar(&reportArgs.Until, "until", "", "last day included, YYYY-MM-DD")
+	reportCmd.Flags().StringVarP(&reportArgs.Format, "format", "f", "markdown", "output format, markdown or html")
+	reportCmd.Flags().StringVarP(&reportArgs.Out, "out", "o", "", "output file (default stdout)")
+}
+
+func reportRun(cmd *cobra.Command, args []string) error {
+	rng := agent.ReportRange{
+		FromHeight: reportArgs.FromHeight,
+		ToHeight:   reportArgs.ToHeight,
+	}
+	var err error
+	if reportArgs.Since != "" {
+		if rng.Since, err = time.Parse(time.DateOnly, reportArgs.Since); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if reportArgs.Until != "" {
+		until, err := time.Parse(time.DateOnly, reportArgs.Until)
+		if err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+		rng.Until = until.AddDate(0, 0, 1)
+	}
+
+	dbPath := reportArgs.DB
+	if dbPath == "" {
+		dbPath = path.Join(os.ExpandEnv("$HOME/.hac"), "indexer.db")
+	}
+	reader, err := agent.OpenIndexReader(dbPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	report, err := reader.Report(rng)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if reportArgs.Out != "" {
+		f, err := os.Create(reportArgs.Out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	switch reportArgs.Format {
+	case "markdown", "md":
+		return markdownReport.Execute(out, report)
+	case "html":
+		return htmlReport.Execute(out, report)
+	default:
+		return fmt.Errorf("unknown format %q", reportArgs.Format)
+	}
+}
+
+var reportFuncs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.UTC().Format(time.DateOnly)
+	},
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.0f%%", f*100)
+	},
+	"oneline": func(s string) string {
+		return shorten(strings.ReplaceAll(s, "|", "/"), 200)
+	},
+}
+
+var markdownReport = template.Must(template.New("report").Funcs(reportFuncs).Parse(`# Governance report
+
+Generated {{ .GeneratedAt.Format "2006-01-02 15:04 MST" }}.
+{{- with .Range }}
+Range:{{ if .FromHeight }} from height {{ .FromHeight }}{{ end }}{{ if .ToHeight }} to height {{ .ToHeight }}{{ end }}{{ if not .Since.IsZero }} since {{ date .Since }}{{ end }}{{ if not .Until.IsZero }} before {{ date .Until }}{{ end }}
+{{- end }}
+
+## Outcomes
+
+{{ len .Proposals }} proposals.
+{{ range $status, $count := .StatusCounts }}
+- {{ $status }}: {{ $count }}
+{{- end }}
+
+## Proposals
+
+| ID | Title | Proposer | Status | Draft (pass/reject) | Decision (pass/reject) | Discussions |
+|----|-------|----------|--------|---------------------|------------------------|-------------|
+{{- range .Proposals }}
+| {{ .Proposal.Id }} | {{ oneline .Proposal.Title }} | {{ .Proposal.ProposerName }} | {{ .Proposal.Status }} | {{ .DraftPass }}/{{ .DraftReject }} | {{ .DecisionPass }}/{{ .DecisionReject }} | {{ .Discussions }} |
+{{- end }}
+
+## Notable discussions
+{{ range .Proposals }}{{ if .Notable }}
+### #{{ .Proposal.Id }} {{ oneline .Proposal.Title }}
+{{ range .Notable }}
+> **{{ .SpeakerName }}** (height {{ .Height }}): {{ oneline .Data }}
+{{ end }}{{ end }}{{ end }}
+## Validator participation
+
+| Validator | Address | Proposals voted | Participation | Discussions |
+|-----------|---------|-----------------|---------------|-------------|
+{{- range .Participation }}
+| {{ .Validator.Name }} | {{ .Validator.Address }} | {{ .Voted }} | {{ percent .Rate }} | {{ .Discussions }} |
+{{- end }}
+`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("report").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Governance report</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+blockquote { color: #444; border-left: 3px solid #ccc; margin-left: 0; padding-left: 1em; }
+</style>
+</head>
+<body>
+<h1>Governance report</h1>
+<p>Generated {{ .GeneratedAt.Format "2006-01-02 15:04 MST" }}.
+{{- with .Range }} Range:{{ if .FromHeight }} from height {{ .FromHeight }}{{ end }}{{ if .ToHeight }} to height {{ .ToHeight }}{{ end }}{{ if not .Since.IsZero }} since {{ date .Since }}{{ end }}{{ if not .Until.IsZero }} before {{ date .Until }}{{ end }}{{ end }}</p>
+
+<h2>Outcomes</h2>
+<p>{{ len .Proposals }} proposals.</p>
+<ul>
+{{- range $status, $count := .StatusCounts }}
+<li>{{ $status }}: {{ $count }}</li>
+{{- end }}
+</ul>
+
+<h2>Proposals</h2>
+<table>
+<tr><th>ID</th><th>Title</th><th>Proposer</th><th>Status</th><th>Draft (pass/reject)</th><th>Decision (pass/reject)</th><th>Discussions</th></tr>
+{{- range .Proposals }}
+<tr><td>{{ .Proposal.Id }}</td><td>{{ .Proposal.Title }}</td><td>{{ .Proposal.ProposerName }}</td><td>{{ .Proposal.Status }}</td><td>{{ .DraftPass }}/{{ .DraftReject }}</td><td>{{ .DecisionPass }}/{{ .DecisionReject }}</td><td>{{ .Discussions }}</td></tr>
+{{- end }}
+</table>
+
+<h2>Notable discussions</h2>
+{{- range .Proposals }}{{ if .Notable }}
+<h3>#{{ .Proposal.Id }} {{ .Proposal.Title }}</h3>
+{{- range .Notable }}
+<blockquote><b>{{ .SpeakerName }}</b> (height {{ .Height }}): {{ oneline .Data }}</blockquote>
+{{- end }}{{ end }}{{ end }}
+
+<h2>Validator participation</h2>
+<table>
+<tr><th>Validator</th><th>Address</th><th>Proposals voted</th><th>Participation</th><th>Discussions</th></tr>
+{{- range .Participation }}
+<tr><td>{{ .Validator.Name }}</td><td>{{ .Validator.Address }}</td><td>{{ .Voted }}</td><td>{{ percent .Rate }}</td><td>{{ .Discussions }}</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/calehh/hac-app/agent"
+	"github.com/calehh/hac-app/crypto"
+	"github.com/calehh/hac-app/tx"
+	hac_types "github.com/calehh/hac-app/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/rpc/client/http"
+	"github.com/spf13/cobra"
+)
+
+type seedArguments struct {
+	Url         string
+	Skey        string
+	Proposals   int
+	Discussions int
+	Grants      int
+	Amount      uint64
+}
+
+var seedArgs seedArguments
+
+var seedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "submit sample governance data to a local devnet",
+	Long: `Submit sample proposals, discussions and grant requests to a devnet, waiting for every tx to be committed,
+so frontends and agents have realistic data to work with. Do not run it against a public network.`,
+	Args: cobra.NoArgs,
+	RunE: seedRun,
+}
+
+func init() {
+	urlFlag(seedCmd, &seedArgs.Url)
+	seedCmd.Flags().StringVarP(&seedArgs.Skey, "skeyPath", "s", "./config/priv_validator_key.json", "private key path")
+	seedCmd.Flags().IntVar(&seedArgs.Proposals, "proposals", 5, "number of proposals")
+	seedCmd.Flags().IntVar(&seedArgs.Discussions, "discussions", 3, "number of discussions per proposal")
+	seedCmd.Flags().IntVar(&seedArgs.Grants, "grants", 2, "number of grant requests")
+	seedCmd.Flags().Uint64Var(&seedArgs.Amount, "amount", 1000000000, "stake of every granted member")
+}
+
+var seedProposals = []agent.ProposalEnvelope{
+	{Title: "Fund a community hackathon", Summary: "Allocate budget for a two-day online hackathon around agent tooling.", Body: "Prizes, judging and infrastructure costs for a hackathon focused on agent plugins."},
+	{Title: "Reduce the discussion window", Summary: "Shorten the time proposals stay open for discussion.", Body: "Most discussions settle quickly, a shorter window speeds up decisions."},
+	{Title: "Publish monthly governance reports", Summary: "Share a report of proposals, votes and participation every month.", Body: "Reports make governance visible to the community and to new validators."},
+	{Title: "Add a second RPC provider", Summary: "Run indexers against two independent RPC endpoints.", Body: "A second provider lets indexers detect inconsistent block results."},
+	{Title: "Open a validator onboarding program", Summary: "Mentor new validators through their first month.", Body: "Pair new validators with experienced operators and document the setup."},
+	{Title: "Archive settled proposals", Summary: "Move proposals settled more than a year ago to cold storage.", Body: "Keeps the indexer database small while preserving history."},
+}
+
+var seedDiscussions = []string{
+	"I support this, the cost is small compared to the benefit.",
+	"Could we start with a smaller pilot and revisit after a month?",
+	"What happens if the budget is not fully used?",
+	"This aligns with the manifesto, count me in.",
+	"I am concerned about the maintenance burden this adds.",
+	"Can the proposer share more details on the timeline?",
+}
+
+func seedRun(cmd *cobra.Command, args []string) error {
+	cli, err := http.New(seedArgs.Url, "/websocket")
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	submitter, err := agent.NewTxSubmitter(ctx, cli, crypto.LoadFilePV(seedArgs.Skey))
+	if err != nil {
+		return err
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	proposals, discussions, grants := 0, 0, 0
+	for i := 0; i < seedArgs.Proposals; i++ {
+		env := seedProposals[i%len(seedProposals)]
+		data, _ := json.Marshal(env)
+		res, err := submitter.SubmitCommit(ctx, tx.HACTxTypeProposal, &tx.ProposalTx{
+			Proposer:  submitter.Index(),
+			EndHeight: 1000000,
+			Title:     env.Title,
+			Data:      data,
+		})
+		if err != nil {
+			fmt.Printf("proposal %q failed: %v\n", env.Title, err)
+			continue
+		}
+		proposals++
+		var proposal uint64
+		for _, ev := range res.TxResult.Events {
+			if ev.Type != hac_types.EventProposalType {
+				continue
+			}
+			if p := hac_types.DecodeEventProposal(ev); p != nil {
+				proposal = p.ProposalIndex
+			}
+		}
+		fmt.Printf("proposal %d %q at height %d\n", proposal, env.Title, res.Height)
+		if proposal == 0 {
+			continue
+		}
+		for j := 0; j < seedArgs.Discussions; j++ {
+			text := seedDiscussions[rnd.Intn(len(seedDiscussions))]
+			if _, err := submitter.SubmitCommit(ctx, tx.HACTxTypeDiscussion, &tx.DiscussionTx{
+				Proposal: proposal,
+				Data:     []byte(text),
+			}); err != nil {
+				fmt.Printf("discussion on proposal %d failed: %v\n", proposal, err)
+				continue
+			}
+			discussions++
+		}
+	}
+
+	for i := 0; i < seedArgs.Grants; i++ {
+		pubkey := ed25519.GenPrivKey().PubKey().Bytes()
+		name := fmt.Sprintf("seed-agent-%d", rnd.Intn(100000))
+		res, err := submitter.SubmitCommit(ctx, tx.HACTxTypeGrant, &tx.GrantTx{
+			Grants: []tx.GrantSt{{
+				Statement: fmt.Sprintf("%s would like to join the network as a validator.", name),
+				Amount:    seedArgs.Amount,
+				AgentUrl:  hac_types.DefaultAgentUrl,
+				Name:      name,
+				Pubkey:    pubkey,
+			}},
+		})
+		if err != nil {
+			fmt.Printf("grant request %s failed: %v\n", name, err)
+			continue
+		}
+		grants++
+		fmt.Printf("grant request %s at height %d\n", name, res.Height)
+	}
+	fmt.Printf("seeded %d proposals, %d discussions and %d grant requests\n", proposals, discussions, grants)
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/calehh/hac-app/agent"
+	"github.com/spf13/cobra"
+)
+
+type exportSnapshotArguments struct {
+	DB       string
+	Out      string
+	Space    string
+	Proposal uint64
+}
+
+var exportSnapshotArgs exportSnapshotArguments
+
+var exportSnapshotCmd = &cobra.Command{
+	Use:   "export-snapshot",
+	Short: "export proposals and votes in the Snapshot format",
+	Long: `Write one json package per proposal with the proposal, the votes with their voting power and the result,
+following the proposal and vote objects of the Snapshot hub api, so results can be mirrored to off-chain governance UIs.`,
+	Args: cobra.NoArgs,
+	RunE: exportSnapshotRun,
+}
+
+func init() {
+	exportSnapshotCmd.Flags().StringVar(&exportSnapshotArgs.DB, "db", "", "local indexer db path (default $HOME/.hac/indexer.db)")
+	exportSnapshotCmd.Flags().StringVarP(&exportSnapshotArgs.Out, "out", "o", "snapshot", "output directory")
+	exportSnapshotCmd.Flags().StringVar(&exportSnapshotArgs.Space, "space", "hac", "snapshot space id")
+	exportSnapshotCmd.Flags().Uint64Var(&exportSnapshotArgs.Proposal, "proposal", 0, "export only this proposal")
+}
+
+func exportSnapshotRun(cmd *cobra.Command, args []string) error {
+	dbPath := exportSnapshotArgs.DB
+	if dbPath == "" {
+		dbPath = path.Join(os.ExpandEnv("$HOME/.hac"), "indexer.db")
+	}
+	reader, err := agent.OpenIndexReader(dbPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if err := os.MkdirAll(exportSnapshotArgs.Out, 0o755); err != nil {
+		return err
+	}
+
+	if exportSnapshotArgs.Proposal != 0 {
+		return exportSnapshot(reader, exportSnapshotArgs.Proposal)
+	}
+	exported := 0
+	var after uint64
+	for {
+		proposals, err := reader.ProposalsAfter(after, 100)
+		if err != nil {
+			return err
+		}
+		if len(proposals) == 0 {
+			break
+		}
+		for _, p := range proposals {
+			if err := exportSnapshot(reader, p.Id); err != nil {
+				return err
+			}
+			exported++
+			after = p.Id
+		}
+	}
+	fmt.Printf("exported %d proposals to %s\n", exported, exportSnapshotArgs.Out)
+	return nil
+}
+
+func exportSnapshot(reader *agent.IndexReader, id uint64) error {
+	pkg, err := reader.SnapshotExport(id, exportSnapshotArgs.Space)
+	if err != nil {
+		return fmt.Errorf("proposal %d: %w", id, err)
+	}
+	data, err := json.MarshalIndent(pkg, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(exportSnapshotArgs.Out, fmt.Sprintf("proposal-%d.json", id))
+	return os.WriteFile(file, data, 0o644)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+
+	"github.com/calehh/hac-app/agent"
+	"github.com/spf13/cobra"
+)
+
+type syncDBArguments struct {
+	Peer   string
+	ApiKey string
+	DB     string
+}
+
+var syncDBArgs syncDBArguments
+
+var syncDBCmd = &cobra.Command{
+	Use:   "sync-db",
+	Short: "fill a fresh indexer db from a trusted peer indexer",
+	Long: `Fetch the indexed tables and the indexed height of a trusted peer indexer over its api instead of
+re-indexing the chain. Every batch and table is checked against the hashes sent by the peer.
+Run it before the first start of the node; the node then indexes from the height after the peer's.
+The peer must run the same release and api keys must give the admin scope.`,
+	Args: cobra.NoArgs,
+	RunE: syncDBRun,
+}
+
+func init() {
+	syncDBCmd.Flags().StringVar(&syncDBArgs.Peer, "peer", "", "api address of the peer indexer, e.g. http://10.0.0.2:8631")
+	syncDBCmd.Flags().StringVar(&syncDBArgs.ApiKey, "api-key", "", "admin api key of the peer")
+	syncDBCmd.Flags().StringVar(&syncDBArgs.DB, "db", "", "indexer db path or postgres url (default $HOME/.hac/indexer.db)")
+}
+
+func syncDBRun(cmd *cobra.Command, args []string) error {
+	if syncDBArgs.Peer == "" {
+		return fmt.Errorf("--peer is required")
+	}
+	db := syncDBArgs.DB
+	if db == "" {
+		db = path.Join(os.ExpandEnv("$HOME/.hac"), "indexer.db")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	height, err := agent.SyncIndexDB(ctx, syncDBArgs.Peer, syncDBArgs.ApiKey, db, func(r agent.SyncReport) {
+		if r.Done {
+			fmt.Printf("%-24s done, %d rows\n", r.Table, r.Copied)
+			return
+		}
+		fmt.Printf("%-24s %d\n", r.Table, r.Copied)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Println("interrupted, run the command again to restart the sync")
+		}
+		return err
+	}
+	fmt.Printf("sync finished at height %d, the node indexes from height %d\n", height, height+1)
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// errTailOutput stops tail when stdout is closed, e.g. by head.
+var errTailOutput = errors.New("write output")
+
+type tailArguments struct {
+	Api    string
+	ApiKey string
+	Type   string
+}
+
+var tailArgs tailArguments
+
+var tailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "stream newly indexed events as json lines",
+	Long:  `Follow the indexer api and print every newly indexed event as one json line, e.g. hac-cl tail | jq .`,
+	Args:  cobra.NoArgs,
+	RunE:  tailRun,
+}
+
+func init() {
+	tailCmd.Flags().StringVar(&tailArgs.Api, "api", "http://127.0.0.1:8631", "indexer api address")
+	tailCmd.Flags().StringVar(&tailArgs.ApiKey, "api-key", "", "api key of the indexer, when it requires one")
+	tailCmd.Flags().StringVarP(&tailArgs.Type, "type", "t", "", "only print events of this type")
+}
+
+func tailRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	endpoint := strings.TrimRight(tailArgs.Api, "/") + "/api/events/stream"
+	if tailArgs.Type != "" {
+		endpoint += "?type=" + url.QueryEscape(tailArgs.Type)
+	}
+	out := bufio.NewWriter(os.Stdout)
+	for {
+		err := tailStream(ctx, endpoint, out)
+		if ctx.Err() != nil || errors.Is(err, errTailOutput) {
+			return nil
+		}
+		// the node restarted or the connection dropped, follow it again
+		fmt.Fprintf(os.Stderr, "stream closed: %v, reconnecting\n", err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(3 * time.Second):
+		}
+	}
+}
+
+func tailStream(ctx context.Context, endpoint string, out *bufio.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if tailArgs.ApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+tailArgs.ApiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		out.Write(scanner.Bytes())
+		out.WriteByte('\n')
+		if err := out.Flush(); err != nil {
+			return fmt.Errorf("%w: %v", errTailOutput, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("end of stream")
+}
@@ -2,40 +2,48 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/calehh/hac-app/agent"
+	"github.com/calehh/hac-app/version"
 	"github.com/spf13/cobra"
 )
 
-var (
-	GitCommit string
-)
-
-const (
-	VersionMajor = 0
-	VersionMinor = 0
-	VersionPatch = 1
-)
-
-var Version = func() string {
-	return fmt.Sprintf("%d.%d.%d", VersionMajor, VersionMinor, VersionPatch)
-}()
-
-func VersionWithCommit(gitCommit string) string {
-	vsn := Version
-	if len(gitCommit) >= 8 {
-		vsn += "-" + gitCommit[:8]
-	}
-	return vsn
-}
+var versionJson bool
 
 var versionCmd = &cobra.Command{
 	Use:     "version",
-	Short:   "",
-	Long:    ``,
+	Short:   "print version and build information",
+	Long:    `Print the version, git commit and build date of the binary together with the indexer schema version and the indexed event types.`,
 	Aliases: []string{"V"},
-	Run:     versionRun,
+	RunE:    versionRun,
 }
 
-func versionRun(cmd *cobra.Command, args []string) {
-	println(VersionWithCommit(GitCommit))
+func init() {
+	versionCmd.Flags().BoolVar(&versionJson, "json", false, "print json")
+}
+
+func versionRun(cmd *cobra.Command, args []string) error {
+	info := version.Get()
+	if versionJson {
+		return printJson(struct {
+			version.Info
+			SchemaVersion uint64   `json:"schemaVersion"`
+			EventTypes    []string `json:"eventTypes"`
+		}{info, agent.SchemaVersionLatest(), agent.SupportedEventTypes()})
+	}
+	fmt.Println(version.WithCommit())
+	fmt.Printf("git commit:     %s\n", orUnknown(info.GitCommit))
+	fmt.Printf("build date:     %s\n", orUnknown(info.BuildDate))
+	fmt.Printf("go version:     %s\n", info.GoVersion)
+	fmt.Printf("schema version: %d\n", agent.SchemaVersionLatest())
+	fmt.Printf("event types:    %s\n", strings.Join(agent.SupportedEventTypes(), ", "))
+	return nil
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
 }
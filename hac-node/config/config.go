@@ -15,17 +15,194 @@ import (
 )
 
 type HACAppConfig struct {
-	Home           string `mapstructure:"-"`
-	TimeoutCommit  uint64 `mapstructure:"-"`
-	AgentUrl       string `mapstructure:"agent_url"`
-	ServiceAddress string `mapstructure:"service_address"`
-	DiscussionRate int    `mapstructure:"discussion_rate"`
+	Home                  string   `mapstructure:"-"`
+	TimeoutCommit         uint64   `mapstructure:"-"`
+	AgentUrl              string   `mapstructure:"agent_url"`
+	AgentBackend          string   `mapstructure:"agent_backend"`
+	AgentId               string   `mapstructure:"agent_id"`
+	AgentRefresh          int      `mapstructure:"agent_refresh"`
+	AgentModel            string   `mapstructure:"agent_model"`
+	AgentApiKey           string   `mapstructure:"agent_api_key"`
+	AgentTemperature      float64  `mapstructure:"agent_temperature"`
+	AgentSeed             int64    `mapstructure:"agent_seed"`
+	AgentRouting          bool     `mapstructure:"agent_routing"`
+	AgentHttpTimeout      int      `mapstructure:"agent_http_timeout"`
+	AgentConnectTimeout   int      `mapstructure:"agent_connect_timeout"`
+	AgentReadTimeout      int      `mapstructure:"agent_read_timeout"`
+	AgentKeepAlive        int      `mapstructure:"agent_keepalive"`
+	AgentProxy            string   `mapstructure:"agent_proxy"`
+	AgentTLSCA            string   `mapstructure:"agent_tls_ca"`
+	AgentTLSCert          string   `mapstructure:"agent_tls_cert"`
+	AgentTLSKey           string   `mapstructure:"agent_tls_key"`
+	AgentTLSServerName    string   `mapstructure:"agent_tls_server_name"`
+	AgentRetryAttempts    int      `mapstructure:"agent_retry_attempts"`
+	AgentRetryBackoff     int      `mapstructure:"agent_retry_backoff"`
+	AgentRetryMaxBackoff  int      `mapstructure:"agent_retry_max_backoff"`
+	AgentRetryStatuses    []int    `mapstructure:"agent_retry_statuses"`
+	AgentAuthHeader       string   `mapstructure:"agent_auth_header"`
+	AgentAuthToken        string   `mapstructure:"agent_auth_token"`
+	AgentAuthTokenEnv     string   `mapstructure:"agent_auth_token_env"`
+	AgentAuthTokenFile    string   `mapstructure:"agent_auth_token_file"`
+	AgentVotePubKey       string   `mapstructure:"agent_vote_pubkey"`
+	AgentRateLimit        float64  `mapstructure:"agent_rate_limit"`
+	AgentRateBurst        int      `mapstructure:"agent_rate_burst"`
+	AgentFailoverUrls     []string `mapstructure:"agent_failover_urls"`
+	AgentFailbackInterval int      `mapstructure:"agent_failback_interval"`
+	AgentHealthInterval   int      `mapstructure:"agent_health_interval"`
+	AgentHealthTimeout    int      `mapstructure:"agent_health_timeout"`
+	AgentBreakerFailures  int      `mapstructure:"agent_breaker_failures"`
+	AgentBreakerCooldown  int      `mapstructure:"agent_breaker_cooldown"`
+	AgentBreakerFallback  string   `mapstructure:"agent_breaker_fallback"`
+	DecisionCache         bool     `mapstructure:"decision_cache"`
+	DecisionCacheTTL      int      `mapstructure:"decision_cache_ttl"`
+	ServiceAddress        string   `mapstructure:"service_address"`
+	DiscussionRate        int      `mapstructure:"discussion_rate"`
+	PeerDiscussionLimit   int      `mapstructure:"peer_discussion_limit"`
+	DiscussionHistory     int      `mapstructure:"discussion_history_limit"`
+	ProposalSummaryBytes  int      `mapstructure:"proposal_summary_threshold"`
+	ProposalChunkBytes    int      `mapstructure:"proposal_chunk_size"`
+	IndexOnly             bool     `mapstructure:"index_only"`
+	VoteDryRun            bool     `mapstructure:"vote_dry_run"`
+	VoteDryRunPolicy      string   `mapstructure:"vote_dry_run_policy"`
+	VoteTimeout           int      `mapstructure:"vote_timeout"`
+	VoteTimeoutDecision   string   `mapstructure:"vote_timeout_decision"`
+	VoteMinConfidence     float64  `mapstructure:"vote_min_confidence"`
+	AgentPush             bool     `mapstructure:"agent_push"`
+	AgentPushUrl          string   `mapstructure:"agent_push_url"`
+	AgentPushCallback     string   `mapstructure:"agent_push_callback"`
+	AgentPushSecret       string   `mapstructure:"agent_push_secret"`
+	AgentPushDeadline     int      `mapstructure:"agent_push_deadline"`
+	AgentPushWait         int      `mapstructure:"agent_push_wait"`
+	AgentEnsembleMode     string   `mapstructure:"agent_ensemble_mode"`
+	DatabaseUrl           string   `mapstructure:"database_url"`
+	DatabaseReplicaUrls   []string `mapstructure:"database_replica_urls"`
+	VerifyRpcUrl          string   `mapstructure:"verify_rpc_url"`
+	VerifyRpcStrict       bool     `mapstructure:"verify_rpc_strict"`
+	StartHeight           int64    `mapstructure:"start_height"`
+	ImportState           bool     `mapstructure:"import_state"`
+	AuditInterval         int      `mapstructure:"audit_interval"`
+	AuditSample           int      `mapstructure:"audit_sample"`
+	AuditRepair           bool     `mapstructure:"audit_repair"`
+	MaintenanceInterval   int      `mapstructure:"maintenance_interval"`
+	SocialPlatform        string   `mapstructure:"social_platform"`
+	SocialToken           string   `mapstructure:"social_token"`
+	SocialSigner          string   `mapstructure:"social_signer"`
+	SocialApiUrl          string   `mapstructure:"social_api_url"`
+	IpfsApiUrl            string   `mapstructure:"ipfs_api_url"`
+	IpfsToken             string   `mapstructure:"ipfs_token"`
+	BridgeRpcUrl          string   `mapstructure:"bridge_rpc_url"`
+	BridgeContract        string   `mapstructure:"bridge_contract"`
+	BridgeKeyFile         string   `mapstructure:"bridge_key_file"`
+	ProposalWebhookSecret string   `mapstructure:"proposal_webhook_secret"`
+	SmtpAddr              string   `mapstructure:"smtp_addr"`
+	SmtpUsername          string   `mapstructure:"smtp_username"`
+	SmtpPassword          string   `mapstructure:"smtp_password"`
+	SmtpFrom              string   `mapstructure:"smtp_from"`
+	DigestTemplate        string   `mapstructure:"digest_template"`
+	EventBus              string   `mapstructure:"event_bus"`
+	EventBusUrl           string   `mapstructure:"event_bus_url"`
+	EventBusTopicPrefix   string   `mapstructure:"event_bus_topic_prefix"`
+	ApiAdminKey           string   `mapstructure:"api_admin_key"`
+	ApiRequireKey         bool     `mapstructure:"api_require_key"`
+	LeaderElection        bool     `mapstructure:"leader_election"`
+	LeaderLeaseUrl        string   `mapstructure:"leader_lease_url"`
+	LeaderLeaseTtl        int      `mapstructure:"leader_lease_ttl"`
+	InstanceId            string   `mapstructure:"instance_id"`
+	AgentQueueWorkers     int      `mapstructure:"agent_queue_workers"`
+	AgentQueueMaxDepth    int      `mapstructure:"agent_queue_max_depth"`
+	AgentQueueOverflow    string   `mapstructure:"agent_queue_overflow"`
+	ProposalScoring       bool     `mapstructure:"proposal_scoring"`
+	ScoringThreshold      float64  `mapstructure:"scoring_threshold"`
+	ScoringBudgetCap      float64  `mapstructure:"scoring_budget_cap"`
+	AgentTranscripts      bool     `mapstructure:"agent_transcripts"`
+	TranscriptMaxBytes    int      `mapstructure:"transcript_max_bytes"`
+	TranscriptRedact      []string `mapstructure:"transcript_redact"`
+	AnchorInterval        int      `mapstructure:"decision_anchor_interval"`
+	AgentProposeInterval  int      `mapstructure:"agent_propose_interval"`
+	ActivationNotice      int      `mapstructure:"activation_notice_blocks"`
+	ExpeditedWindow       int      `mapstructure:"expedited_window_hours"`
+	TreasuryBalance       float64  `mapstructure:"treasury_balance"`
+	UptimeWindow          int      `mapstructure:"uptime_window_blocks"`
+	DebateRounds          int      `mapstructure:"debate_rounds"`
+	DebateRoundBlocks     int      `mapstructure:"debate_round_blocks"`
+	CommentInterval       int      `mapstructure:"comment_interval_blocks"`
+	CommentOnDiscussion   bool     `mapstructure:"comment_on_discussion"`
+	CommentMinGap         int      `mapstructure:"comment_min_gap_blocks"`
+	CommentMaxFollowUps   int      `mapstructure:"comment_max_followups"`
+
+	AgentPriorities map[string]int     `mapstructure:"agent_priorities"`
+	AgentPrompts    map[string]string  `mapstructure:"agent_prompts"`
+	ScoringWeights  map[string]float64 `mapstructure:"scoring_weights"`
+
+	Notifiers     []NotifierConfig       `mapstructure:"notifiers"`
+	AgentEnsemble []EnsembleMemberConfig `mapstructure:"agent_ensemble"`
+}
+
+// EnsembleMemberConfig is one agent of the ensemble voting instead of the
+// single agent_backend. Backend and the other fields are the ones of the
+// agent_ settings, Weight counts in weighted mode and defaults to 1.
+type EnsembleMemberConfig struct {
+	Name        string            `mapstructure:"name"`
+	Backend     string            `mapstructure:"backend"`
+	Url         string            `mapstructure:"url"`
+	AgentId     string            `mapstructure:"agent_id"`
+	Model       string            `mapstructure:"model"`
+	ApiKey      string            `mapstructure:"api_key"`
+	Temperature float64           `mapstructure:"temperature"`
+	Seed        int64             `mapstructure:"seed"`
+	Prompts     map[string]string `mapstructure:"prompts"`
+	Weight      float64           `mapstructure:"weight"`
+}
+
+// NotifierConfig is one chat channel receiving governance notifications.
+// Type is discord, slack or telegram. Discord and slack take an incoming
+// webhook Url, telegram a bot Token and ChatId. Events filters the events
+// posted, all when empty, and Templates overrides the message template of
+// an event.
+type NotifierConfig struct {
+	Type      string            `mapstructure:"type"`
+	Url       string            `mapstructure:"url"`
+	Token     string            `mapstructure:"token"`
+	ChatId    string            `mapstructure:"chat_id"`
+	Events    []string          `mapstructure:"events"`
+	Templates map[string]string `mapstructure:"templates"`
 }
 
 func DefaultHACAppConfig(home string) *HACAppConfig {
 	return &HACAppConfig{
-		Home:     home,
-		AgentUrl: "http://127.0.0.1:3000",
+		Home:                  home,
+		AgentUrl:              "http://127.0.0.1:3000",
+		AgentBackend:          "eliza",
+		AgentTemperature:      0.7,
+		AgentConnectTimeout:   10,
+		AgentKeepAlive:        30,
+		AgentRetryAttempts:    3,
+		AgentRetryBackoff:     200,
+		AgentRetryMaxBackoff:  5000,
+		AgentRateBurst:        10,
+		AgentFailbackInterval: 30,
+		AgentHealthTimeout:    5,
+		AgentBreakerCooldown:  30,
+		AgentBreakerFallback:  "abstain",
+		PeerDiscussionLimit:   5,
+		DiscussionHistory:     20,
+		ProposalSummaryBytes:  16384,
+		ProposalChunkBytes:    8192,
+		AuditSample:           20,
+		VoteDryRunPolicy:      "reject",
+		VoteTimeoutDecision:   "no",
+		AgentPushDeadline:     600,
+		AgentPushWait:         2,
+		AgentEnsembleMode:     "majority",
+		AgentQueueWorkers:     8,
+		AgentQueueOverflow:    "block",
+		ScoringThreshold:      0.5,
+		ActivationNotice:      20,
+		ExpeditedWindow:       72,
+		UptimeWindow:          1000,
+		DebateRoundBlocks:     10,
+		CommentMinGap:         5,
+		CommentMaxFollowUps:   5,
 	}
 
 }
@@ -3,6 +3,7 @@ package state
 import (
 	"sync"
 
+	hac_types "github.com/calehh/hac-app/types"
 	cmtlog "github.com/cometbft/cometbft/libs/log"
 	"github.com/cosmos/iavl"
 	dbm "github.com/cosmos/iavl/db"
@@ -112,3 +113,15 @@ func (db *StateDB) GetAccountByAddress(addr []byte) (acnt *Account, height uint6
 
 	return
 }
+
+func (db *StateDB) GetProposalByIndex(idx uint64) (proposal *hac_types.Proposal, height uint64, err error) {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+	proposal, err = db.state.getProposal(idx)
+	if err != nil {
+		return
+	}
+	height = db.state.header.Height
+
+	return
+}
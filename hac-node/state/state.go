@@ -71,6 +71,7 @@ var (
 	ErrTxMoreThanOneProposal        = errors.New("more than one proposal")
 	ErrTxVoteCodeInvalid            = errors.New("vote code invalid")
 	ErrOneActionInOneBlock          = errors.New("one action in one block")
+	ErrTxAnchorInvalid              = errors.New("anchor root must be 32 bytes over at least one leaf")
 )
 
 type State struct {
@@ -710,6 +711,42 @@ func (s *State) Dicussion(tx *tx.DiscussionTx, validator uint64, checkOnly bool)
 	return
 }
 
+func (s *State) Anchor(tx *tx.AnchorTx, validator uint64, checkOnly bool) (event *hac_types.EventAnchor, err error) {
+	s.logger.Debug("apply anchor", "validator", validator, "height", s.header.Height)
+	a, err := s.GetAccount(validator)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		err = ErrTxValidatorNoexists
+		return
+	}
+	if a.Stake == 0 {
+		err = ErrTxNotMembership
+		return
+	}
+	if len(tx.Root) != 32 || tx.Leaves == 0 {
+		err = ErrTxAnchorInvalid
+		return
+	}
+
+	if !checkOnly {
+		a.Nonce += 1
+		v := s.modifiedAcnts[a.Index]
+		v |= ModifiedFlagMod
+		s.modifiedAcnts[a.Index] = v
+		s.acnts[a.Index] = a.Clone()
+
+		event = &hac_types.EventAnchor{
+			Validator: a.Index,
+			Address:   a.Address(),
+			Root:      tx.Root,
+			Leaves:    tx.Leaves,
+		}
+	}
+	return
+}
+
 func (s *State) Grant(proposer uint64, pk []byte, amount uint64, agentUrl, name string, code tx.VoteCode) (event *hac_types.EventGrant, err error) {
 	if code != txtypes.VoteGrantNewMember && code != txtypes.VoteRejectNewMember {
 		return nil, ErrTxVoteCodeInvalid
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/calehh/hac-app/state"
+	"github.com/calehh/hac-app/tx"
+	"github.com/calehh/hac-app/types"
+	abcitypes "github.com/cometbft/cometbft/abci/types"
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+type AnchorTxHandler struct {
+	logger cmtlog.Logger
+}
+
+func NewAnchorTxHandler(logger cmtlog.Logger) (h *AnchorTxHandler) {
+	logger = logger.With("module", "anchorTx")
+	h = &AnchorTxHandler{
+		logger: logger,
+	}
+	return
+}
+
+func (h *AnchorTxHandler) Check(ctx context.Context, st *state.State, btx *tx.HACTx) (res *abcitypes.ResponseCheckTx, err error) {
+	res = &abcitypes.ResponseCheckTx{Code: 0}
+	atx := btx.Tx.(*tx.AnchorTx)
+	_, err1 := st.Anchor(atx, btx.Validator, true)
+	if err1 != nil {
+		h.logger.Info("CheckTx anchor fail", "err", err1)
+		res.Code = 1
+		res.Log = err1.Error()
+	}
+	return
+}
+
+func (h *AnchorTxHandler) NewContext(ctx context.Context) {}
+
+func (h *AnchorTxHandler) handle(ctx context.Context, st *state.State, btx *tx.HACTx) (res *abcitypes.ExecTxResult, err error) {
+	atx := btx.Tx.(*tx.AnchorTx)
+	event, err := st.Anchor(atx, btx.Validator, false)
+	if err != nil {
+		return nil, err
+	}
+	res = &abcitypes.ExecTxResult{}
+	if event != nil {
+		res.Events = []abcitypes.Event{types.EncodeEventAnchor(event)}
+	}
+	return
+}
+
+func (h *AnchorTxHandler) Prepare(ctx context.Context, st *state.State, btx *tx.HACTx, code tx.VoteCode) (res *abcitypes.ExecTxResult, err error) {
+	return h.handle(ctx, st, btx)
+}
+
+func (h *AnchorTxHandler) Process(ctx context.Context, st *state.State, btx *tx.HACTx, code tx.VoteCode) (res *abcitypes.ExecTxResult, err error) {
+	return h.handle(ctx, st, btx)
+}
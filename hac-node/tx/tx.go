@@ -56,6 +56,13 @@ type RetractTx struct {
 	Amount uint64 `json:"amount"`
 }
 
+// AnchorTx records the merkle root of Leaves entries of a node's decision
+// log on-chain, proving the entries existed at the height it is included.
+type AnchorTx struct {
+	Root   []byte `json:"root"`
+	Leaves uint64 `json:"leaves"`
+}
+
 type hacTxTmpl[Tx any] struct {
 	Version   uint8     `json:"version"`
 	Type      HACTxType `json:"type"`
@@ -112,6 +119,8 @@ func UnmarshalHACTx(dat []byte) (btx *HACTx, err error) {
 		return unmarshalHACTx[RetractTx](dat)
 	case HACTxTypeSettleProposal:
 		return unmarshalHACTx[SettleProposalTx](dat)
+	case HACTxTypeAnchor:
+		return unmarshalHACTx[AnchorTx](dat)
 	default:
 		err = ErrUnsupportedTxType
 	}
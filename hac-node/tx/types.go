@@ -28,6 +28,7 @@ const (
 	HACTxTypeGrant          HACTxType = 3
 	HACTxTypeRetract        HACTxType = 4
 	HACTxTypeSettleProposal HACTxType = 5
+	HACTxTypeAnchor         HACTxType = 6
 
 	HACTxTypeGeneric HACTxType = 255
 )
@@ -0,0 +1,194 @@
+// Package txbuilder constructs, signs and broadcasts HAC transactions from
+// the keys of a validator, so agents and tools can act on-chain: propose,
+// discuss and ask for grants.
+package txbuilder
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/calehh/hac-app/state"
+	"github.com/calehh/hac-app/tx"
+	comethttp "github.com/cometbft/cometbft/rpc/client/http"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+)
+
+// ProposalEndHeight is the end height of the proposals built without one.
+const ProposalEndHeight = 1000000
+
+// Signer signs txs for an account. It is implemented by crypto.PV and by the
+// keys of a Keystore.
+type Signer interface {
+	Address() string
+	PublicKey() []byte
+	Sign(data []byte) ([]byte, error)
+}
+
+// Builder signs and broadcasts txs for one account, keeping track of its
+// nonce between submissions.
+type Builder struct {
+	mtx     sync.Mutex
+	cli     *comethttp.HTTP
+	signer  Signer
+	chainId string
+	index   uint64
+	nonce   uint64
+}
+
+// New loads the chain id and the account of signer from the chain.
+func New(ctx context.Context, cli *comethttp.HTTP, signer Signer) (*Builder, error) {
+	gres, err := cli.Genesis(ctx)
+	if err != nil {
+		return nil, err
+	}
+	act, err := QueryAccount(ctx, cli, 0, signer.Address())
+	if err != nil {
+		return nil, fmt.Errorf("query account %s: %w", signer.Address(), err)
+	}
+	return &Builder{
+		cli:     cli,
+		signer:  signer,
+		chainId: gres.Genesis.ChainID,
+		index:   act.Index,
+		nonce:   act.Nonce,
+	}, nil
+}
+
+// Index is the account index txs are submitted from.
+func (b *Builder) Index() uint64 {
+	return b.index
+}
+
+// Build returns the unsigned tx of body from the account at its next nonce.
+func (b *Builder) Build(typ tx.HACTxType, body any) *tx.HACTx {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.build(typ, body)
+}
+
+func (b *Builder) build(typ tx.HACTxType, body any) *tx.HACTx {
+	return &tx.HACTx{
+		Version:   tx.HACTxVersion1,
+		Type:      typ,
+		Nonce:     b.nonce,
+		Validator: b.index,
+		Tx:        body,
+	}
+}
+
+// Sign signs btx for the chain and returns it encoded for broadcasting.
+func (b *Builder) Sign(btx *tx.HACTx) ([]byte, error) {
+	dat, err := btx.SigData([]byte(b.chainId))
+	if err != nil {
+		return nil, err
+	}
+	sig, err := b.signer.Sign(dat)
+	if err != nil {
+		return nil, err
+	}
+	btx.Sig = [][]byte{sig}
+	return json.Marshal(btx)
+}
+
+// Submit broadcasts a tx and returns once it passed CheckTx.
+func (b *Builder) Submit(ctx context.Context, typ tx.HACTxType, body any) (*coretypes.ResultBroadcastTx, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	dat, err := b.Sign(b.build(typ, body))
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.cli.BroadcastTxSync(ctx, dat)
+	if err != nil {
+		return nil, err
+	}
+	if res.Code != 0 {
+		return res, fmt.Errorf("check tx code %d: %s", res.Code, res.Log)
+	}
+	b.nonce++
+	return res, nil
+}
+
+// SubmitCommit broadcasts a tx and waits until it is included in a block.
+func (b *Builder) SubmitCommit(ctx context.Context, typ tx.HACTxType, body any) (*coretypes.ResultBroadcastTxCommit, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	dat, err := b.Sign(b.build(typ, body))
+	if err != nil {
+		return nil, err
+	}
+	res, err := b.cli.BroadcastTxCommit(ctx, dat)
+	if err != nil {
+		return nil, err
+	}
+	if res.CheckTx.Code != 0 {
+		return res, fmt.Errorf("check tx code %d: %s", res.CheckTx.Code, res.CheckTx.Log)
+	}
+	if res.TxResult.Code != 0 {
+		// whether a failed tx used its nonce is up to the handler, ask the chain
+		if act, err := QueryAccount(ctx, b.cli, b.index, ""); err == nil {
+			b.nonce = act.Nonce
+		}
+		return res, fmt.Errorf("tx code %d: %s", res.TxResult.Code, res.TxResult.Log)
+	}
+	b.nonce++
+	return res, nil
+}
+
+// Propose submits a proposal with data, the envelope of its title and body,
+// and waits for the block including it.
+func (b *Builder) Propose(ctx context.Context, title string, link string, imageUrl string, data []byte) (*coretypes.ResultBroadcastTxCommit, error) {
+	return b.SubmitCommit(ctx, tx.HACTxTypeProposal, &tx.ProposalTx{
+		Proposer:  b.index,
+		EndHeight: ProposalEndHeight,
+		Title:     title,
+		Link:      link,
+		ImageUrl:  imageUrl,
+		Data:      data,
+	})
+}
+
+// Discuss posts text on proposal and returns once it passed CheckTx.
+func (b *Builder) Discuss(ctx context.Context, proposal uint64, text string) (*coretypes.ResultBroadcastTx, error) {
+	return b.Submit(ctx, tx.HACTxTypeDiscussion, &tx.DiscussionTx{Proposal: proposal, Data: []byte(text)})
+}
+
+// RequestGrant asks the validators to grant the membership of grants and
+// waits for the block including it.
+func (b *Builder) RequestGrant(ctx context.Context, grants ...tx.GrantSt) (*coretypes.ResultBroadcastTxCommit, error) {
+	return b.SubmitCommit(ctx, tx.HACTxTypeGrant, &tx.GrantTx{Grants: grants})
+}
+
+// QueryAccount returns the account of address, or of index without one.
+func QueryAccount(ctx context.Context, cli *comethttp.HTTP, index uint64, address string) (*state.Account, error) {
+	var key []byte
+	if len(address) > 0 {
+		var err error
+		key, err = hex.DecodeString(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %s: %w", address, err)
+		}
+	} else {
+		s := fmt.Sprintf("0%x", index)
+		if len(s)&1 == 1 {
+			s = s[1:]
+		}
+		key, _ = hex.DecodeString(s)
+	}
+	res, err := cli.ABCIQuery(ctx, "/accounts/", key)
+	if err != nil {
+		return nil, err
+	}
+	if res.Response.Code != 0 {
+		return nil, errors.New(res.Response.Log)
+	}
+	var act state.Account
+	if err := act.UnmarshalJSON(res.Response.Value); err != nil {
+		return nil, err
+	}
+	return &act, nil
+}
@@ -0,0 +1,261 @@
+package txbuilder
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cometbft/cometbft/crypto"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	cmtjson "github.com/cometbft/cometbft/libs/json"
+	"github.com/cometbft/cometbft/privval"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Scrypt parameters of the keys written to a keystore.
+const (
+	keystoreScryptN = 1 << 18
+	keystoreScryptR = 8
+	keystoreScryptP = 1
+)
+
+var (
+	ErrKeyNotFound   = errors.New("key not found")
+	ErrKeyExists     = errors.New("key already exists")
+	ErrBadPassphrase = errors.New("wrong passphrase")
+)
+
+var keyNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Key is a private key of a keystore, it signs txs for its account.
+type Key struct {
+	Name    string
+	privKey crypto.PrivKey
+}
+
+var _ Signer = &Key{}
+
+func (k *Key) Address() string {
+	return k.privKey.PubKey().Address().String()
+}
+
+func (k *Key) PublicKey() []byte {
+	return k.privKey.PubKey().Bytes()
+}
+
+func (k *Key) Sign(data []byte) ([]byte, error) {
+	return k.privKey.Sign(data)
+}
+
+// keyFile is a key as written to the keystore, its private key encrypted
+// with a secretbox keyed by the scrypt hash of the passphrase.
+type keyFile struct {
+	Name       string `json:"name"`
+	Address    string `json:"address"`
+	PubKey     string `json:"pub_key"`
+	KDF        string `json:"kdf"`
+	Salt       string `json:"salt"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// KeyInfo describes a key of a keystore without decrypting it.
+type KeyInfo struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	PubKey  string `json:"pub_key"`
+}
+
+// Keystore keeps encrypted ed25519 keys as one json file per key in Dir.
+type Keystore struct {
+	Dir string
+}
+
+func NewKeystore(dir string) (*Keystore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Keystore{Dir: dir}, nil
+}
+
+func (ks *Keystore) path(name string) string {
+	return filepath.Join(ks.Dir, name+".json")
+}
+
+// Add generates a new key named name encrypted with passphrase.
+func (ks *Keystore) Add(name string, passphrase string) (KeyInfo, error) {
+	return ks.Import(name, ed25519.GenPrivKey(), passphrase)
+}
+
+// Import stores priv as name encrypted with passphrase.
+func (ks *Keystore) Import(name string, priv crypto.PrivKey, passphrase string) (KeyInfo, error) {
+	if !keyNamePattern.MatchString(name) {
+		return KeyInfo{}, fmt.Errorf("invalid key name %q, use letters, digits, dot, dash and underscore", name)
+	}
+	if _, err := os.Stat(ks.path(name)); err == nil {
+		return KeyInfo{}, fmt.Errorf("%w: %s", ErrKeyExists, name)
+	}
+	raw, err := cmtjson.Marshal(priv)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return KeyInfo{}, err
+	}
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return KeyInfo{}, err
+	}
+	secret, err := scryptKey(passphrase, salt, keystoreScryptN, keystoreScryptR, keystoreScryptP)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	f := keyFile{
+		Name:       name,
+		Address:    priv.PubKey().Address().String(),
+		PubKey:     hex.EncodeToString(priv.PubKey().Bytes()),
+		KDF:        "scrypt",
+		Salt:       hex.EncodeToString(salt),
+		N:          keystoreScryptN,
+		R:          keystoreScryptR,
+		P:          keystoreScryptP,
+		Nonce:      hex.EncodeToString(nonce[:]),
+		Ciphertext: hex.EncodeToString(secretbox.Seal(nil, raw, &nonce, &secret)),
+	}
+	dat, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	if err := os.WriteFile(ks.path(name), dat, 0o600); err != nil {
+		return KeyInfo{}, err
+	}
+	return KeyInfo{Name: f.Name, Address: f.Address, PubKey: f.PubKey}, nil
+}
+
+// ImportMnemonic stores the key of account derived from mnemonic and its
+// passphrase bip39Passphrase, see KeyFromMnemonic.
+func (ks *Keystore) ImportMnemonic(name string, mnemonic string, bip39Passphrase string, account uint32, passphrase string) (KeyInfo, error) {
+	priv, err := KeyFromMnemonic(mnemonic, bip39Passphrase, account)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	return ks.Import(name, priv, passphrase)
+}
+
+// ImportPV stores the key of the priv_validator_key.json file path.
+func (ks *Keystore) ImportPV(name string, path string, passphrase string) (KeyInfo, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return KeyInfo{}, err
+	}
+	var pvKey privval.FilePVKey
+	if err := cmtjson.Unmarshal(dat, &pvKey); err != nil {
+		return KeyInfo{}, fmt.Errorf("read validator key %s: %w", path, err)
+	}
+	return ks.Import(name, pvKey.PrivKey, passphrase)
+}
+
+// Load decrypts the key name with passphrase.
+func (ks *Keystore) Load(name string, passphrase string) (*Key, error) {
+	f, err := ks.read(name)
+	if err != nil {
+		return nil, err
+	}
+	if f.KDF != "scrypt" {
+		return nil, fmt.Errorf("key %s: unsupported kdf %q", name, f.KDF)
+	}
+	salt, err := hex.DecodeString(f.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", name, err)
+	}
+	nonceBytes, err := hex.DecodeString(f.Nonce)
+	if err != nil || len(nonceBytes) != 24 {
+		return nil, fmt.Errorf("key %s: invalid nonce", name)
+	}
+	ciphertext, err := hex.DecodeString(f.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("key %s: %w", name, err)
+	}
+	secret, err := scryptKey(passphrase, salt, f.N, f.R, f.P)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [24]byte
+	copy(nonce[:], nonceBytes)
+	raw, ok := secretbox.Open(nil, ciphertext, &nonce, &secret)
+	if !ok {
+		return nil, ErrBadPassphrase
+	}
+	var priv crypto.PrivKey
+	if err := cmtjson.Unmarshal(raw, &priv); err != nil {
+		return nil, fmt.Errorf("key %s: %w", name, err)
+	}
+	return &Key{Name: name, privKey: priv}, nil
+}
+
+// List describes the keys of the keystore by name.
+func (ks *Keystore) List() ([]KeyInfo, error) {
+	entries, err := os.ReadDir(ks.Dir)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]KeyInfo, 0, len(entries))
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !ok {
+			continue
+		}
+		f, err := ks.read(name)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, KeyInfo{Name: f.Name, Address: f.Address, PubKey: f.PubKey})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys, nil
+}
+
+// Delete removes the key name.
+func (ks *Keystore) Delete(name string) error {
+	if _, err := ks.read(name); err != nil {
+		return err
+	}
+	return os.Remove(ks.path(name))
+}
+
+func (ks *Keystore) read(name string) (keyFile, error) {
+	dat, err := os.ReadFile(ks.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return keyFile{}, fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	if err != nil {
+		return keyFile{}, err
+	}
+	var f keyFile
+	if err := json.Unmarshal(dat, &f); err != nil {
+		return keyFile{}, fmt.Errorf("key %s: %w", name, err)
+	}
+	return f, nil
+}
+
+func scryptKey(passphrase string, salt []byte, n, r, p int) ([32]byte, error) {
+	var secret [32]byte
+	k, err := scrypt.Key([]byte(passphrase), salt, n, r, p, len(secret))
+	if err != nil {
+		return secret, err
+	}
+	copy(secret[:], k)
+	return secret, nil
+}
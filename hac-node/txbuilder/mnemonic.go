@@ -0,0 +1,50 @@
+package txbuilder
+
+import (
+	stded25519 "crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// MnemonicCoinType is the coin type of the derivation path of the keys
+// imported from a mnemonic, the one of cosmos chains.
+const MnemonicCoinType = 118
+
+const hardened = 0x80000000
+
+// KeyFromMnemonic derives the ed25519 key of account from a bip39 mnemonic
+// and its passphrase, following slip-0010 on the path
+// m/44'/118'/account'/0'/0'. The words are not checked against the bip39
+// wordlist, only their count, so a typo derives another key.
+func KeyFromMnemonic(mnemonic string, bip39Passphrase string, account uint32) (ed25519.PrivKey, error) {
+	words := strings.Fields(mnemonic)
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return nil, fmt.Errorf("mnemonic has %d words, expected 12, 15, 18, 21 or 24", len(words))
+	}
+	seed := pbkdf2.Key([]byte(strings.Join(words, " ")), []byte("mnemonic"+bip39Passphrase), 2048, 64, sha512.New)
+
+	mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chain := sum[:32], sum[32:]
+	for _, index := range []uint32{44, MnemonicCoinType, account, 0, 0} {
+		// ed25519 only derives hardened children
+		data := make([]byte, 0, 37)
+		data = append(data, 0)
+		data = append(data, key...)
+		data = binary.BigEndian.AppendUint32(data, index|hardened)
+		mac := hmac.New(sha512.New, chain)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+		key, chain = sum[:32], sum[32:]
+	}
+	return ed25519.PrivKey(stded25519.NewKeyFromSeed(key)), nil
+}
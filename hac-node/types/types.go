@@ -16,6 +16,7 @@ const (
 	EventProposalType        = "proposal"
 	EventSettleProposalType  = "settle_proposal"
 	EventDiscussionType      = "discussion"
+	EventAnchorType          = "anchor"
 )
 
 type EventUnStake struct {
@@ -200,6 +201,54 @@ func DecodeEventDiscussion(originEvent abci.Event) *EventDiscussion {
 	return event
 }
 
+type EventAnchor struct {
+	Validator uint64 `json:"validatorIndex"`
+	Address   string `json:"address"`
+	Root      []byte `json:"root"`
+	Leaves    uint64 `json:"leaves"`
+}
+
+func EncodeEventAnchor(event *EventAnchor) abci.Event {
+	return abci.Event{
+		Type: EventAnchorType,
+		Attributes: []abci.EventAttribute{
+			{Key: "validator", Value: fmt.Sprintf("%v", event.Validator), Index: true},
+			{Key: "address", Value: event.Address, Index: false},
+			{Key: "root", Value: hex.EncodeToString(event.Root), Index: true},
+			{Key: "leaves", Value: fmt.Sprintf("%v", event.Leaves), Index: false},
+		},
+	}
+}
+
+func DecodeEventAnchor(originEvent abci.Event) *EventAnchor {
+	event := &EventAnchor{}
+	for _, v := range originEvent.Attributes {
+		switch v.Key {
+		case "validator":
+			validator, err := strconv.ParseUint(v.Value, 10, 64)
+			if err != nil {
+				return nil
+			}
+			event.Validator = validator
+		case "address":
+			event.Address = v.Value
+		case "root":
+			root, err := hex.DecodeString(v.Value)
+			if err != nil {
+				return nil
+			}
+			event.Root = root
+		case "leaves":
+			leaves, err := strconv.ParseUint(v.Value, 10, 64)
+			if err != nil {
+				return nil
+			}
+			event.Leaves = leaves
+		}
+	}
+	return event
+}
+
 func EncodeEventUpdateValiators(event *EventUpdateValiators) abci.Event {
 	pks := make([]string, len(event.Updates))
 	powers := make([]string, len(event.Updates))
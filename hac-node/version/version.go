@@ -0,0 +1,49 @@
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// GitCommit and BuildDate are set at build time, see the Makefile:
+//
+//	-ldflags "-X github.com/calehh/hac-app/version.GitCommit=... -X github.com/calehh/hac-app/version.BuildDate=..."
+var (
+	GitCommit string
+	BuildDate string
+)
+
+const (
+	Major = 0
+	Minor = 0
+	Patch = 1
+)
+
+var Version = fmt.Sprintf("%d.%d.%d", Major, Minor, Patch)
+
+// Info is the build of the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+func Get() Info {
+	return Info{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// WithCommit returns the version suffixed with the short commit hash, if
+// known.
+func WithCommit() string {
+	vsn := Version
+	if len(GitCommit) >= 8 {
+		vsn += "-" + GitCommit[:8]
+	}
+	return vsn
+}